@@ -3,12 +3,35 @@ package config
 import (
 	"github.com/spf13/viper"
 	"log"
+	"time"
 )
 
 type Config struct {
-	ServerAddress    string `mapstructure:"SERVER_ADDRESS"`
-	RestaurantsTable string `mapstructure:"RESTAURANTS_TABLE"`
-	PlaceIndex       string `mapstructure:"PLACE_INDEX"`
+	ServerAddress     string        `mapstructure:"SERVER_ADDRESS"`
+	RestaurantsTable  string        `mapstructure:"RESTAURANTS_TABLE"`
+	PlaceIndex        string        `mapstructure:"PLACE_INDEX"`
+	DeletedRetention  time.Duration `mapstructure:"DELETED_RETENTION"`
+	IdempotencyTable  string        `mapstructure:"IDEMPOTENCY_TABLE"`
+	IdempotencyTTL    time.Duration `mapstructure:"IDEMPOTENCY_TTL"`
+	ReviewsTable      string        `mapstructure:"REVIEWS_TABLE"`
+	ReservationsTable string        `mapstructure:"RESERVATIONS_TABLE"`
+	HistoryTable      string        `mapstructure:"HISTORY_TABLE"`
+	DuplicateDistance float64       `mapstructure:"DUPLICATE_DISTANCE"`
+	JWKSSource        string        `mapstructure:"JWKS_SOURCE"`
+	JWTIssuer         string        `mapstructure:"JWT_ISSUER"`
+	JWTAudience       string        `mapstructure:"JWT_AUDIENCE"`
+	AdminRole         string        `mapstructure:"ADMIN_ROLE"`
+	ApiKeysTable      string        `mapstructure:"API_KEYS_TABLE"`
+	ApiKeyRateLimit   float64       `mapstructure:"API_KEY_RATE_LIMIT"`
+	ApiKeyBurst       int           `mapstructure:"API_KEY_BURST"`
+	ApiKeyDailyQuota  int           `mapstructure:"API_KEY_DAILY_QUOTA"`
+	PhotoStore        string        `mapstructure:"PHOTO_STORE"`
+	PhotoDir          string        `mapstructure:"PHOTO_DIR"`
+	PhotoBucket       string        `mapstructure:"PHOTO_BUCKET"`
+	PhotoBaseURL      string        `mapstructure:"PHOTO_BASE_URL"`
+	TagsTable         string        `mapstructure:"TAGS_TABLE"`
+	TagMembersTable   string        `mapstructure:"TAG_MEMBERS_TABLE"`
+	WebhooksTable     string        `mapstructure:"WEBHOOKS_TABLE"`
 }
 
 // Init reads configuration from file or environment variables.
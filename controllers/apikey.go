@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/ratelimit"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ApiKeyHeader carries the API key of a partner
+	ApiKeyHeader = "X-API-Key"
+
+	// An API key is apiKeyPrefix, the ID of the key, an underscore and the
+	// secret, so the key can be found without storing it
+	apiKeyPrefix = "rk_"
+	secretSize   = 32
+
+	// apiKeySubject prefixes the ID of a key in the principal of its
+	// requests, so keys and users cannot be confused as owners
+	apiKeySubject = "apikey:"
+)
+
+// Defaults of the limits of the API keys, used when none are configured
+const (
+	defaultRateLimit  = 10
+	defaultBurst      = 20
+	defaultDailyQuota = 10000
+)
+
+type ApiKeyStorer interface {
+	SaveApiKey(apiKey model.ApiKey, secretHash string) error
+	GetApiKey(keyId string) (model.ApiKey, string, bool, error)
+	RevokeApiKey(tenant, keyId string) (model.ApiKey, error)
+	CountUsage(keyId string, now time.Time, quota int) (int, error)
+}
+
+type ApiKey struct {
+	ApiKey ApiKeyStorer
+	// Limiter holds the token buckets of the keys. It is shared by every
+	// route, so a key has one rate limit.
+	Limiter *ratelimit.Limiter
+	// RateLimit, Burst and DailyQuota are the limits of the keys issued
+	// without limits
+	RateLimit  float64
+	Burst      int
+	DailyQuota int
+}
+
+// Issue creates an API key. The key is only returned in the response, as
+// only the hash of its secret is stored.
+func (a ApiKey) Issue(c *gin.Context) {
+	var request model.ApiKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		bindError(c, err, "error binding request body")
+		return
+	}
+
+	if errs := validate.ApiKeyRequest(&request); len(errs) > 0 {
+		detail := "the API key request is not valid"
+		writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &errs})
+		return
+	}
+
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		respondError(c, err)
+		return
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	apiKey := model.ApiKey{
+		Id:         uuid.NewString(),
+		Name:       request.Name,
+		RateLimit:  a.rateLimit(),
+		Burst:      a.burst(),
+		DailyQuota: a.dailyQuota(),
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	if request.RateLimit != nil {
+		apiKey.RateLimit = *request.RateLimit
+	}
+	if request.Burst != nil {
+		apiKey.Burst = *request.Burst
+	}
+	if request.DailyQuota != nil {
+		apiKey.DailyQuota = *request.DailyQuota
+	}
+	// The key can only access the restaurants of the tenant that issued it
+	if keyTenant := tenant(c); keyTenant != dynamo.DefaultTenant {
+		apiKey.Tenant = &keyTenant
+	}
+
+	log.Printf("ApiKey.Issue tenant: %s  keyId: %s  name: %s\n", tenant(c), apiKey.Id, apiKey.Name)
+
+	if err := a.ApiKey.SaveApiKey(apiKey, hashSecret(encodedSecret)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	key := apiKeyPrefix + apiKey.Id + "_" + encodedSecret
+	apiKey.Key = &key
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// Revoke revokes an API key of the tenant, which is rejected from then
+// on. The keys of the other tenants are not found.
+func (a ApiKey) Revoke(c *gin.Context) {
+	keyId := c.Param("keyId")
+
+	log.Printf("ApiKey.Revoke tenant: %s  keyId: %s\n", tenant(c), keyId)
+
+	apiKey, err := a.ApiKey.RevokeApiKey(tenant(c), keyId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKey)
+}
+
+// Authenticate is a middleware that authenticates the requests with an
+// X-API-Key header, and limits their rate and number per day. The requests
+// without the header are passed on, and the routes that change restaurants
+// still require a principal. An unknown or revoked key is rejected with
+// 401. A key over its limits is rejected with 429 and the Retry-After
+// header. The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers describe the limit closest to being reached.
+func (a ApiKey) Authenticate(c *gin.Context) {
+	key := c.GetHeader(ApiKeyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	keyId, secret, ok := parseApiKey(key)
+	if !ok || a.ApiKey == nil {
+		problem(c, http.StatusUnauthorized, "the API key is not valid")
+		return
+	}
+
+	apiKey, secretHash, exists, err := a.ApiKey.GetApiKey(keyId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !exists || apiKey.RevokedAt != nil ||
+		subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(secretHash)) != 1 {
+		problem(c, http.StatusUnauthorized, "the API key is not valid")
+		return
+	}
+
+	// A key of another tenant is rejected before it counts against the limits
+	keyTenant := dynamo.DefaultTenant
+	if apiKey.Tenant != nil {
+		keyTenant = *apiKey.Tenant
+	}
+	if !setPrincipal(c, principal{subject: apiKeySubject + keyId, tenant: keyTenant}) {
+		return
+	}
+
+	now := time.Now()
+	var rate ratelimit.Result
+	if a.Limiter != nil {
+		rate = a.Limiter.Take(keyId, apiKey.RateLimit, apiKey.Burst, now)
+		if !rate.Allowed {
+			limitHeaders{limit: rate.Limit, reset: rate.Reset}.set(c)
+			tooManyRequests(c, rate.RetryAfter, "the rate limit of the API key is reached")
+			return
+		}
+	}
+
+	count, err := a.ApiKey.CountUsage(keyId, now, apiKey.DailyQuota)
+	if errors.Is(err, dynamo.ErrQuotaExceeded) {
+		reset := untilMidnight(now)
+		limitHeaders{limit: apiKey.DailyQuota, reset: reset}.set(c)
+		tooManyRequests(c, reset, "the daily quota of the API key is reached")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	headers := limitHeaders{limit: apiKey.DailyQuota, remaining: apiKey.DailyQuota - count, reset: untilMidnight(now)}
+	if a.Limiter != nil && rate.Remaining < headers.remaining {
+		headers = limitHeaders{limit: rate.Limit, remaining: rate.Remaining, reset: rate.Reset}
+	}
+	headers.set(c)
+
+	c.Next()
+}
+
+// limitHeaders are the RateLimit header fields of a limit (IETF draft
+// "RateLimit header fields for HTTP").
+type limitHeaders struct {
+	limit     int
+	remaining int
+	reset     time.Duration
+}
+
+func (h limitHeaders) set(c *gin.Context) {
+	c.Header("RateLimit-Limit", strconv.Itoa(h.limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(h.remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(h.reset)))
+}
+
+func tooManyRequests(c *gin.Context, retryAfter time.Duration, detail string) {
+	seconds := ceilSeconds(retryAfter)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	problem(c, http.StatusTooManyRequests, detail)
+}
+
+// parseApiKey returns the ID and the secret of an API key.
+func parseApiKey(key string) (string, string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", "", false
+	}
+	keyId, secret, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !found || secret == "" {
+		return "", "", false
+	}
+	if _, err := uuid.Parse(keyId); err != nil {
+		return "", "", false
+	}
+	return keyId, secret, true
+}
+
+// hashSecret returns the hash of the secret of a key. The secret is random,
+// so a fast hash is enough.
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// untilMidnight returns the time until the daily quotas are reset, at
+// midnight UTC.
+func untilMidnight(now time.Time) time.Duration {
+	now = now.UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func (a ApiKey) rateLimit() float64 {
+	if a.RateLimit > 0 {
+		return a.RateLimit
+	}
+	return defaultRateLimit
+}
+
+func (a ApiKey) burst() int {
+	if a.Burst > 0 {
+		return a.Burst
+	}
+	return defaultBurst
+}
+
+func (a ApiKey) dailyQuota() int {
+	if a.DailyQuota > 0 {
+		return a.DailyQuota
+	}
+	return defaultDailyQuota
+}
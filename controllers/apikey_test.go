@@ -0,0 +1,410 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testKeyId  = "0b6f6a46-3c1e-4a3e-9f0e-6d2b1f0c8a11"
+	testSecret = "c2VjcmV0"
+	testKey    = apiKeyPrefix + testKeyId + "_" + testSecret
+)
+
+func Test_ApiKeyIssue(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		body         string
+		tenant       string
+		apiKey       model.ApiKey
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "default limits",
+			body:         `{"name":" Partner "}`,
+			apiKey:       model.ApiKey{Name: "Partner", RateLimit: 5, Burst: 10, DailyQuota: 100},
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "limits of the key",
+			body:         `{"name":"Partner","rateLimit":0.5,"burst":2,"dailyQuota":50}`,
+			apiKey:       model.ApiKey{Name: "Partner", RateLimit: 0.5, Burst: 2, DailyQuota: 50},
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "key of the tenant",
+			body:         `{"name":"Partner"}`,
+			tenant:       "brand-a",
+			apiKey:       model.ApiKey{Name: "Partner", RateLimit: 5, Burst: 10, DailyQuota: 100, Tenant: strPtr("brand-a")},
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "invalid request",
+			body:         `{"name":"","burst":0}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the API key request is not valid","errors":[{"code":"required","field":"name","message":"name is required"},{"code":"out_of_range","field":"burst","message":"burst must be between 1 and 10000"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "empty request body",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			body:         `{"name":"Partner"}`,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := &apiKeyStorerStub{error: tc.stubError}
+			a := ApiKey{ApiKey: stub, RateLimit: 5, Burst: 10, DailyQuota: 100}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/apikeys", bytes.NewBufferString(tc.body))
+			c.Set(tenantKey, tc.tenant)
+			c.Set(principalKey, principal{subject: "admin", admin: true})
+
+			a.Issue(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusCreated {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				return
+			}
+
+			var apiKey model.ApiKey
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &apiKey))
+			assert.Equal(t, tc.apiKey.Name, apiKey.Name)
+			assert.Equal(t, tc.apiKey.RateLimit, apiKey.RateLimit)
+			assert.Equal(t, tc.apiKey.Burst, apiKey.Burst)
+			assert.Equal(t, tc.apiKey.DailyQuota, apiKey.DailyQuota)
+			assert.Equal(t, tc.apiKey.Tenant, apiKey.Tenant)
+			assert.Equal(t, tc.apiKey.Tenant, stub.apiKey.Tenant)
+
+			// The key is returned, and only the hash of its secret is stored
+			if assert.NotNil(t, apiKey.Key) {
+				keyId, secret, ok := parseApiKey(*apiKey.Key)
+				assert.True(t, ok)
+				assert.Equal(t, apiKey.Id, keyId)
+				assert.Equal(t, hashSecret(secret), stub.secretHash)
+				assert.Nil(t, stub.apiKey.Key)
+			}
+		})
+	}
+}
+
+func Test_ApiKeyRevoke(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			responseCode: http.StatusOK,
+			responseBody: `{"burst":20,"createdAt":"2026-10-17T12:00:00Z","dailyQuota":100,"id":"` + testKeyId + `","name":"Partner","rateLimit":10,"revokedAt":"2026-10-18T08:00:00Z"}`,
+		},
+		{
+			name:         "key does not exist",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"API key not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrApiKeyNotFound.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			revokedAt := time.Date(2026, time.October, 18, 8, 0, 0, 0, time.UTC)
+			apiKey := testApiKey()
+			apiKey.RevokedAt = &revokedAt
+			a := ApiKey{ApiKey: &apiKeyStorerStub{apiKey: apiKey, error: tc.stubError}}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodDelete, "/apikeys/"+testKeyId, nil)
+			c.Params = []gin.Param{{Key: "keyId", Value: testKeyId}}
+
+			a.Revoke(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_ApiKeyAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	revokedAt := time.Date(2026, time.October, 18, 8, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		key           string
+		keyTenant     *string
+		tenant        string
+		revokedAt     *time.Time
+		notExist      bool
+		count         int
+		stubError     string
+		requests      int
+		responseCode  int
+		responseBody  string
+		rateLimit     string
+		retryAfter    bool
+		authenticated bool
+	}{
+		{
+			name:          "happy path",
+			key:           testKey,
+			count:         1,
+			requests:      1,
+			responseCode:  http.StatusOK,
+			rateLimit:     "2 1",
+			authenticated: true,
+		},
+		{
+			name:          "quota closest to being reached",
+			key:           testKey,
+			count:         100,
+			requests:      1,
+			responseCode:  http.StatusOK,
+			rateLimit:     "100 0",
+			authenticated: true,
+		},
+		{
+			name:          "key of the tenant",
+			key:           testKey,
+			keyTenant:     strPtr("brand-a"),
+			tenant:        "brand-a",
+			count:         1,
+			requests:      1,
+			responseCode:  http.StatusOK,
+			rateLimit:     "2 1",
+			authenticated: true,
+		},
+		{
+			name:         "key of another tenant",
+			key:          testKey,
+			keyTenant:    strPtr("brand-a"),
+			tenant:       "brand-b",
+			requests:     1,
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"the principal does not belong to the tenant","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "no key",
+			requests:     1,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "malformed key",
+			key:          "not a key",
+			requests:     1,
+			responseCode: http.StatusUnauthorized,
+			responseBody: `{"detail":"the API key is not valid","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:         "unknown key",
+			key:          testKey,
+			notExist:     true,
+			requests:     1,
+			responseCode: http.StatusUnauthorized,
+			responseBody: `{"detail":"the API key is not valid","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:         "wrong secret",
+			key:          apiKeyPrefix + testKeyId + "_d3Jvbmc",
+			requests:     1,
+			responseCode: http.StatusUnauthorized,
+			responseBody: `{"detail":"the API key is not valid","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:         "revoked key",
+			key:          testKey,
+			revokedAt:    &revokedAt,
+			requests:     1,
+			responseCode: http.StatusUnauthorized,
+			responseBody: `{"detail":"the API key is not valid","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:         "rate limit reached",
+			key:          testKey,
+			count:        1,
+			requests:     3,
+			responseCode: http.StatusTooManyRequests,
+			responseBody: `{"detail":"the rate limit of the API key is reached","status":429,"title":"Too Many Requests","type":"about:blank"}`,
+			rateLimit:    "2 0",
+			retryAfter:   true,
+		},
+		{
+			name:         "daily quota reached",
+			key:          testKey,
+			stubError:    dynamo.ErrQuotaExceeded.Error(),
+			requests:     1,
+			responseCode: http.StatusTooManyRequests,
+			responseBody: `{"detail":"the daily quota of the API key is reached","status":429,"title":"Too Many Requests","type":"about:blank"}`,
+			rateLimit:    "100 0",
+			retryAfter:   true,
+		},
+		{
+			name:         "storage error",
+			key:          testKey,
+			stubError:    "an error occurred",
+			requests:     1,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiKey := testApiKey()
+			apiKey.RevokedAt = tc.revokedAt
+			apiKey.Tenant = tc.keyTenant
+			apiKey.RateLimit, apiKey.Burst = 1, 2
+			stub := &apiKeyStorerStub{apiKey: apiKey, secretHash: hashSecret(testSecret), notExist: tc.notExist, count: tc.count, usageError: tc.stubError}
+			a := ApiKey{ApiKey: stub, Limiter: ratelimit.New()}
+
+			router := gin.New()
+			router.Use(Tenant, a.Authenticate)
+			router.GET("/", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"owner": owner(c)})
+			})
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < tc.requests; i++ {
+				w = httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tc.key != "" {
+					r.Header.Set(ApiKeyHeader, tc.key)
+				}
+				if tc.tenant != "" {
+					r.Header.Set(TenantHeader, tc.tenant)
+				}
+				router.ServeHTTP(w, r)
+			}
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseBody != "" {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+			}
+			if tc.authenticated {
+				assert.Equal(t, `{"owner":"apikey:`+testKeyId+`"}`, w.Body.String())
+			}
+			if tc.rateLimit != "" {
+				assert.Equal(t, tc.rateLimit, w.Header().Get("RateLimit-Limit")+" "+w.Header().Get("RateLimit-Remaining"))
+				assert.NotEmpty(t, w.Header().Get("RateLimit-Reset"))
+			}
+			if tc.retryAfter {
+				assert.NotEmpty(t, w.Header().Get("Retry-After"))
+			} else {
+				assert.Empty(t, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func Test_ParseApiKey(t *testing.T) {
+	t.Parallel()
+
+	keyId, secret, ok := parseApiKey(testKey)
+	assert.True(t, ok)
+	assert.Equal(t, testKeyId, keyId)
+	assert.Equal(t, testSecret, secret)
+
+	for _, key := range []string{"", testKeyId + "_" + testSecret, apiKeyPrefix + testKeyId, apiKeyPrefix + testKeyId + "_", apiKeyPrefix + "keyId_" + testSecret} {
+		_, _, ok := parseApiKey(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func Test_UntilMidnight(t *testing.T) {
+	t.Parallel()
+
+	// The quotas are reset at midnight UTC
+	now := time.Date(2026, time.October, 17, 16, 30, 0, 0, time.FixedZone("", -7*3600))
+	assert.Equal(t, 30*time.Minute, untilMidnight(now))
+}
+
+func testApiKey() model.ApiKey {
+	return model.ApiKey{
+		Id:         testKeyId,
+		Name:       "Partner",
+		RateLimit:  10,
+		Burst:      20,
+		DailyQuota: 100,
+		CreatedAt:  time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+type apiKeyStorerStub struct {
+	apiKey     model.ApiKey
+	secretHash string
+	notExist   bool
+	count      int
+	error      string
+	// usageError is only returned by CountUsage
+	usageError string
+}
+
+func (s *apiKeyStorerStub) SaveApiKey(apiKey model.ApiKey, secretHash string) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	s.apiKey, s.secretHash = apiKey, secretHash
+	return nil
+}
+
+func (s *apiKeyStorerStub) GetApiKey(_ string) (model.ApiKey, string, bool, error) {
+	if s.error != "" {
+		return model.ApiKey{}, "", false, stubErr(s.error)
+	}
+	if s.notExist {
+		return model.ApiKey{}, "", false, nil
+	}
+	return s.apiKey, s.secretHash, true, nil
+}
+
+func (s *apiKeyStorerStub) RevokeApiKey(_, _ string) (model.ApiKey, error) {
+	if s.error != "" {
+		return model.ApiKey{}, stubErr(s.error)
+	}
+	return s.apiKey, nil
+}
+
+func (s *apiKeyStorerStub) CountUsage(_ string, _ time.Time, _ int) (int, error) {
+	if s.usageError != "" {
+		return 0, stubErr(s.usageError)
+	}
+	return s.count, nil
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/auth"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// principalKey is the context key of the authenticated principal
+const principalKey = "principal"
+
+type TokenVerifier interface {
+	Verify(token string) (auth.Claims, error)
+}
+
+type Auth struct {
+	Verifier TokenVerifier
+	// AdminRole is the role that can modify every restaurant
+	AdminRole string
+}
+
+// principal is the user authenticated by the bearer token of the request.
+// tenant is empty when the user does not belong to a tenant.
+type principal struct {
+	subject string
+	admin   bool
+	tenant  string
+}
+
+// Authenticate is a middleware that requires a valid bearer token and
+// records the user it was issued to, who becomes the owner of the
+// restaurants it creates. A request already authenticated by its API key
+// is passed on. A missing or invalid token is rejected with 401, and the
+// reason is only logged. Without a verifier, no token can be verified.
+func (a Auth) Authenticate(c *gin.Context) {
+	if _, ok := c.Get(principalKey); ok {
+		c.Next()
+		return
+	}
+	if !a.authenticate(c) {
+		return
+	}
+	c.Next()
+}
+
+// Identify is a middleware that authenticates the requests with a bearer
+// token like Authenticate, and lets the others through anonymously, for
+// the routes that accept another credential too.
+func (a Auth) Identify(c *gin.Context) {
+	if _, ok := c.Get(principalKey); ok || c.GetHeader("Authorization") == "" {
+		c.Next()
+		return
+	}
+	if !a.authenticate(c) {
+		return
+	}
+	c.Next()
+}
+
+// authenticate verifies the bearer token of the request and records its
+// principal. The request is rejected, and false returned, when it cannot.
+func (a Auth) authenticate(c *gin.Context) bool {
+	token, ok := bearerToken(c.GetHeader("Authorization"))
+	if !ok {
+		unauthorized(c, "a bearer token is required")
+		return false
+	}
+	if a.Verifier == nil {
+		unauthorized(c, "the bearer token is not valid")
+		return false
+	}
+
+	claims, err := a.Verifier.Verify(token)
+	if err != nil {
+		log.Printf("Auth.Authenticate requestId: %s  error: %s\n", c.GetString(requestIdKey), err)
+		unauthorized(c, "the bearer token is not valid")
+		return false
+	}
+
+	p := principal{
+		subject: claims.Subject,
+		admin:   a.AdminRole != "" && claims.HasRole(a.AdminRole),
+		tenant:  claims.Tenant,
+	}
+	return setPrincipal(c, p)
+}
+
+// RequireAdmin is a middleware that only lets the admins through, after
+// Authenticate. The other principals are rejected with 403.
+func (a Auth) RequireAdmin(c *gin.Context) {
+	p, ok := c.Get(principalKey)
+	if !ok || !p.(principal).admin {
+		problem(c, http.StatusForbidden, "only an admin can do this")
+		return
+	}
+	c.Next()
+}
+
+// bearerToken returns the token of an Authorization header with the Bearer
+// scheme, which is case-insensitive.
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", `Bearer`)
+	problem(c, http.StatusUnauthorized, detail)
+}
+
+// actor identifies who makes a change, for the owner check and the revision
+// history. It is the authenticated user, or the client IP address when the
+// request was not authenticated, which owns no restaurant.
+func actor(c *gin.Context) dynamo.Actor {
+	if p, ok := c.Get(principalKey); ok {
+		p := p.(principal)
+		return dynamo.Actor{Id: p.subject, Admin: p.admin}
+	}
+	return dynamo.Actor{Id: c.ClientIP()}
+}
+
+// owner returns the authenticated user, who owns the restaurants it
+// creates, or nil when the request was not authenticated.
+func owner(c *gin.Context) *string {
+	p, ok := c.Get(principalKey)
+	if !ok {
+		return nil
+	}
+	subject := p.(principal).subject
+	return &subject
+}
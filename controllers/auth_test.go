@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		authorization string
+		verifier      TokenVerifier
+		principal     *principal
+		responseCode  int
+		responseBody  string
+	}{
+		{
+			name:          "user",
+			authorization: "Bearer user-token",
+			verifier:      tokenVerifierStub{claims: auth.Claims{Subject: "user1", Roles: []string{"editor"}}},
+			responseCode:  http.StatusOK,
+			responseBody:  `{"admin":false,"id":"user1","owner":"user1"}`,
+		},
+		{
+			name:          "admin",
+			authorization: "bearer admin-token",
+			verifier:      tokenVerifierStub{claims: auth.Claims{Subject: "user2", Roles: []string{"admin"}}},
+			responseCode:  http.StatusOK,
+			responseBody:  `{"admin":true,"id":"user2","owner":"user2"}`,
+		},
+		{
+			name:         "authenticated with an API key",
+			principal:    &principal{subject: "apikey:key1"},
+			responseCode: http.StatusOK,
+			responseBody: `{"admin":false,"id":"apikey:key1","owner":"apikey:key1"}`,
+		},
+		{
+			name:         "no token",
+			verifier:     tokenVerifierStub{claims: auth.Claims{Subject: "user1"}},
+			responseCode: http.StatusUnauthorized,
+			responseBody: `{"detail":"a bearer token is required","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:          "other scheme",
+			authorization: "Basic dXNlcjpwYXNz",
+			verifier:      tokenVerifierStub{claims: auth.Claims{Subject: "user1"}},
+			responseCode:  http.StatusUnauthorized,
+			responseBody:  `{"detail":"a bearer token is required","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:          "invalid token",
+			authorization: "Bearer expired-token",
+			verifier:      tokenVerifierStub{error: fmt.Errorf("%w: expired", auth.ErrInvalidToken)},
+			responseCode:  http.StatusUnauthorized,
+			responseBody:  `{"detail":"the bearer token is not valid","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:          "no verifier",
+			authorization: "Bearer user-token",
+			responseCode:  http.StatusUnauthorized,
+			responseBody:  `{"detail":"the bearer token is not valid","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			a := Auth{Verifier: tc.verifier, AdminRole: "admin"}
+
+			router := gin.New()
+			if tc.principal != nil {
+				router.Use(func(c *gin.Context) {
+					c.Set(principalKey, *tc.principal)
+				})
+			}
+			router.POST("/", a.Authenticate, func(c *gin.Context) {
+				actor := actor(c)
+				c.JSON(http.StatusOK, gin.H{"id": actor.Id, "admin": actor.Admin, "owner": owner(c)})
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			if tc.responseCode == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func Test_RequireAdmin(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		principal    *principal
+		responseCode int
+		responseBody string
+	}{
+		{
+			name:         "admin",
+			principal:    &principal{subject: "user2", admin: true},
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "user",
+			principal:    &principal{subject: "user1"},
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only an admin can do this","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "not authenticated",
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only an admin can do this","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/apikeys", nil)
+			if tc.principal != nil {
+				c.Set(principalKey, *tc.principal)
+			}
+
+			Auth{}.RequireAdmin(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_Actor(t *testing.T) {
+	t.Parallel()
+
+	// A request that was not authenticated is made by its client IP
+	// address and owns no restaurant
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	assert.Equal(t, "192.0.2.1", actor(c).Id)
+	assert.False(t, actor(c).Admin)
+	assert.Nil(t, owner(c))
+}
+
+type tokenVerifierStub struct {
+	claims auth.Claims
+	error  error
+}
+
+func (s tokenVerifierStub) Verify(_ string) (auth.Claims, error) {
+	if s.error != nil {
+		return auth.Claims{}, s.error
+	}
+	return s.claims, nil
+}
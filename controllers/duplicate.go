@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/duplicate"
+	"github.com/lfroomin/restaurant-container/internal/geo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+	"net/http"
+)
+
+const (
+	// defaultDuplicateDistance is used when no duplicate distance is configured
+	defaultDuplicateDistance = 100
+	maxDuplicateDistance     = 1000
+)
+
+// Duplicates scans the restaurants and reports the groups of restaurants
+// that are probably the same, the largest first.
+func (r Restaurant) Duplicates(c *gin.Context) {
+	var params model.GetDuplicatesParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+
+	distance := r.duplicateDistance()
+	if params.Distance != nil {
+		distance = *params.Distance
+	}
+	if distance < 1 || distance > maxDuplicateDistance {
+		problem(c, http.StatusBadRequest, "distance must be between 1 and 1000")
+		return
+	}
+
+	log.Printf("Restaurant.Duplicates distance: %f\n", distance)
+
+	var restaurants []model.Restaurant
+	token := ""
+	for {
+		page, next, err := r.Restaurant.List(tenant(c), maxLimit, token)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		restaurants = append(restaurants, page...)
+
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	report := model.DuplicateReport{Distance: distance, Clusters: []model.DuplicateCluster{}}
+	for _, cluster := range duplicate.Clusters(restaurants, distance) {
+		report.Clusters = append(report.Clusters, model.DuplicateCluster{Restaurants: cluster})
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// duplicates returns the restaurants of the tenant within the duplicate
+// distance of the restaurant whose names are similar, nearest first. A
+// restaurant that was not geocoded has no duplicates.
+func (r Restaurant) duplicates(tenant string, restaurant model.Restaurant) ([]model.DuplicateCandidate, error) {
+	center, ok := geo.Location(restaurant)
+	if !ok {
+		return nil, nil
+	}
+
+	nearby, err := r.Restaurant.Nearby(tenant, center, r.duplicateDistance())
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []model.DuplicateCandidate
+	for _, n := range nearby {
+		similarity := duplicate.Similarity(restaurant.Name, n.Restaurant.Name)
+		if similarity >= duplicate.MinSimilarity {
+			candidates = append(candidates, model.DuplicateCandidate{
+				Restaurant: n.Restaurant,
+				Distance:   n.Distance,
+				Similarity: similarity,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+func (r Restaurant) duplicateDistance() float64 {
+	if r.DuplicateDistance > 0 {
+		return r.DuplicateDistance
+	}
+	return defaultDuplicateDistance
+}
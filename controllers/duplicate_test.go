@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Duplicates(t *testing.T) {
+	t.Parallel()
+	restId, geocode := "restId", "47.600000,-122.300000"
+	restaurant := model.Restaurant{
+		Id:      &restId,
+		Name:    "Ramen Bar",
+		Address: &model.Address{Location: &model.Location{Geocode: &geocode}},
+	}
+
+	testCases := []struct {
+		name         string
+		query        string
+		pages        int
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			// The stub returns the same restaurant on every page
+			name:         "happy path",
+			pages:        2,
+			responseCode: http.StatusOK,
+			responseBody: `{"clusters":[{"restaurants":[{"address":{"location":{"geocode":"47.600000,-122.300000"}},"id":"restId","name":"Ramen Bar"},{"address":{"location":{"geocode":"47.600000,-122.300000"}},"id":"restId","name":"Ramen Bar"}]}],"distance":100}`,
+		},
+		{
+			name:         "no duplicates",
+			query:        "distance=250",
+			pages:        1,
+			responseCode: http.StatusOK,
+			responseBody: `{"clusters":[],"distance":250}`,
+		},
+		{
+			name:         "distance too large",
+			query:        "distance=1001",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"distance must be between 1 and 1000","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{restaurant: restaurant, pages: tc.pages, error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodGet, "/duplicates?"+tc.query, nil)
+
+			rc.Duplicates(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
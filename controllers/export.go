@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/export"
+	"log"
+	"net/http"
+)
+
+// exportPageSize is the number of restaurants read from storage at a time
+const exportPageSize = 100
+
+// Export streams every restaurant in the format given by the format query
+// parameter (ndjson, csv or geojson). The restaurants are read a page at a
+// time and written as they are read, so the catalog is never held in
+// memory. An error after the first page has been written can only be
+// logged, and truncates the output.
+func (r Restaurant) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", export.NDJSON)
+
+	enc, contentType, err := export.NewEncoder(format, c.Writer)
+	if err != nil {
+		problem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Restaurant.Export format: %s\n", format)
+
+	// The first page is read before the response is started, so a storage
+	// error can still be reported with a status code
+	restaurants, nextToken, err := r.Restaurant.List(tenant(c), exportPageSize, "")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="restaurants.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	for {
+		for _, restaurant := range restaurants {
+			if err := enc.Encode(restaurant); err != nil {
+				log.Printf("Restaurant.Export error encoding restaurant: %s\n", err)
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if nextToken == "" {
+			break
+		}
+
+		restaurants, nextToken, err = r.Restaurant.List(tenant(c), exportPageSize, nextToken)
+		if err != nil {
+			log.Printf("Restaurant.Export error listing restaurants: %s\n", err)
+			return
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		log.Printf("Restaurant.Export error completing export: %s\n", err)
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Export(t *testing.T) {
+	t.Parallel()
+	restId := "Rest1"
+
+	testCases := []struct {
+		name         string
+		query        string
+		pages        int
+		responseCode int
+		responseBody string
+		contentType  string
+		stubError    string
+	}{
+		{
+			name:         "default format",
+			pages:        1,
+			responseCode: http.StatusOK,
+			responseBody: "{\"id\":\"Rest1\",\"name\":\"Rest 1\"}\n",
+			contentType:  "application/x-ndjson",
+		},
+		{
+			name:         "multiple pages",
+			query:        "format=ndjson",
+			pages:        3,
+			responseCode: http.StatusOK,
+			responseBody: "{\"id\":\"Rest1\",\"name\":\"Rest 1\"}\n{\"id\":\"Rest1\",\"name\":\"Rest 1\"}\n{\"id\":\"Rest1\",\"name\":\"Rest 1\"}\n",
+			contentType:  "application/x-ndjson",
+		},
+		{
+			name:         "geojson",
+			query:        "format=geojson",
+			pages:        2,
+			responseCode: http.StatusOK,
+			responseBody: `{"type":"FeatureCollection","features":[{"type":"Feature","id":"Rest1","geometry":null,"properties":{"id":"Rest1","name":"Rest 1"}},{"type":"Feature","id":"Rest1","geometry":null,"properties":{"id":"Rest1","name":"Rest 1"}}]}`,
+			contentType:  "application/geo+json",
+		},
+		{
+			name:         "unknown format",
+			query:        "format=xml",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"format must be ndjson, csv or geojson","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{
+					restaurant: model.Restaurant{Id: &restId, Name: "Rest 1"},
+					pages:      tc.pages,
+					error:      tc.stubError,
+				},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodGet, "/export?"+tc.query, nil)
+
+			rc.Export(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			if tc.contentType != "" {
+				assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type HistoryStorer interface {
+	ListRevisions(tenant, restaurantId string, limit int32, nextToken string) ([]model.Revision, string, error)
+	GetRevision(tenant, restaurantId string, revision int64) (model.Revision, error)
+	Revert(tenant, restaurantId string, revision int64, ifMatch []int64, actor dynamo.Actor) (model.Restaurant, int64, error)
+}
+
+type History struct {
+	History HistoryStorer
+	Index   SearchIndex
+	Events  EventPublisher
+	Tags    TagStorer
+}
+
+// List returns the revisions of the restaurant, the newest first.
+func (h History) List(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var params model.GetRestaurantIdHistoryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+
+	limit := int32(defaultLimit)
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > maxLimit {
+		problem(c, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	var nextToken string
+	if params.NextToken != nil {
+		nextToken = *params.NextToken
+	}
+
+	log.Printf("History.List restaurantId: %s  limit: %d  nextToken: %s\n", restaurantId, limit, nextToken)
+
+	revisions, token, err := h.History.ListRevisions(tenant(c), restaurantId, limit, nextToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resp := model.RevisionList{Revisions: revisions}
+	if token != "" {
+		resp.NextToken = &token
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Revert restores the restaurant to its state after the given revision.
+// The revert is recorded as a new revision, so it can be reverted too.
+func (h History) Revert(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	revision, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+	if err != nil || revision < 1 {
+		problem(c, http.StatusBadRequest, "revision must be a positive integer")
+		return
+	}
+
+	versions, ok := ifMatch(c)
+	if !ok {
+		problem(c, http.StatusPreconditionFailed, dynamo.ErrPreconditionFailed.Error())
+		return
+	}
+
+	log.Printf("History.Revert restaurantId: %s  revision: %d\n", restaurantId, revision)
+
+	target, err := h.History.GetRevision(tenant(c), restaurantId, revision)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !h.validTags(c, target.Current) {
+		return
+	}
+
+	restaurant, version, err := h.History.Revert(tenant(c), restaurantId, revision, versions, actor(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.Index.Add(tenant(c), restaurant)
+	publish(c, h.Events, model.RestaurantUpdated, restaurantId, &restaurant)
+
+	setOpenStatus(&restaurant, time.Now())
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusOK, restaurant)
+}
+
+// validTags reports whether the tags of the restaurant of a revision are
+// in the vocabulary of the tenant, as the vocabulary may have changed since
+// the revision. When they are not, it responds with 422 Unprocessable
+// Entity and the errors of the tags.
+func (h History) validTags(c *gin.Context, restaurant *model.Restaurant) bool {
+	if restaurant == nil || restaurant.Tags == nil || len(*restaurant.Tags) == 0 {
+		return true
+	}
+	vocabulary, err := vocabulary(h.Tags, tenant(c))
+	if err != nil {
+		respondError(c, err)
+		return false
+	}
+	errs := validate.Tags(*restaurant.Tags, vocabulary)
+	if len(errs) == 0 {
+		return true
+	}
+	detail := "the tags of the revision are not valid"
+	writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &errs})
+	return false
+}
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_HistoryList(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		query        string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			responseCode: http.StatusOK,
+			responseBody: `{"revisions":[{"action":"update","actor":"127.0.0.1","changes":[{"field":"name","from":"Ramen","to":"Ramen Bar"}],"current":{"id":"restId","name":"Ramen Bar"},"previous":{"id":"restId","name":"Ramen"},"restaurantId":"restId","revision":2,"timestamp":"2023-06-09T19:00:00Z"}]}`,
+		},
+		{
+			name:         "next page",
+			restaurantId: "restId",
+			query:        "limit=1&nextToken=token",
+			responseCode: http.StatusOK,
+			responseBody: `{"nextToken":"token","revisions":[{"action":"update","actor":"127.0.0.1","changes":[{"field":"name","from":"Ramen","to":"Ramen Bar"}],"current":{"id":"restId","name":"Ramen Bar"},"previous":{"id":"restId","name":"Ramen"},"restaurantId":"restId","revision":2,"timestamp":"2023-06-09T19:00:00Z"}]}`,
+		},
+		{
+			name:         "limit too large",
+			restaurantId: "restId",
+			query:        "limit=101",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"limit must be between 1 and 100","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant has no history",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			hc := History{
+				History: historyStorerStub{error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tc.restaurantId+"/history?"+tc.query, nil)
+
+			hc.List(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_HistoryRevert(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		revision     string
+		ifMatch      string
+		tags         []string
+		added        int32
+		responseCode int
+		responseBody string
+		stubError    string
+		tagError     string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			revision:     "1",
+			added:        1,
+			responseCode: http.StatusOK,
+			responseBody: `{"id":"restId","name":"Ramen"}`,
+		},
+		{
+			name:         "tags of the vocabulary",
+			restaurantId: "restId",
+			revision:     "1",
+			tags:         []string{"thai"},
+			added:        1,
+			responseCode: http.StatusOK,
+			responseBody: `{"id":"restId","name":"Ramen"}`,
+		},
+		{
+			name:         "tag deleted since the revision",
+			restaurantId: "restId",
+			revision:     "1",
+			tags:         []string{"thai", "ramen"},
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the tags of the revision are not valid","errors":[{"code":"unknown_tag","field":"tags[1]","message":"ramen is not a known tag"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "vocabulary error",
+			restaurantId: "restId",
+			revision:     "1",
+			tags:         []string{"thai"},
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			tagError:     "an error occurred",
+		},
+		{
+			name:         "if-match",
+			restaurantId: "restId",
+			revision:     "1",
+			ifMatch:      `"2"`,
+			added:        1,
+			responseCode: http.StatusOK,
+			responseBody: `{"id":"restId","name":"Ramen"}`,
+		},
+		{
+			name:         "weak if-match",
+			restaurantId: "restId",
+			revision:     "1",
+			ifMatch:      `W/"2"`,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid revision",
+			restaurantId: "restId",
+			revision:     "first",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"revision must be a positive integer","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "revision does not exist",
+			restaurantId: "restId",
+			revision:     "9",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"revision not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrRevisionNotFound.Error(),
+		},
+		{
+			name:         "revision deleted the restaurant",
+			restaurantId: "restId",
+			revision:     "3",
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"revision deleted the restaurant","status":409,"title":"Conflict","type":"about:blank"}`,
+			stubError:    dynamo.ErrNothingToRevert.Error(),
+		},
+		{
+			name:         "stale version",
+			restaurantId: "restId",
+			revision:     "1",
+			ifMatch:      `"1"`,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+			stubError:    dynamo.ErrPreconditionFailed.Error(),
+		},
+		{
+			name:         "empty restaurantId",
+			revision:     "1",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			revision:     "1",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			index := searchIndexStub{added: new(int32)}
+			hc := History{
+				History: historyStorerStub{tags: tc.tags, error: tc.stubError},
+				Index:   index,
+				Tags:    tagStorerStub{tags: []model.Tag{{Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"}}, error: tc.tagError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}, {Key: "revision", Value: tc.revision}}
+			c.Request = httptest.NewRequest(http.MethodPost, "/"+tc.restaurantId+"/revert/"+tc.revision, nil)
+			if tc.ifMatch != "" {
+				c.Request.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			hc.Revert(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			assert.Equal(t, tc.added, atomic.LoadInt32(index.added))
+			if tc.responseCode == http.StatusOK {
+				assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+// historyStorerStub returns revisions whose restaurant has the tags.
+type historyStorerStub struct {
+	tags  []string
+	error string
+}
+
+func (s historyStorerStub) ListRevisions(_, restaurantId string, _ int32, nextToken string) ([]model.Revision, string, error) {
+	if s.error != "" {
+		return nil, "", stubErr(s.error)
+	}
+	previous, current := "Ramen", "Ramen Bar"
+	return []model.Revision{{
+		Action:       model.Update,
+		Actor:        "127.0.0.1",
+		Changes:      []model.RevisionChange{{Field: "name", From: previous, To: current}},
+		Current:      &model.Restaurant{Id: &restaurantId, Name: current},
+		Previous:     &model.Restaurant{Id: &restaurantId, Name: previous},
+		RestaurantId: restaurantId,
+		Revision:     2,
+		Timestamp:    time.Date(2023, time.June, 9, 19, 0, 0, 0, time.UTC),
+	}}, nextToken, nil
+}
+
+func (s historyStorerStub) GetRevision(_, restaurantId string, revision int64) (model.Revision, error) {
+	if s.error != "" {
+		return model.Revision{}, stubErr(s.error)
+	}
+	current := model.Restaurant{Id: &restaurantId, Name: "Ramen"}
+	if s.tags != nil {
+		current.Tags = &s.tags
+	}
+	return model.Revision{Action: model.Update, Current: &current, RestaurantId: restaurantId, Revision: revision}, nil
+}
+
+func (s historyStorerStub) Revert(_, restaurantId string, _ int64, _ []int64, _ dynamo.Actor) (model.Restaurant, int64, error) {
+	if s.error != "" {
+		return model.Restaurant{}, 0, stubErr(s.error)
+	}
+	return model.Restaurant{Id: &restaurantId, Name: "Ramen"}, 3, nil
+}
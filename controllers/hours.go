@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/hours"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"time"
+)
+
+// setOpenStatus sets whether the restaurant is open at the time and when it
+// next opens or closes. The status is unknown without opening hours or a
+// timezone.
+func setOpenStatus(restaurant *model.Restaurant, at time.Time) {
+	restaurant.OpenNow, restaurant.NextChange = nil, nil
+
+	loc, ok := timezone(*restaurant)
+	if !ok {
+		return
+	}
+
+	open, next := hours.Status(*restaurant.OpeningHours, loc, at)
+	restaurant.OpenNow = &open
+	restaurant.NextChange = next
+}
+
+// openAt reports whether the restaurant is open at the time. A restaurant
+// with an unknown status is not.
+func openAt(restaurant model.Restaurant, at time.Time) bool {
+	loc, ok := timezone(restaurant)
+	if !ok {
+		return false
+	}
+
+	open, _ := hours.Status(*restaurant.OpeningHours, loc, at)
+	return open
+}
+
+// timezone returns the timezone the opening hours of the restaurant are in.
+// ok is false when the restaurant has no opening hours or timezone. The
+// geocoder leaves the timezone empty when it finds no result, which is not
+// UTC but unknown.
+func timezone(restaurant model.Restaurant) (*time.Location, bool) {
+	if restaurant.OpeningHours == nil || restaurant.Address == nil ||
+		restaurant.Address.TimezoneName == nil || *restaurant.Address.TimezoneName == "" {
+		return nil, false
+	}
+
+	loc, err := time.LoadLocation(*restaurant.Address.TimezoneName)
+	if err != nil {
+		return nil, false
+	}
+	return loc, true
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"io"
+	"log"
+	"net/http"
+)
+
+// maxIdempotencyKey is the maximum length of an Idempotency-Key header
+const maxIdempotencyKey = 255
+
+type IdempotencyStorer interface {
+	Reserve(tenant, principal, key, requestHash string) (*dynamo.IdempotencyRecord, error)
+	Complete(tenant, principal, key string, statusCode int, body []byte) error
+	Release(tenant, principal, key string) error
+}
+
+type Idempotency struct {
+	Idempotency IdempotencyStorer
+}
+
+// Handle is a middleware that makes a request idempotent when it has an
+// Idempotency-Key header. The response to the first request with a key is
+// stored and returned again for every retry with the same key, body and
+// query. Reusing a key with another body or query (such as force=true after
+// a duplicate was found) is rejected with 422, and a retry while the first
+// request is still in progress with 409. A server error is not stored, so
+// the request can be retried. The keys are scoped to the tenant and the
+// authenticated principal, so a client cannot replay the response to
+// another client that happened to use the same key.
+func (i Idempotency) Handle(c *gin.Context) {
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	if len(key) > maxIdempotencyKey {
+		problem(c, http.StatusBadRequest, "Idempotency-Key is too long")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		problem(c, http.StatusBadRequest, "error reading request body")
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	requestHash := hashRequest(c.Request.URL.RawQuery, body)
+
+	principal := actor(c).Id
+	record, err := i.Idempotency.Reserve(tenant(c), principal, key, requestHash)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if record != nil {
+		switch {
+		case record.RequestHash != requestHash:
+			problem(c, http.StatusUnprocessableEntity, "Idempotency-Key was used with a different request")
+		case record.StatusCode == 0:
+			problem(c, http.StatusConflict, "a request with this Idempotency-Key is in progress")
+		default:
+			log.Printf("Idempotency.Handle replaying key: %s\n", key)
+			c.Header("Idempotent-Replayed", "true")
+			contentType := "application/json; charset=utf-8"
+			if record.StatusCode >= http.StatusBadRequest {
+				contentType = ProblemContentType
+			}
+			c.Data(record.StatusCode, contentType, record.Body)
+			c.Abort()
+		}
+		return
+	}
+
+	w := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = w
+
+	c.Next()
+
+	if w.Status() >= http.StatusInternalServerError {
+		err = i.Idempotency.Release(tenant(c), principal, key)
+	} else {
+		err = i.Idempotency.Complete(tenant(c), principal, key, w.Status(), w.body.Bytes())
+	}
+	if err != nil {
+		log.Printf("Idempotency.Handle key: %s  error: %s\n", key, err)
+	}
+}
+
+// hashRequest returns the hash of the query and body of a request.
+func hashRequest(query string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(query))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body written through it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func Test_Idempotency(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		tenant       string
+		subject      string
+		body         string
+		query        string
+		responseCode int
+		responseBody string
+		replayed     bool
+	}
+
+	testCases := []struct {
+		name      string
+		key       string
+		requests  []request
+		pending   bool
+		failFirst bool
+		handler   int
+		stubError string
+	}{
+		{
+			name: "no key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`},
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `2`},
+			},
+			handler: 2,
+		},
+		{
+			name: "replay",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`},
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`, replayed: true},
+			},
+			handler: 1,
+		},
+		{
+			name: "different body",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`},
+				{body: `{"name":"b"}`, responseCode: http.StatusUnprocessableEntity, responseBody: `{"detail":"Idempotency-Key was used with a different request","status":422,"title":"Unprocessable Entity","type":"about:blank"}`},
+			},
+			handler: 1,
+		},
+		{
+			name: "different query",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`},
+				{body: `{"name":"a"}`, query: "force=true", responseCode: http.StatusUnprocessableEntity, responseBody: `{"detail":"Idempotency-Key was used with a different request","status":422,"title":"Unprocessable Entity","type":"about:blank"}`},
+			},
+			handler: 1,
+		},
+		{
+			name: "other tenant",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`},
+				{tenant: "brand-a", body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `2`},
+				{tenant: "brand-a", body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `2`, replayed: true},
+			},
+			handler: 2,
+		},
+		{
+			name: "other principal",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`},
+				{subject: "user2", body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `2`},
+				{subject: "user2", body: `{"name":"b"}`, responseCode: http.StatusUnprocessableEntity, responseBody: `{"detail":"Idempotency-Key was used with a different request","status":422,"title":"Unprocessable Entity","type":"about:blank"}`},
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `1`, replayed: true},
+			},
+			handler: 2,
+		},
+		{
+			name: "in progress",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusConflict, responseBody: `{"detail":"a request with this Idempotency-Key is in progress","status":409,"title":"Conflict","type":"about:blank"}`},
+			},
+			pending: true,
+		},
+		{
+			name: "key too long",
+			key:  string(make([]byte, 256)),
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusBadRequest, responseBody: `{"detail":"Idempotency-Key is too long","status":400,"title":"Bad Request","type":"about:blank"}`},
+			},
+		},
+		{
+			name: "server error is not stored",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusInternalServerError, responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`},
+				{body: `{"name":"a"}`, responseCode: http.StatusCreated, responseBody: `2`},
+			},
+			failFirst: true,
+			handler:   2,
+		},
+		{
+			name: "storage error",
+			key:  "key",
+			requests: []request{
+				{body: `{"name":"a"}`, responseCode: http.StatusInternalServerError, responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`},
+			},
+			stubError: "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := &idempotencyStorerStub{records: map[string]*dynamo.IdempotencyRecord{}, error: tc.stubError}
+			if tc.pending {
+				stub.records[stubKey(dynamo.DefaultTenant, "user1", tc.key)] = &dynamo.IdempotencyRecord{IdempotencyKey: tc.key, RequestHash: hashRequest("", []byte(tc.requests[0].body))}
+			}
+			ic := Idempotency{Idempotency: stub}
+
+			calls := 0
+			router := gin.New()
+			router.Use(Tenant, func(c *gin.Context) {
+				// The requests are made by a principal of the tenant of the
+				// header, user1 unless the test subject header is set
+				subject := c.GetHeader(testSubjectHeader)
+				if subject == "" {
+					subject = "user1"
+				}
+				c.Set(principalKey, principal{subject: subject, tenant: c.GetString(tenantKey)})
+				c.Next()
+			})
+			router.POST("/", ic.Handle, func(c *gin.Context) {
+				calls++
+				if tc.failFirst && calls == 1 {
+					respondError(c, errors.New("an error occurred"))
+					return
+				}
+				c.JSON(http.StatusCreated, calls)
+			})
+
+			for _, req := range tc.requests {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodPost, "/?"+req.query, bytes.NewBufferString(req.body))
+				if tc.key != "" {
+					r.Header.Set("Idempotency-Key", tc.key)
+				}
+				if req.tenant != "" {
+					r.Header.Set(TenantHeader, req.tenant)
+				}
+				if req.subject != "" {
+					r.Header.Set(testSubjectHeader, req.subject)
+				}
+
+				router.ServeHTTP(w, r)
+
+				assert.Equal(t, req.responseCode, w.Code)
+				assert.Equal(t, req.responseBody, responseBody(w))
+				assert.Equal(t, req.replayed, w.Header().Get("Idempotent-Replayed") == "true")
+			}
+			assert.Equal(t, tc.handler, calls)
+		})
+	}
+}
+
+// testSubjectHeader sets the subject of the principal of a test request
+const testSubjectHeader = "X-Test-Subject"
+
+// idempotencyStorerStub keeps the records in memory, by tenant, principal
+// and key.
+type idempotencyStorerStub struct {
+	mu      sync.Mutex
+	records map[string]*dynamo.IdempotencyRecord
+	error   string
+}
+
+func (s *idempotencyStorerStub) Reserve(tenant, principal, key, requestHash string) (*dynamo.IdempotencyRecord, error) {
+	key = stubKey(tenant, principal, key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.error != "" {
+		return nil, errors.New(s.error)
+	}
+	if record, ok := s.records[key]; ok {
+		cp := *record
+		return &cp, nil
+	}
+	s.records[key] = &dynamo.IdempotencyRecord{IdempotencyKey: key, RequestHash: requestHash}
+	return nil, nil
+}
+
+func (s *idempotencyStorerStub) Complete(tenant, principal, key string, statusCode int, body []byte) error {
+	key = stubKey(tenant, principal, key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key].StatusCode = statusCode
+	s.records[key].Body = body
+	return nil
+}
+
+func (s *idempotencyStorerStub) Release(tenant, principal, key string) error {
+	key = stubKey(tenant, principal, key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func stubKey(tenant, principal, key string) string {
+	return tenant + "/" + principal + "/" + key
+}
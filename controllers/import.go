@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+
+	// importConcurrency is the maximum number of concurrent geocode requests
+	importConcurrency = 8
+	// maxImportRecords is the maximum number of restaurants in one import
+	maxImportRecords = 10000
+	// maxImportLine is the maximum size in bytes of an NDJSON record
+	maxImportLine = 1024 * 1024
+)
+
+// importRecord is a restaurant to import, or the reason it cannot be imported.
+type importRecord struct {
+	restaurant model.Restaurant
+	err        error
+}
+
+// Import creates restaurants in bulk from a JSON array or NDJSON (one
+// restaurant per line). Every record is validated and geocoded, and the
+// response reports the created ID or the error of each record.
+func (r Restaurant) Import(c *gin.Context) {
+	var records []importRecord
+	var err error
+	switch c.ContentType() {
+	case ndjsonContentType:
+		records, err = readNDJSON(c.Request.Body)
+	case "application/json":
+		records, err = readJSONArray(c.Request.Body)
+	default:
+		problem(c, http.StatusUnsupportedMediaType, "content type must be application/json or "+ndjsonContentType)
+		return
+	}
+	if err != nil {
+		problem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Restaurant.Import records: %d\n", len(records))
+
+	// The vocabulary of tags is read once for every record
+	var tags map[string]model.Tag
+	if hasTags(records) {
+		if tags, err = vocabulary(r.Tags, tenant(c)); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	for i := range records {
+		if records[i].err == nil {
+			records[i].err = validateImport(&records[i].restaurant, tags)
+		}
+	}
+
+	r.geocodeRecords(records)
+
+	var restaurants []model.Restaurant
+	var indexes []int
+	for i := range records {
+		if records[i].err != nil {
+			continue
+		}
+		id := uuid.NewString()
+		records[i].restaurant.Id = &id
+		records[i].restaurant.Owner = owner(c)
+		restaurants = append(restaurants, records[i].restaurant)
+		indexes = append(indexes, i)
+	}
+
+	if len(restaurants) > 0 {
+		for i, err := range r.Restaurant.SaveBatch(tenant(c), restaurants, actor(c)) {
+			records[indexes[i]].err = err
+		}
+	}
+
+	report := model.ImportReport{Results: make([]model.ImportResult, 0, len(records))}
+	for i, record := range records {
+		result := model.ImportResult{Index: i}
+		if record.err != nil {
+			msg := errorDetail(c, record.err)
+			result.Error = &msg
+			report.Failed++
+		} else {
+			r.Index.Add(tenant(c), record.restaurant)
+			publish(c, r.Events, model.RestaurantCreated, *record.restaurant.Id, &record.restaurant)
+			result.Id = record.restaurant.Id
+			report.Created++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// geocodeRecords geocodes the addresses of the valid records, with at most
+// importConcurrency requests at a time.
+func (r Restaurant) geocodeRecords(records []importRecord) {
+	sem := make(chan struct{}, importConcurrency)
+	var wg sync.WaitGroup
+	for i := range records {
+		if records[i].err != nil || records[i].restaurant.Address == nil {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(record *importRecord) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			record.err = r.geocode(record.restaurant.Address)
+		}(&records[i])
+	}
+	wg.Wait()
+}
+
+// readNDJSON reads one restaurant per line. A line that is not a valid
+// restaurant is reported as an error of its record, empty lines are skipped.
+func readNDJSON(body io.Reader) ([]importRecord, error) {
+	var records []importRecord
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(records) == maxImportRecords {
+			return nil, fmt.Errorf("import is limited to %d restaurants", maxImportRecords)
+		}
+
+		var record importRecord
+		if err := json.Unmarshal(line, &record.restaurant); err != nil {
+			record.err = invalidError("invalid restaurant JSON")
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+
+	return records, nil
+}
+
+// readJSONArray reads a JSON array of restaurants. An element that is not
+// a valid restaurant is reported as an error of its record.
+func readJSONArray(body io.Reader) ([]importRecord, error) {
+	var elements []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&elements); err != nil {
+		return nil, errors.New("request body must be a JSON array of restaurants")
+	}
+	if len(elements) > maxImportRecords {
+		return nil, fmt.Errorf("import is limited to %d restaurants", maxImportRecords)
+	}
+
+	records := make([]importRecord, len(elements))
+	for i, element := range elements {
+		if err := json.Unmarshal(element, &records[i].restaurant); err != nil {
+			records[i].err = invalidError("invalid restaurant JSON")
+		}
+	}
+
+	return records, nil
+}
+
+// validateImport normalizes the fields of the restaurant and returns the
+// messages of the invalid ones as a single error. The tags are checked
+// against the vocabulary.
+func validateImport(restaurant *model.Restaurant, vocabulary map[string]model.Tag) error {
+	clearComputed(restaurant)
+	errs := validate.Restaurant(restaurant)
+	if restaurant.Tags != nil {
+		errs = append(errs, validate.Tags(*restaurant.Tags, vocabulary)...)
+	}
+	return importErrors(errs)
+}
+
+// hasTags reports whether a valid record has tags.
+func hasTags(records []importRecord) bool {
+	for _, record := range records {
+		if record.err == nil && record.restaurant.Tags != nil && len(*record.restaurant.Tags) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Import(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		contentType  string
+		body         string
+		responseCode int
+		responseBody string
+		results      []model.ImportResult
+		stubError    stubError
+	}{
+		{
+			name:         "json array",
+			contentType:  "application/json",
+			body:         `[{"name":"Rest 1","address":{"city":"Seattle"}},{"name":" "},{"name":"Rest 3"},{"name":3},{"name":"","phoneNumber":"12"}]`,
+			responseCode: http.StatusOK,
+			results: []model.ImportResult{
+				{Index: 0, Id: new(string)},
+				{Index: 1, Error: strPtr("name is required")},
+				{Index: 2, Id: new(string)},
+				{Index: 3, Error: strPtr("invalid restaurant JSON")},
+				{Index: 4, Error: strPtr("name is required; phoneNumber must be in E.164 format, such as +14155550123")},
+			},
+		},
+		{
+			name:         "ndjson",
+			contentType:  "application/x-ndjson",
+			body:         "{\"name\":\"Rest 1\"}\n\n{\"name\":\n{\"name\":\"Rest 3\"}\n",
+			responseCode: http.StatusOK,
+			results: []model.ImportResult{
+				{Index: 0, Id: new(string)},
+				{Index: 1, Error: strPtr("invalid restaurant JSON")},
+				{Index: 2, Id: new(string)},
+			},
+		},
+		{
+			name:         "empty",
+			contentType:  "application/json",
+			body:         `[]`,
+			responseCode: http.StatusOK,
+			results:      []model.ImportResult{},
+		},
+		{
+			name:         "not an array",
+			contentType:  "application/json",
+			body:         `{"name":"Rest 1"}`,
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"request body must be a JSON array of restaurants","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "wrong content type",
+			contentType:  "text/csv",
+			body:         `name`,
+			responseCode: http.StatusUnsupportedMediaType,
+			responseBody: `{"detail":"content type must be application/json or application/x-ndjson","status":415,"title":"Unsupported Media Type","type":"about:blank"}`,
+		},
+		{
+			name:         "tags",
+			contentType:  "application/json",
+			body:         `[{"name":"Rest 1","tags":["Thai"]},{"name":"Rest 2","tags":["vegan"]}]`,
+			responseCode: http.StatusOK,
+			results: []model.ImportResult{
+				{Index: 0, Id: new(string)},
+				{Index: 1, Error: strPtr("vegan is not a known tag")},
+			},
+		},
+		{
+			name:         "tags error",
+			contentType:  "application/json",
+			body:         `[{"name":"Rest 1","tags":["thai"]}]`,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    stubError{tag: "an error occurred"},
+		},
+		{
+			name:         "location error",
+			contentType:  "application/json",
+			body:         `[{"name":"Rest 1","address":{"city":"Seattle"}},{"name":"Rest 2"}]`,
+			responseCode: http.StatusOK,
+			results: []model.ImportResult{
+				{Index: 0, Error: strPtr("the address could not be geocoded")},
+				{Index: 1, Id: new(string)},
+			},
+			stubError: stubError{location: "an error occurred"},
+		},
+		{
+			name:         "storage error",
+			contentType:  "application/json",
+			body:         `[{"name":"Rest 1"},{"name":""}]`,
+			responseCode: http.StatusOK,
+			results: []model.ImportResult{
+				{Index: 0, Error: strPtr("an internal error occurred")},
+				{Index: 1, Error: strPtr("name is required")},
+			},
+			stubError: stubError{restaurant: "an error occurred"},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{error: tc.stubError.restaurant},
+				Location:   locationServiceStub{error: tc.stubError.location},
+				Index:      searchIndexStub{},
+				Tags:       tagStorerStub{tags: testTags, error: tc.stubError.tag},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodPost, "/import", bytes.NewBufferString(tc.body))
+			c.Request.Header.Set("Content-Type", tc.contentType)
+
+			rc.Import(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusOK {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				return
+			}
+
+			var report model.ImportReport
+			_ = json.Unmarshal(w.Body.Bytes(), &report)
+
+			created, failed := 0, 0
+			if assert.Len(t, report.Results, len(tc.results)) {
+				for i, exp := range tc.results {
+					result := report.Results[i]
+					assert.Equal(t, exp.Index, result.Index)
+					assert.Equal(t, exp.Error, result.Error)
+					if exp.Id != nil {
+						created++
+						assert.NotEmpty(t, result.Id)
+					} else {
+						failed++
+						assert.Nil(t, result.Id)
+					}
+				}
+			}
+			assert.Equal(t, created, report.Created)
+			assert.Equal(t, failed, report.Failed)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
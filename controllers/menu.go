@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/hours"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"golang.org/x/text/currency"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// priceAmount is a decimal amount with up to four decimal places
+var priceAmount = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,4})?$`)
+
+var dietaryTags = map[model.MenuItemDietaryTags]bool{
+	model.Vegetarian: true,
+	model.Vegan:      true,
+	model.GlutenFree: true,
+	model.DairyFree:  true,
+	model.Halal:      true,
+	model.Kosher:     true,
+}
+
+var allergens = map[model.MenuItemAllergens]bool{
+	model.Milk:      true,
+	model.Eggs:      true,
+	model.Fish:      true,
+	model.Shellfish: true,
+	model.TreeNuts:  true,
+	model.Peanuts:   true,
+	model.Wheat:     true,
+	model.Soy:       true,
+	model.Sesame:    true,
+}
+
+type MenuStorer interface {
+	GetMenu(tenant, restaurantId string) (model.Menu, error)
+	SaveMenu(tenant, restaurantId string, menu model.Menu, actor dynamo.Actor) error
+	DeleteMenu(tenant, restaurantId string, actor dynamo.Actor) error
+}
+
+type Menu struct {
+	Menu MenuStorer
+}
+
+func (m Menu) Read(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	log.Printf("Menu.Read restaurantId: %s\n", restaurantId)
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	menu, err := m.Menu.GetMenu(tenant(c), restaurantId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, menu)
+}
+
+// Save creates or replaces the menu of the restaurant.
+func (m Menu) Save(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var menu model.Menu
+	if err := c.ShouldBindJSON(&menu); err != nil {
+		problem(c, http.StatusBadRequest, "error binding request body")
+		return
+	}
+
+	if err := validateMenu(menu); err != nil {
+		problem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Menu.Save restaurantId: %s  sections: %d\n", restaurantId, len(menu.Sections))
+
+	if err := m.Menu.SaveMenu(tenant(c), restaurantId, menu, actor(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, menu)
+}
+
+func (m Menu) Delete(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	log.Printf("Menu.Delete restaurantId: %s\n", restaurantId)
+
+	if err := m.Menu.DeleteMenu(tenant(c), restaurantId, actor(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "")
+}
+
+// validateMenu checks that every section and item has a name, that prices
+// are decimal amounts in an ISO 4217 currency, and that the availability
+// windows and tags are valid.
+func validateMenu(menu model.Menu) error {
+	if menu.Sections == nil {
+		return errors.New("sections is required")
+	}
+
+	for i, section := range menu.Sections {
+		field := fmt.Sprintf("sections[%d]", i)
+		if strings.TrimSpace(section.Name) == "" {
+			return fmt.Errorf("%s.name is required", field)
+		}
+		if section.Availability != nil {
+			if err := hours.Validate(field+".availability", *section.Availability); err != nil {
+				return err
+			}
+		}
+
+		for j, item := range section.Items {
+			if err := validateMenuItem(fmt.Sprintf("%s.items[%d]", field, j), item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateMenuItem(field string, item model.MenuItem) error {
+	if strings.TrimSpace(item.Name) == "" {
+		return fmt.Errorf("%s.name is required", field)
+	}
+	if !priceAmount.MatchString(item.Price.Amount) {
+		return fmt.Errorf("%s.price.amount must be a decimal amount", field)
+	}
+	if _, err := currency.ParseISO(item.Price.Currency); err != nil || strings.ToUpper(item.Price.Currency) != item.Price.Currency {
+		return fmt.Errorf("%s.price.currency must be an ISO 4217 currency code", field)
+	}
+	if item.Availability != nil {
+		if err := hours.Validate(field+".availability", *item.Availability); err != nil {
+			return err
+		}
+	}
+	if item.DietaryTags != nil {
+		for k, tag := range *item.DietaryTags {
+			if !dietaryTags[tag] {
+				return fmt.Errorf("%s.dietaryTags[%d] is not a dietary tag", field, k)
+			}
+		}
+	}
+	if item.Allergens != nil {
+		for k, allergen := range *item.Allergens {
+			if !allergens[allergen] {
+				return fmt.Errorf("%s.allergens[%d] is not an allergen", field, k)
+			}
+		}
+	}
+	return nil
+}
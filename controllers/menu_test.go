@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_MenuRead(t *testing.T) {
+	t.Parallel()
+	menuExp, _ := json.Marshal(testMenu())
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			responseCode: http.StatusOK,
+			responseBody: string(menuExp),
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "no menu",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"menu not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrMenuNotFound.Error(),
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mc := Menu{
+				Menu: menuStorerStub{error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+
+			mc.Read(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_MenuSave(t *testing.T) {
+	t.Parallel()
+	menuExp, _ := json.Marshal(testMenu())
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		update       func(menu *model.Menu)
+		emptyReqBody bool
+		principal    string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			responseCode: http.StatusOK,
+			responseBody: string(menuExp),
+		},
+		{
+			name:         "owner",
+			restaurantId: "restId",
+			principal:    "user1",
+			responseCode: http.StatusOK,
+			responseBody: string(menuExp),
+		},
+		{
+			name:         "not the owner",
+			restaurantId: "restId",
+			principal:    "user2",
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the owner of the restaurant or an admin can modify it","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "no sections",
+			restaurantId: "restId",
+			update:       func(menu *model.Menu) { menu.Sections = nil },
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections is required","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "section without name",
+			restaurantId: "restId",
+			update:       func(menu *model.Menu) { menu.Sections[0].Name = " " },
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections[0].name is required","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid amount",
+			restaurantId: "restId",
+			update:       func(menu *model.Menu) { menu.Sections[0].Items[1].Price.Amount = "12,50" },
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections[0].items[1].price.amount must be a decimal amount","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "unknown currency",
+			restaurantId: "restId",
+			update:       func(menu *model.Menu) { menu.Sections[0].Items[0].Price.Currency = "XYZ" },
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections[0].items[0].price.currency must be an ISO 4217 currency code","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "lowercase currency",
+			restaurantId: "restId",
+			update:       func(menu *model.Menu) { menu.Sections[0].Items[0].Price.Currency = "usd" },
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections[0].items[0].price.currency must be an ISO 4217 currency code","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid availability",
+			restaurantId: "restId",
+			update: func(menu *model.Menu) {
+				menu.Sections[0].Items[0].Availability = &[]model.OpeningInterval{{Day: model.Monday, Open: "11", Close: "14:00"}}
+			},
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections[0].items[0].availability[0]: open must be a time in HH:MM format","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "unknown dietary tag",
+			restaurantId: "restId",
+			update:       func(menu *model.Menu) { (*menu.Sections[0].Items[1].DietaryTags)[0] = "paleo" },
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections[0].items[1].dietaryTags[0] is not a dietary tag","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "unknown allergen",
+			restaurantId: "restId",
+			update:       func(menu *model.Menu) { (*menu.Sections[0].Items[0].Allergens)[1] = "gluten" },
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"sections[0].items[0].allergens[1] is not an allergen","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "empty request body",
+			restaurantId: "restId",
+			emptyReqBody: true,
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := menuStorerStub{error: tc.stubError}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tc.principal != "" {
+				stub.owner = "user1"
+				c.Set(principalKey, principal{subject: tc.principal})
+			}
+			mc := Menu{
+				Menu: stub,
+			}
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+
+			var body []byte
+			if !tc.emptyReqBody {
+				menu := testMenu()
+				if tc.update != nil {
+					tc.update(&menu)
+				}
+				body, _ = json.Marshal(menu)
+			}
+			c.Request = httptest.NewRequest(http.MethodPost, "/"+tc.restaurantId+"/menu", bytes.NewBuffer(body))
+
+			mc.Save(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_MenuDelete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		principal    string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+		},
+		{
+			name:         "owner",
+			restaurantId: "restId",
+			principal:    "user1",
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+		},
+		{
+			name:         "not the owner",
+			restaurantId: "restId",
+			principal:    "user2",
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the owner of the restaurant or an admin can modify it","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "no menu",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"menu not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrMenuNotFound.Error(),
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := menuStorerStub{error: tc.stubError}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tc.principal != "" {
+				stub.owner = "user1"
+				c.Set(principalKey, principal{subject: tc.principal})
+			}
+			mc := Menu{
+				Menu: stub,
+			}
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+			c.Request = httptest.NewRequest(http.MethodDelete, "/"+tc.restaurantId+"/menu", nil)
+
+			mc.Delete(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+// testMenu returns a new menu, so test cases can modify it.
+func testMenu() model.Menu {
+	return model.Menu{
+		Sections: []model.MenuSection{
+			{
+				Name: "Mains",
+				Items: []model.MenuItem{
+					{
+						Name:      "Fish and chips",
+						Price:     model.Price{Amount: "14.50", Currency: "GBP"},
+						Allergens: &[]model.MenuItemAllergens{model.Fish, model.Wheat},
+					},
+					{
+						Name:        "Risotto",
+						Price:       model.Price{Amount: "12", Currency: "GBP"},
+						DietaryTags: &[]model.MenuItemDietaryTags{model.Vegetarian, model.GlutenFree},
+						Availability: &[]model.OpeningInterval{
+							{Day: model.Friday, Open: "18:00", Close: "22:00"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// menuStorerStub checks the owner of the restaurant when owner is set
+type menuStorerStub struct {
+	owner string
+	error string
+}
+
+func (s menuStorerStub) GetMenu(_, _ string) (model.Menu, error) {
+	if s.error != "" {
+		return model.Menu{}, stubErr(s.error)
+	}
+	return testMenu(), nil
+}
+
+func (s menuStorerStub) SaveMenu(_, _ string, _ model.Menu, actor dynamo.Actor) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return stubOwnerErr(s.owner, actor)
+}
+
+func (s menuStorerStub) DeleteMenu(_, _ string, actor dynamo.Actor) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return stubOwnerErr(s.owner, actor)
+}
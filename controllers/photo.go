@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/thumbnail"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// MaxPhotoSize is the maximum size of an uploaded photo in bytes
+	MaxPhotoSize = 10 << 20
+	// photoField is the part of the multipart form holding the photo
+	photoField = "photo"
+	// thumbnailSize is the maximum width and height of the thumbnails
+	thumbnailSize = 320
+	// multipartOverhead is allowed on top of the photo for the boundaries
+	// and headers of the multipart form
+	multipartOverhead = 64 << 10
+	// defaultTenantDir is the directory of the blobs of the default tenant,
+	// which cannot be the name of a tenant
+	defaultTenantDir = "_"
+)
+
+type PhotoStorer interface {
+	ListPhotos(tenant, restaurantId string) ([]model.Photo, error)
+	AddPhoto(tenant, restaurantId string, photo model.Photo, actor dynamo.Actor) error
+	ReorderPhotos(tenant, restaurantId string, photoIds []string, actor dynamo.Actor) ([]model.Photo, error)
+	DeletePhoto(tenant, restaurantId, photoId string, actor dynamo.Actor) (model.Photo, error)
+}
+
+// BlobStore stores the photos and their thumbnails, and gives the URLs they
+// are read at.
+type BlobStore interface {
+	Put(key, contentType string, data []byte) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+type Photo struct {
+	Photo PhotoStorer
+	Blob  BlobStore
+}
+
+func (p Photo) List(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	log.Printf("Photo.List restaurantId: %s\n", restaurantId)
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	photos, err := p.Photo.ListPhotos(tenant(c), restaurantId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if photos == nil {
+		photos = []model.Photo{}
+	}
+
+	c.JSON(http.StatusOK, model.PhotoList{Photos: photos})
+}
+
+// Upload stores the photo of the photo part of the multipart form and its
+// thumbnail, and adds it after the other photos of the restaurant. The
+// media type is detected from the content of the photo.
+func (p Photo) Upload(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	data, ok := readPhoto(c)
+	if !ok {
+		return
+	}
+
+	img, contentType, err := thumbnail.Decode(data)
+	switch {
+	case errors.Is(err, thumbnail.ErrTooLarge):
+		problem(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case err != nil:
+		problem(c, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+	thumb, err := thumbnail.Generate(img, thumbnailSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	photoId := uuid.NewString()
+	key, thumbKey := photoKeys(tenant(c), photoId, contentType)
+
+	log.Printf("Photo.Upload restaurantId: %s  photoId: %s  contentType: %s  size: %d\n", restaurantId, photoId, contentType, len(data))
+
+	// The blobs are stored first, so a photo of the restaurant always has
+	// its blobs. They are deleted again if the photo cannot be added.
+	if err := p.Blob.Put(key, contentType, data); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := p.Blob.Put(thumbKey, thumbnail.JPEG, thumb); err != nil {
+		p.deleteBlobs(key)
+		respondError(c, err)
+		return
+	}
+
+	bounds := img.Bounds()
+	photo := model.Photo{
+		Id:           photoId,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        bounds.Dx(),
+		Height:       bounds.Dy(),
+		Url:          p.Blob.URL(key),
+		ThumbnailUrl: p.Blob.URL(thumbKey),
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := p.Photo.AddPhoto(tenant(c), restaurantId, photo, actor(c)); err != nil {
+		p.deleteBlobs(key, thumbKey)
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, photo)
+}
+
+// Reorder puts the photos of the restaurant in the order of the IDs of the
+// request, which lists every photo once.
+func (p Photo) Reorder(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var order model.PhotoOrder
+	if err := c.ShouldBindJSON(&order); err != nil || order.PhotoIds == nil {
+		problem(c, http.StatusBadRequest, "error binding request body")
+		return
+	}
+
+	log.Printf("Photo.Reorder restaurantId: %s  photoIds: %v\n", restaurantId, order.PhotoIds)
+
+	photos, err := p.Photo.ReorderPhotos(tenant(c), restaurantId, order.PhotoIds, actor(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if photos == nil {
+		photos = []model.Photo{}
+	}
+
+	c.JSON(http.StatusOK, model.PhotoList{Photos: photos})
+}
+
+// Delete removes the photo from the restaurant, then deletes its blobs.
+func (p Photo) Delete(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+	photoId := c.Param("photoId")
+
+	// Validate input
+	if restaurantId == "" || photoId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId or photoId is empty")
+		return
+	}
+
+	log.Printf("Photo.Delete restaurantId: %s  photoId: %s\n", restaurantId, photoId)
+
+	photo, err := p.Photo.DeletePhoto(tenant(c), restaurantId, photoId, actor(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// The photo is no longer returned, so a blob that cannot be deleted is
+	// only logged
+	p.deleteBlobs(photoKeys(tenant(c), photo.Id, photo.ContentType))
+
+	c.JSON(http.StatusOK, "")
+}
+
+// readPhoto returns the content of the photo part of the multipart form.
+// It responds with a problem and returns false when the request has no
+// photo or the photo is larger than MaxPhotoSize.
+func readPhoto(c *gin.Context) ([]byte, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxPhotoSize+multipartOverhead)
+
+	file, header, err := c.Request.FormFile(photoField)
+	if err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			problem(c, http.StatusRequestEntityTooLarge, "the photo is larger than 10 MiB")
+		} else {
+			problem(c, http.StatusBadRequest, "the request has no photo part")
+		}
+		return nil, false
+	}
+	defer file.Close()
+
+	if header.Size > MaxPhotoSize {
+		problem(c, http.StatusRequestEntityTooLarge, "the photo is larger than 10 MiB")
+		return nil, false
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		problem(c, http.StatusBadRequest, "error reading the photo")
+		return nil, false
+	}
+	return data, true
+}
+
+// photoKeys returns the keys of the blobs of the photo and its thumbnail.
+// The photo IDs are unique, so the blobs are only grouped by tenant.
+func photoKeys(tenant, photoId, contentType string) (string, string) {
+	dir := tenant
+	if dir == dynamo.DefaultTenant {
+		dir = defaultTenantDir
+	}
+	ext := ".jpg"
+	if contentType == thumbnail.PNG {
+		ext = ".png"
+	}
+	return dir + "/" + photoId + ext, dir + "/" + photoId + "-thumbnail.jpg"
+}
+
+// deleteBlobs deletes the blobs, logging the errors.
+func (p Photo) deleteBlobs(keys ...string) {
+	for _, key := range keys {
+		if err := p.Blob.Delete(key); err != nil {
+			log.Printf("error deleting blob %s: %s\n", key, err)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_PhotoList(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		photos       []model.Photo
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			photos:       []model.Photo{{Id: "p1", ContentType: "image/png", Url: "/photos/_/p1.png", ThumbnailUrl: "/photos/_/p1-thumbnail.jpg"}},
+			responseCode: http.StatusOK,
+			responseBody: `{"photos":[{"contentType":"image/png","createdAt":"0001-01-01T00:00:00Z","height":0,"id":"p1","size":0,"thumbnailUrl":"/photos/_/p1-thumbnail.jpg","url":"/photos/_/p1.png","width":0}]}`,
+		},
+		{
+			name:         "no photos",
+			restaurantId: "restId",
+			responseCode: http.StatusOK,
+			responseBody: `{"photos":[]}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pc := Photo{
+				Photo: photoStorerStub{photos: tc.photos, error: tc.stubError},
+				Blob:  newBlobStoreStub(""),
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+
+			pc.List(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_PhotoUpload(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		tenant       string
+		field        string
+		data         []byte
+		contentType  string
+		responseCode int
+		responseBody string
+		stubError    string
+		blobError    string
+		blobs        []string
+	}{
+		{
+			name:         "jpeg",
+			field:        photoField,
+			data:         encodeImage(t, "jpeg", 640, 480),
+			contentType:  "image/jpeg",
+			responseCode: http.StatusCreated,
+			blobs:        []string{"_/{photoId}-thumbnail.jpg", "_/{photoId}.jpg"},
+		},
+		{
+			name:         "png of a tenant",
+			tenant:       "acme",
+			field:        photoField,
+			data:         encodeImage(t, "png", 640, 480),
+			contentType:  "image/png",
+			responseCode: http.StatusCreated,
+			blobs:        []string{"acme/{photoId}-thumbnail.jpg", "acme/{photoId}.png"},
+		},
+		{
+			name:         "no photo part",
+			field:        "image",
+			data:         encodeImage(t, "jpeg", 640, 480),
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"the request has no photo part","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "photo too large",
+			field:        photoField,
+			data:         make([]byte, MaxPhotoSize+1),
+			responseCode: http.StatusRequestEntityTooLarge,
+			responseBody: `{"detail":"the photo is larger than 10 MiB","status":413,"title":"Request Entity Too Large","type":"about:blank"}`,
+		},
+		{
+			name:         "gif",
+			field:        photoField,
+			data:         encodeImage(t, "gif", 64, 48),
+			responseCode: http.StatusUnsupportedMediaType,
+			responseBody: `{"detail":"the photo is not a JPEG or PNG image","status":415,"title":"Unsupported Media Type","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			field:        photoField,
+			data:         encodeImage(t, "jpeg", 64, 48),
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "not the owner",
+			field:        photoField,
+			data:         encodeImage(t, "jpeg", 64, 48),
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the owner of the restaurant or an admin can modify it","status":403,"title":"Forbidden","type":"about:blank"}`,
+			stubError:    dynamo.ErrForbidden.Error(),
+		},
+		{
+			name:         "too many photos",
+			field:        photoField,
+			data:         encodeImage(t, "jpeg", 64, 48),
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"restaurant has the maximum number of photos","status":409,"title":"Conflict","type":"about:blank"}`,
+			stubError:    dynamo.ErrTooManyPhotos.Error(),
+		},
+		{
+			name:         "blob store error",
+			field:        photoField,
+			data:         encodeImage(t, "jpeg", 64, 48),
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			blobError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			blobs := newBlobStoreStub(tc.blobError)
+			pc := Photo{
+				Photo: photoStorerStub{error: tc.stubError},
+				Blob:  blobs,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = multipartRequest(t, tc.field, tc.data)
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}}
+			c.Set(principalKey, principal{subject: "user1", tenant: tc.tenant})
+
+			pc.Upload(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusCreated {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				// The blobs of a photo that was not added are deleted
+				assert.Empty(t, blobs.keys())
+				return
+			}
+
+			var photo model.Photo
+			if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &photo)) {
+				assert.Equal(t, tc.contentType, photo.ContentType)
+				assert.Equal(t, int64(len(tc.data)), photo.Size)
+				assert.Equal(t, 640, photo.Width)
+				assert.Equal(t, 480, photo.Height)
+				assert.False(t, photo.CreatedAt.IsZero())
+
+				var expected []string
+				for _, key := range tc.blobs {
+					expected = append(expected, strings.ReplaceAll(key, "{photoId}", photo.Id))
+				}
+				assert.Equal(t, expected, blobs.keys())
+				assert.Equal(t, "/photos/"+expected[1], photo.Url)
+				assert.Equal(t, "/photos/"+expected[0], photo.ThumbnailUrl)
+
+				thumb, err := jpeg.Decode(bytes.NewReader(blobs.blobs[expected[0]]))
+				if assert.Nil(t, err) {
+					assert.Equal(t, image.Rect(0, 0, 320, 240), thumb.Bounds())
+				}
+			}
+		})
+	}
+}
+
+func Test_PhotoReorder(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		reqBody      string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			reqBody:      `{"photoIds":["p2","p1"]}`,
+			responseCode: http.StatusOK,
+			responseBody: `{"photos":[{"contentType":"","createdAt":"0001-01-01T00:00:00Z","height":0,"id":"p2","size":0,"thumbnailUrl":"","url":"","width":0},{"contentType":"","createdAt":"0001-01-01T00:00:00Z","height":0,"id":"p1","size":0,"thumbnailUrl":"","url":"","width":0}]}`,
+		},
+		{
+			name:         "no photoIds",
+			reqBody:      `{}`,
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "IDs do not match",
+			reqBody:      `{"photoIds":["p2"]}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"photo IDs do not match the photos of the restaurant","status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+			stubError:    dynamo.ErrPhotoOrder.Error(),
+		},
+		{
+			name:         "modified concurrently",
+			reqBody:      `{"photoIds":["p2","p1"]}`,
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"photos were modified concurrently","status":409,"title":"Conflict","type":"about:blank"}`,
+			stubError:    dynamo.ErrPhotosConflict.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pc := Photo{
+				Photo: photoStorerStub{error: tc.stubError},
+				Blob:  newBlobStoreStub(""),
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodPost, "/restId/photos/order", strings.NewReader(tc.reqBody))
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}}
+
+			pc.Reorder(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_PhotoDelete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		photoId      string
+		responseCode int
+		responseBody string
+		stubError    string
+		blobs        []string
+	}{
+		{
+			name:         "happy path",
+			photoId:      "p1",
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+			blobs:        []string{"_/p2-thumbnail.jpg", "_/p2.jpg"},
+		},
+		{
+			name:         "photo does not exist",
+			photoId:      "p3",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"photo not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrPhotoNotFound.Error(),
+			blobs:        []string{"_/p1-thumbnail.jpg", "_/p1.png", "_/p2-thumbnail.jpg", "_/p2.jpg"},
+		},
+		{
+			name:         "empty photoId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId or photoId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+			blobs:        []string{"_/p1-thumbnail.jpg", "_/p1.png", "_/p2-thumbnail.jpg", "_/p2.jpg"},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			blobs := newBlobStoreStub("")
+			for _, key := range []string{"_/p1.png", "_/p1-thumbnail.jpg", "_/p2.jpg", "_/p2-thumbnail.jpg"} {
+				assert.Nil(t, blobs.Put(key, "", []byte(key)))
+			}
+			pc := Photo{
+				Photo: photoStorerStub{
+					photos: []model.Photo{{Id: "p1", ContentType: "image/png"}, {Id: "p2", ContentType: "image/jpeg"}},
+					error:  tc.stubError,
+				},
+				Blob: blobs,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodDelete, "/restId/photos/"+tc.photoId, nil)
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}, {Key: "photoId", Value: tc.photoId}}
+			c.Set(principalKey, principal{subject: "user1"})
+
+			pc.Delete(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			assert.Equal(t, tc.blobs, blobs.keys())
+		})
+	}
+}
+
+type photoStorerStub struct {
+	photos []model.Photo
+	error  string
+}
+
+func (s photoStorerStub) ListPhotos(_, _ string) ([]model.Photo, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	return s.photos, nil
+}
+
+func (s photoStorerStub) AddPhoto(_, _ string, _ model.Photo, _ dynamo.Actor) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return nil
+}
+
+func (s photoStorerStub) ReorderPhotos(_, _ string, photoIds []string, _ dynamo.Actor) ([]model.Photo, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	var photos []model.Photo
+	for _, id := range photoIds {
+		photos = append(photos, model.Photo{Id: id})
+	}
+	return photos, nil
+}
+
+func (s photoStorerStub) DeletePhoto(_, _, photoId string, _ dynamo.Actor) (model.Photo, error) {
+	if s.error != "" {
+		return model.Photo{}, stubErr(s.error)
+	}
+	for _, photo := range s.photos {
+		if photo.Id == photoId {
+			return photo, nil
+		}
+	}
+	return model.Photo{}, dynamo.ErrPhotoNotFound
+}
+
+// blobStoreStub keeps the blobs in memory. Put fails with error when it is
+// set.
+type blobStoreStub struct {
+	mu    *sync.Mutex
+	blobs map[string][]byte
+	error string
+}
+
+func newBlobStoreStub(error string) blobStoreStub {
+	return blobStoreStub{mu: &sync.Mutex{}, blobs: map[string][]byte{}, error: error}
+}
+
+func (s blobStoreStub) Put(key, _ string, data []byte) error {
+	if s.error != "" {
+		return errors.New(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = data
+	return nil
+}
+
+func (s blobStoreStub) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+func (s blobStoreStub) URL(key string) string {
+	return "/photos/" + key
+}
+
+// keys returns the sorted keys of the blobs.
+func (s blobStoreStub) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.blobs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// multipartRequest returns an upload request with the data in the part
+// named field.
+func multipartRequest(t *testing.T, field string, data []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile(field, "photo")
+	assert.Nil(t, err)
+	_, err = part.Write(data)
+	assert.Nil(t, err)
+	assert.Nil(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/restId/photos", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func encodeImage(t *testing.T, format string, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		assert.Nil(t, jpeg.Encode(&buf, img, nil))
+	case "png":
+		assert.Nil(t, png.Encode(&buf, img))
+	default:
+		assert.Nil(t, gif.Encode(&buf, img, nil))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/geocode"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/webhook"
+	"log"
+	"net/http"
+)
+
+const (
+	// ProblemContentType is the media type of the error responses (RFC 7807)
+	ProblemContentType = "application/problem+json"
+
+	// RequestIdHeader carries the ID of the request. The ID sent by the
+	// client is used when it is valid, otherwise one is generated.
+	RequestIdHeader = "X-Request-Id"
+	requestIdKey    = "requestId"
+	maxRequestId    = 128
+
+	// internalErrorDetail replaces the message of unexpected errors, which
+	// may come from AWS and are only logged
+	internalErrorDetail = "an internal error occurred"
+)
+
+// RequestId is a middleware that identifies the request, so the problem
+// responses and the logs of a request can be matched.
+func RequestId(c *gin.Context) {
+	id := c.GetHeader(RequestIdHeader)
+	if !validRequestId(id) {
+		id = uuid.NewString()
+	}
+
+	c.Set(requestIdKey, id)
+	c.Header(RequestIdHeader, id)
+	c.Next()
+}
+
+// validRequestId reports whether the request ID sent by a client can be
+// used: it is not empty, not too long and only has printable ASCII
+// characters, so it is safe in logs and headers.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestId {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// problem responds with a problem of the status. detail explains the
+// problem to the client.
+func problem(c *gin.Context, status int, detail string) {
+	writeProblem(c, model.Problem{Status: status, Detail: &detail})
+}
+
+// writeProblem completes the problem with its type, title, instance and
+// request ID, and responds with it. The request is aborted, so the
+// problem can be returned by middlewares too.
+func writeProblem(c *gin.Context, p model.Problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	p.RequestId = c.GetString(requestIdKey)
+	if c.Request != nil && c.Request.URL != nil {
+		instance := c.Request.URL.Path
+		p.Instance = &instance
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// respondError responds with the problem matching the error returned by
+// storage, the geocoder or a webhook receiver. Unexpected errors are logged
+// with the request ID and reported without their message.
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, dynamo.ErrNotFound), errors.Is(err, dynamo.ErrMenuNotFound), errors.Is(err, dynamo.ErrReviewNotFound),
+		errors.Is(err, dynamo.ErrSettingsNotFound), errors.Is(err, dynamo.ErrReservationNotFound), errors.Is(err, dynamo.ErrRevisionNotFound),
+		errors.Is(err, dynamo.ErrApiKeyNotFound), errors.Is(err, dynamo.ErrPhotoNotFound), errors.Is(err, dynamo.ErrTagNotFound),
+		errors.Is(err, dynamo.ErrWebhookNotFound), errors.Is(err, dynamo.ErrDeliveryNotFound):
+		problem(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, dynamo.ErrForbidden):
+		problem(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, dynamo.ErrPreconditionFailed):
+		problem(c, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, dynamo.ErrNotDeleted), errors.Is(err, dynamo.ErrReservationCancelled), errors.Is(err, dynamo.ErrReservationConflict),
+		errors.Is(err, dynamo.ErrSlotTaken), errors.Is(err, dynamo.ErrNothingToRevert), errors.Is(err, dynamo.ErrTooManyPhotos),
+		errors.Is(err, dynamo.ErrPhotosConflict), errors.Is(err, dynamo.ErrTagInUse), errors.Is(err, dynamo.ErrTagRemoved):
+		problem(c, http.StatusConflict, err.Error())
+	case errors.Is(err, dynamo.ErrPhotoOrder):
+		problem(c, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, dynamo.ErrInvalidToken):
+		problem(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, geocode.ErrGeocode):
+		log.Printf("requestId: %s  error: %s\n", c.GetString(requestIdKey), err)
+		problem(c, http.StatusBadGateway, geocode.ErrGeocode.Error())
+	case errors.Is(err, webhook.ErrDelivery):
+		// The reason is shown, as only admins redeliver the events
+		problem(c, http.StatusBadGateway, err.Error())
+	default:
+		log.Printf("requestId: %s  error: %s\n", c.GetString(requestIdKey), err)
+		problem(c, http.StatusInternalServerError, internalErrorDetail)
+	}
+}
+
+// errorDetail returns the message of the error that can be shown to the
+// client, for responses that report several errors such as the import.
+func errorDetail(c *gin.Context, err error) string {
+	var invalid invalidError
+	switch {
+	case errors.As(err, &invalid):
+		return invalid.Error()
+	case errors.Is(err, geocode.ErrGeocode):
+		log.Printf("requestId: %s  error: %s\n", c.GetString(requestIdKey), err)
+		return geocode.ErrGeocode.Error()
+	default:
+		log.Printf("requestId: %s  error: %s\n", c.GetString(requestIdKey), err)
+		return internalErrorDetail
+	}
+}
+
+// invalidError is an error of the input of the client, whose message can
+// be shown to it.
+type invalidError string
+
+func (e invalidError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/geocode"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RespondError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		err          error
+		responseCode int
+		responseBody string
+	}{
+		{
+			name:         "not found",
+			err:          fmt.Errorf("reading restaurant: %w", dynamo.ErrNotFound),
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"reading restaurant: restaurant not found","instance":"/restId","requestId":"reqId","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			name:         "forbidden",
+			err:          dynamo.ErrForbidden,
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the owner of the restaurant or an admin can modify it","instance":"/restId","requestId":"reqId","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "precondition failed",
+			err:          dynamo.ErrPreconditionFailed,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","instance":"/restId","requestId":"reqId","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+		},
+		{
+			name:         "geocoder error",
+			err:          fmt.Errorf("%w: %v", geocode.ErrGeocode, errors.New("AccessDeniedException: not authorized")),
+			responseCode: http.StatusBadGateway,
+			responseBody: `{"detail":"the address could not be geocoded","instance":"/restId","requestId":"reqId","status":502,"title":"Bad Gateway","type":"about:blank"}`,
+		},
+		{
+			name:         "unexpected error",
+			err:          errors.New("ResourceNotFoundException: table not found"),
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","instance":"/restId","requestId":"reqId","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodGet, "/restId", nil)
+			c.Set(requestIdKey, "reqId")
+
+			respondError(c, tc.err)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, w.Body.String())
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+			assert.True(t, c.IsAborted())
+		})
+	}
+}
+
+func Test_RequestId(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		requestId string
+		generated bool
+	}{
+		{
+			name:      "client request ID",
+			requestId: "abc-123",
+		},
+		{
+			name:      "no request ID",
+			generated: true,
+		},
+		{
+			name:      "request ID too long",
+			requestId: strings.Repeat("a", 129),
+			generated: true,
+		},
+		{
+			name:      "request ID with spaces",
+			requestId: "abc 123",
+			generated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			router := gin.New()
+			router.Use(RequestId)
+			router.GET("/:restaurantId", func(c *gin.Context) {
+				problem(c, http.StatusBadRequest, "restaurantId is not valid")
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/restId", nil)
+			if tc.requestId != "" {
+				r.Header.Set(RequestIdHeader, tc.requestId)
+			}
+
+			router.ServeHTTP(w, r)
+
+			requestId := w.Header().Get(RequestIdHeader)
+			if tc.generated {
+				assert.Len(t, requestId, 36)
+			} else {
+				assert.Equal(t, tc.requestId, requestId)
+			}
+			assert.Equal(t, `{"detail":"restaurantId is not valid","instance":"/restId","requestId":"`+requestId+`","status":400,"title":"Bad Request","type":"about:blank"}`, w.Body.String())
+		})
+	}
+}
+
+// responseBody returns the response body. The instance and request ID of a
+// problem are removed, as they depend on the request.
+func responseBody(w *httptest.ResponseRecorder) string {
+	if w.Header().Get("Content-Type") != ProblemContentType {
+		return w.Body.String()
+	}
+
+	var p map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		return w.Body.String()
+	}
+	delete(p, "instance")
+	delete(p, "requestId")
+	b, _ := json.Marshal(p)
+	return string(b)
+}
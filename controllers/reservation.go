@@ -0,0 +1,458 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/booking"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/hours"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ReservationTokenHeader carries the management token of a reservation,
+// with which a guest reads, modifies and cancels it
+const ReservationTokenHeader = "X-Reservation-Token"
+
+var errNoTable = errors.New("no table is available for the party")
+
+type ReservationStorer interface {
+	GetSettings(tenant, restaurantId string) (model.ReservationSettings, error)
+	SaveSettings(tenant, restaurantId string, settings model.ReservationSettings, actor dynamo.Actor) error
+	Locks(tenant, restaurantId string, from, to time.Time) ([]booking.Lock, error)
+	SaveReservation(tenant, restaurantId string, reservation model.Reservation, tokenHash string, slots []time.Time) error
+	GetReservation(tenant, restaurantId, reservationId string) (model.Reservation, string, error)
+	UpdateReservation(tenant, restaurantId string, reservation model.Reservation, slots []time.Time) error
+	CancelReservation(tenant, restaurantId, reservationId string) error
+}
+
+type Reservation struct {
+	Restaurant  RestaurantStorer
+	Reservation ReservationStorer
+}
+
+// schedule is what reservations are booked against: the opening hours and
+// timezone of the restaurant and its reservation settings.
+type schedule struct {
+	hours    []model.OpeningInterval
+	loc      *time.Location
+	settings model.ReservationSettings
+}
+
+func (r Reservation) ReadSettings(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	log.Printf("Reservation.ReadSettings restaurantId: %s\n", restaurantId)
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	settings, err := r.Reservation.GetSettings(tenant(c), restaurantId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// SaveSettings creates or replaces the tables and slot configuration of the
+// restaurant. Existing reservations keep their tables and slots.
+func (r Reservation) SaveSettings(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var settings model.ReservationSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		bindError(c, err, "error binding request body")
+		return
+	}
+
+	if err := booking.Validate(settings); err != nil {
+		problem(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	log.Printf("Reservation.SaveSettings restaurantId: %s  tables: %d\n", restaurantId, len(settings.Tables))
+
+	if err := r.Reservation.SaveSettings(tenant(c), restaurantId, settings, actor(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// Availability returns the slots of a day in which the restaurant is open
+// for the whole duration of a reservation and a table seats the party.
+func (r Reservation) Availability(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var params model.GetRestaurantIdReservationsAvailabilityParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+	if params.PartySize < 1 {
+		problem(c, http.StatusBadRequest, "partySize must be at least 1")
+		return
+	}
+	date, err := time.Parse("2006-01-02", params.Date)
+	if err != nil {
+		problem(c, http.StatusBadRequest, "date must be a date in YYYY-MM-DD format")
+		return
+	}
+
+	log.Printf("Reservation.Availability restaurantId: %s  date: %s  partySize: %d\n", restaurantId, params.Date, params.PartySize)
+
+	s, ok := r.schedule(c, restaurantId)
+	if !ok {
+		return
+	}
+
+	availability := model.Availability{Date: params.Date, PartySize: params.PartySize, Slots: []time.Time{}}
+	daySlots := booking.DaySlots(s.settings, s.loc, date)
+	if len(daySlots) == 0 {
+		c.JSON(http.StatusOK, availability)
+		return
+	}
+
+	// The locks of the day, including those of reservations in its last
+	// slots that last into the next day
+	last := booking.Occupied(s.settings, daySlots[len(daySlots)-1])
+	locks, err := r.Reservation.Locks(tenant(c), restaurantId, daySlots[0], last[len(last)-1])
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	now := time.Now()
+	_, duration := booking.Durations(s.settings)
+	for _, start := range daySlots {
+		if start.Before(now) || !hours.OpenBetween(s.hours, s.loc, start, start.Add(duration)) {
+			continue
+		}
+		if len(booking.FreeTables(s.settings, params.PartySize, booking.Occupied(s.settings, start), locks, "")) > 0 {
+			availability.Slots = append(availability.Slots, start)
+		}
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// Create books the smallest free table that seats the party. The table is
+// locked in DynamoDB for every slot of the reservation, so concurrent
+// bookings of the same table fail and the next free table is tried. The
+// management token of the reservation is only returned in the response,
+// as only its hash is stored.
+func (r Reservation) Create(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var reservation model.Reservation
+	if err := c.ShouldBindJSON(&reservation); err != nil {
+		bindError(c, err, "error binding request body")
+		return
+	}
+	if !validReservation(c, &reservation) {
+		return
+	}
+
+	s, ok := r.schedule(c, restaurantId)
+	if !ok || !checkStart(c, s, reservation.Start) {
+		return
+	}
+
+	token := make([]byte, secretSize)
+	if _, err := rand.Read(token); err != nil {
+		respondError(c, err)
+		return
+	}
+	encodedToken := base64.RawURLEncoding.EncodeToString(token)
+
+	id := uuid.NewString()
+	status := model.Booked
+	reservation.Id = &id
+	reservation.Status = &status
+	reservation.TableId = nil
+	reservation.ManagementToken = nil
+
+	log.Printf("Reservation.Create restaurantId: %s  reservationId: %s  partySize: %d  start: %s\n", restaurantId, id, reservation.PartySize, reservation.Start)
+
+	save := func(reservation model.Reservation, slots []time.Time) error {
+		return r.Reservation.SaveReservation(tenant(c), restaurantId, reservation, hashSecret(encodedToken), slots)
+	}
+	if err := r.book(tenant(c), restaurantId, &reservation, s, "", save); err != nil {
+		bookingError(c, err)
+		return
+	}
+
+	reservation.ManagementToken = &encodedToken
+	c.JSON(http.StatusCreated, reservation)
+}
+
+func (r Reservation) Read(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+	reservationId := c.Param("reservationId")
+
+	log.Printf("Reservation.Read restaurantId: %s  reservationId: %s\n", restaurantId, reservationId)
+
+	// Validate input
+	if restaurantId == "" || reservationId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId or reservationId is empty")
+		return
+	}
+
+	reservation, ok := r.manageable(c, restaurantId, reservationId)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// Update modifies the party size, slot or contact details of a booked
+// reservation. It keeps its table when the table is still free and seats
+// the party.
+func (r Reservation) Update(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+	reservationId := c.Param("reservationId")
+
+	// Validate input
+	if restaurantId == "" || reservationId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId or reservationId is empty")
+		return
+	}
+
+	var reservation model.Reservation
+	if err := c.ShouldBindJSON(&reservation); err != nil {
+		bindError(c, err, "error binding request body")
+		return
+	}
+	if !validReservation(c, &reservation) {
+		return
+	}
+
+	current, ok := r.manageable(c, restaurantId, reservationId)
+	if !ok {
+		return
+	}
+	if current.Status != nil && *current.Status == model.Cancelled {
+		respondError(c, dynamo.ErrReservationCancelled)
+		return
+	}
+
+	s, ok := r.schedule(c, restaurantId)
+	if !ok || !checkStart(c, s, reservation.Start) {
+		return
+	}
+
+	status := model.Booked
+	reservation.Id = &reservationId
+	reservation.Status = &status
+	reservation.TableId = nil
+	reservation.ManagementToken = nil
+
+	log.Printf("Reservation.Update restaurantId: %s  reservationId: %s  partySize: %d  start: %s\n", restaurantId, reservationId, reservation.PartySize, reservation.Start)
+
+	var currentTable string
+	if current.TableId != nil {
+		currentTable = *current.TableId
+	}
+	save := func(reservation model.Reservation, slots []time.Time) error {
+		return r.Reservation.UpdateReservation(tenant(c), restaurantId, reservation, slots)
+	}
+	if err := r.book(tenant(c), restaurantId, &reservation, s, currentTable, save); err != nil {
+		bookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// Cancel cancels the reservation, releasing its table.
+func (r Reservation) Cancel(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+	reservationId := c.Param("reservationId")
+
+	// Validate input
+	if restaurantId == "" || reservationId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId or reservationId is empty")
+		return
+	}
+
+	log.Printf("Reservation.Cancel restaurantId: %s  reservationId: %s\n", restaurantId, reservationId)
+
+	if _, ok := r.manageable(c, restaurantId, reservationId); !ok {
+		return
+	}
+
+	if err := r.Reservation.CancelReservation(tenant(c), restaurantId, reservationId); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "")
+}
+
+// manageable reads the reservation, provided the request can manage it: it
+// has the management token of the reservation in the X-Reservation-Token
+// header, or it is made by the owner of the restaurant or an admin. The
+// other requests are rejected with 403, so the contact details of a guest
+// are only seen by the guest and the restaurant. ok is false when a
+// response has been written instead.
+func (r Reservation) manageable(c *gin.Context, restaurantId, reservationId string) (model.Reservation, bool) {
+	reservation, tokenHash, err := r.Reservation.GetReservation(tenant(c), restaurantId, reservationId)
+	if err != nil {
+		respondError(c, err)
+		return model.Reservation{}, false
+	}
+
+	if token := c.GetHeader(ReservationTokenHeader); token != "" && tokenHash != "" &&
+		subtle.ConstantTimeCompare([]byte(hashSecret(token)), []byte(tokenHash)) == 1 {
+		return reservation, true
+	}
+
+	if _, ok := c.Get(principalKey); ok {
+		restaurant, _, exists, err := r.Restaurant.Get(tenant(c), restaurantId)
+		if err != nil {
+			respondError(c, err)
+			return model.Reservation{}, false
+		}
+		if exists && actor(c).CanModify(restaurant) {
+			return reservation, true
+		}
+	}
+
+	problem(c, http.StatusForbidden, "only the guest with the reservation token, the owner of the restaurant or an admin can manage the reservation")
+	return model.Reservation{}, false
+}
+
+// schedule reads what reservations of the restaurant are booked against.
+// ok is false when a response has been written instead.
+func (r Reservation) schedule(c *gin.Context, restaurantId string) (schedule, bool) {
+	restaurant, _, ok, err := r.Restaurant.Get(tenant(c), restaurantId)
+	if err != nil {
+		respondError(c, err)
+		return schedule{}, false
+	}
+	if !ok {
+		respondError(c, dynamo.ErrNotFound)
+		return schedule{}, false
+	}
+
+	loc, ok := timezone(restaurant)
+	if !ok {
+		problem(c, http.StatusConflict, "restaurant has no opening hours or timezone")
+		return schedule{}, false
+	}
+
+	settings, err := r.Reservation.GetSettings(tenant(c), restaurantId)
+	if err != nil {
+		respondError(c, err)
+		return schedule{}, false
+	}
+
+	return schedule{hours: *restaurant.OpeningHours, loc: loc, settings: settings}, true
+}
+
+// book assigns a free table to the reservation of the restaurant of the
+// tenant and stores it with save. The preferred table is tried first if it
+// is free. When a table is booked concurrently, the next free table is
+// tried.
+func (r Reservation) book(tenant, restaurantId string, reservation *model.Reservation, s schedule, preferred string, save func(model.Reservation, []time.Time) error) error {
+	slots := booking.Occupied(s.settings, reservation.Start)
+	locks, err := r.Reservation.Locks(tenant, restaurantId, slots[0], slots[len(slots)-1])
+	if err != nil {
+		return err
+	}
+
+	tables := booking.FreeTables(s.settings, reservation.PartySize, slots, locks, *reservation.Id)
+	for i, table := range tables {
+		if table.Id == preferred {
+			tables[0], tables[i] = tables[i], tables[0]
+			break
+		}
+	}
+
+	for _, table := range tables {
+		tableId := table.Id
+		reservation.TableId = &tableId
+		err := save(*reservation, slots)
+		if errors.Is(err, dynamo.ErrSlotTaken) {
+			continue
+		}
+		return err
+	}
+
+	reservation.TableId = nil
+	return errNoTable
+}
+
+// checkStart checks that the reservation starts at the start of a future
+// slot and the restaurant is open for its whole duration. ok is false when
+// a response has been written instead.
+func checkStart(c *gin.Context, s schedule, start time.Time) bool {
+	if start.Before(time.Now()) {
+		problem(c, http.StatusUnprocessableEntity, "start must be in the future")
+		return false
+	}
+	if !booking.Aligned(s.settings, s.loc, start) {
+		problem(c, http.StatusUnprocessableEntity, "start must be the start of a slot")
+		return false
+	}
+
+	_, duration := booking.Durations(s.settings)
+	if !hours.OpenBetween(s.hours, s.loc, start, start.Add(duration)) {
+		problem(c, http.StatusConflict, "restaurant is not open for the reservation")
+		return false
+	}
+	return true
+}
+
+// validReservation normalizes and validates the reservation. It responds
+// with 422 Unprocessable Entity and the errors of the invalid fields when
+// the reservation is not valid.
+func validReservation(c *gin.Context, reservation *model.Reservation) bool {
+	errs := validate.Reservation(reservation)
+	if len(errs) == 0 {
+		return true
+	}
+	detail := "the reservation is not valid"
+	writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &errs})
+	return false
+}
+
+func bookingError(c *gin.Context, err error) {
+	if errors.Is(err, errNoTable) {
+		problem(c, http.StatusConflict, err.Error())
+		return
+	}
+	respondError(c, err)
+}
@@ -0,0 +1,758 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/booking"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// bookableRestaurant is open on Fridays from 17:00 to 22:00. June 7, 2030
+// is a Friday.
+func bookableRestaurant() model.Restaurant {
+	timezone := "America/Los_Angeles"
+	return model.Restaurant{
+		Address:      &model.Address{TimezoneName: &timezone},
+		OpeningHours: &[]model.OpeningInterval{{Day: model.Friday, Open: "17:00", Close: "22:00"}},
+	}
+}
+
+// guestToken is the management token of the reservations of the stub
+const guestToken = "guest-token"
+
+var reservationSettings = model.ReservationSettings{
+	Tables: []model.Table{{Id: "t1", Capacity: 2}, {Id: "t2", Capacity: 4}},
+}
+
+// friday returns the time on June 7, 2030 in the timezone of the restaurant.
+func friday(hour, min int) time.Time {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	return time.Date(2030, time.June, 7, hour, min, 0, 0, loc)
+}
+
+func Test_ReservationSaveSettings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		body         string
+		principal    string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			body:         `{"tables":[{"id":"t1","capacity":2}],"slotMinutes":15}`,
+			responseCode: http.StatusOK,
+			responseBody: `{"slotMinutes":15,"tables":[{"capacity":2,"id":"t1"}]}`,
+		},
+		{
+			name:         "not the owner",
+			body:         `{"tables":[{"id":"t1","capacity":2}],"slotMinutes":15}`,
+			principal:    "user2",
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the owner of the restaurant or an admin can modify it","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid settings",
+			body:         `{"tables":[{"id":"t1","capacity":0}]}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"tables[0].capacity must be at least 1","status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			body:         `{"tables":[]}`,
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "empty request body",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := reservationStorerStub{error: tc.stubError}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tc.principal != "" {
+				stub.owner = "user1"
+				c.Set(principalKey, principal{subject: tc.principal})
+			}
+			rc := Reservation{
+				Reservation: stub,
+			}
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}}
+			c.Request = httptest.NewRequest(http.MethodPost, "/restId/reservations/settings", bytes.NewBufferString(tc.body))
+
+			rc.SaveSettings(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_ReservationAvailability(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		query        string
+		restaurant   model.Restaurant
+		notExist     bool
+		noSettings   bool
+		locks        []booking.Lock
+		slots        int
+		responseCode int
+		responseBody string
+	}{
+		{
+			// 17:00 to 20:30, so the 90 minutes end at closing
+			name:         "happy path",
+			query:        "date=2030-06-07&partySize=2",
+			restaurant:   bookableRestaurant(),
+			slots:        8,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "table booked",
+			query:        "date=2030-06-07&partySize=3",
+			restaurant:   bookableRestaurant(),
+			locks:        []booking.Lock{{Start: friday(18, 0), TableId: "t2", ReservationId: "r1"}},
+			slots:        5,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "closed",
+			query:        "date=2030-06-08&partySize=2",
+			restaurant:   bookableRestaurant(),
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "party too large",
+			query:        "date=2030-06-07&partySize=5",
+			restaurant:   bookableRestaurant(),
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "invalid date",
+			query:        "date=June&partySize=2",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"date must be a date in YYYY-MM-DD format","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid party size",
+			query:        "date=2030-06-07&partySize=0",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"partySize must be at least 1","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "no timezone",
+			query:        "date=2030-06-07&partySize=2",
+			restaurant:   model.Restaurant{OpeningHours: bookableRestaurant().OpeningHours},
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"restaurant has no opening hours or timezone","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name:         "empty timezone",
+			query:        "date=2030-06-07&partySize=2",
+			restaurant:   model.Restaurant{Address: &model.Address{TimezoneName: new(string)}, OpeningHours: bookableRestaurant().OpeningHours},
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"restaurant has no opening hours or timezone","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name:         "no settings",
+			query:        "date=2030-06-07&partySize=2",
+			restaurant:   bookableRestaurant(),
+			noSettings:   true,
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"reservation settings not found","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			query:        "date=2030-06-07&partySize=2",
+			notExist:     true,
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Reservation{
+				Restaurant:  restaurantStorerStub{restaurant: tc.restaurant, notExist: tc.notExist},
+				Reservation: reservationStorerStub{noSettings: tc.noSettings, locks: tc.locks},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/restId/reservations/availability?"+tc.query, nil)
+
+			rc.Availability(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusOK {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				return
+			}
+
+			var availability model.Availability
+			if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &availability)) {
+				assert.Len(t, availability.Slots, tc.slots)
+			}
+		})
+	}
+}
+
+func Test_ReservationCreate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		body         string
+		locks        []booking.Lock
+		taken        map[string]bool
+		tableId      string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			tableId:      "t1",
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "smallest table booked",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			locks:        []booking.Lock{{Start: friday(20, 0), TableId: "t1", ReservationId: "r1"}},
+			tableId:      "t2",
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "table booked concurrently",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			taken:        map[string]bool{"t1": true},
+			tableId:      "t2",
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "no table",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			taken:        map[string]bool{"t1": true, "t2": true},
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"no table is available for the party","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name:         "closed at end",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T21:00:00-07:00"}`,
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"restaurant is not open for the reservation","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name:         "not a slot",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:10:00-07:00"}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"start must be the start of a slot","status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "in the past",
+			body:         `{"name":"Smith","partySize":2,"start":"2020-06-05T19:00:00-07:00"}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"start must be in the future","status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "no name",
+			body:         `{"name":" ","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the reservation is not valid","errors":[{"code":"required","field":"name","message":"name is required"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "no party",
+			body:         `{"name":"Smith","partySize":0,"start":"2030-06-07T19:00:00-07:00"}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the reservation is not valid","errors":[{"code":"out_of_range","field":"partySize","message":"partySize must be at least 1"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var tokenHash string
+			rc := Reservation{
+				Restaurant:  restaurantStorerStub{restaurant: bookableRestaurant()},
+				Reservation: reservationStorerStub{locks: tc.locks, taken: tc.taken, writeError: tc.stubError, savedTokenHash: &tokenHash},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}}
+			c.Request = httptest.NewRequest(http.MethodPost, "/restId/reservations", bytes.NewBufferString(tc.body))
+
+			rc.Create(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusCreated {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				return
+			}
+
+			var reservation model.Reservation
+			if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &reservation)) {
+				assert.NotNil(t, reservation.Id)
+				assert.Equal(t, model.Booked, *reservation.Status)
+				assert.Equal(t, tc.tableId, *reservation.TableId)
+				// Only the hash of the management token is stored
+				if assert.NotNil(t, reservation.ManagementToken) {
+					assert.Equal(t, hashSecret(*reservation.ManagementToken), tokenHash)
+				}
+			}
+		})
+	}
+}
+
+func Test_ReservationRead(t *testing.T) {
+	t.Parallel()
+
+	forbidden := `{"detail":"only the guest with the reservation token, the owner of the restaurant or an admin can manage the reservation","status":403,"title":"Forbidden","type":"about:blank"}`
+
+	testCases := []struct {
+		name         string
+		token        string
+		principal    *principal
+		noToken      bool
+		notExist     bool
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "with the token",
+			token:        guestToken,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "by the owner of the restaurant",
+			principal:    &principal{subject: "user1"},
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "by an admin",
+			principal:    &principal{subject: "user2", admin: true},
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "by another principal",
+			principal:    &principal{subject: "user2"},
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "by another principal with a wrong token",
+			token:        "other-token",
+			principal:    &principal{subject: "user2"},
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "anonymous",
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "booked before tokens",
+			token:        guestToken,
+			noToken:      true,
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "restaurant deleted",
+			principal:    &principal{subject: "user1"},
+			notExist:     true,
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "reservation does not exist",
+			token:        guestToken,
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"reservation not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrReservationNotFound.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			restaurant := bookableRestaurant()
+			owner := "user1"
+			restaurant.Owner = &owner
+			rc := Reservation{
+				Restaurant:  restaurantStorerStub{restaurant: restaurant, notExist: tc.notExist},
+				Reservation: reservationStorerStub{status: model.Booked, noToken: tc.noToken, error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tc.principal != nil {
+				c.Set(principalKey, *tc.principal)
+			}
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}, {Key: "reservationId", Value: "r1"}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/restId/reservations/r1", nil)
+			if tc.token != "" {
+				c.Request.Header.Set(ReservationTokenHeader, tc.token)
+			}
+
+			rc.Read(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusOK {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				return
+			}
+
+			var reservation model.Reservation
+			if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &reservation)) {
+				assert.Equal(t, "r1", *reservation.Id)
+				assert.Nil(t, reservation.ManagementToken)
+			}
+		})
+	}
+}
+
+func Test_ReservationUpdate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		body         string
+		status       model.ReservationStatus
+		locks        []booking.Lock
+		token        string
+		principal    *principal
+		tableId      string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "keeps table",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:30:00-07:00"}`,
+			status:       model.Booked,
+			locks:        []booking.Lock{{Start: friday(19, 0), TableId: "t2", ReservationId: "r1"}},
+			token:        guestToken,
+			tableId:      "t2",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "by the owner of the restaurant",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			status:       model.Booked,
+			principal:    &principal{subject: "user1"},
+			tableId:      "t1",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "by another principal",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			status:       model.Booked,
+			principal:    &principal{subject: "user2"},
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the guest with the reservation token, the owner of the restaurant or an admin can manage the reservation","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "wrong token",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			status:       model.Booked,
+			token:        "other-token",
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the guest with the reservation token, the owner of the restaurant or an admin can manage the reservation","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "larger party",
+			body:         `{"name":"Smith","partySize":4,"start":"2030-06-07T19:00:00-07:00"}`,
+			status:       model.Booked,
+			locks:        []booking.Lock{{Start: friday(19, 0), TableId: "t1", ReservationId: "r1"}},
+			token:        guestToken,
+			tableId:      "t2",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "cancelled",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			status:       model.Cancelled,
+			token:        guestToken,
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"reservation is cancelled","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name:         "no party",
+			body:         `{"name":"Smith","partySize":0,"start":"2030-06-07T19:00:00-07:00"}`,
+			status:       model.Booked,
+			token:        guestToken,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the reservation is not valid","errors":[{"code":"out_of_range","field":"partySize","message":"partySize must be at least 1"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "reservation does not exist",
+			body:         `{"name":"Smith","partySize":2,"start":"2030-06-07T19:00:00-07:00"}`,
+			token:        guestToken,
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"reservation not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrReservationNotFound.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			restaurant := bookableRestaurant()
+			owner := "user1"
+			restaurant.Owner = &owner
+			rc := Reservation{
+				Restaurant:  restaurantStorerStub{restaurant: restaurant},
+				Reservation: reservationStorerStub{status: tc.status, locks: tc.locks, error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tc.principal != nil {
+				c.Set(principalKey, *tc.principal)
+			}
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}, {Key: "reservationId", Value: "r1"}}
+			c.Request = httptest.NewRequest(http.MethodPost, "/restId/reservations/r1", bytes.NewBufferString(tc.body))
+			if tc.token != "" {
+				c.Request.Header.Set(ReservationTokenHeader, tc.token)
+			}
+
+			rc.Update(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusOK {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				return
+			}
+
+			var reservation model.Reservation
+			if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &reservation)) {
+				assert.Equal(t, "r1", *reservation.Id)
+				assert.Equal(t, tc.tableId, *reservation.TableId)
+			}
+		})
+	}
+}
+
+func Test_ReservationCancel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		reservationId string
+		token         string
+		principal     *principal
+		responseCode  int
+		responseBody  string
+		stubError     string
+	}{
+		{
+			name:          "happy path",
+			reservationId: "r1",
+			token:         guestToken,
+			responseCode:  http.StatusOK,
+			responseBody:  `""`,
+		},
+		{
+			name:          "by an admin",
+			reservationId: "r1",
+			principal:     &principal{subject: "user2", admin: true},
+			responseCode:  http.StatusOK,
+			responseBody:  `""`,
+		},
+		{
+			name:          "by another principal",
+			reservationId: "r1",
+			principal:     &principal{subject: "user2"},
+			responseCode:  http.StatusForbidden,
+			responseBody:  `{"detail":"only the guest with the reservation token, the owner of the restaurant or an admin can manage the reservation","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:          "anonymous",
+			reservationId: "r1",
+			responseCode:  http.StatusForbidden,
+			responseBody:  `{"detail":"only the guest with the reservation token, the owner of the restaurant or an admin can manage the reservation","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:          "already cancelled",
+			reservationId: "r1",
+			token:         guestToken,
+			responseCode:  http.StatusConflict,
+			responseBody:  `{"detail":"reservation is cancelled","status":409,"title":"Conflict","type":"about:blank"}`,
+			stubError:     dynamo.ErrReservationCancelled.Error(),
+		},
+		{
+			name:         "empty reservationId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId or reservationId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			restaurant := bookableRestaurant()
+			owner := "user1"
+			restaurant.Owner = &owner
+			rc := Reservation{
+				Restaurant:  restaurantStorerStub{restaurant: restaurant},
+				Reservation: reservationStorerStub{writeError: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tc.principal != nil {
+				c.Set(principalKey, *tc.principal)
+			}
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}, {Key: "reservationId", Value: tc.reservationId}}
+			c.Request = httptest.NewRequest(http.MethodDelete, "/restId/reservations/"+tc.reservationId, nil)
+			if tc.token != "" {
+				c.Request.Header.Set(ReservationTokenHeader, tc.token)
+			}
+
+			rc.Cancel(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+// reservationStorerStub rejects bookings of the taken tables with
+// ErrSlotTaken. error fails the reads and writeError the writes.
+// reservationStorerStub checks the owner of the restaurant when owner is set.
+// Its reservations have the management token guestToken, unless noToken is
+// set, and the hash of the token of a saved reservation is kept in
+// savedTokenHash when it is set.
+type reservationStorerStub struct {
+	owner          string
+	noSettings     bool
+	noToken        bool
+	locks          []booking.Lock
+	taken          map[string]bool
+	status         model.ReservationStatus
+	savedTokenHash *string
+	error          string
+	writeError     string
+}
+
+func (s reservationStorerStub) GetSettings(_, _ string) (model.ReservationSettings, error) {
+	if s.error != "" {
+		return model.ReservationSettings{}, stubErr(s.error)
+	}
+	if s.noSettings {
+		return model.ReservationSettings{}, dynamo.ErrSettingsNotFound
+	}
+	return reservationSettings, nil
+}
+
+func (s reservationStorerStub) SaveSettings(_, _ string, _ model.ReservationSettings, actor dynamo.Actor) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return stubOwnerErr(s.owner, actor)
+}
+
+func (s reservationStorerStub) Locks(_, _ string, _, _ time.Time) ([]booking.Lock, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	return s.locks, nil
+}
+
+func (s reservationStorerStub) SaveReservation(_, _ string, reservation model.Reservation, tokenHash string, _ []time.Time) error {
+	if s.writeError != "" {
+		return stubErr(s.writeError)
+	}
+	if s.savedTokenHash != nil {
+		*s.savedTokenHash = tokenHash
+	}
+	if s.taken[*reservation.TableId] {
+		return dynamo.ErrSlotTaken
+	}
+	return nil
+}
+
+func (s reservationStorerStub) GetReservation(_, _, reservationId string) (model.Reservation, string, error) {
+	if s.error != "" {
+		return model.Reservation{}, "", stubErr(s.error)
+	}
+	tableId, status := "t1", s.status
+	for _, l := range s.locks {
+		if l.ReservationId == reservationId {
+			tableId = l.TableId
+		}
+	}
+	tokenHash := hashSecret(guestToken)
+	if s.noToken {
+		tokenHash = ""
+	}
+	return model.Reservation{Id: &reservationId, Name: "Smith", PartySize: 2, Start: friday(19, 0), Status: &status, TableId: &tableId}, tokenHash, nil
+}
+
+func (s reservationStorerStub) UpdateReservation(_, _ string, reservation model.Reservation, _ []time.Time) error {
+	if s.writeError != "" {
+		return stubErr(s.writeError)
+	}
+	if s.taken[*reservation.TableId] {
+		return dynamo.ErrSlotTaken
+	}
+	return nil
+}
+
+func (s reservationStorerStub) CancelReservation(_, _, _ string) error {
+	if s.writeError != "" {
+		return stubErr(s.writeError)
+	}
+	return nil
+}
@@ -1,18 +1,41 @@
 package controllers
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/geo"
+	"github.com/lfroomin/restaurant-container/internal/mergepatch"
 	"github.com/lfroomin/restaurant-container/internal/model"
+	"io"
 	"log"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+
+	defaultRadius = 1000
+	maxRadius     = 50000
 )
 
 type RestaurantStorer interface {
-	Save(restaurant model.Restaurant) error
-	Get(restaurantId string) (model.Restaurant, bool, error)
-	Update(restaurant model.Restaurant) error
-	Delete(restaurantId string) error
+	Save(tenant string, restaurant model.Restaurant, actor dynamo.Actor) error
+	SaveBatch(tenant string, restaurants []model.Restaurant, actor dynamo.Actor) []error
+	Get(tenant, restaurantId string) (model.Restaurant, int64, bool, error)
+	Update(tenant string, restaurant model.Restaurant, ifMatch []int64, actor dynamo.Actor) (model.Restaurant, int64, error)
+	Delete(tenant, restaurantId string, ifMatch []int64, actor dynamo.Actor) error
+	Restore(tenant, restaurantId string, actor dynamo.Actor) (model.Restaurant, int64, error)
+	List(tenant string, limit int32, nextToken string) ([]model.Restaurant, string, error)
+	Nearby(tenant string, center geo.Point, radius float64) ([]model.NearbyRestaurant, error)
 }
 
 type Geocoder interface {
@@ -22,37 +45,69 @@ type Geocoder interface {
 type Restaurant struct {
 	Restaurant RestaurantStorer
 	Location   Geocoder
+	Index      SearchIndex
+	Tags       TagStorer
+	// Events delivers the changes of the restaurants to the webhooks, none
+	// are delivered when it is nil
+	Events EventPublisher
+	// DuplicateDistance is the distance in meters within which restaurants
+	// with similar names are duplicates
+	DuplicateDistance float64
 }
 
 func (r Restaurant) Create(c *gin.Context) {
+	var params model.PostParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+
 	var restaurant model.Restaurant
 	err := c.ShouldBindJSON(&restaurant)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"Message": "error binding request body"})
+		bindError(c, err, "error binding request body")
+		return
+	}
+
+	clearComputed(&restaurant)
+	if !r.validRestaurant(c, &restaurant) {
 		return
 	}
 
 	id := uuid.NewString()
 	restaurant.Id = &id
+	restaurant.Owner = owner(c)
 	log.Printf("Restaurant.Create restaurantName: %s  restaurantId: %s\n", restaurant.Name, *restaurant.Id)
 
 	// Get the geocode of the restaurant address
-	if restaurant.Address != nil {
-		location, timezoneName, err := r.Location.Geocode(*restaurant.Address)
+	if err := r.geocode(restaurant.Address); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// The same restaurant may already exist with a slightly different name
+	if params.Force == nil || !*params.Force {
+		duplicates, err := r.duplicates(tenant(c), restaurant)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"Message": err.Error()})
+			respondError(c, err)
+			return
+		}
+		if len(duplicates) > 0 {
+			detail := "the restaurant is probably a duplicate, use force=true to create it anyway"
+			writeProblem(c, model.Problem{Status: http.StatusConflict, Detail: &detail, Duplicates: &duplicates})
 			return
 		}
-
-		restaurant.Address.Location = &location
-		restaurant.Address.TimezoneName = &timezoneName
 	}
 
-	if err := r.Restaurant.Save(restaurant); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"Message": err.Error()})
+	if err := r.Restaurant.Save(tenant(c), restaurant, actor(c)); err != nil {
+		respondError(c, err)
 		return
 	}
+	r.Index.Add(tenant(c), restaurant)
+	publish(c, r.Events, model.RestaurantCreated, *restaurant.Id, &restaurant)
 
+	setOpenStatus(&restaurant, time.Now())
+	c.Header("ETag", etag(1))
 	c.JSON(http.StatusCreated, restaurant)
 }
 
@@ -63,77 +118,464 @@ func (r Restaurant) Read(c *gin.Context) {
 
 	// Validate input
 	if restaurantId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"Message": "restaurantId is empty"})
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
 		return
 	}
 
-	restaurant, exists, err := r.Restaurant.Get(restaurantId)
+	restaurant, version, exists, err := r.Restaurant.Get(tenant(c), restaurantId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"Message": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	if !exists {
-		c.AbortWithStatus(http.StatusNotFound)
+		respondError(c, dynamo.ErrNotFound)
 		return
 	}
 
+	setOpenStatus(&restaurant, time.Now())
+	c.Header("ETag", etag(version))
 	c.JSON(http.StatusOK, restaurant)
 }
 
+func (r Restaurant) List(c *gin.Context) {
+	var params model.GetParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+
+	limit := int32(defaultLimit)
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > maxLimit {
+		problem(c, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	var nextToken string
+	if params.NextToken != nil {
+		nextToken = *params.NextToken
+	}
+
+	tags := tagParams(params.Tags)
+
+	log.Printf("Restaurant.List limit: %d  nextToken: %s  tags: %v\n", limit, nextToken, tags)
+
+	var restaurants []model.Restaurant
+	var token string
+	var facets *model.Facets
+	var err error
+	if len(tags) > 0 {
+		restaurants, token, facets, err = r.filter(tenant(c), tags, limit, nextToken)
+	} else {
+		restaurants, token, err = r.Restaurant.List(tenant(c), limit, nextToken)
+		if err == nil && params.Facets != nil && *params.Facets {
+			facets, err = r.facets(tenant(c))
+		}
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	now := time.Now()
+	resp := model.RestaurantList{Restaurants: make([]model.Restaurant, 0, len(restaurants)), Facets: facets}
+	for _, restaurant := range restaurants {
+		if params.OpenAt != nil && !openAt(restaurant, *params.OpenAt) {
+			continue
+		}
+		setOpenStatus(&restaurant, now)
+		resp.Restaurants = append(resp.Restaurants, restaurant)
+	}
+	if token != "" {
+		resp.NextToken = &token
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// filter returns up to limit restaurants of the tenant having every tag,
+// ordered by ID, after the restaurant whose ID is encoded in nextToken, and
+// the facet counts of every restaurant having the tags. The restaurants are
+// found in the members of the tags, so the table is not scanned.
+func (r Restaurant) filter(tenant string, tags []string, limit int32, nextToken string) ([]model.Restaurant, string, *model.Facets, error) {
+	after, err := base64.RawURLEncoding.DecodeString(nextToken)
+	if err != nil {
+		return nil, "", nil, dynamo.ErrInvalidToken
+	}
+
+	restaurantIds, counts, err := r.Tags.Tagged(tenant, tags)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	start := sort.Search(len(restaurantIds), func(i int) bool {
+		return restaurantIds[i] > string(after)
+	})
+	restaurantIds = restaurantIds[start:]
+
+	token := ""
+	if len(restaurantIds) > int(limit) {
+		restaurantIds = restaurantIds[:limit]
+		token = base64.RawURLEncoding.EncodeToString([]byte(restaurantIds[len(restaurantIds)-1]))
+	}
+
+	restaurants := make([]model.Restaurant, 0, len(restaurantIds))
+	for _, restaurantId := range restaurantIds {
+		restaurant, _, exists, err := r.Restaurant.Get(tenant, restaurantId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		// The restaurant was deleted after its tags were read
+		if !exists {
+			continue
+		}
+		restaurants = append(restaurants, restaurant)
+	}
+
+	facets := model.Facets(counts)
+	return restaurants, token, &facets, nil
+}
+
+// facets returns the facet counts of every restaurant of the tenant.
+func (r Restaurant) facets(tenant string) (*model.Facets, error) {
+	counts, err := r.Tags.Facets(tenant)
+	if err != nil {
+		return nil, err
+	}
+	facets := model.Facets(counts)
+	return &facets, nil
+}
+
+func (r Restaurant) Nearby(c *gin.Context) {
+	var params model.GetNearbyParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+
+	_, latOk := c.GetQuery("lat")
+	_, lonOk := c.GetQuery("lon")
+	center := geo.Point{Lat: params.Lat, Lon: params.Lon}
+	if !latOk || !lonOk || !center.Valid() {
+		problem(c, http.StatusBadRequest, "lat and lon must be a valid location")
+		return
+	}
+
+	radius := float64(defaultRadius)
+	if params.Radius != nil {
+		radius = *params.Radius
+	}
+	if radius < 1 || radius > maxRadius {
+		problem(c, http.StatusBadRequest, "radius must be between 1 and 50000")
+		return
+	}
+
+	log.Printf("Restaurant.Nearby lat: %f  lon: %f  radius: %f\n", center.Lat, center.Lon, radius)
+
+	restaurants, err := r.Restaurant.Nearby(tenant(c), center, radius)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	now := time.Now()
+	resp := model.NearbyRestaurantList{Restaurants: make([]model.NearbyRestaurant, 0, len(restaurants))}
+	for _, restaurant := range restaurants {
+		if params.OpenAt != nil && !openAt(restaurant.Restaurant, *params.OpenAt) {
+			continue
+		}
+		setOpenStatus(&restaurant.Restaurant, now)
+		resp.Restaurants = append(resp.Restaurants, restaurant)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 func (r Restaurant) Update(c *gin.Context) {
 	restaurantId := c.Param("restaurantId")
 
 	var restaurant model.Restaurant
 	err := c.ShouldBindJSON(&restaurant)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"Message": "error binding request body"})
+		bindError(c, err, "error binding request body")
+		return
+	}
+
+	clearComputed(&restaurant)
+	if !r.validRestaurant(c, &restaurant) {
 		return
 	}
 
 	if restaurant.Id == nil || restaurantId != *restaurant.Id {
-		c.JSON(http.StatusBadRequest, gin.H{"Message": "restaurantId in URL path parameters and restaurant in body do not match"})
+		problem(c, http.StatusBadRequest, "restaurantId in URL path parameters and restaurant in body do not match")
+		return
+	}
+
+	versions, ok := ifMatch(c)
+	if !ok {
+		problem(c, http.StatusPreconditionFailed, dynamo.ErrPreconditionFailed.Error())
 		return
 	}
 
 	log.Printf("Restaurant.Update restaurantName: %s  restaurantId: %s\n", restaurant.Name, *restaurant.Id)
 
 	// Get the geocode of the restaurant address
-	if restaurant.Address != nil {
-		location, timezoneName, err := r.Location.Geocode(*restaurant.Address)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"Message": err.Error()})
+	if err := r.geocode(restaurant.Address); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	restaurant, version, err := r.Restaurant.Update(tenant(c), restaurant, versions, actor(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	r.Index.Add(tenant(c), restaurant)
+	publish(c, r.Events, model.RestaurantUpdated, *restaurant.Id, &restaurant)
+
+	setOpenStatus(&restaurant, time.Now())
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusOK, restaurant)
+}
+
+// Patch applies a JSON Merge Patch (RFC 7396) to the stored restaurant. The
+// address is only geocoded again when one of its fields changed. The update
+// is conditional on the version that was patched, so a concurrent write
+// results in 412 Precondition Failed instead of being overwritten.
+func (r Restaurant) Patch(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	if c.ContentType() != mergepatch.ContentType {
+		problem(c, http.StatusUnsupportedMediaType, "content type must be "+mergepatch.ContentType)
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		problem(c, http.StatusBadRequest, "error reading request body")
+		return
+	}
+
+	versions, ok := ifMatch(c)
+	if !ok {
+		problem(c, http.StatusPreconditionFailed, dynamo.ErrPreconditionFailed.Error())
+		return
+	}
+
+	log.Printf("Restaurant.Patch restaurantId: %s\n", restaurantId)
+
+	stored, version, exists, err := r.Restaurant.Get(tenant(c), restaurantId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if !exists {
+		respondError(c, dynamo.ErrNotFound)
+		return
+	}
+
+	if len(versions) > 0 && !containsVersion(versions, version) {
+		problem(c, http.StatusPreconditionFailed, dynamo.ErrPreconditionFailed.Error())
+		return
+	}
+
+	target, err := json.Marshal(stored)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	patched, err := mergepatch.Apply(target, patch)
+	if err != nil {
+		problem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var restaurant model.Restaurant
+	if err = json.Unmarshal(patched, &restaurant); err != nil {
+		bindError(c, err, "patched restaurant is not valid")
+		return
+	}
+
+	if restaurant.Id == nil || restaurantId != *restaurant.Id {
+		problem(c, http.StatusBadRequest, "restaurantId cannot be changed")
+		return
+	}
+
+	clearComputed(&restaurant)
+	restaurant.Owner = stored.Owner
+	if !r.validRestaurant(c, &restaurant) {
+		return
+	}
+
+	// The location and timezone are derived from the address, so they are
+	// only replaced by geocoding a changed address
+	if addressChanged(stored.Address, restaurant.Address) {
+		if err := r.geocode(restaurant.Address); err != nil {
+			respondError(c, err)
 			return
 		}
-
-		restaurant.Address.Location = &location
-		restaurant.Address.TimezoneName = &timezoneName
+	} else if restaurant.Address != nil {
+		restaurant.Address.Location = stored.Address.Location
+		restaurant.Address.TimezoneName = stored.Address.TimezoneName
 	}
 
-	if err := r.Restaurant.Update(restaurant); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"Message": err.Error()})
+	restaurant, version, err = r.Restaurant.Update(tenant(c), restaurant, []int64{version}, actor(c))
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	r.Index.Add(tenant(c), restaurant)
+	publish(c, r.Events, model.RestaurantUpdated, *restaurant.Id, &restaurant)
 
+	setOpenStatus(&restaurant, time.Now())
+	c.Header("ETag", etag(version))
 	c.JSON(http.StatusOK, restaurant)
 }
 
+// Delete marks the restaurant as deleted. The menu is stored with the
+// restaurant, so it is deleted, restored and purged along with it.
 func (r Restaurant) Delete(c *gin.Context) {
 	restaurantId := c.Param("restaurantId")
 
 	// Validate input
 	if restaurantId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"Message": "restaurantId is empty"})
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	versions, ok := ifMatch(c)
+	if !ok {
+		problem(c, http.StatusPreconditionFailed, dynamo.ErrPreconditionFailed.Error())
 		return
 	}
 
 	log.Printf("Restaurant.Delete restaurantId: %s\n", restaurantId)
 
-	err := r.Restaurant.Delete(restaurantId)
+	err := r.Restaurant.Delete(tenant(c), restaurantId, versions, actor(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"Message": err.Error()})
+		respondError(c, err)
 		return
 	}
+	r.Index.Remove(tenant(c), restaurantId)
+	publish(c, r.Events, model.RestaurantDeleted, restaurantId, nil)
 
 	c.JSON(http.StatusOK, "")
 }
+
+// clearComputed removes the fields that are computed for the responses and
+// never stored, the owner, which is set by the service, and the photos,
+// which are changed with their own routes, so values sent by the client are
+// dropped.
+func clearComputed(restaurant *model.Restaurant) {
+	restaurant.OpenNow, restaurant.NextChange = nil, nil
+	restaurant.RatingAverage, restaurant.RatingCount = nil, nil
+	restaurant.Owner = nil
+	restaurant.Photos = nil
+}
+
+// geocode sets the location and timezone of the address, if there is one.
+func (r Restaurant) geocode(address *model.Address) error {
+	if address == nil {
+		return nil
+	}
+
+	location, timezoneName, err := r.Location.Geocode(*address)
+	if err != nil {
+		return err
+	}
+
+	address.Location = &location
+	address.TimezoneName = &timezoneName
+	return nil
+}
+
+// addressChanged reports whether the fields used for geocoding differ
+// between the two addresses.
+func addressChanged(a1, a2 *model.Address) bool {
+	if a1 == nil || a2 == nil {
+		return a1 != a2
+	}
+
+	c1, c2 := *a1, *a2
+	c1.Location, c1.TimezoneName = nil, nil
+	c2.Location, c2.TimezoneName = nil, nil
+	return !reflect.DeepEqual(c1, c2)
+}
+
+// Restore undoes the deletion of a restaurant that is still within the
+// retention period.
+func (r Restaurant) Restore(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	log.Printf("Restaurant.Restore restaurantId: %s\n", restaurantId)
+
+	restaurant, version, err := r.Restaurant.Restore(tenant(c), restaurantId, actor(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	r.Index.Add(tenant(c), restaurant)
+	// The restaurant was deleted for the receivers, so it is created again
+	publish(c, r.Events, model.RestaurantCreated, restaurantId, &restaurant)
+
+	setOpenStatus(&restaurant, time.Now())
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusOK, restaurant)
+}
+
+// etag returns the entity tag of a restaurant version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch returns the versions listed in the If-Match header. No versions
+// means there is no precondition (no header or "*"). ok is false when the
+// header only contains entity tags that can never match, such as weak or
+// malformed ones.
+func ifMatch(c *gin.Context) ([]int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil || v < 0 {
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, len(versions) > 0
+}
+
+func containsVersion(versions []int64, version int64) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -4,20 +4,26 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/geo"
+	"github.com/lfroomin/restaurant-container/internal/geocode"
 	"github.com/lfroomin/restaurant-container/internal/model"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 )
 
 type stubError struct {
 	restaurant string
 	location   string
+	tag        string
 }
 
 func Test_Create(t *testing.T) {
@@ -33,11 +39,36 @@ func Test_Create(t *testing.T) {
 	restaurantNoAddressExp, _ := json.Marshal(model.Restaurant{
 		Name: restName,
 	})
+	geocode := "47.600000,-122.300000"
+	restaurantGeocodedExp, _ := json.Marshal(model.Restaurant{
+		Name: restName,
+		Address: &model.Address{
+			Location:     &model.Location{Geocode: &geocode},
+			TimezoneName: new(string),
+		},
+	})
+	owner, clientOwner := "user1", "user2"
+	restaurantOwnedExp, _ := json.Marshal(model.Restaurant{
+		Name:  restName,
+		Owner: &owner,
+	})
+	invalidCountry, invalidZipCode := "XX", "#"
+	nearbyId := "restId"
+	tags := []string{" Thai", "price-2", "thai"}
+	restaurantTaggedExp, _ := json.Marshal(model.Restaurant{
+		Name: restName,
+		Tags: &[]string{"thai", "price-2"},
+	})
 
 	testCases := []struct {
 		name         string
 		restaurant   model.Restaurant
+		body         string
+		query        string
 		emptyReqBody bool
+		geocode      string
+		nearby       model.Restaurant
+		principal    string
 		responseCode int
 		responseBody string
 		stubError    stubError
@@ -59,11 +90,62 @@ func Test_Create(t *testing.T) {
 			responseCode: http.StatusCreated,
 			responseBody: string(restaurantNoAddressExp),
 		},
+		{
+			name: "owner is the authenticated user",
+			restaurant: model.Restaurant{
+				Name:  restName,
+				Owner: &clientOwner,
+			},
+			principal:    owner,
+			responseCode: http.StatusCreated,
+			responseBody: string(restaurantOwnedExp),
+		},
+		{
+			name: "nearby restaurant with another name",
+			restaurant: model.Restaurant{
+				Name:    restName,
+				Address: &model.Address{},
+			},
+			geocode:      geocode,
+			nearby:       model.Restaurant{Id: &nearbyId, Name: "Ramen Bar"},
+			responseCode: http.StatusCreated,
+			responseBody: string(restaurantGeocodedExp),
+		},
+		{
+			name: "duplicate",
+			restaurant: model.Restaurant{
+				Name:    restName,
+				Address: &model.Address{},
+			},
+			geocode:      geocode,
+			nearby:       model.Restaurant{Id: &nearbyId, Name: "Rest-1"},
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"the restaurant is probably a duplicate, use force=true to create it anyway","duplicates":[{"distance":100,"restaurant":{"id":"restId","name":"Rest-1"},"similarity":1}],"status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name: "duplicate forced",
+			restaurant: model.Restaurant{
+				Name:    restName,
+				Address: &model.Address{},
+			},
+			query:        "force=true",
+			geocode:      geocode,
+			nearby:       model.Restaurant{Id: &nearbyId, Name: "Rest-1"},
+			responseCode: http.StatusCreated,
+			responseBody: string(restaurantGeocodedExp),
+		},
+		{
+			name:         "invalid force",
+			restaurant:   model.Restaurant{Name: restName},
+			query:        "force=maybe",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding query parameters","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
 		{
 			name:         "storage error",
-			restaurant:   model.Restaurant{},
+			restaurant:   model.Restaurant{Name: restName},
 			responseCode: http.StatusInternalServerError,
-			responseBody: `{"Message":"an error occurred"}`,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
 			stubError:    stubError{restaurant: "an error occurred"},
 		},
 		{
@@ -72,15 +154,58 @@ func Test_Create(t *testing.T) {
 				Name:    restName,
 				Address: &model.Address{},
 			},
-			responseCode: http.StatusInternalServerError,
-			responseBody: `{"Message":"an error occurred"}`,
+			responseCode: http.StatusBadGateway,
+			responseBody: `{"detail":"the address could not be geocoded","status":502,"title":"Bad Gateway","type":"about:blank"}`,
 			stubError:    stubError{location: "an error occurred"},
 		},
+		{
+			name: "invalid opening hours",
+			restaurant: model.Restaurant{
+				Name:         restName,
+				OpeningHours: &[]model.OpeningInterval{{Day: model.Monday, Open: "11:00", Close: "25:00"}},
+			},
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the restaurant is not valid","errors":[{"code":"invalid_opening_hours","field":"openingHours","message":"openingHours[0]: close must be a time in HH:MM format"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name: "invalid fields",
+			restaurant: model.Restaurant{
+				Name:    " ",
+				Address: &model.Address{Country: &invalidCountry, ZipCode: &invalidZipCode},
+			},
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the restaurant is not valid","errors":[{"code":"required","field":"name","message":"name is required"},{"code":"invalid_country","field":"address.country","message":"address.country must be an ISO 3166-1 alpha-2 country code, such as US"},{"code":"invalid_postal_code","field":"address.zipCode","message":"address.zipCode must be 2 to 10 letters, digits, spaces or dashes"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "tags",
+			restaurant:   model.Restaurant{Name: restName, Tags: &tags},
+			responseCode: http.StatusCreated,
+			responseBody: string(restaurantTaggedExp),
+		},
+		{
+			name:         "unknown tags",
+			restaurant:   model.Restaurant{Name: restName, Tags: &[]string{"thai", "vegan", "price-1", "price-2"}},
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the restaurant is not valid","errors":[{"code":"unknown_tag","field":"tags[1]","message":"vegan is not a known tag"},{"code":"conflicting_tags","field":"tags[3]","message":"price-2 conflicts with price-1, a restaurant has one price level"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "tags error",
+			restaurant:   model.Restaurant{Name: restName, Tags: &tags},
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    stubError{tag: "an error occurred"},
+		},
+		{
+			name:         "wrong type",
+			body:         `{"name":"Rest 1","address":{"city":7}}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the request body is not valid","errors":[{"code":"invalid_type","field":"address.city","message":"address.city must be a string"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
 		{
 			name:         "empty request body",
 			emptyReqBody: true,
 			responseCode: http.StatusBadRequest,
-			responseBody: `{"Message":"error binding request body"}`,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
 		},
 	}
 
@@ -90,17 +215,22 @@ func Test_Create(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			rc := Restaurant{
-				Restaurant: restaurantStorerStub{error: tc.stubError.restaurant},
-				Location:   locationServiceStub{error: tc.stubError.location},
+				Restaurant: restaurantStorerStub{restaurant: tc.nearby, error: tc.stubError.restaurant},
+				Location:   locationServiceStub{geocode: tc.geocode, error: tc.stubError.location},
+				Index:      searchIndexStub{},
+				Tags:       tagStorerStub{tags: testTags, error: tc.stubError.tag},
 			}
 
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
 
-			c.Request = &http.Request{
-				Body: io.NopCloser(bytes.NewBuffer([]byte{})),
+			c.Request = httptest.NewRequest(http.MethodPost, "/?"+tc.query, bytes.NewBuffer([]byte{}))
+			if tc.principal != "" {
+				c.Set(principalKey, principal{subject: tc.principal})
 			}
-			if !tc.emptyReqBody {
+			if tc.body != "" {
+				c.Request.Body = io.NopCloser(bytes.NewBufferString(tc.body))
+			} else if !tc.emptyReqBody {
 				b, _ := json.Marshal(tc.restaurant)
 				c.Request.Body = io.NopCloser(bytes.NewBuffer(b))
 			}
@@ -108,9 +238,12 @@ func Test_Create(t *testing.T) {
 			rc.Create(c)
 
 			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode == http.StatusCreated {
+				assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+			}
 
 			if tc.responseCode != http.StatusCreated {
-				assert.Equal(t, tc.responseBody, w.Body.String())
+				assert.Equal(t, tc.responseBody, responseBody(w))
 			} else {
 				// Convert to type Restaurant so comparison can be done
 				// without the "Id" field
@@ -136,6 +269,8 @@ func Test_Read(t *testing.T) {
 	testCases := []struct {
 		name         string
 		restaurantId string
+		tenant       string
+		stubTenant   string
 		notExist     bool
 		responseCode int
 		responseBody string
@@ -150,13 +285,13 @@ func Test_Read(t *testing.T) {
 		{
 			name:         "empty restaurantId",
 			responseCode: http.StatusBadRequest,
-			responseBody: `{"Message":"restaurantId is empty"}`,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
 		},
 		{
 			name:         "storage error",
 			restaurantId: "restId",
 			responseCode: http.StatusInternalServerError,
-			responseBody: `{"Message":"an error occurred"}`,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
 			stubError:    "an error occurred",
 		},
 		{
@@ -164,6 +299,30 @@ func Test_Read(t *testing.T) {
 			restaurantId: "restId",
 			notExist:     true,
 			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant of the tenant",
+			restaurantId: "restId",
+			tenant:       "brand-a",
+			stubTenant:   "brand-a",
+			responseCode: http.StatusOK,
+			responseBody: `{"name":""}`,
+		},
+		{
+			name:         "restaurant of another tenant",
+			restaurantId: "restId",
+			tenant:       "brand-b",
+			stubTenant:   "brand-a",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant of a tenant read by the default tenant",
+			restaurantId: "restId",
+			stubTenant:   "brand-a",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
 		},
 	}
 
@@ -173,13 +332,14 @@ func Test_Read(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			rc := Restaurant{
-				Restaurant: restaurantStorerStub{notExist: tc.notExist, error: tc.stubError},
+				Restaurant: restaurantStorerStub{tenant: tc.stubTenant, notExist: tc.notExist, error: tc.stubError},
 			}
 
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
 
 			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+			c.Set(principalKey, principal{subject: "user1", tenant: tc.tenant})
 
 			c.Request = &http.Request{
 				Body: io.NopCloser(bytes.NewBuffer([]byte{})),
@@ -188,7 +348,278 @@ func Test_Read(t *testing.T) {
 			rc.Read(c)
 
 			assert.Equal(t, tc.responseCode, w.Code)
-			assert.Equal(t, tc.responseBody, w.Body.String())
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			if tc.responseCode == http.StatusOK {
+				assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func Test_List(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		query        string
+		restaurant   model.Restaurant
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			responseCode: http.StatusOK,
+			responseBody: `{"restaurants":[{"name":""}]}`,
+		},
+		{
+			name:         "next page",
+			query:        "limit=1&nextToken=token",
+			responseCode: http.StatusOK,
+			responseBody: `{"nextToken":"token","restaurants":[{"name":""}]}`,
+		},
+		{
+			name:         "limit too large",
+			query:        "limit=101",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"limit must be between 1 and 100","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "limit not a number",
+			query:        "limit=abc",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding query parameters","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "open at",
+			query:        "openAt=2023-06-05T12:00:00Z",
+			restaurant:   model.Restaurant{Address: &model.Address{TimezoneName: strPtr("America/Los_Angeles")}, OpeningHours: &alwaysOpen},
+			responseCode: http.StatusOK,
+			responseBody: `{"restaurants":[{"address":{"timezoneName":"America/Los_Angeles"},"name":"","openNow":true,"openingHours":[{"close":"24:00","day":"monday","open":"00:00"},{"close":"24:00","day":"tuesday","open":"00:00"},{"close":"24:00","day":"wednesday","open":"00:00"},{"close":"24:00","day":"thursday","open":"00:00"},{"close":"24:00","day":"friday","open":"00:00"},{"close":"24:00","day":"saturday","open":"00:00"},{"close":"24:00","day":"sunday","open":"00:00"}]}]}`,
+		},
+		{
+			name:         "open at without timezone",
+			query:        "openAt=2023-06-05T12:00:00Z",
+			restaurant:   model.Restaurant{OpeningHours: &alwaysOpen},
+			responseCode: http.StatusOK,
+			responseBody: `{"restaurants":[]}`,
+		},
+		{
+			name:         "open at with empty timezone",
+			query:        "openAt=2023-06-05T12:00:00Z",
+			restaurant:   model.Restaurant{Address: &model.Address{TimezoneName: new(string)}, OpeningHours: &alwaysOpen},
+			responseCode: http.StatusOK,
+			responseBody: `{"restaurants":[]}`,
+		},
+		{
+			name:         "empty timezone",
+			restaurant:   model.Restaurant{Address: &model.Address{TimezoneName: new(string)}, OpeningHours: &alwaysOpen},
+			responseCode: http.StatusOK,
+			responseBody: `{"restaurants":[{"address":{"timezoneName":""},"name":"","openingHours":[{"close":"24:00","day":"monday","open":"00:00"},{"close":"24:00","day":"tuesday","open":"00:00"},{"close":"24:00","day":"wednesday","open":"00:00"},{"close":"24:00","day":"thursday","open":"00:00"},{"close":"24:00","day":"friday","open":"00:00"},{"close":"24:00","day":"saturday","open":"00:00"},{"close":"24:00","day":"sunday","open":"00:00"}]}]}`,
+		},
+		{
+			name:         "open at not a time",
+			query:        "openAt=noon",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding query parameters","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid token",
+			query:        "nextToken=token",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"invalid pagination token","status":400,"title":"Bad Request","type":"about:blank"}`,
+			stubError:    dynamo.ErrInvalidToken.Error(),
+		},
+		{
+			name:         "storage error",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{restaurant: tc.restaurant, error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+
+			rc.List(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_ListTagged(t *testing.T) {
+	t.Parallel()
+	restId := "restId"
+
+	testCases := []struct {
+		name         string
+		query        string
+		notExist     bool
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "first page",
+			query:        "limit=2&tags=japanese",
+			responseCode: http.StatusOK,
+			responseBody: `{"facets":{"japanese":3},"nextToken":"cjI","restaurants":[{"id":"restId","name":"Ramen Bar"},{"id":"restId","name":"Ramen Bar"}]}`,
+		},
+		{
+			name:         "last page",
+			query:        "limit=2&tags=japanese&nextToken=cjI",
+			responseCode: http.StatusOK,
+			responseBody: `{"facets":{"japanese":3},"restaurants":[{"id":"restId","name":"Ramen Bar"}]}`,
+		},
+		{
+			name:         "facets without tags",
+			query:        "facets=true",
+			responseCode: http.StatusOK,
+			responseBody: `{"facets":{"japanese":3},"restaurants":[{"id":"restId","name":"Ramen Bar"}]}`,
+		},
+		{
+			name:         "deleted restaurants",
+			query:        "tags=japanese",
+			notExist:     true,
+			responseCode: http.StatusOK,
+			responseBody: `{"facets":{"japanese":3},"restaurants":[]}`,
+		},
+		{
+			name:         "invalid token",
+			query:        "tags=japanese&nextToken=%21",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"invalid pagination token","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			query:        "tags=japanese",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+		{
+			name:         "facets storage error",
+			query:        "facets=true",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tags := tagStorerStub{
+				tagged: []string{"r1", "r2", "r3"},
+				facets: map[string]int{"japanese": 3},
+				error:  tc.stubError,
+			}
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{restaurant: model.Restaurant{Id: &restId, Name: "Ramen Bar"}, notExist: tc.notExist},
+				Tags:       tags,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+
+			rc.List(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_Nearby(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		query        string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			query:        "lat=47.6&lon=-122.3&radius=500",
+			responseCode: http.StatusOK,
+			responseBody: `{"restaurants":[{"distance":500,"restaurant":{"name":""}}]}`,
+		},
+		{
+			name:         "default radius",
+			query:        "lat=47.6&lon=-122.3",
+			responseCode: http.StatusOK,
+			responseBody: `{"restaurants":[{"distance":1000,"restaurant":{"name":""}}]}`,
+		},
+		{
+			name:         "missing lon",
+			query:        "lat=47.6",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"lat and lon must be a valid location","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid lat",
+			query:        "lat=91&lon=-122.3",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"lat and lon must be a valid location","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "lat not a number",
+			query:        "lat=abc&lon=-122.3",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding query parameters","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "radius too large",
+			query:        "lat=47.6&lon=-122.3&radius=50001",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"radius must be between 1 and 50000","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			query:        "lat=47.6&lon=-122.3",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodGet, "/nearby?"+tc.query, nil)
+
+			rc.Nearby(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
 		})
 	}
 }
@@ -208,11 +639,24 @@ func Test_Update(t *testing.T) {
 		Id:   &restId,
 		Name: restName,
 	})
+	owner, count, average := "user1", 2, 4.5
+	photos := []model.Photo{{Id: "photoId", Url: "/photos/photoId.jpg"}}
+	stored := model.Restaurant{Id: &restId, Name: "Old name", Owner: &owner, RatingCount: &count, RatingAverage: &average, Photos: &photos}
+	storedExp, _ := json.Marshal(model.Restaurant{
+		Id:            &restId,
+		Name:          restName,
+		Owner:         &owner,
+		RatingCount:   &count,
+		RatingAverage: &average,
+		Photos:        &photos,
+	})
 
 	testCases := []struct {
 		name         string
 		restaurantId string
 		restaurant   model.Restaurant
+		ifMatch      string
+		stored       model.Restaurant
 		emptyReqBody bool
 		responseCode int
 		responseBody string
@@ -229,6 +673,62 @@ func Test_Update(t *testing.T) {
 			responseCode: http.StatusOK,
 			responseBody: string(restaurantExp),
 		},
+		{
+			name:         "if-match",
+			restaurantId: restId,
+			restaurant: model.Restaurant{
+				Id:   &restId,
+				Name: restName,
+			},
+			ifMatch:      `"2", "3"`,
+			responseCode: http.StatusOK,
+			responseBody: string(restaurantNoAddressExp),
+		},
+		{
+			name:         "fields held by the server",
+			restaurantId: restId,
+			restaurant: model.Restaurant{
+				Id:   &restId,
+				Name: restName,
+			},
+			stored:       stored,
+			responseCode: http.StatusOK,
+			responseBody: string(storedExp),
+		},
+		{
+			name:         "stale version",
+			restaurantId: restId,
+			restaurant: model.Restaurant{
+				Id:   &restId,
+				Name: restName,
+			},
+			ifMatch:      `"2"`,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+			stubError:    stubError{restaurant: dynamo.ErrPreconditionFailed.Error()},
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: restId,
+			restaurant: model.Restaurant{
+				Id:   &restId,
+				Name: restName,
+			},
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    stubError{restaurant: dynamo.ErrNotFound.Error()},
+		},
+		{
+			name:         "malformed entity tag",
+			restaurantId: restId,
+			restaurant: model.Restaurant{
+				Id:   &restId,
+				Name: restName,
+			},
+			ifMatch:      `3`,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+		},
 		{
 			name:         "no address",
 			restaurantId: restId,
@@ -246,7 +746,7 @@ func Test_Update(t *testing.T) {
 				Name: restName,
 			},
 			responseCode: http.StatusBadRequest,
-			responseBody: `{"Message":"restaurantId in URL path parameters and restaurant in body do not match"}`,
+			responseBody: `{"detail":"restaurantId in URL path parameters and restaurant in body do not match","status":400,"title":"Bad Request","type":"about:blank"}`,
 		},
 		{
 			name:         "mismatch restaurantId",
@@ -256,14 +756,14 @@ func Test_Update(t *testing.T) {
 				Name: restName,
 			},
 			responseCode: http.StatusBadRequest,
-			responseBody: `{"Message":"restaurantId in URL path parameters and restaurant in body do not match"}`,
+			responseBody: `{"detail":"restaurantId in URL path parameters and restaurant in body do not match","status":400,"title":"Bad Request","type":"about:blank"}`,
 		},
 		{
 			name:         "storage error",
 			restaurantId: restId,
-			restaurant:   model.Restaurant{Id: &restId},
+			restaurant:   model.Restaurant{Id: &restId, Name: "Rest 1"},
 			responseCode: http.StatusInternalServerError,
-			responseBody: `{"Message":"an error occurred"}`,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
 			stubError:    stubError{restaurant: "an error occurred"},
 		},
 		{
@@ -274,15 +774,15 @@ func Test_Update(t *testing.T) {
 				Name:    restName,
 				Address: &model.Address{},
 			},
-			responseCode: http.StatusInternalServerError,
-			responseBody: `{"Message":"an error occurred"}`,
+			responseCode: http.StatusBadGateway,
+			responseBody: `{"detail":"the address could not be geocoded","status":502,"title":"Bad Gateway","type":"about:blank"}`,
 			stubError:    stubError{location: "an error occurred"},
 		},
 		{
 			name:         "empty request body",
 			emptyReqBody: true,
 			responseCode: http.StatusBadRequest,
-			responseBody: `{"Message":"error binding request body"}`,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
 		},
 	}
 
@@ -292,8 +792,9 @@ func Test_Update(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			rc := Restaurant{
-				Restaurant: restaurantStorerStub{error: tc.stubError.restaurant},
+				Restaurant: restaurantStorerStub{restaurant: tc.stored, error: tc.stubError.restaurant},
 				Location:   locationServiceStub{error: tc.stubError.location},
+				Index:      searchIndexStub{},
 			}
 
 			w := httptest.NewRecorder()
@@ -302,7 +803,8 @@ func Test_Update(t *testing.T) {
 			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
 
 			c.Request = &http.Request{
-				Body: io.NopCloser(bytes.NewBuffer([]byte{})),
+				Header: http.Header{"If-Match": []string{tc.ifMatch}},
+				Body:   io.NopCloser(bytes.NewBuffer([]byte{})),
 			}
 			if !tc.emptyReqBody {
 				b, _ := json.Marshal(tc.restaurant)
@@ -312,7 +814,180 @@ func Test_Update(t *testing.T) {
 			rc.Update(c)
 
 			assert.Equal(t, tc.responseCode, w.Code)
-			assert.Equal(t, tc.responseBody, w.Body.String())
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			if tc.responseCode == http.StatusOK {
+				assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func Test_Patch(t *testing.T) {
+	t.Parallel()
+	restId, restName, city, geocode, timezone := "Rest1", "Rest 1", "Seattle", "47.6,-122.3", "America/Los_Angeles"
+	stored := model.Restaurant{
+		Id:   &restId,
+		Name: restName,
+		Address: &model.Address{
+			City:         &city,
+			Location:     &model.Location{Geocode: &geocode},
+			TimezoneName: &timezone,
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		contentType  string
+		patch        string
+		ifMatch      string
+		notExist     bool
+		responseCode int
+		responseBody string
+		stubError    stubError
+	}{
+		{
+			name:         "happy path",
+			restaurantId: restId,
+			patch:        `{"phoneNumber":"+1 206-555-1234","description":null}`,
+			responseCode: http.StatusOK,
+			responseBody: `{"address":{"city":"Seattle","location":{"geocode":"47.6,-122.3"},"timezoneName":"America/Los_Angeles"},"id":"Rest1","name":"Rest 1","phoneNumber":"+12065551234"}`,
+		},
+		{
+			name:         "if-match",
+			restaurantId: restId,
+			patch:        `{}`,
+			ifMatch:      `"3"`,
+			responseCode: http.StatusOK,
+			responseBody: `{"address":{"city":"Seattle","location":{"geocode":"47.6,-122.3"},"timezoneName":"America/Los_Angeles"},"id":"Rest1","name":"Rest 1"}`,
+		},
+		{
+			name:         "stale if-match",
+			restaurantId: restId,
+			patch:        `{}`,
+			ifMatch:      `"2"`,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+		},
+		{
+			name:         "address changed",
+			restaurantId: restId,
+			patch:        `{"address":{"city":"Portland"}}`,
+			responseCode: http.StatusOK,
+			responseBody: `{"address":{"city":"Portland","location":{},"timezoneName":""},"id":"Rest1","name":"Rest 1"}`,
+		},
+		{
+			name:         "location cannot be patched",
+			restaurantId: restId,
+			patch:        `{"address":{"location":{"geocode":"0,0"}}}`,
+			responseCode: http.StatusOK,
+			responseBody: `{"address":{"city":"Seattle","location":{"geocode":"47.6,-122.3"},"timezoneName":"America/Los_Angeles"},"id":"Rest1","name":"Rest 1"}`,
+		},
+		{
+			name:         "address removed",
+			restaurantId: restId,
+			patch:        `{"address":null}`,
+			responseCode: http.StatusOK,
+			responseBody: `{"id":"Rest1","name":"Rest 1"}`,
+		},
+		{
+			name:         "empty restaurantId",
+			patch:        `{}`,
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "wrong content type",
+			restaurantId: restId,
+			contentType:  "application/json",
+			patch:        `{}`,
+			responseCode: http.StatusUnsupportedMediaType,
+			responseBody: `{"detail":"content type must be application/merge-patch+json","status":415,"title":"Unsupported Media Type","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid patch",
+			restaurantId: restId,
+			patch:        `{`,
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"invalid merge patch","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurantId changed",
+			restaurantId: restId,
+			patch:        `{"id":"Rest2"}`,
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId cannot be changed","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "wrong type",
+			restaurantId: restId,
+			patch:        `{"name":1}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the request body is not valid","errors":[{"code":"invalid_type","field":"name","message":"name must be a string"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid phone number",
+			restaurantId: restId,
+			patch:        `{"phoneNumber":"555-1234"}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the restaurant is not valid","errors":[{"code":"invalid_phone_number","field":"phoneNumber","message":"phoneNumber must be in E.164 format, such as +14155550123"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: restId,
+			patch:        `{}`,
+			notExist:     true,
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: restId,
+			patch:        `{}`,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    stubError{restaurant: "an error occurred"},
+		},
+		{
+			name:         "location error",
+			restaurantId: restId,
+			patch:        `{"address":{"city":"Portland"}}`,
+			responseCode: http.StatusBadGateway,
+			responseBody: `{"detail":"the address could not be geocoded","status":502,"title":"Bad Gateway","type":"about:blank"}`,
+			stubError:    stubError{location: "an error occurred"},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{restaurant: copyRestaurant(stored), notExist: tc.notExist, error: tc.stubError.restaurant},
+				Location:   locationServiceStub{error: tc.stubError.location},
+				Index:      searchIndexStub{},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+
+			c.Request = httptest.NewRequest(http.MethodPatch, "/"+tc.restaurantId, bytes.NewBufferString(tc.patch))
+			c.Request.Header.Set("Content-Type", "application/merge-patch+json")
+			if tc.contentType != "" {
+				c.Request.Header.Set("Content-Type", tc.contentType)
+			}
+			c.Request.Header.Set("If-Match", tc.ifMatch)
+
+			rc.Patch(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			if tc.responseCode == http.StatusOK {
+				assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+			}
 		})
 	}
 }
@@ -323,6 +998,7 @@ func Test_Delete(t *testing.T) {
 	testCases := []struct {
 		name         string
 		restaurantId string
+		ifMatch      string
 		responseCode int
 		responseBody string
 		stubError    string
@@ -333,16 +1009,45 @@ func Test_Delete(t *testing.T) {
 			responseCode: http.StatusOK,
 			responseBody: `""`,
 		},
+		{
+			name:         "if-match",
+			restaurantId: "restId",
+			ifMatch:      `"3"`,
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+		},
+		{
+			name:         "weak entity tag",
+			restaurantId: "restId",
+			ifMatch:      `W/"3"`,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "stale version",
+			restaurantId: "restId",
+			ifMatch:      `"2"`,
+			responseCode: http.StatusPreconditionFailed,
+			responseBody: `{"detail":"restaurant version does not match","status":412,"title":"Precondition Failed","type":"about:blank"}`,
+			stubError:    dynamo.ErrPreconditionFailed.Error(),
+		},
 		{
 			name:         "empty restaurantId",
 			responseCode: http.StatusBadRequest,
-			responseBody: `{"Message":"restaurantId is empty"}`,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
 		},
 		{
 			name:         "storage error",
 			restaurantId: "restId",
 			responseCode: http.StatusInternalServerError,
-			responseBody: `{"Message":"an error occurred"}`,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
 			stubError:    "an error occurred",
 		},
 	}
@@ -354,6 +1059,7 @@ func Test_Delete(t *testing.T) {
 			t.Parallel()
 			rc := Restaurant{
 				Restaurant: restaurantStorerStub{error: tc.stubError},
+				Index:      searchIndexStub{},
 			}
 
 			w := httptest.NewRecorder()
@@ -361,57 +1067,230 @@ func Test_Delete(t *testing.T) {
 
 			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
 
+			c.Request = httptest.NewRequest(http.MethodDelete, "/"+tc.restaurantId, nil)
+			c.Request.Header.Set("If-Match", tc.ifMatch)
+
 			rc.Delete(c)
 
 			assert.Equal(t, tc.responseCode, w.Code)
-			assert.Equal(t, tc.responseBody, w.Body.String())
+			assert.Equal(t, tc.responseBody, responseBody(w))
 		})
 	}
 }
 
+func Test_Restore(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			responseCode: http.StatusOK,
+			responseBody: `{"name":""}`,
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: "restId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "restaurant not deleted",
+			restaurantId: "restId",
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"restaurant is not deleted","status":409,"title":"Conflict","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotDeleted.Error(),
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{error: tc.stubError},
+				Index:      searchIndexStub{},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodPost, "/"+tc.restaurantId+"/restore", nil)
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+
+			rc.Restore(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			if tc.responseCode == http.StatusOK {
+				assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+// restaurantStorerStub stores the restaurant of the tenant, so the
+// restaurant is not found by the other tenants.
 type restaurantStorerStub struct {
-	notExist bool
-	error    string
+	tenant     string
+	restaurant model.Restaurant
+	pages      int
+	notExist   bool
+	error      string
 }
 
-func (s restaurantStorerStub) Save(_ model.Restaurant) error {
+func (s restaurantStorerStub) Save(_ string, _ model.Restaurant, _ dynamo.Actor) error {
 	if s.error != "" {
-		return errors.New(s.error)
+		return stubErr(s.error)
 	}
 	return nil
 }
 
-func (s restaurantStorerStub) Get(_ string) (model.Restaurant, bool, error) {
+func (s restaurantStorerStub) SaveBatch(_ string, restaurants []model.Restaurant, _ dynamo.Actor) []error {
+	errs := make([]error, len(restaurants))
+	if s.error != "" {
+		for i := range errs {
+			errs[i] = stubErr(s.error)
+		}
+	}
+	return errs
+}
+
+func (s restaurantStorerStub) Get(tenant, _ string) (model.Restaurant, int64, bool, error) {
 	if s.error != "" {
-		return model.Restaurant{}, false, errors.New(s.error)
+		return model.Restaurant{}, 0, false, stubErr(s.error)
 	}
-	if s.notExist {
-		return model.Restaurant{}, false, nil
+	if s.notExist || tenant != s.tenant {
+		return model.Restaurant{}, 0, false, nil
 	}
-	return model.Restaurant{}, true, nil
+	return s.restaurant, 3, true, nil
 }
 
-func (s restaurantStorerStub) Update(_ model.Restaurant) error {
+// Update returns the restaurant with the owner, rating aggregates and
+// photos of the stored restaurant.
+func (s restaurantStorerStub) Update(_ string, restaurant model.Restaurant, _ []int64, _ dynamo.Actor) (model.Restaurant, int64, error) {
 	if s.error != "" {
-		return errors.New(s.error)
+		return model.Restaurant{}, 0, stubErr(s.error)
+	}
+	restaurant.Owner = s.restaurant.Owner
+	restaurant.RatingAverage = s.restaurant.RatingAverage
+	restaurant.RatingCount = s.restaurant.RatingCount
+	restaurant.Photos = s.restaurant.Photos
+	return restaurant, 4, nil
+}
+
+func (s restaurantStorerStub) Delete(_, _ string, _ []int64, _ dynamo.Actor) error {
+	if s.error != "" {
+		return stubErr(s.error)
 	}
 	return nil
 }
 
-func (s restaurantStorerStub) Delete(_ string) error {
+func (s restaurantStorerStub) Restore(_, _ string, _ dynamo.Actor) (model.Restaurant, int64, error) {
+	if s.error != "" {
+		return model.Restaurant{}, 0, stubErr(s.error)
+	}
+	return s.restaurant, 5, nil
+}
+
+// List returns the same token it is given, unless pages is set: then it
+// returns pages pages, each with a token that is the number of the page.
+func (s restaurantStorerStub) List(_ string, _ int32, nextToken string) ([]model.Restaurant, string, error) {
+	if s.error != "" {
+		return nil, "", stubErr(s.error)
+	}
+	if s.pages > 0 {
+		page, _ := strconv.Atoi(nextToken)
+		if page+1 < s.pages {
+			return []model.Restaurant{s.restaurant}, strconv.Itoa(page + 1), nil
+		}
+		return []model.Restaurant{s.restaurant}, "", nil
+	}
+	return []model.Restaurant{s.restaurant}, nextToken, nil
+}
+
+func (s restaurantStorerStub) Nearby(_ string, _ geo.Point, radius float64) ([]model.NearbyRestaurant, error) {
 	if s.error != "" {
-		return errors.New(s.error)
+		return nil, stubErr(s.error)
+	}
+	return []model.NearbyRestaurant{{Restaurant: s.restaurant, Distance: radius}}, nil
+}
+
+// stubErr returns the sentinel error with the given message, so the
+// controllers can map it to a status code, or a new error otherwise.
+// stubOwnerErr returns ErrForbidden, like storage, when the stub has an owner
+// and the actor is neither the owner nor an admin.
+func stubOwnerErr(owner string, actor dynamo.Actor) error {
+	if owner != "" && actor.Id != owner && !actor.Admin {
+		return dynamo.ErrForbidden
 	}
 	return nil
 }
 
+func stubErr(msg string) error {
+	for _, err := range []error{dynamo.ErrInvalidToken, dynamo.ErrNotFound, dynamo.ErrMenuNotFound, dynamo.ErrReviewNotFound, dynamo.ErrNotDeleted, dynamo.ErrPreconditionFailed,
+		dynamo.ErrSettingsNotFound, dynamo.ErrReservationNotFound, dynamo.ErrReservationCancelled, dynamo.ErrSlotTaken, dynamo.ErrRevisionNotFound, dynamo.ErrNothingToRevert,
+		dynamo.ErrApiKeyNotFound, dynamo.ErrQuotaExceeded, dynamo.ErrForbidden,
+		dynamo.ErrPhotoNotFound, dynamo.ErrTooManyPhotos, dynamo.ErrPhotoOrder, dynamo.ErrPhotosConflict,
+		dynamo.ErrTagNotFound, dynamo.ErrTagInUse, dynamo.ErrWebhookNotFound, dynamo.ErrDeliveryNotFound} {
+		if msg == err.Error() {
+			return err
+		}
+	}
+	return errors.New(msg)
+}
+
 type locationServiceStub struct {
-	error string
+	geocode string
+	error   string
 }
 
 func (s locationServiceStub) Geocode(_ model.Address) (model.Location, string, error) {
 	if s.error != "" {
-		return model.Location{}, "", errors.New(s.error)
+		return model.Location{}, "", fmt.Errorf("%w: %s", geocode.ErrGeocode, s.error)
+	}
+	if s.geocode != "" {
+		return model.Location{Geocode: &s.geocode}, "", nil
 	}
 	return model.Location{}, "", nil
 }
+
+// copyRestaurant returns a deep copy so test cases running in parallel do
+// not share pointers.
+func copyRestaurant(restaurant model.Restaurant) model.Restaurant {
+	var cp model.Restaurant
+	b, _ := json.Marshal(restaurant)
+	_ = json.Unmarshal(b, &cp)
+	return cp
+}
+
+// alwaysOpen are opening hours from midnight to midnight every day
+var alwaysOpen = []model.OpeningInterval{
+	{Day: model.Monday, Open: "00:00", Close: "24:00"},
+	{Day: model.Tuesday, Open: "00:00", Close: "24:00"},
+	{Day: model.Wednesday, Open: "00:00", Close: "24:00"},
+	{Day: model.Thursday, Open: "00:00", Close: "24:00"},
+	{Day: model.Friday, Open: "00:00", Close: "24:00"},
+	{Day: model.Saturday, Open: "00:00", Close: "24:00"},
+	{Day: model.Sunday, Open: "00:00", Close: "24:00"},
+}
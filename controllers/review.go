@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"log"
+	"net/http"
+	"time"
+)
+
+type ReviewStorer interface {
+	SaveReview(tenant, restaurantId string, review model.Review) error
+	ListReviews(tenant, restaurantId string, limit int32, nextToken string) ([]model.Review, string, error)
+	DeleteReview(tenant, restaurantId, reviewId string, actor dynamo.Actor) error
+}
+
+type Review struct {
+	Review ReviewStorer
+}
+
+// Create adds a review to the restaurant. The rating aggregates of the
+// restaurant are updated along with it.
+func (r Review) Create(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var review model.Review
+	if err := c.ShouldBindJSON(&review); err != nil {
+		bindError(c, err, "error binding request body")
+		return
+	}
+
+	if errs := validate.Review(&review); len(errs) > 0 {
+		detail := "the review is not valid"
+		writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &errs})
+		return
+	}
+
+	id := uuid.NewString()
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	review.Id = &id
+	review.CreatedAt = &createdAt
+
+	log.Printf("Review.Create restaurantId: %s  reviewId: %s\n", restaurantId, id)
+
+	if err := r.Review.SaveReview(tenant(c), restaurantId, review); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+func (r Review) List(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+
+	// Validate input
+	if restaurantId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId is empty")
+		return
+	}
+
+	var params model.GetRestaurantIdReviewsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+
+	limit := int32(defaultLimit)
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > maxLimit {
+		problem(c, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	var nextToken string
+	if params.NextToken != nil {
+		nextToken = *params.NextToken
+	}
+
+	log.Printf("Review.List restaurantId: %s  limit: %d  nextToken: %s\n", restaurantId, limit, nextToken)
+
+	reviews, token, err := r.Review.ListReviews(tenant(c), restaurantId, limit, nextToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resp := model.ReviewList{Reviews: reviews}
+	if token != "" {
+		resp.NextToken = &token
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Delete removes a review of the restaurant. The rating aggregates of the
+// restaurant are updated along with it.
+func (r Review) Delete(c *gin.Context) {
+	restaurantId := c.Param("restaurantId")
+	reviewId := c.Param("reviewId")
+
+	// Validate input
+	if restaurantId == "" || reviewId == "" {
+		problem(c, http.StatusBadRequest, "restaurantId or reviewId is empty")
+		return
+	}
+
+	log.Printf("Review.Delete restaurantId: %s  reviewId: %s\n", restaurantId, reviewId)
+
+	if err := r.Review.DeleteReview(tenant(c), restaurantId, reviewId, actor(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "")
+}
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_ReviewCreate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		body         string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			body:         `{"rating":4,"text":"Great food"}`,
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "rating too low",
+			restaurantId: "restId",
+			body:         `{"rating":0}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the review is not valid","errors":[{"code":"out_of_range","field":"rating","message":"rating must be between 1 and 5"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "rating too high",
+			restaurantId: "restId",
+			body:         `{"rating":6}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the review is not valid","errors":[{"code":"out_of_range","field":"rating","message":"rating must be between 1 and 5"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "text too long",
+			restaurantId: "restId",
+			body:         `{"rating":4,"text":"` + strings.Repeat("a", 4001) + `"}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the review is not valid","errors":[{"code":"too_long","field":"text","message":"text must be at most 4000 characters"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "restaurant does not exist",
+			restaurantId: "restId",
+			body:         `{"rating":4}`,
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"restaurant not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrNotFound.Error(),
+		},
+		{
+			name:         "empty request body",
+			restaurantId: "restId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "rating of the wrong type",
+			restaurantId: "restId",
+			body:         `{"rating":"five"}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the request body is not valid","errors":[{"code":"invalid_type","field":"rating","message":"rating must be a number"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			body:         `{"rating":4}`,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Review{
+				Review: reviewStorerStub{error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+			c.Request = httptest.NewRequest(http.MethodPost, "/"+tc.restaurantId+"/reviews", bytes.NewBufferString(tc.body))
+
+			rc.Create(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusCreated {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				return
+			}
+
+			// The ID and creation time are generated
+			var review model.Review
+			if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &review)) {
+				assert.NotNil(t, review.Id)
+				assert.NotNil(t, review.CreatedAt)
+				assert.Equal(t, 4, review.Rating)
+			}
+		})
+	}
+}
+
+func Test_ReviewList(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		restaurantId string
+		query        string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			restaurantId: "restId",
+			responseCode: http.StatusOK,
+			responseBody: `{"reviews":[{"id":"reviewId","rating":5}]}`,
+		},
+		{
+			name:         "next page",
+			restaurantId: "restId",
+			query:        "limit=1&nextToken=token",
+			responseCode: http.StatusOK,
+			responseBody: `{"nextToken":"token","reviews":[{"id":"reviewId","rating":5}]}`,
+		},
+		{
+			name:         "limit too small",
+			restaurantId: "restId",
+			query:        "limit=0",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"limit must be between 1 and 100","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "invalid token",
+			restaurantId: "restId",
+			query:        "nextToken=token",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"invalid pagination token","status":400,"title":"Bad Request","type":"about:blank"}`,
+			stubError:    dynamo.ErrInvalidToken.Error(),
+		},
+		{
+			name:         "empty restaurantId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			restaurantId: "restId",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rc := Review{
+				Review: reviewStorerStub{error: tc.stubError},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: tc.restaurantId}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tc.restaurantId+"/reviews?"+tc.query, nil)
+
+			rc.List(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_ReviewDelete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		reviewId     string
+		principal    string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			reviewId:     "reviewId",
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+		},
+		{
+			name:         "owner of the restaurant",
+			reviewId:     "reviewId",
+			principal:    "user1",
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+		},
+		{
+			name:         "not the owner of the restaurant",
+			reviewId:     "reviewId",
+			principal:    "user2",
+			responseCode: http.StatusForbidden,
+			responseBody: `{"detail":"only the owner of the restaurant or an admin can modify it","status":403,"title":"Forbidden","type":"about:blank"}`,
+		},
+		{
+			name:         "review does not exist",
+			reviewId:     "reviewId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"review not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    dynamo.ErrReviewNotFound.Error(),
+		},
+		{
+			name:         "empty reviewId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"restaurantId or reviewId is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			reviewId:     "reviewId",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := reviewStorerStub{error: tc.stubError}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tc.principal != "" {
+				stub.owner = "user1"
+				c.Set(principalKey, principal{subject: tc.principal})
+			}
+			rc := Review{
+				Review: stub,
+			}
+
+			c.Params = []gin.Param{{Key: "restaurantId", Value: "restId"}, {Key: "reviewId", Value: tc.reviewId}}
+			c.Request = httptest.NewRequest(http.MethodDelete, "/restId/reviews/"+tc.reviewId, nil)
+
+			rc.Delete(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+// reviewStorerStub checks the owner of the restaurant when owner is set
+type reviewStorerStub struct {
+	owner string
+	error string
+}
+
+func (s reviewStorerStub) SaveReview(_, _ string, _ model.Review) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return nil
+}
+
+func (s reviewStorerStub) ListReviews(_, _ string, _ int32, nextToken string) ([]model.Review, string, error) {
+	if s.error != "" {
+		return nil, "", stubErr(s.error)
+	}
+	reviewId := "reviewId"
+	return []model.Review{{Id: &reviewId, Rating: 5}}, nextToken, nil
+}
+
+func (s reviewStorerStub) DeleteReview(_, _, _ string, actor dynamo.Actor) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return stubOwnerErr(s.owner, actor)
+}
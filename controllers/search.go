@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/search"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SearchIndex is the full-text and tag index of the restaurants of each
+// tenant. It is kept in sync by the handlers that write restaurants.
+type SearchIndex interface {
+	Add(tenant string, restaurant model.Restaurant)
+	Remove(tenant, restaurantId string)
+	Search(tenant, query string, tags []string, limit int) (search.Results, error)
+}
+
+// Search returns the restaurants matching the words of the query and having
+// every tag, the most relevant first, with the facet counts of the matches.
+func (r Restaurant) Search(c *gin.Context) {
+	var params model.GetSearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		problem(c, http.StatusBadRequest, "error binding query parameters")
+		return
+	}
+
+	query := strings.TrimSpace(params.Q)
+	if query == "" {
+		problem(c, http.StatusBadRequest, "q is empty")
+		return
+	}
+
+	limit := int32(defaultLimit)
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > maxLimit {
+		problem(c, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	tags := tagParams(params.Tags)
+
+	log.Printf("Restaurant.Search tenant: %s  q: %s  tags: %v  limit: %d\n", tenant(c), query, tags, limit)
+
+	results, err := r.Index.Search(tenant(c), query, tags, int(limit))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	facets := model.Facets(results.Facets)
+	resp := model.SearchResults{Results: []model.SearchResult{}, Facets: &facets}
+	now := time.Now()
+	for _, hit := range results.Hits {
+		restaurant, _, exists, err := r.Restaurant.Get(tenant(c), hit.RestaurantId)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		// The restaurant was deleted through another instance
+		if !exists {
+			r.Index.Remove(tenant(c), hit.RestaurantId)
+			continue
+		}
+
+		setOpenStatus(&restaurant, now)
+		resp.Results = append(resp.Results, model.SearchResult{Restaurant: restaurant, Score: hit.Score})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// LoadRestaurants returns the loader of the search index, which reads every
+// restaurant of a tenant from storage, a page at a time.
+func LoadRestaurants(storer RestaurantStorer) search.Loader {
+	return func(tenant string) ([]model.Restaurant, error) {
+		var restaurants []model.Restaurant
+		token := ""
+		for {
+			page, next, err := storer.List(tenant, maxLimit, token)
+			if err != nil {
+				return nil, err
+			}
+			restaurants = append(restaurants, page...)
+
+			if next == "" {
+				return restaurants, nil
+			}
+			token = next
+		}
+	}
+}
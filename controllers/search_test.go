@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/search"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_Search(t *testing.T) {
+	t.Parallel()
+	restId := "restId"
+
+	testCases := []struct {
+		name         string
+		query        string
+		notExist     bool
+		removed      int32
+		responseCode int
+		responseBody string
+		stubError    string
+		indexError   string
+	}{
+		{
+			name:         "happy path",
+			query:        "q=ramen",
+			responseCode: http.StatusOK,
+			responseBody: `{"facets":{"japanese":1},"results":[{"restaurant":{"id":"restId","name":"Ramen Bar"},"score":1.5}]}`,
+		},
+		{
+			name:         "tags",
+			query:        "q=ramen&tags=Japanese&tags=outdoor-seating",
+			responseCode: http.StatusOK,
+			responseBody: `{"facets":{"japanese":1},"results":[{"restaurant":{"id":"restId","name":"Ramen Bar"},"score":1.5}]}`,
+		},
+		{
+			name:         "deleted restaurant",
+			query:        "q=ramen",
+			notExist:     true,
+			removed:      1,
+			responseCode: http.StatusOK,
+			responseBody: `{"facets":{"japanese":1},"results":[]}`,
+		},
+		{
+			name:         "empty query",
+			query:        "q=%20",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"q is empty","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "limit too large",
+			query:        "q=ramen&limit=101",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"limit must be between 1 and 100","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			query:        "q=ramen",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+		{
+			name:         "index error",
+			query:        "q=ramen",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			indexError:   "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			index := searchIndexStub{
+				hits:    []search.Hit{{RestaurantId: restId, Score: 1.5}},
+				facets:  map[string]int{"japanese": 1},
+				removed: new(int32),
+				error:   tc.indexError,
+			}
+			rc := Restaurant{
+				Restaurant: restaurantStorerStub{
+					restaurant: model.Restaurant{Id: &restId, Name: "Ramen Bar"},
+					notExist:   tc.notExist,
+					error:      tc.stubError,
+				},
+				Index: index,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodGet, "/search?"+tc.query, nil)
+
+			rc.Search(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			assert.Equal(t, tc.removed, atomic.LoadInt32(index.removed))
+		})
+	}
+}
+
+func Test_LoadRestaurants(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		tenant    string
+		pages     int
+		count     int
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:  "one page",
+			pages: 1,
+			count: 1,
+		},
+		{
+			name:  "several pages",
+			pages: 3,
+			count: 3,
+		},
+		{
+			name:   "tenant",
+			tenant: "brand-a",
+			pages:  2,
+			count:  2,
+		},
+		{
+			name:      "error",
+			pages:     1,
+			stubError: "an error occurred",
+			errMsg:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			restId := "restId"
+			storer := restaurantStorerStub{restaurant: model.Restaurant{Id: &restId}, pages: tc.pages, error: tc.stubError}
+
+			restaurants, err := LoadRestaurants(storer)(tc.tenant)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Len(t, restaurants, tc.count)
+		})
+	}
+}
+
+// searchIndexStub returns the hits for every query, and counts the
+// restaurants added to and removed from the index.
+type searchIndexStub struct {
+	hits    []search.Hit
+	facets  map[string]int
+	added   *int32
+	removed *int32
+	error   string
+}
+
+func (s searchIndexStub) Add(_ string, _ model.Restaurant) {
+	if s.added != nil {
+		atomic.AddInt32(s.added, 1)
+	}
+}
+
+func (s searchIndexStub) Remove(_, _ string) {
+	if s.removed != nil {
+		atomic.AddInt32(s.removed, 1)
+	}
+}
+
+func (s searchIndexStub) Search(_, _ string, _ []string, _ int) (search.Results, error) {
+	if s.error != "" {
+		return search.Results{}, stubErr(s.error)
+	}
+	return search.Results{Hits: s.hits, Facets: s.facets}, nil
+}
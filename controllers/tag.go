@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type TagStorer interface {
+	ListTags(tenant string) ([]model.Tag, error)
+	SaveTag(tenant string, tag model.Tag) error
+	DeleteTag(tenant, tagId string) error
+	Tagged(tenant string, tags []string) ([]string, map[string]int, error)
+	Facets(tenant string) (map[string]int, error)
+}
+
+// Tag manages the vocabulary of tags of each tenant, the only tags the
+// restaurants of the tenant can be tagged with.
+type Tag struct {
+	Tag TagStorer
+}
+
+// List returns the tags of the tenant, by category and ID.
+func (t Tag) List(c *gin.Context) {
+	log.Printf("Tag.List tenant: %s\n", tenant(c))
+
+	tags, err := t.Tag.ListTags(tenant(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.TagList{Tags: tags})
+}
+
+// Save adds a tag to the vocabulary of the tenant, or replaces the label or
+// category of the tag with the same ID.
+func (t Tag) Save(c *gin.Context) {
+	var tag model.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		bindError(c, err, "error binding request body")
+		return
+	}
+
+	if errs := validate.Tag(&tag); len(errs) > 0 {
+		detail := "the tag is not valid"
+		writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &errs})
+		return
+	}
+
+	log.Printf("Tag.Save tenant: %s  tagId: %s\n", tenant(c), tag.Id)
+
+	if err := t.Tag.SaveTag(tenant(c), tag); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// Delete removes a tag from the vocabulary of the tenant. A tag that is
+// assigned to restaurants is not deleted, so the restaurants keep valid
+// tags.
+func (t Tag) Delete(c *gin.Context) {
+	tagId := c.Param("tagId")
+
+	log.Printf("Tag.Delete tenant: %s  tagId: %s\n", tenant(c), tagId)
+
+	if err := t.Tag.DeleteTag(tenant(c), tagId); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "")
+}
+
+// vocabulary returns the tags of the tenant by ID.
+func vocabulary(storer TagStorer, tenant string) (map[string]model.Tag, error) {
+	tags, err := storer.ListTags(tenant)
+	if err != nil {
+		return nil, err
+	}
+	vocabulary := make(map[string]model.Tag, len(tags))
+	for _, tag := range tags {
+		vocabulary[tag.Id] = tag
+	}
+	return vocabulary, nil
+}
+
+// tagParams returns the trimmed, lower cased tags of the query.
+func tagParams(tags *model.Tags) []string {
+	if tags == nil {
+		return nil
+	}
+	params := make([]string, 0, len(*tags))
+	for _, tag := range *tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			params = append(params, tag)
+		}
+	}
+	return params
+}
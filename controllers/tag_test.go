@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testTags = []model.Tag{
+	{Id: "outdoor-seating", Category: model.TagCategoryAmenity, Label: "Outdoor seating"},
+	{Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"},
+	{Id: "price-1", Category: model.TagCategoryPrice, Label: "$"},
+	{Id: "price-2", Category: model.TagCategoryPrice, Label: "$$"},
+}
+
+func Test_TagList(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		tags         []model.Tag
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			tags:         testTags[:2],
+			responseCode: http.StatusOK,
+			responseBody: `{"tags":[{"category":"amenity","id":"outdoor-seating","label":"Outdoor seating"},{"category":"cuisine","id":"thai","label":"Thai"}]}`,
+		},
+		{
+			name:         "no tags",
+			tags:         []model.Tag{},
+			responseCode: http.StatusOK,
+			responseBody: `{"tags":[]}`,
+		},
+		{
+			name:         "storage error",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tag := Tag{Tag: tagStorerStub{tags: tc.tags, error: tc.stubError}}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			tag.List(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_TagSave(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		body         string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			body:         `{"id":" Thai","category":"cuisine","label":"Thai "}`,
+			responseCode: http.StatusOK,
+			responseBody: `{"category":"cuisine","id":"thai","label":"Thai"}`,
+		},
+		{
+			name:         "invalid tag",
+			body:         `{"id":"outdoor seating","category":"diet","label":""}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the tag is not valid","errors":[{"code":"invalid_tag","field":"id","message":"id must be at most 64 lower case letters, digits or dashes, such as outdoor-seating"},{"code":"invalid_category","field":"category","message":"category must be one of amenity, cuisine or price"},{"code":"required","field":"label","message":"label is required"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "empty request body",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			body:         `{"id":"thai","category":"cuisine","label":"Thai"}`,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tag := Tag{Tag: tagStorerStub{error: tc.stubError}}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodPost, "/tags", bytes.NewBufferString(tc.body))
+
+			tag.Save(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+func Test_TagDelete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+		},
+		{
+			name:         "tag in use",
+			responseCode: http.StatusConflict,
+			responseBody: `{"detail":"tag is assigned to restaurants","status":409,"title":"Conflict","type":"about:blank"}`,
+			stubError:    "tag is assigned to restaurants",
+		},
+		{
+			name:         "tag not found",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"tag not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    "tag not found",
+		},
+		{
+			name:         "storage error",
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tag := Tag{Tag: tagStorerStub{error: tc.stubError}}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "tagId", Value: "thai"}}
+
+			tag.Delete(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
+
+// tagStorerStub returns the tagged restaurants and the facets for every
+// tag.
+type tagStorerStub struct {
+	tags   []model.Tag
+	tagged []string
+	facets map[string]int
+	error  string
+}
+
+func (s tagStorerStub) ListTags(_ string) ([]model.Tag, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	return s.tags, nil
+}
+
+func (s tagStorerStub) SaveTag(_ string, _ model.Tag) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return nil
+}
+
+func (s tagStorerStub) DeleteTag(_, _ string) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return nil
+}
+
+func (s tagStorerStub) Tagged(_ string, _ []string) ([]string, map[string]int, error) {
+	if s.error != "" {
+		return nil, nil, stubErr(s.error)
+	}
+	return s.tagged, s.facets, nil
+}
+
+func (s tagStorerStub) Facets(_ string) (map[string]int, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	return s.facets, nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"net/http"
+	"regexp"
+)
+
+// TenantHeader is the header with which an admin of no tenant selects the
+// tenant of a request
+const TenantHeader = "X-Tenant-Id"
+
+// tenantKey is the context key of the tenant of the X-Tenant-Id header
+const tenantKey = "tenant"
+
+// tenantPattern restricts the tenants to lower-case letters, digits and
+// hyphens, so a tenant cannot contain the separator of the storage keys.
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// Tenant is a middleware that records the tenant of the X-Tenant-Id header.
+// A tenant that is not valid is rejected with 400.
+func Tenant(c *gin.Context) {
+	header := c.GetHeader(TenantHeader)
+	if header != "" && !tenantPattern.MatchString(header) {
+		problem(c, http.StatusBadRequest, "X-Tenant-Id is not valid")
+		return
+	}
+	c.Set(tenantKey, header)
+	c.Next()
+}
+
+// setPrincipal records the authenticated principal of the request. A
+// principal of a tenant cannot make requests for another tenant, which is
+// rejected with 403.
+func setPrincipal(c *gin.Context, p principal) bool {
+	if header := c.GetString(tenantKey); p.tenant != "" && header != "" && header != p.tenant {
+		problem(c, http.StatusForbidden, "the principal does not belong to the tenant")
+		return false
+	}
+	c.Set(principalKey, p)
+	return true
+}
+
+// AuthorizeTenant is a middleware that only accepts the X-Tenant-Id header
+// from the admins of no tenant, after the API keys are authenticated. The
+// bearer token of a request with the header is verified, even on the
+// routes open to everyone. A principal can send the header of its own
+// tenant, and every other request with the header, including the
+// anonymous ones, is rejected with 403.
+func (a Auth) AuthorizeTenant(c *gin.Context) {
+	header := c.GetString(tenantKey)
+	if header == "" {
+		c.Next()
+		return
+	}
+
+	if _, ok := c.Get(principalKey); !ok && c.GetHeader("Authorization") != "" {
+		if !a.authenticate(c) {
+			return
+		}
+	}
+
+	p, _ := c.Get(principalKey)
+	if p, ok := p.(principal); !ok || p.tenant != header && (p.tenant != "" || !p.admin) {
+		problem(c, http.StatusForbidden, "the principal does not belong to the tenant")
+		return
+	}
+	c.Next()
+}
+
+// tenant returns the tenant of the request: the tenant of the principal,
+// otherwise the tenant an admin of no tenant selected with the X-Tenant-Id
+// header, otherwise the default tenant. The restaurants of the other
+// tenants are not found.
+func tenant(c *gin.Context) string {
+	p, ok := c.Get(principalKey)
+	if !ok {
+		return dynamo.DefaultTenant
+	}
+	if p.(principal).tenant != "" {
+		return p.(principal).tenant
+	}
+	if header := c.GetString(tenantKey); header != "" && p.(principal).admin {
+		return header
+	}
+	return dynamo.DefaultTenant
+}
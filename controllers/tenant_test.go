@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Tenant(t *testing.T) {
+	t.Parallel()
+
+	forbidden := `{"detail":"the principal does not belong to the tenant","status":403,"title":"Forbidden","type":"about:blank"}`
+
+	testCases := []struct {
+		name         string
+		method       string
+		header       string
+		claims       *auth.Claims
+		verifyError  error
+		responseCode int
+		responseBody string
+	}{
+		{
+			name:         "default tenant",
+			method:       http.MethodGet,
+			responseCode: http.StatusOK,
+			responseBody: `{"tenant":""}`,
+		},
+		{
+			name:         "anonymous read of another tenant",
+			method:       http.MethodGet,
+			header:       "brand-a",
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "invalid header",
+			method:       http.MethodGet,
+			header:       "Brand A",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"X-Tenant-Id is not valid","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "header with the key separator",
+			method:       http.MethodGet,
+			header:       "brand-a#restId",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"X-Tenant-Id is not valid","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "tenant of the principal",
+			method:       http.MethodPost,
+			claims:       &auth.Claims{Subject: "user1", Tenant: "brand-a"},
+			responseCode: http.StatusOK,
+			responseBody: `{"tenant":"brand-a"}`,
+		},
+		{
+			name:         "tenant of the principal and the header",
+			method:       http.MethodGet,
+			header:       "brand-a",
+			claims:       &auth.Claims{Subject: "user1", Tenant: "brand-a"},
+			responseCode: http.StatusOK,
+			responseBody: `{"tenant":"brand-a"}`,
+		},
+		{
+			name:         "read by a principal of another tenant",
+			method:       http.MethodGet,
+			header:       "brand-b",
+			claims:       &auth.Claims{Subject: "user1", Tenant: "brand-a"},
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "read by a principal of no tenant",
+			method:       http.MethodGet,
+			header:       "brand-b",
+			claims:       &auth.Claims{Subject: "user1"},
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "write by a principal of no tenant",
+			method:       http.MethodPost,
+			header:       "brand-b",
+			claims:       &auth.Claims{Subject: "user1"},
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+		{
+			name:         "principal of no tenant",
+			method:       http.MethodPost,
+			claims:       &auth.Claims{Subject: "user1"},
+			responseCode: http.StatusOK,
+			responseBody: `{"tenant":""}`,
+		},
+		{
+			name:         "admin of no tenant",
+			method:       http.MethodPost,
+			header:       "brand-b",
+			claims:       &auth.Claims{Subject: "user2", Roles: []string{"admin"}},
+			responseCode: http.StatusOK,
+			responseBody: `{"tenant":"brand-b"}`,
+		},
+		{
+			name:         "read by an admin of no tenant",
+			method:       http.MethodGet,
+			header:       "brand-b",
+			claims:       &auth.Claims{Subject: "user2", Roles: []string{"admin"}},
+			responseCode: http.StatusOK,
+			responseBody: `{"tenant":"brand-b"}`,
+		},
+		{
+			name:         "invalid token with the header",
+			method:       http.MethodGet,
+			header:       "brand-b",
+			claims:       &auth.Claims{Subject: "user2", Roles: []string{"admin"}},
+			verifyError:  errors.New("token expired"),
+			responseCode: http.StatusUnauthorized,
+			responseBody: `{"detail":"the bearer token is not valid","status":401,"title":"Unauthorized","type":"about:blank"}`,
+		},
+		{
+			name:         "admin of another tenant",
+			method:       http.MethodPost,
+			header:       "brand-b",
+			claims:       &auth.Claims{Subject: "user2", Roles: []string{"admin"}, Tenant: "brand-a"},
+			responseCode: http.StatusForbidden,
+			responseBody: forbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			verifier := tokenVerifierStub{error: tc.verifyError}
+			if tc.claims != nil {
+				verifier.claims = *tc.claims
+			}
+			a := Auth{Verifier: verifier, AdminRole: "admin"}
+			handler := func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"tenant": tenant(c)})
+			}
+
+			router := gin.New()
+			router.Use(Tenant, a.AuthorizeTenant)
+			router.GET("/", handler)
+			router.POST("/", a.Authenticate, handler)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "/", nil)
+			if tc.header != "" {
+				r.Header.Set(TenantHeader, tc.header)
+			}
+			if tc.claims != nil {
+				r.Header.Set("Authorization", "Bearer user-token")
+			}
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+		})
+	}
+}
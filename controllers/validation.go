@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"net/http"
+	"strings"
+)
+
+// validRestaurant normalizes the fields of the restaurant and reports
+// whether it is valid. The tags are checked against the vocabulary of the
+// tenant, which is only read when the restaurant has tags. When it is not
+// valid, it responds with 422 Unprocessable Entity and the errors of the
+// invalid fields.
+func (r Restaurant) validRestaurant(c *gin.Context, restaurant *model.Restaurant) bool {
+	errs := validate.Restaurant(restaurant)
+	if restaurant.Tags != nil && len(*restaurant.Tags) > 0 {
+		vocabulary, err := vocabulary(r.Tags, tenant(c))
+		if err != nil {
+			respondError(c, err)
+			return false
+		}
+		errs = append(errs, validate.Tags(*restaurant.Tags, vocabulary)...)
+	}
+	if len(errs) == 0 {
+		return true
+	}
+	detail := "the restaurant is not valid"
+	writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &errs})
+	return false
+}
+
+// bindError responds to a request body that could not be bound. A value of
+// the wrong type is reported as an error of its field with 422
+// Unprocessable Entity, any other error with 400 Bad Request and message.
+func bindError(c *gin.Context, err error, message string) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		detail := "the request body is not valid"
+		writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &[]model.FieldError{{
+			Field:   typeErr.Field,
+			Code:    validate.InvalidType,
+			Message: typeErr.Field + " must be " + jsonType(typeErr.Type.Kind().String()),
+		}}})
+		return
+	}
+	problem(c, http.StatusBadRequest, message)
+}
+
+// jsonType returns the JSON type of a Go kind, with its article.
+func jsonType(kind string) string {
+	switch {
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "slice" || kind == "array":
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// importErrors returns the errors of an invalid restaurant as the error of
+// its import record, which is shown to the client.
+func importErrors(errs []model.FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Message)
+	}
+	return invalidError(strings.Join(messages, "; "))
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/validate"
+	"log"
+	"net/http"
+	"time"
+)
+
+type WebhookStorer interface {
+	SaveWebhook(tenant string, webhook model.Webhook) error
+	GetWebhook(tenant, webhookId string) (model.Webhook, error)
+	ListWebhooks(tenant string) ([]model.Webhook, error)
+	DeleteWebhook(tenant, webhookId string) error
+	SaveDeadLetter(tenant string, delivery model.Delivery) error
+	GetDeadLetter(tenant, deliveryId string) (model.Delivery, error)
+	ListDeadLetters(tenant string) ([]model.Delivery, error)
+	DeleteDeadLetter(tenant, deliveryId string) error
+}
+
+// Deliverer makes one attempt of a delivery to a webhook.
+type Deliverer interface {
+	Deliver(webhook model.Webhook, delivery model.Delivery) (model.Delivery, error)
+}
+
+// EventPublisher delivers the events of the restaurants to the webhooks of
+// their tenant, in the background.
+type EventPublisher interface {
+	Publish(tenant string, event model.WebhookEvent)
+}
+
+// SubscriptionCache caches the webhook subscriptions of the tenants for
+// the events.
+type SubscriptionCache interface {
+	Forget(tenant string)
+}
+
+// Webhook manages the webhook subscriptions of each tenant and their failed
+// deliveries. Subscriptions, when set, is told when the subscriptions of a
+// tenant change.
+type Webhook struct {
+	Webhook       WebhookStorer
+	Deliverer     Deliverer
+	Subscriptions SubscriptionCache
+}
+
+// List returns the webhook subscriptions of the tenant, without their
+// secret.
+func (w Webhook) List(c *gin.Context) {
+	log.Printf("Webhook.List tenant: %s\n", tenant(c))
+
+	webhooks, err := w.Webhook.ListWebhooks(tenant(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = nil
+	}
+	c.JSON(http.StatusOK, model.WebhookList{Webhooks: webhooks})
+}
+
+// Create subscribes a URL to events of the restaurants of the tenant. The
+// secret is stored to sign the events, and never returned.
+func (w Webhook) Create(c *gin.Context) {
+	var webhook model.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		bindError(c, err, "error binding request body")
+		return
+	}
+
+	if errs := validate.Webhook(&webhook); len(errs) > 0 {
+		detail := "the webhook is not valid"
+		writeProblem(c, model.Problem{Status: http.StatusUnprocessableEntity, Detail: &detail, Errors: &errs})
+		return
+	}
+
+	webhook.Id = uuid.NewString()
+	webhook.CreatedAt = time.Now().UTC()
+
+	log.Printf("Webhook.Create tenant: %s  webhookId: %s  url: %s\n", tenant(c), webhook.Id, webhook.Url)
+
+	if err := w.Webhook.SaveWebhook(tenant(c), webhook); err != nil {
+		respondError(c, err)
+		return
+	}
+	w.forget(c)
+
+	webhook.Secret = nil
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// Delete deletes a webhook subscription of the tenant. The events are no
+// longer delivered to its URL, and the retries in progress stop before
+// their next attempt.
+func (w Webhook) Delete(c *gin.Context) {
+	webhookId := c.Param("webhookId")
+
+	log.Printf("Webhook.Delete tenant: %s  webhookId: %s\n", tenant(c), webhookId)
+
+	if err := w.Webhook.DeleteWebhook(tenant(c), webhookId); err != nil {
+		respondError(c, err)
+		return
+	}
+	w.forget(c)
+
+	c.JSON(http.StatusOK, "")
+}
+
+// ListDeliveries returns the deliveries of the tenant that failed after
+// every retry.
+func (w Webhook) ListDeliveries(c *gin.Context) {
+	log.Printf("Webhook.ListDeliveries tenant: %s\n", tenant(c))
+
+	deliveries, err := w.Webhook.ListDeadLetters(tenant(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.DeliveryList{Deliveries: deliveries})
+}
+
+// Redeliver delivers a failed delivery again, once, to the current URL of
+// its webhook. The delivery is removed from the failed deliveries when it
+// succeeds, and updated with the outcome of the attempt otherwise.
+func (w Webhook) Redeliver(c *gin.Context) {
+	deliveryId := c.Param("deliveryId")
+
+	log.Printf("Webhook.Redeliver tenant: %s  deliveryId: %s\n", tenant(c), deliveryId)
+
+	delivery, err := w.Webhook.GetDeadLetter(tenant(c), deliveryId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	webhook, err := w.Webhook.GetWebhook(tenant(c), delivery.WebhookId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	delivery, deliveryErr := w.Deliverer.Deliver(webhook, delivery)
+	if deliveryErr != nil {
+		if err := w.Webhook.SaveDeadLetter(tenant(c), delivery); err != nil {
+			respondError(c, err)
+			return
+		}
+		respondError(c, deliveryErr)
+		return
+	}
+
+	if err := w.Webhook.DeleteDeadLetter(tenant(c), deliveryId); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// forget drops the cached subscriptions of the tenant, when they are
+// cached.
+func (w Webhook) forget(c *gin.Context) {
+	if w.Subscriptions != nil {
+		w.Subscriptions.Forget(tenant(c))
+	}
+}
+
+// publish delivers the event of the restaurant to the webhooks of the
+// tenant, when there is a publisher. The restaurant is nil when it is
+// deleted. It is copied, as the event is delivered after the response is
+// written.
+func publish(c *gin.Context, events EventPublisher, eventType model.WebhookEventType, restaurantId string, restaurant *model.Restaurant) {
+	if events == nil {
+		return
+	}
+	if restaurant != nil {
+		stored := *restaurant
+		restaurant = &stored
+	}
+	events.Publish(tenant(c), model.WebhookEvent{
+		Id:           uuid.NewString(),
+		Type:         eventType,
+		CreatedAt:    time.Now().UTC(),
+		RestaurantId: restaurantId,
+		Restaurant:   restaurant,
+	})
+}
@@ -0,0 +1,462 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/lfroomin/restaurant-container/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func Test_WebhookCreate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		body         string
+		responseCode int
+		responseBody string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			body:         `{"url":"https://partner.example.com/hooks","secret":"` + testWebhookSecret + `","events":["restaurant.created","restaurant.deleted"]}`,
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "invalid webhook",
+			body:         `{"url":"partner.example.com","secret":"secret","events":[]}`,
+			responseCode: http.StatusUnprocessableEntity,
+			responseBody: `{"detail":"the webhook is not valid","errors":[{"code":"invalid_url","field":"url","message":"url must be an absolute HTTP or HTTPS URL"},{"code":"too_short","field":"secret","message":"secret must be at least 16 characters"},{"code":"required","field":"events","message":"events is required"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:         "empty request body",
+			responseCode: http.StatusBadRequest,
+			responseBody: `{"detail":"error binding request body","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:         "storage error",
+			body:         `{"url":"https://partner.example.com/hooks","secret":"` + testWebhookSecret + `","events":["restaurant.created"]}`,
+			responseCode: http.StatusInternalServerError,
+			responseBody: `{"detail":"an internal error occurred","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+			stubError:    "an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			storer := &webhookStorerStub{error: tc.stubError}
+			cache := &subscriptionCacheStub{}
+			wc := Webhook{Webhook: storer, Subscriptions: cache}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(tc.body))
+
+			wc.Create(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode != http.StatusCreated {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+				assert.Empty(t, cache.forgotten)
+				return
+			}
+
+			// The subscriptions of the tenant are listed again for the events
+			assert.Equal(t, []string{dynamo.DefaultTenant}, cache.forgotten)
+
+			// The secret is stored but not returned
+			var created model.Webhook
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+			assert.NotEmpty(t, created.Id)
+			assert.Nil(t, created.Secret)
+			assert.Equal(t, []model.WebhookEventType{model.RestaurantCreated, model.RestaurantDeleted}, created.Events)
+			if assert.Len(t, storer.webhooks, 1) {
+				assert.Equal(t, created.Id, storer.webhooks[0].Id)
+				assert.Equal(t, testWebhookSecret, *storer.webhooks[0].Secret)
+			}
+		})
+	}
+}
+
+func Test_WebhookList(t *testing.T) {
+	t.Parallel()
+
+	secret := testWebhookSecret
+	createdAt := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	storer := &webhookStorerStub{webhooks: []model.Webhook{{
+		Id:        "webhookId",
+		Url:       "https://partner.example.com/hooks",
+		Secret:    &secret,
+		Events:    []model.WebhookEventType{model.RestaurantUpdated},
+		CreatedAt: createdAt,
+	}}}
+	wc := Webhook{Webhook: storer}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	wc.List(c)
+
+	// The secrets are not returned
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"webhooks":[{"createdAt":"2026-10-17T12:00:00Z","events":["restaurant.updated"],"id":"webhookId","url":"https://partner.example.com/hooks"}]}`, responseBody(w))
+}
+
+func Test_WebhookDelete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		responseCode int
+		responseBody string
+		forgotten    []string
+		stubError    string
+	}{
+		{
+			name:         "happy path",
+			responseCode: http.StatusOK,
+			responseBody: `""`,
+			forgotten:    []string{dynamo.DefaultTenant},
+		},
+		{
+			name:         "webhook not found",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"webhook not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			stubError:    "webhook not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cache := &subscriptionCacheStub{}
+			wc := Webhook{Webhook: &webhookStorerStub{error: tc.stubError}, Subscriptions: cache}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "webhookId", Value: "webhookId"}}
+
+			wc.Delete(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			assert.Equal(t, tc.responseBody, responseBody(w))
+			assert.Equal(t, tc.forgotten, cache.forgotten)
+		})
+	}
+}
+
+func Test_WebhookListDeliveries(t *testing.T) {
+	t.Parallel()
+
+	failed := "the receiver responded with status 500"
+	wc := Webhook{Webhook: &webhookStorerStub{dead: []model.Delivery{{Id: "deliveryId", WebhookId: "webhookId", Attempts: 5, LastError: &failed}}}}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	wc.ListDeliveries(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"deliveries":[{"attempts":5,"event":{"createdAt":"0001-01-01T00:00:00Z","id":"","restaurantId":"","type":""},"id":"deliveryId","lastError":"the receiver responded with status 500","webhookId":"webhookId"}]}`, responseBody(w))
+}
+
+func Test_WebhookRedeliver(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		// status is the status of the receiver
+		status       int
+		deliveryId   string
+		webhookId    string
+		responseCode int
+		responseBody string
+		dead         int
+	}{
+		{
+			name:         "delivered",
+			status:       http.StatusNoContent,
+			deliveryId:   "deliveryId",
+			webhookId:    "webhookId",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "failed again",
+			status:       http.StatusServiceUnavailable,
+			deliveryId:   "deliveryId",
+			webhookId:    "webhookId",
+			responseCode: http.StatusBadGateway,
+			responseBody: `{"detail":"the webhook could not be delivered: the receiver responded with status 503","status":502,"title":"Bad Gateway","type":"about:blank"}`,
+			dead:         1,
+		},
+		{
+			name:         "delivery not found",
+			deliveryId:   "unknown",
+			webhookId:    "webhookId",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"delivery not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			dead:         1,
+		},
+		{
+			name:         "webhook deleted",
+			deliveryId:   "deliveryId",
+			webhookId:    "deleted",
+			responseCode: http.StatusNotFound,
+			responseBody: `{"detail":"webhook not found","status":404,"title":"Not Found","type":"about:blank"}`,
+			dead:         1,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+
+			secret := testWebhookSecret
+			storer := &webhookStorerStub{
+				webhooks: []model.Webhook{{Id: "webhookId", Url: receiver.URL, Secret: &secret}},
+				dead:     []model.Delivery{{Id: "deliveryId", WebhookId: tc.webhookId, Attempts: 5}},
+			}
+			wc := Webhook{Webhook: storer, Deliverer: webhook.New(storer)}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Params = []gin.Param{{Key: "deliveryId", Value: tc.deliveryId}}
+
+			wc.Redeliver(c)
+
+			assert.Equal(t, tc.responseCode, w.Code)
+			if tc.responseCode == http.StatusOK {
+				var delivery model.Delivery
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &delivery))
+				assert.Equal(t, 6, delivery.Attempts)
+				assert.NotNil(t, delivery.DeliveredAt)
+			} else {
+				assert.Equal(t, tc.responseBody, responseBody(w))
+			}
+
+			// A delivery is only removed from the dead letters when it is
+			// delivered, and records the failed attempts
+			if assert.Len(t, storer.dead, tc.dead) && tc.responseCode == http.StatusBadGateway {
+				assert.Equal(t, 6, storer.dead[0].Attempts)
+				assert.Equal(t, http.StatusServiceUnavailable, *storer.dead[0].LastStatus)
+			}
+		})
+	}
+}
+
+func Test_RestaurantEvents(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var events []model.WebhookEvent
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature := "sha256=" + webhook.Sign(testWebhookSecret, r.Header.Get(webhook.TimestampHeader), body)
+		if r.Header.Get(webhook.SignatureHeader) != signature {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var event model.WebhookEvent
+		_ = json.Unmarshal(body, &event)
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	defer receiver.Close()
+
+	secret := testWebhookSecret
+	storer := &webhookStorerStub{webhooks: []model.Webhook{{
+		Id:     "webhookId",
+		Url:    receiver.URL,
+		Secret: &secret,
+		Events: []model.WebhookEventType{model.RestaurantCreated, model.RestaurantDeleted},
+	}}}
+	dispatcher := webhook.New(storer)
+	restId := "restId"
+	rc := Restaurant{
+		Restaurant: restaurantStorerStub{restaurant: model.Restaurant{Id: &restId, Name: "Ramen Bar"}},
+		Location:   locationServiceStub{},
+		Index:      searchIndexStub{},
+		Events:     dispatcher,
+	}
+
+	for _, request := range []struct {
+		method  string
+		body    string
+		handler gin.HandlerFunc
+	}{
+		{http.MethodPost, `{"name":"Ramen Bar"}`, rc.Create},
+		{http.MethodPost, `{"id":"restId","name":"Ramen Bar"}`, rc.Update},
+		{http.MethodDelete, "", rc.Delete},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		c.Request = httptest.NewRequest(request.method, "/restId", bytes.NewBufferString(request.body))
+		c.Params = []gin.Param{{Key: "restaurantId", Value: restId}}
+		c.Set(principalKey, principal{subject: "user1"})
+
+		request.handler(c)
+		assert.Less(t, w.Code, 300, fmt.Sprintf("%s %s", request.method, request.body))
+	}
+	dispatcher.Wait()
+
+	// The update is not delivered, the webhook is not subscribed to it
+	assert.Empty(t, storer.dead)
+	if assert.Len(t, events, 2) {
+		byType := map[model.WebhookEventType]model.WebhookEvent{}
+		for _, event := range events {
+			byType[event.Type] = event
+		}
+
+		created := byType[model.RestaurantCreated]
+		if assert.NotNil(t, created.Restaurant) {
+			assert.Equal(t, "Ramen Bar", created.Restaurant.Name)
+			assert.Equal(t, *created.Restaurant.Id, created.RestaurantId)
+		}
+		deleted := byType[model.RestaurantDeleted]
+		assert.Equal(t, restId, deleted.RestaurantId)
+		assert.Nil(t, deleted.Restaurant)
+	}
+}
+
+// webhookStorerStub keeps the webhooks and the dead letters in memory. The
+// error is returned by every method.
+type webhookStorerStub struct {
+	error string
+
+	mu       sync.Mutex
+	webhooks []model.Webhook
+	dead     []model.Delivery
+}
+
+func (s *webhookStorerStub) SaveWebhook(_ string, webhook model.Webhook) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks = append(s.webhooks, webhook)
+	return nil
+}
+
+func (s *webhookStorerStub) GetWebhook(_, webhookId string) (model.Webhook, error) {
+	if s.error != "" {
+		return model.Webhook{}, stubErr(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, webhook := range s.webhooks {
+		if webhook.Id == webhookId {
+			return webhook, nil
+		}
+	}
+	return model.Webhook{}, stubErr("webhook not found")
+}
+
+func (s *webhookStorerStub) ListWebhooks(_ string) ([]model.Webhook, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.Webhook{}, s.webhooks...), nil
+}
+
+func (s *webhookStorerStub) DeleteWebhook(_, _ string) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	return nil
+}
+
+func (s *webhookStorerStub) SaveDeadLetter(_ string, delivery model.Delivery) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.dead {
+		if s.dead[i].Id == delivery.Id {
+			s.dead[i] = delivery
+			return nil
+		}
+	}
+	s.dead = append(s.dead, delivery)
+	return nil
+}
+
+func (s *webhookStorerStub) GetDeadLetter(_, deliveryId string) (model.Delivery, error) {
+	if s.error != "" {
+		return model.Delivery{}, stubErr(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range s.dead {
+		if delivery.Id == deliveryId {
+			return delivery, nil
+		}
+	}
+	return model.Delivery{}, stubErr("delivery not found")
+}
+
+func (s *webhookStorerStub) ListDeadLetters(_ string) ([]model.Delivery, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.Delivery{}, s.dead...), nil
+}
+
+func (s *webhookStorerStub) DeleteDeadLetter(_, deliveryId string) error {
+	if s.error != "" {
+		return stubErr(s.error)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.dead {
+		if s.dead[i].Id == deliveryId {
+			s.dead = append(s.dead[:i], s.dead[i+1:]...)
+			return nil
+		}
+	}
+	return stubErr("delivery not found")
+}
+
+// subscriptionCacheStub records the tenants whose subscriptions are
+// forgotten.
+type subscriptionCacheStub struct {
+	forgotten []string
+}
+
+func (s *subscriptionCacheStub) Forget(tenant string) {
+	s.forgotten = append(s.forgotten, tenant)
+}
@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval is the minimum time between two fetches of a JWKS
+// URL, so tokens with unknown key IDs cannot flood the issuer.
+const minRefreshInterval = 5 * time.Minute
+
+// maxJWKSSize is the maximum size of a JWKS document
+const maxJWKSSize = 1 << 20
+
+// Key is a public key of the issuer, and the algorithm it is restricted
+// to, empty when the JWK does not say.
+type Key struct {
+	Public crypto.PublicKey
+	Alg    string
+}
+
+// JWKS is the JSON Web Key Set (RFC 7517) of the token issuer, loaded from
+// a file or a URL. The keys of a URL are fetched again when a token is
+// signed with a key ID that is not in the set, as the issuer rotates its
+// keys. The keys are fetched outside of the lock, so the tokens signed with
+// the known keys are verified while the set is being fetched.
+type JWKS struct {
+	source string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]Key
+	refreshed time.Time
+	// refreshing is closed when the fetch in progress is done, nil when the
+	// keys are not being fetched
+	refreshing chan struct{}
+}
+
+// LoadJWKS loads the key set from source, an http(s) URL or a file path.
+func LoadJWKS(source string) (*JWKS, error) {
+	j := &JWKS{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+
+	keys, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	j.keys = keys
+	j.refreshed = time.Now()
+	return j, nil
+}
+
+// Key returns the key with the key ID. An empty ID matches the only key of
+// a set with one key. A key ID that is not in the set waits for the keys to
+// be fetched again, by this call or by the one already fetching them.
+func (j *JWKS) Key(kid string) (Key, error) {
+	j.mu.Lock()
+	key, ok := find(j.keys, kid)
+	refreshing := j.refreshing
+	if !ok && refreshing == nil && j.remote() && time.Since(j.refreshed) >= minRefreshInterval {
+		refreshing = make(chan struct{})
+		j.refreshing = refreshing
+		j.refreshed = time.Now()
+		go j.refresh(refreshing)
+	}
+	j.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if refreshing != nil {
+		<-refreshing
+
+		j.mu.Lock()
+		key, ok = find(j.keys, kid)
+		j.mu.Unlock()
+		if ok {
+			return key, nil
+		}
+	}
+
+	return Key{}, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// refresh fetches the keys and swaps them in, keeping the current keys when
+// the fetch fails. done is closed once the keys are swapped.
+func (j *JWKS) refresh(done chan struct{}) {
+	keys, err := j.load()
+	if err != nil {
+		log.Printf("JWKS.Key error refreshing keys: %s\n", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err == nil {
+		j.keys = keys
+	}
+	j.refreshing = nil
+	close(done)
+}
+
+func (j *JWKS) remote() bool {
+	return strings.HasPrefix(j.source, "https://") || strings.HasPrefix(j.source, "http://")
+}
+
+func (j *JWKS) load() (map[string]Key, error) {
+	var data []byte
+	var err error
+	if j.remote() {
+		data, err = j.fetch()
+	} else {
+		data, err = os.ReadFile(j.source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading JWKS %s: %w", j.source, err)
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JWKS %s: %w", j.source, err)
+	}
+	return keys, nil
+}
+
+func (j *JWKS) fetch() ([]byte, error) {
+	resp, err := j.client.Get(j.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+func find(keys map[string]Key, kid string) (Key, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// jwk is a JSON Web Key. Only the RSA and P-256 signature keys are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS returns the signature keys of the key set by key ID. The keys
+// of other types or uses are skipped.
+func ParseJWKS(data []byte) (map[string]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]Key{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var public crypto.PublicKey
+		var err error
+		switch k.Kty {
+		case "RSA":
+			public, err = rsaKey(k)
+		case "EC":
+			public, err = ecKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = Key{Public: public, Alg: k.Alg}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signature keys")
+	}
+	return keys, nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	if public.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+	return public, nil
+}
+
+func ecKey(k jwk) (*ecdsa.PublicKey, error) {
+	if k.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(x) != 32 {
+		return nil, errors.New("invalid x coordinate")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil || len(y) != 32 {
+		return nil, errors.New("invalid y coordinate")
+	}
+
+	// The point is checked to be on the curve
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, errors.New("invalid point")
+	}
+
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_ParseJWKS(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		data   string
+		kids   []string
+		errMsg string
+	}{
+		{
+			name: "RSA and EC keys",
+			data: `{"keys":[` + rsaJWK("rsa") + `,` + ecJWK("ec") + `]}`,
+			kids: []string{"ec", "rsa"},
+		},
+		{
+			name: "encryption key skipped",
+			data: `{"keys":[` + rsaJWK("rsa") + `,{"kty":"RSA","kid":"enc","use":"enc"}]}`,
+			kids: []string{"rsa"},
+		},
+		{
+			name:   "no signature keys",
+			data:   `{"keys":[{"kty":"oct","kid":"hmac","k":"c2VjcmV0"}]}`,
+			errMsg: "no signature keys",
+		},
+		{
+			name:   "RSA key too small",
+			data:   `{"keys":[{"kty":"RSA","kid":"small","n":"` + b64(big.NewInt(1<<62).Bytes()) + `","e":"AQAB"}]}`,
+			errMsg: `key "small": RSA keys must be at least 2048 bits`,
+		},
+		{
+			name:   "EC point not on the curve",
+			data:   `{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"` + b64(make([]byte, 32)) + `","y":"` + b64(make([]byte, 32)) + `"}]}`,
+			errMsg: `key "bad": invalid point`,
+		},
+		{
+			name:   "unsupported curve",
+			data:   `{"keys":[{"kty":"EC","kid":"p384","crv":"P-384"}]}`,
+			errMsg: `key "p384": unsupported curve "P-384"`,
+		},
+		{
+			name:   "not JSON",
+			data:   `keys`,
+			errMsg: "invalid character 'k' looking for beginning of value",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			keys, err := ParseJWKS([]byte(tc.data))
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Len(t, keys, len(tc.kids))
+				for _, kid := range tc.kids {
+					assert.Contains(t, keys, kid)
+				}
+			}
+		})
+	}
+}
+
+func Test_LoadJWKS_File(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(`{"keys":[`+ecJWK("")+`]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := LoadJWKS(path)
+	if assert.Nil(t, err) {
+		// The only key of the set matches a token without a key ID
+		key, err := jwks.Key("")
+		assert.Nil(t, err)
+		assert.Equal(t, &ecPrivate.PublicKey, key.Public)
+	}
+
+	_, err = LoadJWKS(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func Test_LoadJWKS_URL(t *testing.T) {
+	t.Parallel()
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		_, _ = fmt.Fprint(w, `{"keys":[`+rsaJWK("rsa")+`]}`)
+	}))
+	defer server.Close()
+
+	jwks, err := LoadJWKS(server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	key, err := jwks.Key("rsa")
+	assert.Nil(t, err)
+	assert.Equal(t, &rsaPrivate.PublicKey, key.Public)
+
+	// An unknown key ID does not fetch the keys again right after loading
+	_, err = jwks.Key("rotated")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func Test_JWKS_Refresh(t *testing.T) {
+	t.Parallel()
+
+	var fetches int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The refresh is held until the known key has been returned
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			<-release
+			_, _ = fmt.Fprint(w, `{"keys":[`+rsaJWK("rsa")+`,`+ecJWK("rotated")+`]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"keys":[`+rsaJWK("rsa")+`]}`)
+	}))
+	defer server.Close()
+
+	jwks, err := LoadJWKS(server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	jwks.refreshed = time.Now().Add(-minRefreshInterval)
+
+	// The tokens with the new key wait for a single fetch
+	var wg sync.WaitGroup
+	rotated := make([]error, 3)
+	for i := range rotated {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, rotated[i] = jwks.Key("rotated")
+		}(i)
+	}
+
+	// The known key is returned while the keys are being fetched
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&fetches) == 2 }, time.Second, time.Millisecond)
+	key, err := jwks.Key("rsa")
+	assert.Nil(t, err)
+	assert.Equal(t, &rsaPrivate.PublicKey, key.Public)
+
+	close(release)
+	wg.Wait()
+	for _, err := range rotated {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func rsaJWK(kid string) string {
+	public := rsaPrivate.PublicKey
+	return `{"kty":"RSA","kid":"` + kid + `","use":"sig","alg":"RS256","n":"` + b64(public.N.Bytes()) +
+		`","e":"` + b64(big.NewInt(int64(public.E)).Bytes()) + `"}`
+}
+
+func ecJWK(kid string) string {
+	public := ecPrivate.PublicKey
+	x, y := make([]byte, 32), make([]byte, 32)
+	public.X.FillBytes(x)
+	public.Y.FillBytes(y)
+	return `{"kty":"EC","kid":"` + kid + `","crv":"P-256","x":"` + b64(x) + `","y":"` + b64(y) + `"}`
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,208 @@
+// Package auth verifies the bearer tokens of the requests: JSON Web Tokens
+// (RFC 7519) signed by the issuer with RS256 or ES256, whose public keys
+// are published in a JSON Web Key Set.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned when a token cannot be trusted. It wraps the
+// reason, which is only meant for the logs.
+var ErrInvalidToken = errors.New("invalid token")
+
+// leeway is the clock skew tolerated between the issuer and the service
+const leeway = time.Minute
+
+// Claims are the claims of a token used by the service. Tenant is empty
+// when the user does not belong to a tenant.
+type Claims struct {
+	Subject string
+	Roles   []string
+	Tenant  string
+}
+
+// HasRole reports whether the claims grant the role.
+func (c Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// KeySource returns the public key with a key ID.
+type KeySource interface {
+	Key(kid string) (Key, error)
+}
+
+// Verifier verifies the signature and the claims of tokens. The issuer and
+// the audience are only checked when they are set.
+type Verifier struct {
+	Keys     KeySource
+	Issuer   string
+	Audience string
+	Now      func() time.Time
+}
+
+func NewVerifier(keys KeySource, issuer, audience string) Verifier {
+	return Verifier{
+		Keys:     keys,
+		Issuer:   issuer,
+		Audience: audience,
+		Now:      time.Now,
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type payload struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Roles     []string `json:"roles"`
+	Tenant    string   `json:"tenant"`
+}
+
+// audience is the aud claim, a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify returns the claims of the token when it is signed by a key of the
+// issuer, has a subject and is valid at the current time.
+func (v Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodePart(parts[0], &h); err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+
+	key, err := v.Keys.Key(h.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	if key.Alg != "" && key.Alg != h.Alg {
+		return Claims{}, fmt.Errorf("%w: key %q is not for %s", ErrInvalidToken, h.Kid, h.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := verifySignature(h.Alg, key.Public, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	var p payload
+	if err := decodePart(parts[1], &p); err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed payload", ErrInvalidToken)
+	}
+	if err := v.checkClaims(p); err != nil {
+		return Claims{}, err
+	}
+
+	return Claims{Subject: p.Subject, Roles: p.Roles, Tenant: p.Tenant}, nil
+}
+
+func (v Verifier) checkClaims(p payload) error {
+	now := v.Now()
+	switch {
+	case p.Subject == "":
+		return fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case p.ExpiresAt == nil:
+		return fmt.Errorf("%w: no expiration time", ErrInvalidToken)
+	case now.After(time.Unix(*p.ExpiresAt, 0).Add(leeway)):
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	case p.NotBefore != nil && now.Before(time.Unix(*p.NotBefore, 0).Add(-leeway)):
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	case v.Issuer != "" && p.Issuer != v.Issuer:
+		return fmt.Errorf("%w: issuer %q", ErrInvalidToken, p.Issuer)
+	case v.Audience != "" && !contains(p.Audience, v.Audience):
+		return fmt.Errorf("%w: audience %q", ErrInvalidToken, p.Audience)
+	}
+	return nil
+}
+
+// verifySignature checks the signature of the signing input with the key.
+// The algorithm of the header must match the type of the key, so an RSA
+// key cannot be used to verify another algorithm.
+func verifySignature(alg string, public crypto.PublicKey, input string, signature []byte) error {
+	digest := sha256.Sum256([]byte(input))
+
+	switch alg {
+	case "RS256":
+		key, ok := public.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key is not an RSA key", ErrInvalidToken)
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidToken)
+		}
+	case "ES256":
+		key, ok := public.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key is not an EC key", ErrInvalidToken)
+		}
+		// The signature is R and S as 32 byte big-endian integers (RFC 7518)
+		if len(signature) != 64 {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidToken)
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidToken)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+	return nil
+}
+
+func decodePart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	rsaPrivate, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecPrivate, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	now           = time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+)
+
+func Test_Verify(t *testing.T) {
+	t.Parallel()
+
+	exp, past := now.Add(time.Hour).Unix(), now.Add(-time.Hour).Unix()
+	valid := map[string]interface{}{"sub": "user1", "iss": "issuer", "aud": "api", "exp": exp, "roles": []string{"admin"}}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	testCases := []struct {
+		name   string
+		token  string
+		claims Claims
+		errMsg string
+	}{
+		{
+			name:   "RS256",
+			token:  sign(t, "RS256", "rsa", valid),
+			claims: Claims{Subject: "user1", Roles: []string{"admin"}},
+		},
+		{
+			name:   "ES256",
+			token:  sign(t, "ES256", "ec", valid),
+			claims: Claims{Subject: "user1", Roles: []string{"admin"}},
+		},
+		{
+			name:   "tenant",
+			token:  sign(t, "RS256", "rsa", with("tenant", "acme")),
+			claims: Claims{Subject: "user1", Roles: []string{"admin"}, Tenant: "acme"},
+		},
+		{
+			name:   "audience array",
+			token:  sign(t, "ES256", "ec", with("aud", []string{"other", "api"})),
+			claims: Claims{Subject: "user1", Roles: []string{"admin"}},
+		},
+		{
+			name:   "expired",
+			token:  sign(t, "RS256", "rsa", with("exp", past)),
+			errMsg: "invalid token: expired",
+		},
+		{
+			name:   "no expiration time",
+			token:  sign(t, "RS256", "rsa", with("exp", nil)),
+			errMsg: "invalid token: no expiration time",
+		},
+		{
+			name:   "not valid yet",
+			token:  sign(t, "RS256", "rsa", with("nbf", exp)),
+			errMsg: "invalid token: not valid yet",
+		},
+		{
+			name:   "no subject",
+			token:  sign(t, "RS256", "rsa", with("sub", nil)),
+			errMsg: "invalid token: no subject",
+		},
+		{
+			name:   "other issuer",
+			token:  sign(t, "RS256", "rsa", with("iss", "attacker")),
+			errMsg: `invalid token: issuer "attacker"`,
+		},
+		{
+			name:   "other audience",
+			token:  sign(t, "RS256", "rsa", with("aud", "other")),
+			errMsg: `invalid token: audience ["other"]`,
+		},
+		{
+			name:   "unknown key",
+			token:  sign(t, "RS256", "other", valid),
+			errMsg: `invalid token: unknown key "other"`,
+		},
+		{
+			name:   "algorithm of another key type",
+			token:  sign(t, "ES256", "rsa", valid),
+			errMsg: "invalid token: key is not an EC key",
+		},
+		{
+			name:   "algorithm none",
+			token:  encode(map[string]string{"alg": "none", "kid": "rsa"}) + "." + encode(valid) + ".",
+			errMsg: `invalid token: unsupported algorithm "none"`,
+		},
+		{
+			name:   "tampered payload",
+			token:  tamper(sign(t, "RS256", "rsa", valid), with("sub", "admin")),
+			errMsg: "invalid token: invalid signature",
+		},
+		{
+			name:   "malformed",
+			token:  "not a token",
+			errMsg: "invalid token: malformed token",
+		},
+	}
+
+	v := NewVerifier(keySourceStub{}, "issuer", "api")
+	v.Now = func() time.Time { return now }
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			claims, err := v.Verify(tc.token)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.ErrorIs(t, err, ErrInvalidToken)
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.claims, claims)
+			}
+		})
+	}
+}
+
+func Test_HasRole(t *testing.T) {
+	t.Parallel()
+
+	claims := Claims{Subject: "user1", Roles: []string{"editor", "admin"}}
+	assert.True(t, claims.HasRole("admin"))
+	assert.False(t, claims.HasRole("owner"))
+}
+
+// sign returns a token with the claims, signed with the private key
+// matching the algorithm.
+func sign(t *testing.T, alg, kid string, claims interface{}) string {
+	input := encode(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, rsaPrivate, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, ecPrivate, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tamper replaces the claims of the token, keeping its signature.
+func tamper(token string, claims interface{}) string {
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + encode(claims) + "." + parts[2]
+}
+
+func encode(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type keySourceStub struct{}
+
+func (keySourceStub) Key(kid string) (Key, error) {
+	keys := map[string]Key{
+		"rsa": {Public: &rsaPrivate.PublicKey},
+		"ec":  {Public: &ecPrivate.PublicKey, Alg: "ES256"},
+	}
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return Key{}, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
@@ -0,0 +1,87 @@
+// Package blob stores the photos of the restaurants: in a local directory
+// for development and the tests, or in an S3 bucket.
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidKey is returned for a key that is not a relative slash-separated
+// path without . or .. elements, so a blob cannot be written outside its
+// store.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Local stores the blobs in files under Dir, which the server serves under
+// BaseURL.
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocal(dir, baseURL string) Local {
+	return Local{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Put writes the blob to a temporary file renamed into place, so a blob is
+// never read half written. The content type is given by the extension of
+// the key when the file is served.
+func (l Local) Put(key, _ string, data []byte) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating directory of blob %q: %w", key, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error writing blob %q: %w", key, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("error writing blob %q: %w", key, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("error writing blob %q: %w", key, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("error writing blob %q: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the blob. Deleting a blob that does not exist succeeds.
+func (l Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error deleting blob %q: %w", key, err)
+	}
+	return nil
+}
+
+// URL returns the URL the blob is served at.
+func (l Local) URL(key string) string {
+	return l.BaseURL + "/" + key
+}
+
+func (l Local) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,44 @@
+package blob
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Local(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l := NewLocal(dir, "/photos/")
+
+	assert.Nil(t, l.Put("acme/restId/photoId.jpg", "image/jpeg", []byte("photo")))
+	data, err := os.ReadFile(filepath.Join(dir, "acme", "restId", "photoId.jpg"))
+	assert.Nil(t, err)
+	assert.Equal(t, "photo", string(data))
+	assert.Equal(t, "/photos/acme/restId/photoId.jpg", l.URL("acme/restId/photoId.jpg"))
+
+	// A blob is replaced, and no temporary file is left
+	assert.Nil(t, l.Put("acme/restId/photoId.jpg", "image/jpeg", []byte("replaced")))
+	entries, err := os.ReadDir(filepath.Join(dir, "acme", "restId"))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Nil(t, l.Delete("acme/restId/photoId.jpg"))
+	_, err = os.Stat(filepath.Join(dir, "acme", "restId", "photoId.jpg"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Deleting a blob that does not exist succeeds
+	assert.Nil(t, l.Delete("acme/restId/photoId.jpg"))
+}
+
+func Test_LocalInvalidKey(t *testing.T) {
+	t.Parallel()
+	l := NewLocal(t.TempDir(), "/photos")
+
+	for _, key := range []string{"", ".", "../photo.jpg", "acme/../../photo.jpg", "/photo.jpg", "acme//photo.jpg"} {
+		err := l.Put(key, "image/jpeg", []byte("photo"))
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+		assert.ErrorIs(t, l.Delete(key), ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,126 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxErrorBody is the maximum size of the error response of S3 read for
+// the logs
+const maxErrorBody = 4096
+
+// S3 stores the blobs as objects of an S3 bucket. The PutObject and
+// DeleteObject requests are signed with Signature Version 4 by the signer
+// of the SDK, so the S3 client of the SDK is not needed for two requests.
+// The blobs are read from BaseURL, such as a CloudFront distribution of the
+// bucket, or from the bucket itself when it is empty.
+type S3 struct {
+	Client      *http.Client
+	Credentials aws.CredentialsProvider
+	Signer      *v4.Signer
+	Region      string
+	// Endpoint is the URL of the bucket (virtual-hosted style)
+	Endpoint string
+	BaseURL  string
+}
+
+func NewS3(cfg aws.Config, bucket, baseURL string) S3 {
+	endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, cfg.Region)
+	if baseURL == "" {
+		baseURL = endpoint
+	}
+	return S3{
+		Client:      &http.Client{Timeout: 30 * time.Second},
+		Credentials: cfg.Credentials,
+		Signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// The keys of S3 are escaped once, not twice as other services
+			o.DisableURIPathEscaping = true
+		}),
+		Region:   cfg.Region,
+		Endpoint: endpoint,
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Put writes the object with its content type.
+func (s S3) Put(key, contentType string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, s.Endpoint+"/"+escapeKey(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	if err := s.do(req, data, http.StatusOK); err != nil {
+		return fmt.Errorf("error putting blob %q in S3: %w", key, err)
+	}
+	return nil
+}
+
+// Delete deletes the object. Deleting an object that does not exist
+// succeeds.
+func (s S3) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.Endpoint+"/"+escapeKey(key), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := s.do(req, nil, http.StatusNoContent, http.StatusOK, http.StatusNotFound); err != nil {
+		return fmt.Errorf("error deleting blob %q in S3: %w", key, err)
+	}
+	return nil
+}
+
+// URL returns the URL the blob is read at.
+func (s S3) URL(key string) string {
+	return s.BaseURL + "/" + escapeKey(key)
+}
+
+// do signs and sends the request, whose body is payload, and checks that
+// the status is one of the expected statuses.
+func (s S3) do(req *http.Request, payload []byte, statuses ...int) error {
+	ctx := context.Background()
+	creds, err := s.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving credentials: %w", err)
+	}
+
+	hash := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(hash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.Signer.SignHTTP(ctx, creds, req, payloadHash, "s3", s.Region, time.Now()); err != nil {
+		return fmt.Errorf("error signing request: %w", err)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, status := range statuses {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+}
+
+// escapeKey escapes each segment of the key for the path of a URL.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
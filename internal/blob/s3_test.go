@@ -0,0 +1,117 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_S3(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		delete  bool
+		status  int
+		path    string
+		errMsg  string
+		payload string
+	}{
+		{
+			name:    "put",
+			status:  http.StatusOK,
+			path:    "/acme/restId/photo%20Id.jpg",
+			payload: "photo",
+		},
+		{
+			name:    "put error",
+			status:  http.StatusForbidden,
+			path:    "/acme/restId/photo%20Id.jpg",
+			payload: "photo",
+			errMsg:  `error putting blob "acme/restId/photo Id.jpg" in S3: unexpected status 403: <Error><Code>AccessDenied</Code></Error>`,
+		},
+		{
+			name:   "delete",
+			delete: true,
+			status: http.StatusNoContent,
+			path:   "/acme/restId/photo%20Id.jpg",
+		},
+		{
+			name:   "delete of a missing object",
+			delete: true,
+			status: http.StatusNotFound,
+			path:   "/acme/restId/photo%20Id.jpg",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				hash := sha256.Sum256(body)
+
+				if tc.delete {
+					assert.Equal(t, http.MethodDelete, r.Method)
+				} else {
+					assert.Equal(t, http.MethodPut, r.Method)
+					assert.Equal(t, "image/jpeg", r.Header.Get("Content-Type"))
+				}
+				assert.Equal(t, tc.path, r.URL.EscapedPath())
+				assert.Equal(t, tc.payload, string(body))
+				assert.Equal(t, hex.EncodeToString(hash[:]), r.Header.Get("X-Amz-Content-Sha256"))
+				assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"), r.Header.Get("Authorization"))
+				assert.Contains(t, r.Header.Get("Authorization"), "/us-west-2/s3/aws4_request")
+
+				w.WriteHeader(tc.status)
+				if tc.status >= http.StatusBadRequest {
+					w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+				}
+			}))
+			defer server.Close()
+
+			s := NewS3(aws.Config{Region: "us-west-2", Credentials: staticCredentials{}}, "bucket", "")
+			s.Endpoint = server.URL
+
+			var err error
+			if tc.delete {
+				err = s.Delete("acme/restId/photo Id.jpg")
+			} else {
+				err = s.Put("acme/restId/photo Id.jpg", "image/jpeg", []byte(tc.payload))
+			}
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_S3URL(t *testing.T) {
+	t.Parallel()
+
+	s := NewS3(aws.Config{Region: "us-west-2"}, "bucket", "")
+	assert.Equal(t, "https://bucket.s3.us-west-2.amazonaws.com/acme/restId/photoId.jpg", s.URL("acme/restId/photoId.jpg"))
+
+	s = NewS3(aws.Config{Region: "us-west-2"}, "bucket", "https://cdn.example.com/")
+	assert.Equal(t, "https://cdn.example.com/acme/restId/photoId.jpg", s.URL("acme/restId/photoId.jpg"))
+}
+
+type staticCredentials struct{}
+
+func (staticCredentials) Retrieve(_ context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+}
@@ -0,0 +1,151 @@
+// Package booking computes the slots and tables of table reservations.
+//
+// A day is divided into slots of SlotMinutes starting at local midnight. A
+// reservation starts at the start of a slot and occupies its table for the
+// slots covered by DurationMinutes. A table is free for a reservation when
+// none of those slots is locked by another reservation.
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSlotMinutes     = 30
+	DefaultDurationMinutes = 90
+
+	// maxSlots bounds the slots of a reservation, so a booking fits in a
+	// single DynamoDB transaction with one lock item per slot
+	maxSlots = 24
+)
+
+// Lock records that a table is booked by a reservation in a slot.
+type Lock struct {
+	Start         time.Time
+	TableId       string
+	ReservationId string
+}
+
+// Validate checks that the tables have unique IDs and a capacity, and that
+// the slots divide a day and the duration is a multiple of the slots.
+func Validate(settings model.ReservationSettings) error {
+	if settings.Tables == nil {
+		return errors.New("tables is required")
+	}
+
+	ids := make(map[string]bool, len(settings.Tables))
+	for i, table := range settings.Tables {
+		if strings.TrimSpace(table.Id) == "" {
+			return fmt.Errorf("tables[%d].id is required", i)
+		}
+		if ids[table.Id] {
+			return fmt.Errorf("tables[%d].id is not unique", i)
+		}
+		ids[table.Id] = true
+		if table.Capacity < 1 {
+			return fmt.Errorf("tables[%d].capacity must be at least 1", i)
+		}
+	}
+
+	slot, duration := Durations(settings)
+	if slot < 5*time.Minute || slot > 2*time.Hour || (24*time.Hour)%slot != 0 {
+		return errors.New("slotMinutes must divide a day and be between 5 and 120")
+	}
+	if duration < slot || duration%slot != 0 {
+		return errors.New("durationMinutes must be a multiple of slotMinutes")
+	}
+	if duration/slot > maxSlots {
+		return fmt.Errorf("durationMinutes must be at most %d slots", maxSlots)
+	}
+
+	return nil
+}
+
+// Durations returns the slot interval and the reservation duration of the
+// settings, using the defaults for those that are not set.
+func Durations(settings model.ReservationSettings) (slot, duration time.Duration) {
+	slotMinutes, durationMinutes := DefaultSlotMinutes, DefaultDurationMinutes
+	if settings.SlotMinutes != nil {
+		slotMinutes = *settings.SlotMinutes
+	}
+	if settings.DurationMinutes != nil {
+		durationMinutes = *settings.DurationMinutes
+	}
+	return time.Duration(slotMinutes) * time.Minute, time.Duration(durationMinutes) * time.Minute
+}
+
+// DaySlots returns the start times of the slots of the day of date in loc.
+// On days with a DST transition the slots follow the wall clock, so there
+// are fewer or more of them.
+func DaySlots(settings model.ReservationSettings, loc *time.Location, date time.Time) []time.Time {
+	slot, _ := Durations(settings)
+	y, m, d := date.Date()
+
+	var slots []time.Time
+	seen := make(map[int64]bool)
+	for offset := time.Duration(0); offset < 24*time.Hour; offset += slot {
+		start := time.Date(y, m, d, 0, int(offset/time.Minute), 0, 0, loc)
+		// Wall clock times skipped by a DST transition normalize to a later
+		// slot or to the next day
+		if start.Day() != d || seen[start.Unix()] {
+			continue
+		}
+		seen[start.Unix()] = true
+		slots = append(slots, start)
+	}
+	return slots
+}
+
+// Aligned reports whether start is the start of a slot in loc.
+func Aligned(settings model.ReservationSettings, loc *time.Location, start time.Time) bool {
+	slot, _ := Durations(settings)
+	local := start.In(loc)
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	return local.Second() == 0 && local.Nanosecond() == 0 && sinceMidnight%slot == 0
+}
+
+// Occupied returns the start times of the slots a reservation starting at
+// start occupies its table for.
+func Occupied(settings model.ReservationSettings, start time.Time) []time.Time {
+	slot, duration := Durations(settings)
+	slots := make([]time.Time, 0, duration/slot)
+	for offset := time.Duration(0); offset < duration; offset += slot {
+		slots = append(slots, start.Add(offset).UTC())
+	}
+	return slots
+}
+
+// FreeTables returns the tables that seat the party and are not locked in
+// any of the slots, smallest first so larger tables stay available for
+// larger parties. Locks of the reservation itself are ignored, so it can
+// be moved to slots it overlaps.
+func FreeTables(settings model.ReservationSettings, partySize int, slots []time.Time, locks []Lock, reservationId string) []model.Table {
+	occupied := make(map[int64]bool, len(slots))
+	for _, s := range slots {
+		occupied[s.Unix()] = true
+	}
+
+	taken := make(map[string]bool)
+	for _, lock := range locks {
+		if lock.ReservationId != reservationId && occupied[lock.Start.Unix()] {
+			taken[lock.TableId] = true
+		}
+	}
+
+	var free []model.Table
+	for _, table := range settings.Tables {
+		if table.Capacity >= partySize && !taken[table.Id] {
+			free = append(free, table)
+		}
+	}
+
+	sort.SliceStable(free, func(i, j int) bool {
+		return free[i].Capacity < free[j].Capacity
+	})
+	return free
+}
@@ -0,0 +1,238 @@
+package booking
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testSettings = model.ReservationSettings{
+	Tables: []model.Table{
+		{Id: "t1", Capacity: 6},
+		{Id: "t2", Capacity: 2},
+		{Id: "t3", Capacity: 4},
+	},
+}
+
+func Test_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		settings model.ReservationSettings
+		errMsg   string
+	}{
+		{
+			name:     "happy path",
+			settings: testSettings,
+		},
+		{
+			name:     "custom durations",
+			settings: model.ReservationSettings{Tables: []model.Table{}, SlotMinutes: intPtr(15), DurationMinutes: intPtr(120)},
+		},
+		{
+			name:   "no tables",
+			errMsg: "tables is required",
+		},
+		{
+			name:     "missing table id",
+			settings: model.ReservationSettings{Tables: []model.Table{{Id: " ", Capacity: 2}}},
+			errMsg:   "tables[0].id is required",
+		},
+		{
+			name:     "duplicate table id",
+			settings: model.ReservationSettings{Tables: []model.Table{{Id: "t1", Capacity: 2}, {Id: "t1", Capacity: 4}}},
+			errMsg:   "tables[1].id is not unique",
+		},
+		{
+			name:     "invalid capacity",
+			settings: model.ReservationSettings{Tables: []model.Table{{Id: "t1"}}},
+			errMsg:   "tables[0].capacity must be at least 1",
+		},
+		{
+			name:     "slot does not divide a day",
+			settings: model.ReservationSettings{Tables: []model.Table{}, SlotMinutes: intPtr(35)},
+			errMsg:   "slotMinutes must divide a day and be between 5 and 120",
+		},
+		{
+			name:     "duration not a multiple of slot",
+			settings: model.ReservationSettings{Tables: []model.Table{}, DurationMinutes: intPtr(100)},
+			errMsg:   "durationMinutes must be a multiple of slotMinutes",
+		},
+		{
+			name:     "too many slots",
+			settings: model.ReservationSettings{Tables: []model.Table{}, SlotMinutes: intPtr(5), DurationMinutes: intPtr(180)},
+			errMsg:   "durationMinutes must be at most 24 slots",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := Validate(tc.settings)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_DaySlots(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name  string
+		date  time.Time
+		count int
+		last  time.Time
+	}{
+		{
+			name:  "regular day",
+			date:  time.Date(2023, time.June, 9, 0, 0, 0, 0, time.UTC),
+			count: 48,
+			last:  time.Date(2023, time.June, 9, 23, 30, 0, 0, loc),
+		},
+		{
+			name:  "spring forward",
+			date:  time.Date(2023, time.March, 12, 0, 0, 0, 0, time.UTC),
+			count: 46,
+			last:  time.Date(2023, time.March, 12, 23, 30, 0, 0, loc),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			slots := DaySlots(testSettings, loc, tc.date)
+
+			if assert.Len(t, slots, tc.count) {
+				assert.True(t, tc.last.Equal(slots[len(slots)-1]))
+			}
+		})
+	}
+}
+
+func Test_Aligned(t *testing.T) {
+	t.Parallel()
+
+	// Kolkata is 5:30 ahead of UTC, so slots are not aligned in UTC
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		start   time.Time
+		aligned bool
+	}{
+		{
+			name:    "start of slot",
+			start:   time.Date(2023, time.June, 9, 19, 30, 0, 0, loc),
+			aligned: true,
+		},
+		{
+			name:    "start of slot in UTC",
+			start:   time.Date(2023, time.June, 9, 14, 0, 0, 0, time.UTC),
+			aligned: true,
+		},
+		{
+			name:  "within slot",
+			start: time.Date(2023, time.June, 9, 19, 45, 0, 0, loc),
+		},
+		{
+			name:  "seconds",
+			start: time.Date(2023, time.June, 9, 19, 30, 1, 0, loc),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.aligned, Aligned(testSettings, loc, tc.start))
+		})
+	}
+}
+
+func Test_FreeTables(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2023, time.June, 9, 19, 0, 0, 0, time.UTC)
+	slots := Occupied(testSettings, start)
+
+	testCases := []struct {
+		name          string
+		partySize     int
+		locks         []Lock
+		reservationId string
+		tables        []string
+	}{
+		{
+			name:      "smallest table first",
+			partySize: 2,
+			tables:    []string{"t2", "t3", "t1"},
+		},
+		{
+			name:      "capacity",
+			partySize: 5,
+			tables:    []string{"t1"},
+		},
+		{
+			name:      "locked in a later slot",
+			partySize: 4,
+			locks:     []Lock{{Start: start.Add(time.Hour), TableId: "t3", ReservationId: "r1"}},
+			tables:    []string{"t1"},
+		},
+		{
+			name:      "locked after the reservation",
+			partySize: 4,
+			locks:     []Lock{{Start: start.Add(90 * time.Minute), TableId: "t3", ReservationId: "r1"}},
+			tables:    []string{"t3", "t1"},
+		},
+		{
+			name:          "own locks",
+			partySize:     4,
+			locks:         []Lock{{Start: start, TableId: "t3", ReservationId: "r1"}},
+			reservationId: "r1",
+			tables:        []string{"t3", "t1"},
+		},
+		{
+			name:      "no table",
+			partySize: 7,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var ids []string
+			for _, table := range FreeTables(testSettings, tc.partySize, slots, tc.locks, tc.reservationId) {
+				ids = append(ids, table.Id)
+			}
+			assert.Equal(t, tc.tables, ids)
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
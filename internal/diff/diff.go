@@ -0,0 +1,83 @@
+// Package diff compares two values field by field through their JSON
+// representation.
+package diff
+
+import (
+	"encoding/json"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"reflect"
+	"sort"
+)
+
+// Fields returns the fields that differ between from and to, sorted by
+// field. Nested objects are compared field by field and their fields are
+// named with a dotted path, such as "address.city". Arrays are compared as
+// a whole. A nil value has no fields, so comparing with nil lists every
+// field of the other value.
+func Fields(from, to interface{}) ([]model.RevisionChange, error) {
+	f, err := toJSON(from)
+	if err != nil {
+		return nil, err
+	}
+	t, err := toJSON(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []model.RevisionChange{}
+	compare("", f, t, &changes)
+	return changes, nil
+}
+
+func compare(path string, from, to interface{}, changes *[]model.RevisionChange) {
+	fromObj, fromIsObj := from.(map[string]interface{})
+	toObj, toIsObj := to.(map[string]interface{})
+
+	// A missing object is compared as an empty one, so its fields are listed
+	if (fromIsObj || from == nil) && (toIsObj || to == nil) && (fromIsObj || toIsObj) {
+		keys := make(map[string]bool, len(fromObj)+len(toObj))
+		for k := range fromObj {
+			keys[k] = true
+		}
+		for k := range toObj {
+			keys[k] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			compare(field, fromObj[k], toObj[k], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, model.RevisionChange{Field: path, From: from, To: to})
+	}
+}
+
+// toJSON converts the value to the generic JSON representation.
+func toJSON(v interface{}) (interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err = json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Fields(t *testing.T) {
+	t.Parallel()
+
+	desc := "Noodles"
+	city1, city2 := "Oakland", "Berkeley"
+
+	testCases := []struct {
+		name    string
+		from    *model.Restaurant
+		to      *model.Restaurant
+		changes []model.RevisionChange
+	}{
+		{
+			name: "changed fields",
+			from: &model.Restaurant{Name: "Ramen", Address: &model.Address{City: &city1}},
+			to:   &model.Restaurant{Name: "Ramen Bar", Description: &desc, Address: &model.Address{City: &city2}},
+			changes: []model.RevisionChange{
+				{Field: "address.city", From: "Oakland", To: "Berkeley"},
+				{Field: "description", From: nil, To: "Noodles"},
+				{Field: "name", From: "Ramen", To: "Ramen Bar"},
+			},
+		},
+		{
+			name:    "no changes",
+			from:    &model.Restaurant{Name: "Ramen"},
+			to:      &model.Restaurant{Name: "Ramen"},
+			changes: []model.RevisionChange{},
+		},
+		{
+			name: "created",
+			to:   &model.Restaurant{Name: "Ramen", Address: &model.Address{City: &city1}},
+			changes: []model.RevisionChange{
+				{Field: "address.city", From: nil, To: "Oakland"},
+				{Field: "name", From: nil, To: "Ramen"},
+			},
+		},
+		{
+			name: "deleted",
+			from: &model.Restaurant{Name: "Ramen"},
+			changes: []model.RevisionChange{
+				{Field: "name", From: "Ramen", To: nil},
+			},
+		},
+		{
+			name: "arrays compared as a whole",
+			from: &model.Restaurant{Name: "Ramen", OpeningHours: &[]model.OpeningInterval{{Day: model.Monday, Open: "11:00", Close: "14:00"}}},
+			to:   &model.Restaurant{Name: "Ramen", OpeningHours: &[]model.OpeningInterval{{Day: model.Monday, Open: "12:00", Close: "14:00"}}},
+			changes: []model.RevisionChange{
+				{
+					Field: "openingHours",
+					From:  []interface{}{map[string]interface{}{"day": "monday", "open": "11:00", "close": "14:00"}},
+					To:    []interface{}{map[string]interface{}{"day": "monday", "open": "12:00", "close": "14:00"}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			changes, err := Fields(tc.from, tc.to)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.changes, changes)
+		})
+	}
+}
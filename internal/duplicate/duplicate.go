@@ -0,0 +1,213 @@
+// Package duplicate finds restaurants that are probably the same: they are
+// close to each other and their names are similar once normalized, such as
+// "Joe's Pizza" and "Joes Pizza".
+//
+// Names are compared with the Jaro-Winkler similarity, which tolerates
+// typos and favors names sharing a prefix.
+package duplicate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/fold"
+	"github.com/lfroomin/restaurant-container/internal/geo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"sort"
+	"strings"
+)
+
+// MinSimilarity is the similarity from which two names are the same
+const MinSimilarity = 0.9
+
+// metersPerDegree is the length of a degree of latitude
+const metersPerDegree = 111320
+
+// ignoredWords do not tell restaurants apart
+var ignoredWords = map[string]bool{
+	"and": true, "the": true, "restaurant": true,
+}
+
+// Normalize folds the name (lower case, no diacritics), removes the
+// punctuation and the words that do not tell restaurants apart, so
+// "The Café & Bar" becomes "cafe bar". A name of only such words keeps
+// them, so "The Restaurant" is "the restaurant", and a name of only
+// punctuation is empty.
+func Normalize(name string) string {
+	// Apostrophes are removed rather than split on, so "joe's" is "joes"
+	words := fold.Words(strings.NewReplacer("'", "", "’", "").Replace(name))
+
+	var result []string
+	for _, w := range words {
+		if !ignoredWords[w] {
+			result = append(result, w)
+		}
+	}
+	if len(result) == 0 {
+		result = words
+	}
+	return strings.Join(result, " ")
+}
+
+// Similarity returns the Jaro-Winkler similarity of the normalized names,
+// from 0 (nothing in common) to 1 (the same). A name that is empty once
+// normalized is similar to no name.
+func Similarity(name1, name2 string) float64 {
+	normalized1, normalized2 := Normalize(name1), Normalize(name2)
+	if normalized1 == "" || normalized2 == "" {
+		return 0
+	}
+	return jaroWinkler(normalized1, normalized2)
+}
+
+// Similar reports whether the names are similar enough for the restaurants
+// to be the same.
+func Similar(name1, name2 string) bool {
+	return Similarity(name1, name2) >= MinSimilarity
+}
+
+// Clusters returns the groups of restaurants within distance meters of one
+// another whose names are similar, the largest groups first. Being a
+// duplicate is transitive: a restaurant similar to one of a group is in
+// the group. Restaurants without a location are not compared.
+func Clusters(restaurants []model.Restaurant, distance float64) [][]model.Restaurant {
+	type located struct {
+		restaurant model.Restaurant
+		point      geo.Point
+	}
+
+	var items []located
+	for _, restaurant := range restaurants {
+		if point, ok := geo.Location(restaurant); ok {
+			items = append(items, located{restaurant: restaurant, point: point})
+		}
+	}
+
+	// Sorted by latitude, only the following restaurants within the
+	// distance in latitude need to be compared
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].point.Lat < items[j].point.Lat
+	})
+
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	maxLat := distance / metersPerDegree
+	for i := range items {
+		for j := i + 1; j < len(items) && items[j].point.Lat-items[i].point.Lat <= maxLat; j++ {
+			if geo.Distance(items[i].point, items[j].point) <= distance &&
+				Similar(items[i].restaurant.Name, items[j].restaurant.Name) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	groups := map[int][]model.Restaurant{}
+	for i, item := range items {
+		root := find(i)
+		groups[root] = append(groups[root], item.restaurant)
+	}
+
+	var clusters [][]model.Restaurant
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return id(group[i]) < id(group[j])
+		})
+		clusters = append(clusters, group)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i]) != len(clusters[j]) {
+			return len(clusters[i]) > len(clusters[j])
+		}
+		return id(clusters[i][0]) < id(clusters[j][0])
+	})
+	return clusters
+}
+
+func id(restaurant model.Restaurant) string {
+	if restaurant.Id == nil {
+		return ""
+	}
+	return *restaurant.Id
+}
+
+// jaroWinkler returns the Jaro similarity of the strings, increased for
+// the characters of their common prefix (up to 4).
+func jaroWinkler(s1, s2 string) float64 {
+	r1, r2 := []rune(s1), []rune(s2)
+	if len(r1) == 0 && len(r2) == 0 {
+		return 1
+	}
+	if len(r1) == 0 || len(r2) == 0 {
+		return 0
+	}
+
+	// Characters match when they are the same and not farther apart than
+	// half the length of the longest string
+	longest := len(r1)
+	if len(r2) > longest {
+		longest = len(r2)
+	}
+	window := longest/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched1 := make([]bool, len(r1))
+	matched2 := make([]bool, len(r2))
+	matches := 0
+	for i := range r1 {
+		start, end := i-window, i+window+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(r2) {
+			end = len(r2)
+		}
+		for j := start; j < end; j++ {
+			if !matched2[j] && r1[i] == r2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	// Matching characters that are not in the same order, a transposition
+	// of two characters counts twice
+	transpositions := 0
+	j := 0
+	for i := range r1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if r1[i] != r2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(r1)) + m/float64(len(r2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < 4 && prefix < len(r1) && prefix < len(r2) && r1[prefix] == r2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
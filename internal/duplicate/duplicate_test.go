@@ -0,0 +1,111 @@
+package duplicate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Normalize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		normalized string
+	}{
+		{name: "Joe's Pizza", normalized: "joes pizza"},
+		{name: "The Café & Bar", normalized: "cafe bar"},
+		{name: "  Ramen-Ya  Restaurant ", normalized: "ramen ya"},
+		{name: "Pho 99", normalized: "pho 99"},
+		{name: "The Restaurant", normalized: "the restaurant"},
+		{name: "&", normalized: ""},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.normalized, Normalize(tc.name))
+		})
+	}
+}
+
+func Test_Similar(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name1   string
+		name2   string
+		similar bool
+	}{
+		{name1: "Joe's Pizza", name2: "Joes Pizza", similar: true},
+		{name1: "Joe's Pizza", name2: "Joe's Piza", similar: true},
+		{name1: "The Ramen Bar", name2: "Ramen Bar", similar: true},
+		{name1: "Café Roma", name2: "Cafe Roma Restaurant", similar: true},
+		{name1: "Joe's Pizza", name2: "Pizza Hut", similar: false},
+		{name1: "Thai Palace", name2: "Thai Garden", similar: false},
+		{name1: "Pho 99", name2: "Pho 88", similar: false},
+		{name1: "The Restaurant", name2: "Restaurant", similar: false},
+		{name1: "&", name2: "-", similar: false},
+		{name1: "", name2: "", similar: false},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name1+" "+tc.name2, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.similar, Similar(tc.name1, tc.name2))
+		})
+	}
+}
+
+func Test_JaroWinkler(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 0.961, jaroWinkler("martha", "marhta"), 0.001)
+	assert.InDelta(t, 0.840, jaroWinkler("dwayne", "duane"), 0.001)
+	assert.Equal(t, 1.0, jaroWinkler("", ""))
+	assert.Equal(t, 0.0, jaroWinkler("abc", ""))
+	assert.Equal(t, 0.0, jaroWinkler("abc", "xyz"))
+}
+
+func Test_Clusters(t *testing.T) {
+	t.Parallel()
+
+	restaurants := []model.Restaurant{
+		restaurant("1", "Joe's Pizza", "47.600000,-122.300000"),
+		restaurant("2", "Ramen Bar", "47.600100,-122.300000"),
+		restaurant("3", "Joes Pizza", "47.600200,-122.300000"),
+		restaurant("4", "Joe's Pizzeria", "47.600300,-122.300000"),
+		// Same name but too far
+		restaurant("5", "Ramen Bar", "47.610000,-122.300000"),
+		restaurant("6", "The Ramen Bar", "47.600150,-122.300050"),
+		// No location
+		{Id: str("7"), Name: "Ramen Bar"},
+		// Names that are empty once normalized
+		restaurant("8", "&", "47.620000,-122.300000"),
+		restaurant("9", "-", "47.620050,-122.300000"),
+	}
+
+	clusters := Clusters(restaurants, 100)
+
+	ids := make([][]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		var clusterIds []string
+		for _, r := range cluster {
+			clusterIds = append(clusterIds, *r.Id)
+		}
+		ids = append(ids, clusterIds)
+	}
+	assert.Equal(t, [][]string{{"1", "3", "4"}, {"2", "6"}}, ids)
+}
+
+func restaurant(id, name, geocode string) model.Restaurant {
+	return model.Restaurant{Id: &id, Name: name, Address: &model.Address{Location: &model.Location{Geocode: &geocode}}}
+}
+
+func str(s string) *string {
+	return &s
+}
@@ -0,0 +1,221 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+	"strconv"
+	"time"
+)
+
+// The API keys table has KeyId as partition key. It holds the keys, with
+// the hash of their secret, and the daily usage counters of the keys,
+// whose KeyId is usagePrefix, the key ID and the day. ExpiresAt is the TTL
+// attribute of the counters.
+const (
+	apiKeyKey   = "KeyId"
+	usagePrefix = "usage#"
+	// usageRetention is how long a daily counter is kept after its day
+	usageRetention = 48 * time.Hour
+)
+
+type ApiKeyStorage struct {
+	Client dynamoRestaurantStorer
+	Table  string
+}
+
+// Tenant is empty for the keys of the default tenant.
+type apiKeyItem struct {
+	KeyId  string
+	Tenant string `dynamodbav:",omitempty"`
+	// SecretHash is the SHA-256 hash of the secret of the key, which is
+	// never stored
+	SecretHash string
+	ApiKey     model.ApiKey
+}
+
+func NewApiKey(cfg aws.Config, table string) ApiKeyStorage {
+	return ApiKeyStorage{
+		Client: dynamodb.NewFromConfig(cfg),
+		Table:  table,
+	}
+}
+
+// SaveApiKey stores a new API key with the hash of its secret. The key
+// itself is not stored.
+func (as ApiKeyStorage) SaveApiKey(apiKey model.ApiKey, secretHash string) error {
+	log.Printf("ApiKeyStorage.SaveApiKey keyId: %s\n", apiKey.Id)
+
+	apiKey.Key = nil
+	item := apiKeyItem{
+		KeyId:      apiKey.Id,
+		SecretHash: secretHash,
+		ApiKey:     apiKey,
+	}
+	if apiKey.Tenant != nil {
+		item.Tenant = *apiKey.Tenant
+	}
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+	// The unset fields of the key are not stored rather than stored as NULL,
+	// so RevokedAt does not exist until the key is revoked
+	if m, ok := av["ApiKey"].(*types.AttributeValueMemberM); ok {
+		omitNull(m.Value)
+	}
+
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(apiKeyKey))).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.PutItemInput{
+		Item:                     av,
+		TableName:                aws.String(as.Table),
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+	}
+
+	_, err = as.Client.PutItem(context.Background(), &input)
+	if err != nil {
+		return fmt.Errorf("error saving API key %q in dynamo: %w", apiKey.Id, err)
+	}
+	return nil
+}
+
+// GetApiKey returns the API key and the hash of its secret.
+func (as ApiKeyStorage) GetApiKey(keyId string) (model.ApiKey, string, bool, error) {
+	input := dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			apiKeyKey: &types.AttributeValueMemberS{Value: keyId},
+		},
+		TableName: aws.String(as.Table),
+	}
+
+	data, err := as.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return model.ApiKey{}, "", false, fmt.Errorf("error getting API key %q in dynamo: %w", keyId, err)
+	}
+	if data.Item == nil {
+		return model.ApiKey{}, "", false, nil
+	}
+
+	var item apiKeyItem
+	if err = attributevalue.UnmarshalMap(data.Item, &item); err != nil {
+		return model.ApiKey{}, "", false, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return item.ApiKey, item.SecretHash, true, nil
+}
+
+// RevokeApiKey records the revocation of the API key of the tenant and
+// returns it. A revoked key keeps its first revocation time.
+// ErrApiKeyNotFound is returned when the key does not exist or is a key of
+// another tenant.
+func (as ApiKeyStorage) RevokeApiKey(tenant, keyId string) (model.ApiKey, error) {
+	log.Printf("ApiKeyStorage.RevokeApiKey tenant: %s  keyId: %s\n", tenant, keyId)
+
+	update := expression.Set(
+		expression.Name("ApiKey.RevokedAt"),
+		expression.IfNotExists(expression.Name("ApiKey.RevokedAt"), expression.Value(time.Now().UTC())),
+	)
+	expr, err := expression.NewBuilder().
+		WithUpdate(update).
+		WithCondition(expression.AttributeExists(expression.Name(apiKeyKey)).And(tenantCondition(tenant))).
+		Build()
+	if err != nil {
+		return model.ApiKey{}, err
+	}
+
+	input := dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			apiKeyKey: &types.AttributeValueMemberS{Value: keyId},
+		},
+		TableName:                 aws.String(as.Table),
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              types.ReturnValueAllNew,
+	}
+
+	data, err := as.Client.UpdateItem(context.Background(), &input)
+	if err != nil {
+		if conditionFailed(err) {
+			return model.ApiKey{}, ErrApiKeyNotFound
+		}
+		return model.ApiKey{}, fmt.Errorf("error revoking API key %q in dynamo: %w", keyId, err)
+	}
+
+	var item apiKeyItem
+	if err = attributevalue.UnmarshalMap(data.Attributes, &item); err != nil {
+		return model.ApiKey{}, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return item.ApiKey, nil
+}
+
+// CountUsage counts a request of the API key against its quota for the day
+// (UTC) of now, and returns the number of requests of the day.
+// ErrQuotaExceeded is returned, and the request is not counted, when the
+// quota is reached.
+func (as ApiKeyStorage) CountUsage(keyId string, now time.Time, quota int) (int, error) {
+	day := now.UTC().Truncate(24 * time.Hour)
+
+	update := expression.Add(
+		expression.Name("Count"),
+		expression.Value(1),
+	).Set(
+		expression.Name("ExpiresAt"),
+		expression.Value(day.Add(usageRetention).Unix()),
+	)
+	cond := expression.AttributeNotExists(expression.Name("Count")).
+		Or(expression.Name("Count").LessThan(expression.Value(quota)))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return 0, err
+	}
+
+	usageId := usagePrefix + keyId + "#" + day.Format(time.DateOnly)
+	input := dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			apiKeyKey: &types.AttributeValueMemberS{Value: usageId},
+		},
+		TableName:                 aws.String(as.Table),
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              types.ReturnValueUpdatedNew,
+	}
+
+	data, err := as.Client.UpdateItem(context.Background(), &input)
+	if err != nil {
+		if conditionFailed(err) {
+			return 0, ErrQuotaExceeded
+		}
+		return 0, fmt.Errorf("error counting usage of API key %q in dynamo: %w", keyId, err)
+	}
+
+	count, ok := data.Attributes["Count"].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("error counting usage of API key %q in dynamo: no count returned", keyId)
+	}
+	return strconv.Atoi(count.Value)
+}
+
+// omitNull removes the NULL attributes of the map.
+func omitNull(m map[string]types.AttributeValue) {
+	for name, value := range m {
+		if _, ok := value.(*types.AttributeValueMemberNULL); ok {
+			delete(m, name)
+		}
+	}
+}
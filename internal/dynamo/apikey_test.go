@@ -0,0 +1,297 @@
+package dynamo
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testApiKey = model.ApiKey{
+	Id:         "keyId",
+	Name:       "Partner",
+	RateLimit:  10,
+	Burst:      20,
+	DailyQuota: 1000,
+	CreatedAt:  time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC),
+}
+
+func Test_SaveApiKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		tenant    string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:   "key of a tenant",
+			tenant: "acme",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error saving API key \"keyId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var put dynamodb.PutItemInput
+			as := ApiKeyStorage{
+				Client: apiKeyStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, put: &put},
+				Table:  "ApiKeysTable-Test",
+			}
+			apiKey := testApiKey
+			if tc.tenant != "" {
+				apiKey.Tenant = &tc.tenant
+			}
+			stored := apiKey
+			secret := "secret"
+			apiKey.Key = &secret
+			err := as.SaveApiKey(apiKey, "hash")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+
+			// The key itself is not stored
+			assert.Nil(t, err)
+			var item apiKeyItem
+			assert.Nil(t, attributevalue.UnmarshalMap(put.Item, &item))
+			assert.Equal(t, apiKeyItem{KeyId: "keyId", Tenant: tc.tenant, SecretHash: "hash", ApiKey: stored}, item)
+
+			// RevokedAt does not exist, so it is set when the key is revoked
+			attributes := put.Item["ApiKey"].(*types.AttributeValueMemberM).Value
+			assert.NotContains(t, attributes, "RevokedAt")
+			assert.NotContains(t, attributes, "Key")
+		})
+	}
+}
+
+func Test_GetApiKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		item      *apiKeyItem
+		exists    bool
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:   "happy path",
+			item:   &apiKeyItem{KeyId: "keyId", SecretHash: "hash", ApiKey: testApiKey},
+			exists: true,
+		},
+		{
+			name: "key does not exist",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error getting API key \"keyId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			as := ApiKeyStorage{
+				Client: apiKeyStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, item: tc.item},
+				Table:  "ApiKeysTable-Test",
+			}
+			apiKey, hash, exists, err := as.GetApiKey("keyId")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.exists, exists)
+				if tc.exists {
+					assert.Equal(t, testApiKey, apiKey)
+					assert.Equal(t, "hash", hash)
+				}
+			}
+		})
+	}
+}
+
+func Test_RevokeApiKey(t *testing.T) {
+	t.Parallel()
+
+	revokedAt := time.Date(2026, time.October, 18, 8, 0, 0, 0, time.UTC)
+	revoked := testApiKey
+	revoked.RevokedAt = &revokedAt
+
+	testCases := []struct {
+		name      string
+		item      *apiKeyItem
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+			item: &apiKeyItem{KeyId: "keyId", SecretHash: "hash", ApiKey: revoked},
+		},
+		{
+			name:   "key does not exist",
+			errMsg: "API key not found",
+		},
+		{
+			name:      "error",
+			item:      &apiKeyItem{KeyId: "keyId", SecretHash: "hash", ApiKey: revoked},
+			stubError: "an error occurred",
+			errMsg:    "error revoking API key \"keyId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			as := ApiKeyStorage{
+				Client: apiKeyStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{writeError: tc.stubError}, item: tc.item},
+				Table:  "ApiKeysTable-Test",
+			}
+			apiKey, err := as.RevokeApiKey(DefaultTenant, "keyId")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, revoked, apiKey)
+			}
+		})
+	}
+}
+
+func Test_CountUsage(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		count     int
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:  "happy path",
+			count: 3,
+		},
+		{
+			name:      "quota exceeded",
+			stubError: conditionalCheckFailed,
+			errMsg:    "daily quota exceeded",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error counting usage of API key \"keyId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var update dynamodb.UpdateItemInput
+			as := ApiKeyStorage{
+				Client: apiKeyStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{writeError: tc.stubError}, count: tc.count, update: &update},
+				Table:  "ApiKeysTable-Test",
+			}
+			count, err := as.CountUsage("keyId", time.Date(2026, time.October, 17, 23, 59, 0, 0, time.FixedZone("", -7*3600)), 1000)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+
+			// The requests are counted per day in UTC
+			assert.Nil(t, err)
+			assert.Equal(t, tc.count, count)
+			assert.Equal(t, &types.AttributeValueMemberS{Value: "usage#keyId#2026-10-18"}, update.Key[apiKeyKey])
+		})
+	}
+}
+
+// apiKeyStub serves the API key item, fails the conditional UpdateItem
+// when there is none, and returns the count of the usage counters.
+type apiKeyStub struct {
+	dynamoRestaurantStorerStub
+	item   *apiKeyItem
+	count  int
+	put    *dynamodb.PutItemInput
+	update *dynamodb.UpdateItemInput
+}
+
+func (s apiKeyStub) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if s.put != nil {
+		*s.put = *input
+	}
+	return s.dynamoRestaurantStorerStub.PutItem(ctx, input, optFns...)
+}
+
+func (s apiKeyStub) GetItem(_ context.Context, _ *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	if s.item == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	av, err := attributevalue.MarshalMap(s.item)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: av}, nil
+}
+
+func (s apiKeyStub) UpdateItem(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if s.update != nil {
+		*s.update = *input
+	}
+	if s.writeError != "" {
+		return nil, stubErr(s.writeError)
+	}
+
+	if input.ReturnValues == types.ReturnValueUpdatedNew {
+		av, err := attributevalue.MarshalMap(map[string]int{"Count": s.count})
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.UpdateItemOutput{Attributes: av}, nil
+	}
+
+	if s.item == nil {
+		return nil, stubErr(conditionalCheckFailed)
+	}
+	av, err := attributevalue.MarshalMap(s.item)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateItemOutput{Attributes: av}, nil
+}
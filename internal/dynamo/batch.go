@@ -0,0 +1,193 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+	"time"
+)
+
+const (
+	// batchSize is the maximum number of items in a BatchWriteItem request
+	batchSize = 25
+	// batchRestaurants is the number of restaurants written per request,
+	// each with the revision of its creation
+	batchRestaurants = batchSize / 2
+	// batchAttempts is the number of times unprocessed items are written
+	batchAttempts = 5
+)
+
+// batchBackoff is the delay before the first retry of unprocessed items,
+// doubled for every following retry.
+var batchBackoff = 100 * time.Millisecond
+
+// SaveBatch stores new restaurants using BatchWriteItem, together with the
+// revisions of their creation and the members and counts of their tags.
+// The returned errors are in the same order as the restaurants, nil when
+// the restaurant was saved. Unlike Save, the restaurant, its revision and
+// its members are not written atomically: a revision, a member or a count
+// that could not be written is logged but does not fail the restaurant, so
+// a restaurant keeps a tag deleted while it was imported.
+func (rs RestaurantStorage) SaveBatch(tenant string, restaurants []model.Restaurant, actor Actor) []error {
+	log.Printf("RestaurantStorage.SaveBatch tenant: %s  restaurants: %d\n", tenant, len(restaurants))
+
+	errs := make([]error, len(restaurants))
+	for start := 0; start < len(restaurants); start += batchRestaurants {
+		end := start + batchRestaurants
+		if end > len(restaurants) {
+			end = len(restaurants)
+		}
+		rs.saveBatch(tenant, restaurants[start:end], actor, errs[start:end])
+	}
+	return errs
+}
+
+// saveBatch writes at most batchRestaurants restaurants and their
+// revisions, then the counts and the members of the tags of the restaurants
+// that were written, and sets the error of each restaurant that could not
+// be written. The members of a tag are only written once the restaurants
+// are counted in it, as a tag with no count can be deleted.
+func (rs RestaurantStorage) saveBatch(tenant string, restaurants []model.Restaurant, actor Actor, errs []error) {
+	index := map[string]int{}
+	requests := map[string][]types.WriteRequest{}
+	for i, restaurant := range restaurants {
+		item := newRestaurantItem(tenant, restaurant)
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			errs[i] = fmt.Errorf("error marshalling value: %w", err)
+			continue
+		}
+		revision, err := rs.revisionPut(item.RestaurantId, item.Version, nil, &restaurant, change{action: model.Create, actor: actor})
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		index[item.RestaurantId] = i
+		requests[rs.Table] = append(requests[rs.Table], types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+		requests[rs.HistoryTable] = append(requests[rs.HistoryTable], types.WriteRequest{PutRequest: &types.PutRequest{Item: revision.Put.Item}})
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	unprocessed, err := rs.writeBatch(requests)
+	if err != nil {
+		setBatchErrors(unprocessed[rs.Table], index, errs, fmt.Errorf("error saving restaurants in dynamo: %w", err))
+	} else if len(unprocessed) > 0 {
+		setBatchErrors(unprocessed[rs.Table], index, errs, fmt.Errorf("error saving restaurants in dynamo: %d items unprocessed after %d attempts", len(unprocessed[rs.Table]), batchAttempts))
+		if revisions := unprocessed[rs.HistoryTable]; len(revisions) > 0 {
+			log.Printf("RestaurantStorage.SaveBatch %d revisions unprocessed after %d attempts\n", len(revisions), batchAttempts)
+		}
+	}
+
+	counts := map[string]int{}
+	for i, restaurant := range restaurants {
+		if errs[i] == nil {
+			for _, tag := range restaurantTags(&restaurant) {
+				counts[tag]++
+			}
+		}
+	}
+	counted := rs.countBatch(tenant, counts)
+
+	var members []types.WriteRequest
+	for i, restaurant := range restaurants {
+		if errs[i] != nil {
+			continue
+		}
+		writes, err := memberWrites(rs.TagMembersTable, tenant, *restaurant.Id, nil, &restaurant)
+		if err != nil {
+			log.Printf("RestaurantStorage.SaveBatch members of restaurant %s not written: %s\n", *restaurant.Id, err)
+			continue
+		}
+		for _, write := range writes {
+			var tagId string
+			if err := attributevalue.Unmarshal(write.Put.Item[tagKey], &tagId); err != nil || !counted[tagId] {
+				continue
+			}
+			members = append(members, types.WriteRequest{PutRequest: &types.PutRequest{Item: write.Put.Item}})
+		}
+	}
+	for start := 0; start < len(members); start += batchSize {
+		end := start + batchSize
+		if end > len(members) {
+			end = len(members)
+		}
+		unprocessed, err := rs.writeBatch(map[string][]types.WriteRequest{rs.TagMembersTable: members[start:end]})
+		if err != nil || len(unprocessed) > 0 {
+			log.Printf("RestaurantStorage.SaveBatch %d members unprocessed: %v\n", len(unprocessed[rs.TagMembersTable]), err)
+		}
+	}
+}
+
+// countBatch adds the number of restaurants written with each tag of the
+// tenant to the count of the tag, and returns the keys of the tags counted.
+// A tag that is no longer in the vocabulary is not counted.
+func (rs RestaurantStorage) countBatch(tenant string, counts map[string]int) map[string]bool {
+	counted := make(map[string]bool, len(counts))
+	for tag, count := range counts {
+		update, err := countUpdate(rs.TagsTable, tenant, tag, count)
+		if err == nil {
+			_, err = rs.Client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+				Key:                       update.Key,
+				TableName:                 update.TableName,
+				UpdateExpression:          update.UpdateExpression,
+				ConditionExpression:       update.ConditionExpression,
+				ExpressionAttributeNames:  update.ExpressionAttributeNames,
+				ExpressionAttributeValues: update.ExpressionAttributeValues,
+			})
+		}
+		if err != nil {
+			log.Printf("RestaurantStorage.SaveBatch restaurants of tag %s not counted: %s\n", tag, err)
+			continue
+		}
+		counted[tenantKey(tenant, tag)] = true
+	}
+	return counted
+}
+
+// writeBatch writes the requests, retrying the unprocessed items with
+// exponential backoff. It returns the requests that were not written, after
+// batchAttempts attempts or with the error of the request that failed.
+func (rs RestaurantStorage) writeBatch(requests map[string][]types.WriteRequest) (map[string][]types.WriteRequest, error) {
+	backoff := batchBackoff
+	for attempt := 1; ; attempt++ {
+		input := dynamodb.BatchWriteItemInput{
+			RequestItems: requests,
+		}
+
+		data, err := rs.Client.BatchWriteItem(context.Background(), &input)
+		if err != nil {
+			return requests, err
+		}
+
+		requests = data.UnprocessedItems
+		if len(requests) == 0 || attempt == batchAttempts {
+			return requests, nil
+		}
+
+		count := 0
+		for _, table := range requests {
+			count += len(table)
+		}
+		log.Printf("RestaurantStorage.SaveBatch retrying %d unprocessed items in %s\n", count, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func setBatchErrors(requests []types.WriteRequest, index map[string]int, errs []error, err error) {
+	for _, req := range requests {
+		var restaurantId string
+		if err := attributevalue.Unmarshal(req.PutRequest.Item[key], &restaurantId); err != nil {
+			continue
+		}
+		if i, ok := index[restaurantId]; ok {
+			errs[i] = err
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package dynamo
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_SaveBatch(t *testing.T) {
+	batchBackoff = time.Millisecond
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		count       int
+		tags        int
+		unprocessed int32
+		stubError   string
+		countError  string
+		calls       int32
+		errMsg      string
+	}{
+		{
+			name:  "happy path",
+			count: 3,
+			calls: 1,
+		},
+		{
+			name:  "multiple batches",
+			count: 60,
+			calls: 5,
+		},
+		{
+			name:  "members of the tags in batches",
+			count: 3,
+			tags:  10,
+			calls: 3,
+		},
+		{
+			name:       "members of deleted tags not written",
+			count:      3,
+			tags:       10,
+			countError: conditionalCheckFailed,
+			calls:      1,
+		},
+		{
+			name:        "unprocessed items retried",
+			count:       3,
+			unprocessed: 2,
+			calls:       3,
+		},
+		{
+			name:        "unprocessed items exhausted",
+			count:       3,
+			unprocessed: batchAttempts,
+			calls:       batchAttempts,
+			errMsg:      "error saving restaurants in dynamo: 1 items unprocessed after 5 attempts",
+		},
+		{
+			name:      "error",
+			count:     3,
+			stubError: "an error occurred",
+			calls:     1,
+			errMsg:    "error saving restaurants in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var calls int32
+			rs := RestaurantStorage{
+				Client:          dynamoRestaurantStorerStub{error: tc.stubError, writeError: tc.countError, unprocessed: tc.unprocessed, calls: &calls},
+				Table:           "RestaurantsTable-Test",
+				HistoryTable:    "HistoryTable-Test",
+				TagsTable:       "TagsTable-Test",
+				TagMembersTable: "TagMembersTable-Test",
+			}
+
+			tags := make([]string, tc.tags)
+			for i := range tags {
+				tags[i] = fmt.Sprintf("tag%d", i)
+			}
+			restaurants := make([]model.Restaurant, tc.count)
+			for i := range restaurants {
+				id := fmt.Sprintf("restId%d", i)
+				restaurants[i] = model.Restaurant{Id: &id}
+				if tc.tags > 0 {
+					restaurants[i].Tags = &tags
+				}
+			}
+
+			errs := rs.SaveBatch(DefaultTenant, restaurants, testOwner)
+
+			assert.Len(t, errs, tc.count)
+			assert.Equal(t, tc.calls, atomic.LoadInt32(&calls))
+			for i, err := range errs {
+				// The stub leaves the last item of a batch unprocessed
+				if tc.errMsg != "" && (tc.stubError != "" || i == tc.count-1) {
+					if assert.Error(t, err) {
+						assert.Equal(t, tc.errMsg, err.Error())
+					}
+				} else {
+					assert.Nil(t, err)
+				}
+			}
+		})
+	}
+}
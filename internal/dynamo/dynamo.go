@@ -8,8 +8,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/geo"
 	"github.com/lfroomin/restaurant-container/internal/model"
 	"log"
+	"math"
+	"sort"
 	"time"
 )
 
@@ -18,57 +21,124 @@ type dynamoRestaurantStorer interface {
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
 	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
 const key = "RestaurantId"
 
+// ownerAttribute is the path of the owner in the restaurant item
+const ownerAttribute = "Restaurant.Owner"
+
+// The geohash index is a sparse GSI with GeohashPrefix as partition key and
+// Geohash as sort key. GeohashPrefix is prefixed with the tenant like the
+// restaurant key. Only restaurants with a geocode are in the index.
+const (
+	geohashIndex    = "GeohashIndex"
+	prefixPrecision = 4
+	hashPrecision   = 9
+	maxCells        = 9
+)
+
 type RestaurantStorage struct {
 	Client dynamoRestaurantStorer
 	Table  string
+	// HistoryTable holds the revision log of the restaurants
+	HistoryTable string
+	// TagsTable holds the vocabulary of tags, with the count of the
+	// restaurants having each tag
+	TagsTable string
+	// TagMembersTable holds the restaurants having each tag
+	TagMembersTable string
+	// Retention is how long a deleted restaurant can be restored before
+	// DynamoDB TTL purges it
+	Retention time.Duration
 }
 
+// RestaurantId is the key of the restaurant in its tenant (see tenantKey),
+// and Tenant is empty for the default tenant. A deleted restaurant is a
+// tombstone: DeletedAt is set and ExpiresAt is the time (epoch seconds) at
+// which the TTL of the table purges the item.
+// RatingCount and RatingSum are atomic counters maintained with the reviews.
+// Photos are the photos of the restaurant, in their display order.
 type restaurantItem struct {
-	RestaurantId string
-	Restaurant   model.Restaurant
-	Updated      int64
+	RestaurantId  string
+	Tenant        string `dynamodbav:",omitempty"`
+	Restaurant    model.Restaurant
+	Updated       int64
+	Version       int64
+	GeohashPrefix string        `dynamodbav:",omitempty"`
+	Geohash       string        `dynamodbav:",omitempty"`
+	DeletedAt     int64         `dynamodbav:",omitempty"`
+	ExpiresAt     int64         `dynamodbav:",omitempty"`
+	RatingCount   int64         `dynamodbav:",omitempty"`
+	RatingSum     int64         `dynamodbav:",omitempty"`
+	Photos        []model.Photo `dynamodbav:",omitempty"`
 }
 
-func New(cfg aws.Config, table string) RestaurantStorage {
+func New(cfg aws.Config, table, historyTable, tagsTable, tagMembersTable string, retention time.Duration) RestaurantStorage {
 	return RestaurantStorage{
-		Client: dynamodb.NewFromConfig(cfg),
-		Table:  table,
+		Client:          dynamodb.NewFromConfig(cfg),
+		Table:           table,
+		HistoryTable:    historyTable,
+		TagsTable:       tagsTable,
+		TagMembersTable: tagMembersTable,
+		Retention:       retention,
 	}
 }
 
-func (rs RestaurantStorage) Save(restaurant model.Restaurant) error {
-	log.Printf("RestaurantStorage.Save restaurantId: %s\n", *restaurant.Id)
+// Save stores a new restaurant with version 1 and records its creation in
+// the revision log and the members of its tags. ErrTagRemoved is returned
+// when a tag of the restaurant is not in the vocabulary.
+func (rs RestaurantStorage) Save(tenant string, restaurant model.Restaurant, actor Actor) error {
+	log.Printf("RestaurantStorage.Save tenant: %s  restaurantId: %s\n", tenant, *restaurant.Id)
 
-	r := restaurantItem{
-		RestaurantId: *restaurant.Id,
-		Restaurant:   restaurant,
-		Updated:      time.Now().UnixMilli(),
+	item := newRestaurantItem(tenant, restaurant)
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
 	}
 
-	av, err := attributevalue.MarshalMap(r)
+	revision, err := rs.revisionPut(item.RestaurantId, item.Version, nil, &restaurant, change{action: model.Create, actor: actor})
 	if err != nil {
-		return fmt.Errorf("error marshalling value: %w", err)
+		return err
 	}
 
-	input := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(rs.Table),
+	tags, err := rs.tagWrites(tenant, *restaurant.Id, nil, &restaurant)
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					Item:      av,
+					TableName: aws.String(rs.Table),
+				},
+			},
+			revision,
+		}, tags...),
 	}
 
-	_, err = rs.Client.PutItem(context.Background(), input)
+	_, err = rs.Client.TransactWriteItems(context.Background(), &input)
 	if err != nil {
+		if anyConditionFailed(err, 2, len(input.TransactItems)) {
+			return ErrTagRemoved
+		}
 		return fmt.Errorf("error saving restaurant %q in dynamo: %w", *restaurant.Id, err)
 	}
 	return nil
 }
 
-func (rs RestaurantStorage) Get(restaurantId string) (model.Restaurant, bool, error) {
-	log.Printf("RestaurantStorage.Get restaurantId: %s\n", restaurantId)
+// Get returns the restaurant of the tenant and its version. Deleted
+// restaurants and the restaurants of other tenants do not exist.
+func (rs RestaurantStorage) Get(tenant, restaurantId string) (model.Restaurant, int64, bool, error) {
+	log.Printf("RestaurantStorage.Get tenant: %s  restaurantId: %s\n", tenant, restaurantId)
 
+	restaurantId = tenantKey(tenant, restaurantId)
 	input := dynamodb.GetItemInput{
 		Key: map[string]types.AttributeValue{
 			key: &types.AttributeValueMemberS{Value: restaurantId},
@@ -79,69 +149,411 @@ func (rs RestaurantStorage) Get(restaurantId string) (model.Restaurant, bool, er
 	item := &restaurantItem{}
 	data, err := rs.Client.GetItem(context.Background(), &input)
 	if err != nil {
-		return model.Restaurant{}, false, fmt.Errorf("error getting restaurant %q in dynamo: %w", restaurantId, err)
+		return model.Restaurant{}, 0, false, fmt.Errorf("error getting restaurant %q in dynamo: %w", restaurantId, err)
 	}
 
 	if data.Item != nil {
 		if err = attributevalue.UnmarshalMap(data.Item, &item); err != nil {
-			return model.Restaurant{}, false, fmt.Errorf("error unmarshalling value: %w", err)
+			return model.Restaurant{}, 0, false, fmt.Errorf("error unmarshalling value: %w", err)
 		}
-		return item.Restaurant, true, nil
+		if item.DeletedAt != 0 {
+			return model.Restaurant{}, 0, false, nil
+		}
+		return item.restaurant(), item.Version, true, nil
 	}
 
-	return model.Restaurant{}, false, nil
+	return model.Restaurant{}, 0, false, nil
+}
+
+// Update replaces the restaurant and returns it with the rating aggregates,
+// photos and owner of the stored item, and its new version. ErrNotFound
+// is returned when the restaurant does not exist, and ErrForbidden when the
+// actor cannot modify it. When ifMatch is not empty
+// the restaurant is only updated if its current version is one of the
+// given versions, otherwise ErrPreconditionFailed is returned.
+func (rs RestaurantStorage) Update(tenant string, restaurant model.Restaurant, ifMatch []int64, actor Actor) (model.Restaurant, int64, error) {
+	log.Printf("RestaurantStorage.Update tenant: %s  restaurantId: %s\n", tenant, *restaurant.Id)
+
+	return rs.replace(tenant, restaurant, ifMatch, change{action: model.Update, actor: actor})
 }
 
-func (rs RestaurantStorage) Update(restaurant model.Restaurant) error {
-	log.Printf("RestaurantStorage.Update restaurantId: %s\n", *restaurant.Id)
+// replace writes the restaurant over the stored one and returns it with the
+// rating aggregates, photos and owner of the stored item, and its new
+// version.
+func (rs RestaurantStorage) replace(tenant string, restaurant model.Restaurant, ifMatch []int64, c change) (model.Restaurant, int64, error) {
+	var replaced model.Restaurant
+	version, err := rs.write(tenantKey(tenant, *restaurant.Id), ifMatch, c, func(item *restaurantItem) (mutation, error) {
+		if item == nil || item.DeletedAt != 0 {
+			return mutation{}, ErrNotFound
+		}
 
-	cond := expression.Equal(expression.Name(key), expression.Value(*restaurant.Id))
+		// The owner cannot be changed
+		restaurant.Owner = item.Restaurant.Owner
+		update := expression.Set(expression.Name("Restaurant"), expression.Value(restaurant))
+		if hash, ok := geohash(restaurant); ok {
+			update = update.Set(expression.Name("GeohashPrefix"), expression.Value(tenantKey(tenant, hash[:prefixPrecision]))).
+				Set(expression.Name("Geohash"), expression.Value(hash))
+		} else {
+			update = update.Remove(expression.Name("GeohashPrefix")).
+				Remove(expression.Name("Geohash"))
+		}
 
-	update := expression.Set(
-		expression.Name("Restaurant"),
-		expression.Value(restaurant),
-	).Set(
-		expression.Name("Updated"),
-		expression.Value(time.Now().UnixMilli()),
-	)
+		stored := *item
+		stored.Restaurant = restaurant
+		replaced = stored.restaurant()
 
-	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+		return mutation{
+			update:   update,
+			cond:     existsCondition(),
+			previous: &item.Restaurant,
+			current:  &restaurant,
+		}, nil
+	})
 	if err != nil {
-		return err
+		return model.Restaurant{}, 0, err
 	}
+	return replaced, version, nil
+}
 
-	input := dynamodb.UpdateItemInput{
-		Key: map[string]types.AttributeValue{
-			key: &types.AttributeValueMemberS{Value: *restaurant.Id},
-		},
-		TableName:                 aws.String(rs.Table),
-		UpdateExpression:          expr.Update(),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		ConditionExpression:       expr.Condition(),
-	}
+// Delete marks the restaurant as deleted. It can be restored until the
+// retention period has passed, after which DynamoDB TTL purges it.
+// ErrNotFound is returned when the restaurant does not exist, and
+// ErrForbidden when the actor cannot modify it. When ifMatch is not empty
+// the restaurant is only deleted if its current version is one of the
+// given versions, otherwise ErrPreconditionFailed is returned.
+func (rs RestaurantStorage) Delete(tenant, restaurantId string, ifMatch []int64, actor Actor) error {
+	log.Printf("RestaurantStorage.Delete tenant: %s  restaurantId: %s\n", tenant, restaurantId)
 
-	_, err = rs.Client.UpdateItem(context.Background(), &input)
+	restaurantId = tenantKey(tenant, restaurantId)
+	_, err := rs.write(restaurantId, ifMatch, change{action: model.Delete, actor: actor}, func(item *restaurantItem) (mutation, error) {
+		if item == nil || item.DeletedAt != 0 {
+			return mutation{}, ErrNotFound
+		}
+
+		now := time.Now()
+		update := expression.Set(
+			expression.Name("DeletedAt"),
+			expression.Value(now.UnixMilli()),
+		).Set(
+			expression.Name("ExpiresAt"),
+			expression.Value(now.Add(rs.Retention).Unix()),
+		)
+
+		return mutation{
+			update:   update,
+			cond:     existsCondition(),
+			previous: &item.Restaurant,
+		}, nil
+	})
+	return err
+}
+
+// Restore undoes the deletion of a restaurant and returns the restaurant
+// and its new version. ErrNotFound is returned when the restaurant does not
+// exist or has expired, ErrNotDeleted when it is not deleted and
+// ErrForbidden when the actor cannot modify it.
+func (rs RestaurantStorage) Restore(tenant, restaurantId string, actor Actor) (model.Restaurant, int64, error) {
+	log.Printf("RestaurantStorage.Restore tenant: %s  restaurantId: %s\n", tenant, restaurantId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+	var restored model.Restaurant
+	version, err := rs.write(restaurantId, nil, change{action: model.Restore, actor: actor}, func(item *restaurantItem) (mutation, error) {
+		now := time.Now()
+		switch {
+		case item == nil:
+			return mutation{}, ErrNotFound
+		case item.DeletedAt == 0:
+			return mutation{}, ErrNotDeleted
+		case item.ExpiresAt <= now.Unix():
+			return mutation{}, ErrNotFound
+		}
+
+		cond := expression.AttributeExists(expression.Name("DeletedAt")).
+			And(expression.Name("ExpiresAt").GreaterThan(expression.Value(now.Unix())))
+		update := expression.Remove(
+			expression.Name("DeletedAt"),
+		).Remove(
+			expression.Name("ExpiresAt"),
+		)
+
+		restored = item.restaurant()
+		return mutation{
+			update:  update,
+			cond:    cond,
+			current: &item.Restaurant,
+		}, nil
+	})
 	if err != nil {
-		return fmt.Errorf("error updating restaurant %q in dynamo: %w", *restaurant.Id, err)
+		return model.Restaurant{}, 0, err
 	}
-	return nil
+	return restored, version, nil
 }
 
-func (rs RestaurantStorage) Delete(restaurantId string) error {
-	log.Printf("RestaurantStorage.Delete restaurantId: %s\n", restaurantId)
+// itemState returns the key, deletion attributes and owner of the stored
+// item, or nil when there is no item. restaurantId is the key of the item in
+// its tenant.
+func (rs RestaurantStorage) itemState(restaurantId string) (*restaurantItem, error) {
+	proj := expression.NamesList(expression.Name(key), expression.Name("DeletedAt"), expression.Name("ExpiresAt"), expression.Name(ownerAttribute))
+	expr, err := expression.NewBuilder().WithProjection(proj).Build()
+	if err != nil {
+		return nil, err
+	}
 
-	input := dynamodb.DeleteItemInput{
-		TableName: aws.String(rs.Table),
+	input := dynamodb.GetItemInput{
 		Key: map[string]types.AttributeValue{
 			key: &types.AttributeValueMemberS{Value: restaurantId},
 		},
+		TableName:                aws.String(rs.Table),
+		ProjectionExpression:     expr.Projection(),
+		ExpressionAttributeNames: expr.Names(),
+		ConsistentRead:           aws.Bool(true),
+	}
+
+	data, err := rs.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return nil, fmt.Errorf("error getting restaurant %q in dynamo: %w", restaurantId, err)
+	}
+	if data.Item == nil {
+		return nil, nil
 	}
 
-	_, err := rs.Client.DeleteItem(context.Background(), &input)
+	item := &restaurantItem{}
+	if err = attributevalue.UnmarshalMap(data.Item, item); err != nil {
+		return nil, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return item, nil
+}
+
+// List returns up to limit restaurants of the tenant starting after the
+// position encoded in nextToken. The returned token is empty when there are
+// no more pages. The table is scanned, and filtered on the tenant, until
+// the page is full, so only the last page can have fewer restaurants than
+// limit. The token is the key of the last restaurant returned, never the
+// key of a restaurant of another tenant, and ErrInvalidToken is returned
+// for a token that is not the key of a restaurant of the tenant.
+func (rs RestaurantStorage) List(tenant string, limit int32, nextToken string) ([]model.Restaurant, string, error) {
+	log.Printf("RestaurantStorage.List tenant: %s  limit: %d  nextToken: %s\n", tenant, limit, nextToken)
+
+	startKey, err := decodeToken(nextToken)
 	if err != nil {
-		return fmt.Errorf("error deleting restaurant %q from dynamo: %w", restaurantId, err)
+		return nil, "", err
+	}
+	if startKey != nil && !tenantStartKey(tenant, startKey) {
+		return nil, "", ErrInvalidToken
 	}
 
+	expr, err := expression.NewBuilder().WithFilter(notDeletedCondition().And(tenantCondition(tenant))).Build()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var items []restaurantItem
+	for {
+		input := dynamodb.ScanInput{
+			TableName:                 aws.String(rs.Table),
+			Limit:                     aws.Int32(limit),
+			ExclusiveStartKey:         startKey,
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}
+
+		data, err := rs.Client.Scan(context.Background(), &input)
+		if err != nil {
+			return nil, "", fmt.Errorf("error listing restaurants in dynamo: %w", err)
+		}
+
+		var page []restaurantItem
+		if err = attributevalue.UnmarshalListOfMaps(data.Items, &page); err != nil {
+			return nil, "", fmt.Errorf("error unmarshalling value: %w", err)
+		}
+		items = append(items, page...)
+
+		startKey = data.LastEvaluatedKey
+		if len(startKey) == 0 || len(items) >= int(limit) {
+			break
+		}
+	}
+
+	var lastKey map[string]types.AttributeValue
+	if len(startKey) > 0 || len(items) > int(limit) {
+		if len(items) > int(limit) {
+			items = items[:limit]
+		}
+		if len(items) > 0 {
+			lastKey = map[string]types.AttributeValue{
+				key: &types.AttributeValueMemberS{Value: items[len(items)-1].RestaurantId},
+			}
+		}
+	}
+
+	restaurants := make([]model.Restaurant, 0, len(items))
+	for _, item := range items {
+		restaurants = append(restaurants, item.restaurant())
+	}
+
+	token, err := encodeToken(lastKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encoding pagination token: %w", err)
+	}
+
+	return restaurants, token, nil
+}
+
+// Nearby returns the restaurants of the tenant within radius meters of
+// center, sorted by distance. The geohash cells covering the circle are
+// queried in the geohash index, whose partitions are the cells of each
+// tenant, and the results are filtered by their exact distance.
+func (rs RestaurantStorage) Nearby(tenant string, center geo.Point, radius float64) ([]model.NearbyRestaurant, error) {
+	log.Printf("RestaurantStorage.Nearby tenant: %s  lat: %f  lon: %f  radius: %f\n", tenant, center.Lat, center.Lon, radius)
+
+	nearby := []model.NearbyRestaurant{}
+	for _, cell := range geo.CoveringCells(center, radius, prefixPrecision, hashPrecision, maxCells) {
+		keyCond := expression.Key("GeohashPrefix").Equal(expression.Value(tenantKey(tenant, cell[:prefixPrecision])))
+		if len(cell) > prefixPrecision {
+			keyCond = keyCond.And(expression.Key("Geohash").BeginsWith(cell))
+		}
+
+		expr, err := expression.NewBuilder().WithKeyCondition(keyCond).WithFilter(notDeletedCondition()).Build()
+		if err != nil {
+			return nil, err
+		}
+
+		input := dynamodb.QueryInput{
+			TableName:                 aws.String(rs.Table),
+			IndexName:                 aws.String(geohashIndex),
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}
+
+		paginator := dynamodb.NewQueryPaginator(rs.Client, &input)
+		for paginator.HasMorePages() {
+			data, err := paginator.NextPage(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("error querying nearby restaurants in dynamo: %w", err)
+			}
+
+			var items []restaurantItem
+			if err = attributevalue.UnmarshalListOfMaps(data.Items, &items); err != nil {
+				return nil, fmt.Errorf("error unmarshalling value: %w", err)
+			}
+
+			for _, item := range items {
+				point, ok := geo.Location(item.Restaurant)
+				if !ok {
+					continue
+				}
+				if distance := geo.Distance(center, point); distance <= radius {
+					nearby = append(nearby, model.NearbyRestaurant{Distance: distance, Restaurant: item.restaurant()})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].Distance < nearby[j].Distance
+	})
+
+	return nearby, nil
+}
+
+// geohash returns the geohash of the restaurant's geocode, if it has one.
+func geohash(restaurant model.Restaurant) (string, bool) {
+	point, ok := geo.Location(restaurant)
+	if !ok {
+		return "", false
+	}
+	return geo.Geohash(point, hashPrecision), true
+}
+
+// restaurant returns the restaurant of the item with its rating aggregates
+// and its photos.
+func (item restaurantItem) restaurant() model.Restaurant {
+	restaurant := item.Restaurant
+	if item.RatingCount > 0 {
+		count := int(item.RatingCount)
+		average := math.Round(float64(item.RatingSum)/float64(item.RatingCount)*100) / 100
+		restaurant.RatingCount = &count
+		restaurant.RatingAverage = &average
+	}
+	if len(item.Photos) > 0 {
+		photos := item.Photos
+		restaurant.Photos = &photos
+	}
+	return restaurant
+}
+
+// newRestaurantItem returns the item of a new restaurant of the tenant, with
+// version 1.
+func newRestaurantItem(tenant string, restaurant model.Restaurant) restaurantItem {
+	r := restaurantItem{
+		RestaurantId: tenantKey(tenant, *restaurant.Id),
+		Tenant:       tenant,
+		Restaurant:   restaurant,
+		Updated:      time.Now().UnixMilli(),
+		Version:      1,
+	}
+	if hash, ok := geohash(restaurant); ok {
+		r.GeohashPrefix = tenantKey(tenant, hash[:prefixPrecision])
+		r.Geohash = hash
+	}
+	return r
+}
+
+// existsCondition matches an item that exists and is not deleted.
+func existsCondition() expression.ConditionBuilder {
+	return expression.AttributeExists(expression.Name(key)).And(notDeletedCondition())
+}
+
+// modifiableCondition matches an item that exists, is not deleted and can
+// be modified by the actor, like Actor.CanModify: an admin can modify every
+// restaurant, the other actors the restaurants they own.
+func modifiableCondition(actor Actor) expression.ConditionBuilder {
+	if actor.Admin {
+		return existsCondition()
+	}
+	return existsCondition().And(expression.Name(ownerAttribute).Equal(expression.Value(actor.Id)))
+}
+
+// notModifiable returns why the restaurant item, whose key in its tenant is
+// restaurantId, did not match modifiableCondition: ErrNotFound when it does
+// not exist or is deleted, ErrForbidden when the actor cannot modify it,
+// and nil when it matches now.
+func (rs RestaurantStorage) notModifiable(restaurantId string, actor Actor) error {
+	item, err := rs.itemState(restaurantId)
+	if err != nil {
+		return err
+	}
+	if item == nil || item.DeletedAt != 0 {
+		return ErrNotFound
+	}
+	if !actor.CanModify(item.Restaurant) {
+		return ErrForbidden
+	}
 	return nil
 }
+
+// notDeletedCondition matches an item that is not deleted.
+func notDeletedCondition() expression.ConditionBuilder {
+	return expression.AttributeNotExists(expression.Name("DeletedAt"))
+}
+
+// versionCondition matches an item whose version is one of the given
+// versions. Items written before versioning was introduced have no
+// Version attribute and are treated as version 0.
+func versionCondition(versions []int64) expression.ConditionBuilder {
+	operands := make([]expression.OperandBuilder, 0, len(versions))
+	legacy := false
+	for _, v := range versions {
+		operands = append(operands, expression.Value(v))
+		legacy = legacy || v == 0
+	}
+
+	cond := expression.In(expression.Name("Version"), operands[0], operands[1:]...)
+	if legacy {
+		cond = cond.Or(expression.AttributeNotExists(expression.Name("Version")))
+	}
+	return cond
+}
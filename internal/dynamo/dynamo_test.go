@@ -3,20 +3,29 @@ package dynamo
 import (
 	"context"
 	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/geo"
 	"github.com/lfroomin/restaurant-container/internal/model"
 	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func Test_Save(t *testing.T) {
 	t.Parallel()
 	restId := "restId"
 
+	tags := []string{"thai"}
+
 	testCases := []struct {
 		name       string
 		restaurant model.Restaurant
+		canceled   []string
 		stubError  string
 		errMsg     string
 	}{
@@ -24,6 +33,12 @@ func Test_Save(t *testing.T) {
 			name:       "happy path",
 			restaurant: model.Restaurant{Id: &restId},
 		},
+		{
+			name:       "tag deleted concurrently",
+			restaurant: model.Restaurant{Id: &restId, Tags: &tags},
+			canceled:   []string{"None", "None", "None", "ConditionalCheckFailed"},
+			errMsg:     "tag is not in the vocabulary",
+		},
 		{
 			name:       "error",
 			restaurant: model.Restaurant{Id: &restId},
@@ -38,10 +53,11 @@ func Test_Save(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			rs := RestaurantStorage{
-				Client: dynamoRestaurantStorerStub{error: tc.stubError},
-				Table:  "RestaurantsTable-Test",
+				Client:       dynamoRestaurantStorerStub{writeError: tc.stubError, canceled: tc.canceled},
+				Table:        "RestaurantsTable-Test",
+				HistoryTable: "HistoryTable-Test",
 			}
-			err := rs.Save(tc.restaurant)
+			err := rs.Save(DefaultTenant, tc.restaurant, testOwner)
 
 			if tc.errMsg != "" {
 				if assert.Error(t, err) {
@@ -58,18 +74,43 @@ func Test_Get(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		name      string
-		restId    string
-		stubError string
-		errMsg    string
+		name       string
+		restId     string
+		tenant     string
+		readTenant string
+		deleted    bool
+		stubError  string
+		errMsg     string
 	}{
 		{
 			name:   "happy path",
 			restId: "restId",
 		},
+		{
+			name:       "restaurant of the tenant",
+			restId:     "restId",
+			tenant:     "acme",
+			readTenant: "acme",
+		},
+		{
+			name:       "restaurant of another tenant",
+			restId:     "restId",
+			tenant:     "acme",
+			readTenant: "globex",
+		},
+		{
+			name:   "restaurant of a tenant read in the default tenant",
+			restId: "restId",
+			tenant: "acme",
+		},
 		{
 			name: "unknown restaurantId",
 		},
+		{
+			name:    "deleted restaurant",
+			restId:  "restId",
+			deleted: true,
+		},
 		{
 			name:      "error",
 			restId:    "restId",
@@ -84,17 +125,18 @@ func Test_Get(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			rs := RestaurantStorage{
-				Client: dynamoRestaurantStorerStub{restaurantId: tc.restId, error: tc.stubError},
+				Client: dynamoRestaurantStorerStub{restaurantId: tc.restId, tenant: tc.tenant, deleted: tc.deleted, error: tc.stubError},
 			}
-			restaurant, ok, err := rs.Get(tc.restId)
+			restaurant, version, ok, err := rs.Get(tc.readTenant, tc.restId)
 
 			if tc.errMsg != "" {
 				if assert.Error(t, err) {
 					assert.Equal(t, tc.errMsg, err.Error())
 				}
-			} else if tc.restId != "" {
+			} else if tc.restId != "" && !tc.deleted && tc.tenant == tc.readTenant {
 				assert.Nil(t, err)
-				assert.Equal(t, model.Restaurant{Id: &tc.restId}, restaurant)
+				assert.Equal(t, model.Restaurant{Id: &tc.restId, Owner: &testOwner.Id}, restaurant)
+				assert.Equal(t, int64(2), version)
 				assert.True(t, ok)
 			} else {
 				assert.Nil(t, err)
@@ -110,15 +152,97 @@ func Test_Update(t *testing.T) {
 	restId := "restId"
 
 	testCases := []struct {
-		name       string
-		restaurant model.Restaurant
-		stubError  string
-		errMsg     string
+		name        string
+		restaurant  model.Restaurant
+		tenant      string
+		writeTenant string
+		ifMatch     []int64
+		notExist    bool
+		deleted     bool
+		canceled    []string
+		notOwner    bool
+		admin       bool
+		readError   string
+		stubError   string
+		errMsg      string
 	}{
 		{
 			name:       "happy path",
 			restaurant: model.Restaurant{Id: &restId},
 		},
+		{
+			name:        "restaurant of the tenant",
+			restaurant:  model.Restaurant{Id: &restId},
+			tenant:      "acme",
+			writeTenant: "acme",
+		},
+		{
+			name:        "restaurant of another tenant",
+			restaurant:  model.Restaurant{Id: &restId},
+			tenant:      "acme",
+			writeTenant: "globex",
+			errMsg:      "restaurant not found",
+		},
+		{
+			name:       "if-match",
+			restaurant: model.Restaurant{Id: &restId},
+			ifMatch:    []int64{0, 2},
+		},
+		{
+			name:       "not the owner",
+			restaurant: model.Restaurant{Id: &restId},
+			notOwner:   true,
+			errMsg:     "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:       "admin",
+			restaurant: model.Restaurant{Id: &restId},
+			notOwner:   true,
+			admin:      true,
+		},
+		{
+			name:       "restaurant does not exist",
+			restaurant: model.Restaurant{Id: &restId},
+			notExist:   true,
+			errMsg:     "restaurant not found",
+		},
+		{
+			name:       "if-match restaurant does not exist",
+			restaurant: model.Restaurant{Id: &restId},
+			ifMatch:    []int64{1},
+			notExist:   true,
+			errMsg:     "restaurant not found",
+		},
+		{
+			name:       "restaurant deleted",
+			restaurant: model.Restaurant{Id: &restId},
+			deleted:    true,
+			errMsg:     "restaurant not found",
+		},
+		{
+			name:       "stale version",
+			restaurant: model.Restaurant{Id: &restId},
+			ifMatch:    []int64{1},
+			errMsg:     "restaurant version does not match",
+		},
+		{
+			name:       "modified concurrently",
+			restaurant: model.Restaurant{Id: &restId},
+			canceled:   []string{"ConditionalCheckFailed", "None"},
+			errMsg:     "restaurant version does not match",
+		},
+		{
+			name:       "tag deleted concurrently",
+			restaurant: model.Restaurant{Id: &restId, Tags: &[]string{"thai"}},
+			canceled:   []string{"None", "None", "None", "ConditionalCheckFailed"},
+			errMsg:     "tag is not in the vocabulary",
+		},
+		{
+			name:       "read error",
+			restaurant: model.Restaurant{Id: &restId},
+			readError:  "an error occurred",
+			errMsg:     "error getting restaurant \"restId\" in dynamo: an error occurred",
+		},
 		{
 			name:       "error",
 			restaurant: model.Restaurant{Id: &restId},
@@ -132,11 +256,20 @@ func Test_Update(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
+			stub := dynamoRestaurantStorerStub{tenant: tc.tenant, deleted: tc.deleted, canceled: tc.canceled, error: tc.readError, writeError: tc.stubError}
+			if !tc.notExist {
+				stub.restaurantId = restId
+			}
 			rs := RestaurantStorage{
-				Client: dynamoRestaurantStorerStub{error: tc.stubError},
-				Table:  "RestaurantsTable-Test",
+				Client:       stub,
+				Table:        "RestaurantsTable-Test",
+				HistoryTable: "HistoryTable-Test",
 			}
-			err := rs.Update(tc.restaurant)
+			actor := testOwner
+			if tc.notOwner {
+				actor = Actor{Id: "user2", Admin: tc.admin}
+			}
+			restaurant, version, err := rs.Update(tc.writeTenant, tc.restaurant, tc.ifMatch, actor)
 
 			if tc.errMsg != "" {
 				if assert.Error(t, err) {
@@ -144,6 +277,9 @@ func Test_Update(t *testing.T) {
 				}
 			} else {
 				assert.Nil(t, err)
+				assert.Equal(t, int64(3), version)
+				// The restaurant keeps its stored owner
+				assert.Equal(t, &testOwner.Id, restaurant.Owner)
 			}
 		})
 	}
@@ -152,21 +288,168 @@ func Test_Update(t *testing.T) {
 func Test_Delete(t *testing.T) {
 	t.Parallel()
 
+	testCases := []struct {
+		name        string
+		restId      string
+		tenant      string
+		writeTenant string
+		ifMatch     []int64
+		notExist    bool
+		deleted     bool
+		canceled    []string
+		notOwner    bool
+		admin       bool
+		stubError   string
+		errMsg      string
+	}{
+		{
+			name:   "happy path",
+			restId: "restId",
+		},
+		{
+			name:        "restaurant of the tenant",
+			restId:      "restId",
+			tenant:      "acme",
+			writeTenant: "acme",
+		},
+		{
+			name:        "restaurant of another tenant",
+			restId:      "restId",
+			tenant:      "acme",
+			writeTenant: "globex",
+			errMsg:      "restaurant not found",
+		},
+		{
+			name:     "restaurant does not exist",
+			restId:   "restId",
+			notExist: true,
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:     "not the owner",
+			restId:   "restId",
+			notOwner: true,
+			errMsg:   "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:     "admin",
+			restId:   "restId",
+			notOwner: true,
+			admin:    true,
+		},
+		{
+			name:    "if-match",
+			restId:  "restId",
+			ifMatch: []int64{2},
+		},
+		{
+			name:     "if-match restaurant does not exist",
+			restId:   "restId",
+			ifMatch:  []int64{2},
+			notExist: true,
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:    "if-match restaurant already deleted",
+			restId:  "restId",
+			ifMatch: []int64{2},
+			deleted: true,
+			errMsg:  "restaurant not found",
+		},
+		{
+			name:    "stale version",
+			restId:  "restId",
+			ifMatch: []int64{1},
+			errMsg:  "restaurant version does not match",
+		},
+		{
+			name:     "if-match modified concurrently",
+			restId:   "restId",
+			ifMatch:  []int64{2},
+			canceled: []string{"ConditionalCheckFailed", "None"},
+			errMsg:   "restaurant version does not match",
+		},
+		{
+			name:      "error",
+			restId:    "restId",
+			stubError: "an error occurred",
+			errMsg:    "error deleting restaurant \"restId\" from dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := dynamoRestaurantStorerStub{tenant: tc.tenant, deleted: tc.deleted, canceled: tc.canceled, writeError: tc.stubError}
+			if !tc.notExist {
+				stub.restaurantId = tc.restId
+			}
+			rs := RestaurantStorage{
+				Client:       stub,
+				Table:        "RestaurantsTable-Test",
+				HistoryTable: "HistoryTable-Test",
+				Retention:    time.Hour,
+			}
+			actor := testOwner
+			if tc.notOwner {
+				actor = Actor{Id: "user2", Admin: tc.admin}
+			}
+			err := rs.Delete(tc.writeTenant, tc.restId, tc.ifMatch, actor)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_Restore(t *testing.T) {
+	t.Parallel()
+
 	testCases := []struct {
 		name      string
 		restId    string
+		notExist  bool
+		deleted   bool
+		notOwner  bool
 		stubError string
 		errMsg    string
 	}{
 		{
-			name:   "happy path",
+			name:    "happy path",
+			restId:  "restId",
+			deleted: true,
+		},
+		{
+			name:     "not the owner",
+			restId:   "restId",
+			deleted:  true,
+			notOwner: true,
+			errMsg:   "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:     "restaurant does not exist",
+			restId:   "restId",
+			notExist: true,
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:   "restaurant not deleted",
 			restId: "restId",
+			errMsg: "restaurant is not deleted",
 		},
 		{
 			name:      "error",
 			restId:    "restId",
+			deleted:   true,
 			stubError: "an error occurred",
-			errMsg:    "error deleting restaurant \"restId\" from dynamo: an error occurred",
+			errMsg:    "error restoring restaurant \"restId\" in dynamo: an error occurred",
 		},
 	}
 
@@ -175,11 +458,228 @@ func Test_Delete(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
+			stub := dynamoRestaurantStorerStub{deleted: tc.deleted, writeError: tc.stubError}
+			if !tc.notExist {
+				stub.restaurantId = tc.restId
+			}
 			rs := RestaurantStorage{
-				Client: dynamoRestaurantStorerStub{error: tc.stubError},
+				Client:       stub,
+				Table:        "RestaurantsTable-Test",
+				HistoryTable: "HistoryTable-Test",
+			}
+			actor := testOwner
+			if tc.notOwner {
+				actor = Actor{Id: "user2"}
+			}
+			restaurant, version, err := rs.Restore(DefaultTenant, tc.restId, actor)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, model.Restaurant{Id: &tc.restId, Owner: &testOwner.Id}, restaurant)
+				assert.Equal(t, int64(3), version)
+			}
+		})
+	}
+}
+
+func Test_List(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		nextToken   string
+		restaurants []model.Restaurant
+		stubError   string
+		errMsg      string
+	}{
+		{
+			name:        "happy path",
+			restaurants: []model.Restaurant{{Id: aws.String("restId1")}, {Id: aws.String("restId2")}},
+		},
+		{
+			name:        "next page",
+			nextToken:   "eyJSZXN0YXVyYW50SWQiOnsiUyI6InJlc3RJZDEifX0",
+			restaurants: []model.Restaurant{{Id: aws.String("restId2")}},
+		},
+		{
+			name:      "invalid token",
+			nextToken: "not a token",
+			errMsg:    "invalid pagination token",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error listing restaurants in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := RestaurantStorage{
+				Client: dynamoRestaurantStorerStub{restaurants: tc.restaurants, error: tc.stubError},
+				Table:  "RestaurantsTable-Test",
+			}
+			restaurants, token, err := rs.List(DefaultTenant, 1, tc.nextToken)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.restaurants[:1], restaurants)
+				if len(tc.restaurants) > 1 {
+					assert.NotEmpty(t, token)
+				} else {
+					assert.Empty(t, token)
+				}
+			}
+		})
+	}
+}
+
+func Test_ListTenant(t *testing.T) {
+	t.Parallel()
+
+	items := []restaurantItem{
+		tenantItem("acme", "a"), tenantItem("globex", "b"), tenantItem("acme", "c"),
+		tenantItem("acme", "d"), tenantItem("globex", "e"), tenantItem("globex", "f"),
+	}
+	token := func(restaurantId string) string {
+		token, _ := encodeToken(map[string]types.AttributeValue{key: &types.AttributeValueMemberS{Value: restaurantId}})
+		return token
+	}
+
+	testCases := []struct {
+		name          string
+		tenant        string
+		nextToken     string
+		restaurantIds []string
+		token         string
+		errMsg        string
+	}{
+		{
+			name:          "pages filled across the scan",
+			tenant:        "acme",
+			restaurantIds: []string{"a", "c"},
+			token:         token("acme#c"),
+		},
+		{
+			name:          "last page",
+			tenant:        "acme",
+			nextToken:     token("acme#c"),
+			restaurantIds: []string{"d"},
+		},
+		{
+			name:          "no more restaurants of the tenant",
+			tenant:        "acme",
+			nextToken:     token("acme#d"),
+			restaurantIds: []string{},
+		},
+		{
+			name:          "token is the key of a restaurant of the tenant",
+			tenant:        "globex",
+			restaurantIds: []string{"b", "e"},
+			token:         token("globex#e"),
+		},
+		{
+			name:      "token of another tenant",
+			tenant:    "acme",
+			nextToken: token("globex#e"),
+			errMsg:    "invalid pagination token",
+		},
+		{
+			name:      "token of another tenant for the default tenant",
+			nextToken: token("globex#e"),
+			errMsg:    "invalid pagination token",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := RestaurantStorage{
+				Client: listStub{items: items, tenant: tc.tenant},
+				Table:  "RestaurantsTable-Test",
+			}
+			restaurants, token, err := rs.List(tc.tenant, 2, tc.nextToken)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			restaurantIds := []string{}
+			for _, restaurant := range restaurants {
+				restaurantIds = append(restaurantIds, *restaurant.Id)
+			}
+			assert.Equal(t, tc.restaurantIds, restaurantIds)
+			assert.Equal(t, tc.token, token)
+		})
+	}
+}
+
+func Test_Nearby(t *testing.T) {
+	t.Parallel()
+
+	near, far, noGeocode := restaurantAt("near", "47.607000,-122.333000"), restaurantAt("far", "47.650000,-122.350000"), model.Restaurant{Id: aws.String("none")}
+	center := geo.Point{Lat: 47.606209, Lon: -122.332071}
+
+	testCases := []struct {
+		name        string
+		radius      float64
+		restaurants []model.Restaurant
+		nearby      []string
+		stubError   string
+		errMsg      string
+	}{
+		{
+			name:        "happy path",
+			radius:      1000,
+			restaurants: []model.Restaurant{far, near, noGeocode},
+			nearby:      []string{"near"},
+		},
+		{
+			name:        "sorted by distance",
+			radius:      10000,
+			restaurants: []model.Restaurant{far, near, noGeocode},
+			nearby:      []string{"near", "far"},
+		},
+		{
+			name:        "none nearby",
+			radius:      10,
+			restaurants: []model.Restaurant{far, near},
+			nearby:      []string{},
+		},
+		{
+			name:      "error",
+			radius:    1000,
+			stubError: "an error occurred",
+			errMsg:    "error querying nearby restaurants in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := RestaurantStorage{
+				Client: dynamoRestaurantStorerStub{restaurants: tc.restaurants, error: tc.stubError},
 				Table:  "RestaurantsTable-Test",
 			}
-			err := rs.Delete(tc.restId)
+			nearby, err := rs.Nearby(DefaultTenant, center, tc.radius)
 
 			if tc.errMsg != "" {
 				if assert.Error(t, err) {
@@ -187,15 +687,105 @@ func Test_Delete(t *testing.T) {
 				}
 			} else {
 				assert.Nil(t, err)
+				ids := []string{}
+				for i, n := range nearby {
+					ids = append(ids, *n.Restaurant.Id)
+					assert.LessOrEqual(t, n.Distance, tc.radius)
+					if i > 0 {
+						assert.LessOrEqual(t, nearby[i-1].Distance, n.Distance)
+					}
+				}
+				assert.Equal(t, tc.nearby, ids)
 			}
 		})
 	}
 }
 
+func Test_Geohash(t *testing.T) {
+	t.Parallel()
+
+	hash, ok := geohash(restaurantAt("restId", "47.606209,-122.332071"))
+	assert.True(t, ok)
+	assert.Equal(t, "c23nb62w2", hash)
+
+	_, ok = geohash(restaurantAt("restId", "not a geocode"))
+	assert.False(t, ok)
+
+	_, ok = geohash(model.Restaurant{Address: &model.Address{}})
+	assert.False(t, ok)
+}
+
+func Test_Token(t *testing.T) {
+	t.Parallel()
+
+	startKey, err := decodeToken("")
+	assert.Nil(t, err)
+	assert.Nil(t, startKey)
+
+	token, err := encodeToken(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, token)
+
+	lastKey := map[string]types.AttributeValue{key: &types.AttributeValueMemberS{Value: "restId"}}
+	token, err = encodeToken(lastKey)
+	assert.Nil(t, err)
+
+	startKey, err = decodeToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, lastKey, startKey)
+
+	// The types of the attributes are kept, so a numeric sort key is not
+	// sent back as a string
+	lastKey = map[string]types.AttributeValue{
+		key:         &types.AttributeValueMemberS{Value: "restId"},
+		revisionKey: &types.AttributeValueMemberN{Value: "5"},
+	}
+	token, err = encodeToken(lastKey)
+	assert.Nil(t, err)
+
+	startKey, err = decodeToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, lastKey, startKey)
+
+	for _, invalid := range []string{
+		// {}
+		"e30",
+		// {"RestaurantId":"restId"}, without the type
+		"eyJSZXN0YXVyYW50SWQiOiJyZXN0SWQifQ",
+		// {"Revision":{"N":"five"}}
+		"eyJSZXZpc2lvbiI6eyJOIjoiZml2ZSJ9fQ",
+		// {"Revision":{"S":"5","N":"5"}}
+		"eyJSZXZpc2lvbiI6eyJTIjoiNSIsIk4iOiI1In19",
+	} {
+		_, err = decodeToken(invalid)
+		assert.ErrorIs(t, err, ErrInvalidToken, invalid)
+	}
+}
+
+// testOwner is the owner of the restaurant returned by the stub
+var testOwner = Actor{Id: "user1"}
+
+// conditionalCheckFailed makes the stub return a ConditionalCheckFailedException.
+const conditionalCheckFailed = "conditional check failed"
+
 type dynamoRestaurantStorerStub struct {
 	restaurantId string
-	restaurants  []model.Restaurant
-	error        string
+	// tenant is the tenant of the restaurant, only returned by GetItem for
+	// its key in the tenant
+	tenant      string
+	deleted     bool
+	restaurants []model.Restaurant
+	error       string
+	// writeError is only returned by UpdateItem, DeleteItem and
+	// TransactWriteItems, so the GetItem call made after a failed condition
+	// succeeds
+	writeError string
+	// canceled are the cancellation reason codes of a canceled transaction
+	canceled []string
+	// unprocessed is the number of BatchWriteItem calls that leave the
+	// last item of each table unprocessed, counted in calls
+	unprocessed int32
+	calls       *int32
 }
 
 func (s dynamoRestaurantStorerStub) PutItem(_ context.Context, _ *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
@@ -205,38 +795,204 @@ func (s dynamoRestaurantStorerStub) PutItem(_ context.Context, _ *dynamodb.PutIt
 	return nil, nil
 }
 
-func (s dynamoRestaurantStorerStub) GetItem(_ context.Context, _ *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+func (s dynamoRestaurantStorerStub) GetItem(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
 	if s.error != "" {
 		return nil, errors.New(s.error)
 	}
-	if s.restaurantId != "" {
-		return restaurantItemOutput(s.restaurantId)
+	var restaurantId string
+	_ = attributevalue.Unmarshal(input.Key[key], &restaurantId)
+	if s.restaurantId != "" && restaurantId == tenantKey(s.tenant, s.restaurantId) {
+		return restaurantItemOutput(s.tenant, s.restaurantId, s.deleted)
 	}
 	return &dynamodb.GetItemOutput{}, nil
 }
 
 func (s dynamoRestaurantStorerStub) UpdateItem(_ context.Context, _ *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if s.writeError != "" {
+		return nil, stubErr(s.writeError)
+	}
+	av, err := attributevalue.MarshalMap(restaurantItem{
+		RestaurantId: s.restaurantId,
+		Restaurant:   model.Restaurant{Id: &s.restaurantId},
+		Version:      3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateItemOutput{Attributes: av}, nil
+}
+
+func (s dynamoRestaurantStorerStub) DeleteItem(_ context.Context, _ *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if s.writeError != "" {
+		return nil, stubErr(s.writeError)
+	}
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// Scan returns one restaurant per page, using the restaurant IDs as keys.
+func (s dynamoRestaurantStorerStub) Scan(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
 	if s.error != "" {
 		return nil, errors.New(s.error)
 	}
-	return nil, nil
+
+	restaurants := s.restaurants
+	if input.ExclusiveStartKey != nil {
+		var startKey map[string]string
+		_ = attributevalue.UnmarshalMap(input.ExclusiveStartKey, &startKey)
+		for i, r := range restaurants {
+			if *r.Id == startKey[key] {
+				restaurants = restaurants[i+1:]
+				break
+			}
+		}
+	}
+
+	output := &dynamodb.ScanOutput{}
+	if len(restaurants) == 0 {
+		return output, nil
+	}
+
+	av, err := attributevalue.MarshalMap(restaurantItem{RestaurantId: *restaurants[0].Id, Restaurant: restaurants[0]})
+	if err != nil {
+		return nil, err
+	}
+	output.Items = []map[string]types.AttributeValue{av}
+
+	if len(restaurants) > 1 {
+		output.LastEvaluatedKey = map[string]types.AttributeValue{key: av[key]}
+	}
+	return output, nil
 }
 
-func (s dynamoRestaurantStorerStub) DeleteItem(_ context.Context, _ *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+// Query returns the restaurants whose geohash starts with the longest
+// geohash in the key condition values.
+func (s dynamoRestaurantStorerStub) Query(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
 	if s.error != "" {
 		return nil, errors.New(s.error)
 	}
-	return nil, nil
+
+	var cell string
+	for _, v := range input.ExpressionAttributeValues {
+		if sv, ok := v.(*types.AttributeValueMemberS); ok && len(sv.Value) > len(cell) {
+			cell = sv.Value
+		}
+	}
+
+	output := &dynamodb.QueryOutput{}
+	for _, r := range s.restaurants {
+		if hash, ok := geohash(r); ok && strings.HasPrefix(hash, cell) {
+			av, err := attributevalue.MarshalMap(restaurantItem{RestaurantId: *r.Id, Restaurant: r, Geohash: hash})
+			if err != nil {
+				return nil, err
+			}
+			output.Items = append(output.Items, av)
+		}
+	}
+	return output, nil
+}
+
+func (s dynamoRestaurantStorerStub) BatchWriteItem(_ context.Context, input *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	call := atomic.AddInt32(s.calls, 1)
+	if s.error != "" {
+		return nil, errors.New(s.error)
+	}
+
+	output := &dynamodb.BatchWriteItemOutput{}
+	if call <= s.unprocessed {
+		output.UnprocessedItems = map[string][]types.WriteRequest{}
+		for table, requests := range input.RequestItems {
+			output.UnprocessedItems[table] = requests[len(requests)-1:]
+		}
+	}
+	return output, nil
+}
+
+func (s dynamoRestaurantStorerStub) TransactWriteItems(_ context.Context, _ *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if s.writeError != "" {
+		return nil, stubErr(s.writeError)
+	}
+	if s.canceled != nil {
+		reasons := make([]types.CancellationReason, 0, len(s.canceled))
+		for _, code := range s.canceled {
+			reasons = append(reasons, types.CancellationReason{Code: aws.String(code)})
+		}
+		return nil, &types.TransactionCanceledException{CancellationReasons: reasons}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
 }
 
-func restaurantItemOutput(restaurantId string) (*dynamodb.GetItemOutput, error) {
+// listStub scans the items like DynamoDB: Limit items are evaluated after
+// the start key, those of the tenant are returned and the key of the last
+// item evaluated is the LastEvaluatedKey, whatever its tenant.
+type listStub struct {
+	dynamoRestaurantStorerStub
+	items  []restaurantItem
+	tenant string
+}
+
+func (s listStub) Scan(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		var startKey string
+		_ = attributevalue.Unmarshal(input.ExclusiveStartKey[key], &startKey)
+		for i, item := range s.items {
+			if item.RestaurantId == startKey {
+				start = i + 1
+			}
+		}
+	}
+	end := start + int(aws.ToInt32(input.Limit))
+	if end > len(s.items) {
+		end = len(s.items)
+	}
+
+	output := &dynamodb.ScanOutput{}
+	for _, item := range s.items[start:end] {
+		if item.Tenant != s.tenant {
+			continue
+		}
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	if end < len(s.items) {
+		output.LastEvaluatedKey = map[string]types.AttributeValue{key: &types.AttributeValueMemberS{Value: s.items[end-1].RestaurantId}}
+	}
+	return output, nil
+}
+
+func tenantItem(tenant, restaurantId string) restaurantItem {
+	return restaurantItem{
+		RestaurantId: tenantKey(tenant, restaurantId),
+		Tenant:       tenant,
+		Restaurant:   model.Restaurant{Id: aws.String(restaurantId)},
+	}
+}
+
+func restaurantAt(restaurantId, geocode string) model.Restaurant {
+	return model.Restaurant{
+		Id:      &restaurantId,
+		Address: &model.Address{Location: &model.Location{Geocode: &geocode}},
+	}
+}
+
+func restaurantItemOutput(tenant, restaurantId string, deleted bool) (*dynamodb.GetItemOutput, error) {
 	restaurant := model.Restaurant{
-		Id: &restaurantId,
+		Id:    &restaurantId,
+		Owner: &testOwner.Id,
 	}
 	restaurantItem := restaurantItem{
-		RestaurantId: restaurantId,
+		RestaurantId: tenantKey(tenant, restaurantId),
+		Tenant:       tenant,
 		Restaurant:   restaurant,
 		Updated:      12345,
+		Version:      2,
+	}
+	if deleted {
+		restaurantItem.DeletedAt = 12345
+		restaurantItem.ExpiresAt = time.Now().Add(time.Hour).Unix()
 	}
 
 	av, err := attributevalue.MarshalMap(restaurantItem)
@@ -245,3 +1001,10 @@ func restaurantItemOutput(restaurantId string) (*dynamodb.GetItemOutput, error)
 	}
 	return &dynamodb.GetItemOutput{Item: av}, nil
 }
+
+func stubErr(msg string) error {
+	if msg == conditionalCheckFailed {
+		return &types.ConditionalCheckFailedException{Message: &msg}
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,106 @@
+package dynamo
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrNotFound is returned when the restaurant to modify does not exist.
+var ErrNotFound = errors.New("restaurant not found")
+
+// ErrMenuNotFound is returned when the restaurant has no menu.
+var ErrMenuNotFound = errors.New("menu not found")
+
+// ErrReviewNotFound is returned when the review to delete does not exist.
+var ErrReviewNotFound = errors.New("review not found")
+
+// ErrSettingsNotFound is returned when the restaurant has no reservation settings.
+var ErrSettingsNotFound = errors.New("reservation settings not found")
+
+// ErrReservationNotFound is returned when the reservation does not exist.
+var ErrReservationNotFound = errors.New("reservation not found")
+
+// ErrReservationCancelled is returned when modifying a cancelled reservation.
+var ErrReservationCancelled = errors.New("reservation is cancelled")
+
+// ErrReservationConflict is returned when the reservation was modified
+// concurrently.
+var ErrReservationConflict = errors.New("reservation was modified concurrently")
+
+// ErrSlotTaken is returned when the table is already booked in the slot.
+var ErrSlotTaken = errors.New("table is already booked")
+
+// ErrRevisionNotFound is returned when the revision of the restaurant does
+// not exist.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ErrNothingToRevert is returned when reverting to a revision that deleted
+// the restaurant.
+var ErrNothingToRevert = errors.New("revision deleted the restaurant")
+
+// ErrNotDeleted is returned when restoring a restaurant that is not deleted.
+var ErrNotDeleted = errors.New("restaurant is not deleted")
+
+// ErrForbidden is returned when the actor is neither the owner of the
+// restaurant nor an admin.
+var ErrForbidden = errors.New("only the owner of the restaurant or an admin can modify it")
+
+// ErrApiKeyNotFound is returned when the API key does not exist.
+var ErrApiKeyNotFound = errors.New("API key not found")
+
+// ErrQuotaExceeded is returned when the API key has reached its daily quota.
+var ErrQuotaExceeded = errors.New("daily quota exceeded")
+
+// ErrPhotoNotFound is returned when the photo does not exist.
+var ErrPhotoNotFound = errors.New("photo not found")
+
+// ErrTooManyPhotos is returned when adding a photo to a restaurant that has
+// MaxPhotos photos.
+var ErrTooManyPhotos = errors.New("restaurant has the maximum number of photos")
+
+// ErrPhotoOrder is returned when the IDs of a new order are not the IDs of
+// the photos of the restaurant.
+var ErrPhotoOrder = errors.New("photo IDs do not match the photos of the restaurant")
+
+// ErrPhotosConflict is returned when the photos keep being modified
+// concurrently.
+var ErrPhotosConflict = errors.New("photos were modified concurrently")
+
+// ErrTagNotFound is returned when the tag is not in the vocabulary.
+var ErrTagNotFound = errors.New("tag not found")
+
+// ErrTagInUse is returned when deleting a tag that is assigned to
+// restaurants.
+var ErrTagInUse = errors.New("tag is assigned to restaurants")
+
+// ErrTagRemoved is returned when saving a restaurant with a tag that is no
+// longer in the vocabulary.
+var ErrTagRemoved = errors.New("tag is not in the vocabulary")
+
+// ErrWebhookNotFound is returned when the webhook subscription does not
+// exist.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// ErrDeliveryNotFound is returned when the failed delivery does not exist.
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// ErrPreconditionFailed is returned when a conditional write is rejected
+// because the stored version does not match the expected version.
+var ErrPreconditionFailed = errors.New("restaurant version does not match")
+
+// conditionFailed reports whether err was caused by a failed condition expression.
+func conditionFailed(err error) bool {
+	var ccf *types.ConditionalCheckFailedException
+	return errors.As(err, &ccf)
+}
+
+// transactionConditionFailed reports whether err is a canceled transaction
+// in which the condition expression of the item at index failed.
+func transactionConditionFailed(err error, index int) bool {
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &tce) || index >= len(tce.CancellationReasons) {
+		return false
+	}
+	code := tce.CancellationReasons[index].Code
+	return code != nil && *code == "ConditionalCheckFailed"
+}
@@ -0,0 +1,335 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/diff"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+	"time"
+)
+
+// The history table is the append-only revision log of the restaurants,
+// with RestaurantId as partition key and Revision as sort key. A revision
+// is written in the same transaction as the change of the restaurant and
+// its number is the version of the restaurant after the change, so it
+// matches the ETag returned for that version. Revisions are not purged
+// with the restaurant.
+const revisionKey = "Revision"
+
+// maxWriteAttempts is the number of times a change is attempted when the
+// restaurant is modified between reading and writing it.
+const maxWriteAttempts = 3
+
+// writeErrors are the formats of the errors returned when the write of a
+// change fails.
+var writeErrors = map[model.RevisionAction]string{
+	model.Update:  "error updating restaurant %q in dynamo: %w",
+	model.Revert:  "error reverting restaurant %q in dynamo: %w",
+	model.Delete:  "error deleting restaurant %q from dynamo: %w",
+	model.Restore: "error restoring restaurant %q in dynamo: %w",
+}
+
+// Actor is who makes a change. Only the owner of a restaurant, the actor
+// who created it, or an admin can change it.
+type Actor struct {
+	Id    string
+	Admin bool
+}
+
+// CanModify reports whether the actor can change the restaurant. The
+// restaurants created before owners were recorded have no owner, so only an
+// admin can change them.
+func (a Actor) CanModify(restaurant model.Restaurant) bool {
+	return a.Admin || (restaurant.Owner != nil && *restaurant.Owner == a.Id)
+}
+
+// change describes a change of a restaurant for its revision.
+type change struct {
+	action       model.RevisionAction
+	actor        Actor
+	revertedFrom *int64
+}
+
+// mutation is the update of a restaurant item and the restaurant before
+// and after it, nil when it does not exist.
+type mutation struct {
+	update   expression.UpdateBuilder
+	cond     expression.ConditionBuilder
+	previous *model.Restaurant
+	current  *model.Restaurant
+}
+
+// write reads the restaurant item, whose key in its tenant is restaurantId,
+// and passes it to apply, nil when there is no item, to build the mutation.
+// ErrForbidden is returned when the actor cannot modify the stored
+// restaurant. The update is written in a transaction with the revision and
+// the members and counts of the tags, conditional on the version that was
+// read. ErrTagRemoved is returned when an added tag is not in the
+// vocabulary. When
+// the restaurant was modified in between, the change is attempted again
+// unless ifMatch is set, in which case ErrPreconditionFailed is returned.
+// write returns the new version of the restaurant.
+func (rs RestaurantStorage) write(restaurantId string, ifMatch []int64, c change, apply func(item *restaurantItem) (mutation, error)) (int64, error) {
+	for attempt := 1; ; attempt++ {
+		item, err := rs.currentItem(restaurantId)
+		if err != nil {
+			return 0, err
+		}
+
+		m, err := apply(item)
+		if err != nil {
+			return 0, err
+		}
+		if !c.actor.CanModify(item.Restaurant) {
+			return 0, ErrForbidden
+		}
+		if len(ifMatch) > 0 && !matchesVersion(ifMatch, item.Version) {
+			return 0, ErrPreconditionFailed
+		}
+
+		version := item.Version + 1
+		update := m.update.Set(
+			expression.Name("Updated"),
+			expression.Value(time.Now().UnixMilli()),
+		).Set(
+			expression.Name("Version"),
+			expression.Value(version),
+		)
+		cond := m.cond.And(versionCondition([]int64{item.Version}))
+
+		expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+		if err != nil {
+			return 0, err
+		}
+
+		revision, err := rs.revisionPut(restaurantId, version, m.previous, m.current, c)
+		if err != nil {
+			return 0, err
+		}
+
+		tags, err := rs.tagWrites(item.Tenant, aws.ToString(item.Restaurant.Id), m.previous, m.current)
+		if err != nil {
+			return 0, err
+		}
+
+		input := dynamodb.TransactWriteItemsInput{
+			TransactItems: append([]types.TransactWriteItem{
+				{
+					Update: &types.Update{
+						Key: map[string]types.AttributeValue{
+							key: &types.AttributeValueMemberS{Value: restaurantId},
+						},
+						TableName:                 aws.String(rs.Table),
+						UpdateExpression:          expr.Update(),
+						ExpressionAttributeNames:  expr.Names(),
+						ExpressionAttributeValues: expr.Values(),
+						ConditionExpression:       expr.Condition(),
+					},
+				},
+				revision,
+			}, tags...),
+		}
+
+		_, err = rs.Client.TransactWriteItems(context.Background(), &input)
+		if err == nil {
+			return version, nil
+		}
+		if anyConditionFailed(err, 2, len(input.TransactItems)) {
+			return 0, ErrTagRemoved
+		}
+		if !anyConditionFailed(err, 0, 2) {
+			return 0, fmt.Errorf(writeErrors[c.action], restaurantId, err)
+		}
+		if len(ifMatch) > 0 || attempt == maxWriteAttempts {
+			return 0, ErrPreconditionFailed
+		}
+
+		log.Printf("RestaurantStorage.write restaurant %s modified concurrently, attempt %d\n", restaurantId, attempt)
+	}
+}
+
+// revisionPut returns the transaction item that appends the revision to the
+// log. It fails if the revision already exists.
+func (rs RestaurantStorage) revisionPut(restaurantId string, number int64, previous, current *model.Restaurant, c change) (types.TransactWriteItem, error) {
+	changes, err := diff.Fields(previous, current)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("error comparing revisions: %w", err)
+	}
+
+	av, err := attributevalue.MarshalMap(model.Revision{
+		Action:       c.action,
+		Actor:        c.actor.Id,
+		Changes:      changes,
+		Current:      current,
+		Previous:     previous,
+		RestaurantId: restaurantId,
+		RevertedFrom: c.revertedFrom,
+		Revision:     number,
+		Timestamp:    time.Now().UTC(),
+	})
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(revisionKey))).
+		Build()
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			Item:                     av,
+			TableName:                aws.String(rs.HistoryTable),
+			ConditionExpression:      expr.Condition(),
+			ExpressionAttributeNames: expr.Names(),
+		},
+	}, nil
+}
+
+// ListRevisions returns up to limit revisions of the restaurant of the
+// tenant, the newest first, starting after the position encoded in
+// nextToken. The returned token is empty when there are no more pages.
+// ErrNotFound is returned when the restaurant has no revisions.
+func (rs RestaurantStorage) ListRevisions(tenant, restaurantId string, limit int32, nextToken string) ([]model.Revision, string, error) {
+	log.Printf("RestaurantStorage.ListRevisions tenant: %s  restaurantId: %s  limit: %d  nextToken: %s\n", tenant, restaurantId, limit, nextToken)
+
+	startKey, err := decodeToken(nextToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyCond := expression.Key(key).Equal(expression.Value(tenantKey(tenant, restaurantId)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, "", err
+	}
+
+	input := dynamodb.QueryInput{
+		TableName:                 aws.String(rs.HistoryTable),
+		Limit:                     aws.Int32(limit),
+		ExclusiveStartKey:         startKey,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(false),
+	}
+
+	data, err := rs.Client.Query(context.Background(), &input)
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing revisions of restaurant %q in dynamo: %w", restaurantId, err)
+	}
+
+	revisions := []model.Revision{}
+	if err = attributevalue.UnmarshalListOfMaps(data.Items, &revisions); err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	if len(revisions) == 0 && startKey == nil {
+		return nil, "", ErrNotFound
+	}
+	for i := range revisions {
+		revisions[i].RestaurantId = restaurantId
+	}
+
+	token, err := encodeToken(data.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encoding pagination token: %w", err)
+	}
+
+	return revisions, token, nil
+}
+
+// Revert replaces the restaurant with its state after the given revision
+// and returns the restaurant and its new version. The revert is recorded as
+// a new revision. ErrRevisionNotFound is returned when the revision does
+// not exist, ErrNothingToRevert when the revision deleted the restaurant
+// and ErrNotFound when the restaurant of the tenant does not exist or is
+// deleted. When ifMatch is not empty the restaurant is only reverted if its
+// current version is one of the given versions, otherwise
+// ErrPreconditionFailed is returned.
+func (rs RestaurantStorage) Revert(tenant, restaurantId string, revision int64, ifMatch []int64, actor Actor) (model.Restaurant, int64, error) {
+	log.Printf("RestaurantStorage.Revert tenant: %s  restaurantId: %s  revision: %d\n", tenant, restaurantId, revision)
+
+	target, err := rs.GetRevision(tenant, restaurantId, revision)
+	if err != nil {
+		return model.Restaurant{}, 0, err
+	}
+	if target.Current == nil {
+		return model.Restaurant{}, 0, ErrNothingToRevert
+	}
+
+	restaurant := *target.Current
+	restaurant.Id = &restaurantId
+	return rs.replace(tenant, restaurant, ifMatch, change{action: model.Revert, actor: actor, revertedFrom: &revision})
+}
+
+// GetRevision returns the revision of the restaurant of the tenant.
+// ErrRevisionNotFound is returned when the revision does not exist.
+func (rs RestaurantStorage) GetRevision(tenant, restaurantId string, revision int64) (model.Revision, error) {
+	log.Printf("RestaurantStorage.GetRevision tenant: %s  restaurantId: %s  revision: %d\n", tenant, restaurantId, revision)
+
+	input := dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			key:         &types.AttributeValueMemberS{Value: tenantKey(tenant, restaurantId)},
+			revisionKey: &types.AttributeValueMemberN{Value: fmt.Sprint(revision)},
+		},
+		TableName: aws.String(rs.HistoryTable),
+	}
+
+	data, err := rs.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return model.Revision{}, fmt.Errorf("error getting revision %d of restaurant %q in dynamo: %w", revision, restaurantId, err)
+	}
+	if data.Item == nil {
+		return model.Revision{}, ErrRevisionNotFound
+	}
+
+	var target model.Revision
+	if err = attributevalue.UnmarshalMap(data.Item, &target); err != nil {
+		return model.Revision{}, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return target, nil
+}
+
+// currentItem returns the stored restaurant item, deleted or not, or nil
+// when there is no item.
+func (rs RestaurantStorage) currentItem(restaurantId string) (*restaurantItem, error) {
+	input := dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			key: &types.AttributeValueMemberS{Value: restaurantId},
+		},
+		TableName:      aws.String(rs.Table),
+		ConsistentRead: aws.Bool(true),
+	}
+
+	data, err := rs.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return nil, fmt.Errorf("error getting restaurant %q in dynamo: %w", restaurantId, err)
+	}
+	if data.Item == nil {
+		return nil, nil
+	}
+
+	item := &restaurantItem{}
+	if err = attributevalue.UnmarshalMap(data.Item, item); err != nil {
+		return nil, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return item, nil
+}
+
+// matchesVersion reports whether version is one of the versions.
+func matchesVersion(versions []int64, version int64) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
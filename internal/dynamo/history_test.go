@@ -0,0 +1,290 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_ListRevisions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		nextToken string
+		revisions []model.Revision
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:      "happy path",
+			revisions: []model.Revision{testRevision(2, model.Update), testRevision(1, model.Create)},
+		},
+		{
+			name:      "next page",
+			nextToken: "eyJSZXN0YXVyYW50SWQiOnsiUyI6InJlc3RJZCJ9LCJSZXZpc2lvbiI6eyJOIjoiMiJ9fQ",
+			revisions: []model.Revision{},
+		},
+		{
+			name:   "no revisions",
+			errMsg: "restaurant not found",
+		},
+		{
+			name:      "invalid token",
+			nextToken: "not a token",
+			errMsg:    "invalid pagination token",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error listing revisions of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := RestaurantStorage{
+				Client:       historyStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, revisions: tc.revisions},
+				Table:        "RestaurantsTable-Test",
+				HistoryTable: "HistoryTable-Test",
+			}
+			revisions, token, err := rs.ListRevisions(DefaultTenant, "restId", 20, tc.nextToken)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.revisions, revisions)
+				assert.Empty(t, token)
+			}
+		})
+	}
+}
+
+func Test_Revert(t *testing.T) {
+	t.Parallel()
+	restId := "restId"
+
+	deleted := testRevision(4, model.Delete)
+	deleted.Current = nil
+
+	testCases := []struct {
+		name      string
+		revision  *model.Revision
+		ifMatch   []int64
+		deleted   bool
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:     "happy path",
+			revision: revisionPtr(testRevision(1, model.Create)),
+		},
+		{
+			name:     "if-match",
+			revision: revisionPtr(testRevision(1, model.Create)),
+			ifMatch:  []int64{2},
+		},
+		{
+			name:   "revision does not exist",
+			errMsg: "revision not found",
+		},
+		{
+			name:     "revision deleted the restaurant",
+			revision: &deleted,
+			errMsg:   "revision deleted the restaurant",
+		},
+		{
+			name:     "restaurant deleted",
+			revision: revisionPtr(testRevision(1, model.Create)),
+			deleted:  true,
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:     "stale version",
+			revision: revisionPtr(testRevision(1, model.Create)),
+			ifMatch:  []int64{1},
+			errMsg:   "restaurant version does not match",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error getting revision 1 of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var transaction dynamodb.TransactWriteItemsInput
+			rs := RestaurantStorage{
+				Client: historyStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: restId, deleted: tc.deleted, error: tc.stubError},
+					revision:                   tc.revision,
+					transaction:                &transaction,
+				},
+				Table:        "RestaurantsTable-Test",
+				HistoryTable: "HistoryTable-Test",
+			}
+			restaurant, version, err := rs.Revert(DefaultTenant, restId, 1, tc.ifMatch, testOwner)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+
+			// The restaurant keeps its owner
+			current := *tc.revision.Current
+			current.Owner = &testOwner.Id
+
+			assert.Nil(t, err)
+			assert.Equal(t, current, restaurant)
+			assert.Equal(t, int64(3), version)
+
+			// The revert is recorded as revision 3, from the stored restaurant
+			revision := transactionRevision(t, transaction)
+			assert.Equal(t, model.Revert, revision.Action)
+			assert.Equal(t, int64(3), revision.Revision)
+			assert.Equal(t, aws.Int64(1), revision.RevertedFrom)
+			assert.Equal(t, &model.Restaurant{Id: &restId, Owner: &testOwner.Id}, revision.Previous)
+			assert.Equal(t, &current, revision.Current)
+			assert.Equal(t, []model.RevisionChange{{Field: "name", From: "", To: "Ramen Bar"}}, revision.Changes)
+		})
+	}
+}
+
+func Test_UpdateRevision(t *testing.T) {
+	t.Parallel()
+	restId := "restId"
+
+	var transaction dynamodb.TransactWriteItemsInput
+	rs := RestaurantStorage{
+		Client: historyStub{
+			dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: restId},
+			transaction:                &transaction,
+		},
+		Table:        "RestaurantsTable-Test",
+		HistoryTable: "HistoryTable-Test",
+	}
+	restaurant := model.Restaurant{Id: &restId, Name: "Noodle House"}
+	_, version, err := rs.Update(DefaultTenant, restaurant, nil, testOwner)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), version)
+	if assert.Len(t, transaction.TransactItems, 2) {
+		// The update is conditional on the version that was read
+		update := transaction.TransactItems[0].Update
+		assert.Equal(t, "RestaurantsTable-Test", aws.ToString(update.TableName))
+		assert.Contains(t, update.ExpressionAttributeValues, ":0")
+	}
+
+	revision := transactionRevision(t, transaction)
+	assert.Equal(t, model.Update, revision.Action)
+	assert.Equal(t, "user1", revision.Actor)
+	assert.Equal(t, restId, revision.RestaurantId)
+	assert.Equal(t, int64(3), revision.Revision)
+	assert.Nil(t, revision.RevertedFrom)
+	assert.Equal(t, []model.RevisionChange{{Field: "name", From: "", To: "Noodle House"}}, revision.Changes)
+	assert.WithinDuration(t, time.Now(), revision.Timestamp, time.Minute)
+}
+
+// transactionRevision returns the revision put in the history table by the
+// transaction.
+func transactionRevision(t *testing.T, transaction dynamodb.TransactWriteItemsInput) model.Revision {
+	var revision model.Revision
+	for _, item := range transaction.TransactItems {
+		if item.Put != nil && aws.ToString(item.Put.TableName) == "HistoryTable-Test" {
+			assert.Nil(t, attributevalue.UnmarshalMap(item.Put.Item, &revision))
+		}
+	}
+	return revision
+}
+
+// historyStub serves the revisions from the history table and records the
+// last transaction.
+type historyStub struct {
+	dynamoRestaurantStorerStub
+	revisions   []model.Revision
+	revision    *model.Revision
+	transaction *dynamodb.TransactWriteItemsInput
+}
+
+func (s historyStub) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if aws.ToString(input.TableName) == "RestaurantsTable-Test" {
+		return s.dynamoRestaurantStorerStub.GetItem(ctx, input, optFns...)
+	}
+
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	if s.revision == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	av, err := attributevalue.MarshalMap(s.revision)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: av}, nil
+}
+
+func (s historyStub) Query(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	// Like DynamoDB, a start key whose revision is not a number is rejected
+	if input.ExclusiveStartKey != nil {
+		if _, ok := input.ExclusiveStartKey[revisionKey].(*types.AttributeValueMemberN); !ok {
+			return nil, errors.New("the provided starting key is invalid")
+		}
+	}
+
+	output := &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{}}
+	for _, r := range s.revisions {
+		av, err := attributevalue.MarshalMap(r)
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	return output, nil
+}
+
+func (s historyStub) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if s.transaction != nil {
+		*s.transaction = *input
+	}
+	return s.dynamoRestaurantStorerStub.TransactWriteItems(ctx, input, optFns...)
+}
+
+func testRevision(number int64, action model.RevisionAction) model.Revision {
+	restId := "restId"
+	return model.Revision{
+		Action:       action,
+		Actor:        "127.0.0.1",
+		Changes:      []model.RevisionChange{},
+		Current:      &model.Restaurant{Id: &restId, Name: "Ramen Bar"},
+		RestaurantId: restId,
+		Revision:     number,
+		Timestamp:    time.Date(2023, time.June, 9, 19, 0, 0, 0, time.UTC),
+	}
+}
+
+func revisionPtr(revision model.Revision) *model.Revision {
+	return &revision
+}
@@ -0,0 +1,188 @@
+package dynamo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"log"
+	"time"
+)
+
+const idempotencyKey = "IdempotencyKey"
+
+// pendingTimeout is how long a reservation stays pending before another
+// request with the same key can take it over, e.g. after a crash.
+const pendingTimeout = time.Minute
+
+type IdempotencyStorage struct {
+	Client dynamoRestaurantStorer
+	Table  string
+	// TTL is how long a completed response is kept for replay
+	TTL time.Duration
+}
+
+// IdempotencyRecord is the state of a request made with an idempotency key.
+// A record without a status code is pending: its request is in progress.
+// ExpiresAt (epoch seconds) is the TTL attribute of the table.
+type IdempotencyRecord struct {
+	IdempotencyKey string
+	RequestHash    string
+	StatusCode     int    `dynamodbav:",omitempty"`
+	Body           []byte `dynamodbav:",omitempty"`
+	ExpiresAt      int64
+}
+
+func NewIdempotency(cfg aws.Config, table string, ttl time.Duration) IdempotencyStorage {
+	return IdempotencyStorage{
+		Client: dynamodb.NewFromConfig(cfg),
+		Table:  table,
+		TTL:    ttl,
+	}
+}
+
+// Reserve records a pending request for the key of the principal in the
+// tenant. When the key is already in use the existing record is returned
+// instead, and nothing is changed. The keys of the tenants, and of the
+// principals of a tenant, are distinct, so a client cannot replay the
+// responses of another.
+func (is IdempotencyStorage) Reserve(tenant, principal, key, requestHash string) (*IdempotencyRecord, error) {
+	log.Printf("IdempotencyStorage.Reserve tenant: %s  principal: %s  key: %s\n", tenant, principal, key)
+	id := recordKey(tenant, principal, key)
+
+	now := time.Now()
+	av, err := attributevalue.MarshalMap(IdempotencyRecord{
+		IdempotencyKey: id,
+		RequestHash:    requestHash,
+		ExpiresAt:      now.Add(pendingTimeout).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	// TTL deletion is not immediate, so an expired record can be replaced
+	cond := expression.AttributeNotExists(expression.Name(idempotencyKey)).
+		Or(expression.Name("ExpiresAt").LessThan(expression.Value(now.Unix())))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	input := dynamodb.PutItemInput{
+		Item:                      av,
+		TableName:                 aws.String(is.Table),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	getInput := dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			idempotencyKey: &types.AttributeValueMemberS{Value: id},
+		},
+		TableName:      aws.String(is.Table),
+		ConsistentRead: aws.Bool(true),
+	}
+
+	// The record in use can be purged by TTL between the put and the get,
+	// in which case the key is reserved again
+	for attempt := 1; ; attempt++ {
+		_, err = is.Client.PutItem(context.Background(), &input)
+		if err == nil {
+			return nil, nil
+		}
+		if !conditionFailed(err) {
+			return nil, fmt.Errorf("error reserving idempotency key %q in dynamo: %w", key, err)
+		}
+
+		data, err := is.Client.GetItem(context.Background(), &getInput)
+		if err != nil {
+			return nil, fmt.Errorf("error getting idempotency key %q in dynamo: %w", key, err)
+		}
+
+		if data.Item != nil {
+			record := &IdempotencyRecord{}
+			if err = attributevalue.UnmarshalMap(data.Item, record); err != nil {
+				return nil, fmt.Errorf("error unmarshalling value: %w", err)
+			}
+			return record, nil
+		}
+		if attempt == maxWriteAttempts {
+			return nil, fmt.Errorf("error reserving idempotency key %q in dynamo: the key is reserved and purged concurrently", key)
+		}
+
+		log.Printf("IdempotencyStorage.Reserve key %s purged concurrently, attempt %d\n", key, attempt)
+	}
+}
+
+// Complete stores the response of the request reserved with the key of the
+// principal in the tenant, so it can be replayed until the TTL expires.
+func (is IdempotencyStorage) Complete(tenant, principal, key string, statusCode int, body []byte) error {
+	log.Printf("IdempotencyStorage.Complete tenant: %s  principal: %s  key: %s  statusCode: %d\n", tenant, principal, key, statusCode)
+	id := recordKey(tenant, principal, key)
+
+	update := expression.Set(
+		expression.Name("StatusCode"),
+		expression.Value(statusCode),
+	).Set(
+		expression.Name("Body"),
+		expression.Value(body),
+	).Set(
+		expression.Name("ExpiresAt"),
+		expression.Value(time.Now().Add(is.TTL).Unix()),
+	)
+
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			idempotencyKey: &types.AttributeValueMemberS{Value: id},
+		},
+		TableName:                 aws.String(is.Table),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	_, err = is.Client.UpdateItem(context.Background(), &input)
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key %q in dynamo: %w", key, err)
+	}
+	return nil
+}
+
+// Release removes the reservation of the key of the principal in the
+// tenant, so the request can be retried.
+func (is IdempotencyStorage) Release(tenant, principal, key string) error {
+	log.Printf("IdempotencyStorage.Release tenant: %s  principal: %s  key: %s\n", tenant, principal, key)
+	id := recordKey(tenant, principal, key)
+
+	input := dynamodb.DeleteItemInput{
+		Key: map[string]types.AttributeValue{
+			idempotencyKey: &types.AttributeValueMemberS{Value: id},
+		},
+		TableName: aws.String(is.Table),
+	}
+
+	_, err := is.Client.DeleteItem(context.Background(), &input)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key %q in dynamo: %w", key, err)
+	}
+	return nil
+}
+
+// recordKey returns the key of the record of an idempotency key. The
+// principal is hashed, so it has a fixed length and a subject that
+// contains the separator cannot collide with the key of another one.
+func recordKey(tenant, principal, key string) string {
+	h := sha256.Sum256([]byte(principal))
+	return tenantKey(tenant, hex.EncodeToString(h[:])+tenantSeparator+key)
+}
@@ -0,0 +1,199 @@
+package dynamo
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Reserve(t *testing.T) {
+	t.Parallel()
+
+	existing := &IdempotencyRecord{IdempotencyKey: "key", RequestHash: "hash", StatusCode: 201, Body: []byte(`{}`), ExpiresAt: 12345}
+
+	testCases := []struct {
+		name     string
+		existing *IdempotencyRecord
+		// purged is the number of times the existing record is purged
+		// between the put and the get
+		purged    int
+		record    *IdempotencyRecord
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:     "key in use",
+			existing: existing,
+			record:   existing,
+		},
+		{
+			name:     "key purged while reserving",
+			existing: existing,
+			purged:   1,
+		},
+		{
+			name:     "key purged on every attempt",
+			existing: existing,
+			purged:   maxWriteAttempts,
+			errMsg:   "error reserving idempotency key \"key\" in dynamo: the key is reserved and purged concurrently",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error reserving idempotency key \"key\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := IdempotencyStorage{
+				Client: &idempotencyStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, record: tc.existing, purged: tc.purged},
+				Table:  "IdempotencyTable-Test",
+				TTL:    time.Hour,
+			}
+			record, err := is.Reserve(DefaultTenant, "user1", "key", "hash")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.record, record)
+			}
+		})
+	}
+}
+
+func Test_RecordKey(t *testing.T) {
+	t.Parallel()
+
+	// The principals of a tenant, and the tenants, have distinct keys
+	assert.NotEqual(t, recordKey(DefaultTenant, "user1", "key"), recordKey(DefaultTenant, "user2", "key"))
+	assert.NotEqual(t, recordKey(DefaultTenant, "user1", "key"), recordKey("acme", "user1", "key"))
+
+	// A principal with the separator cannot reach the keys of another one
+	assert.NotEqual(t, recordKey(DefaultTenant, "user1#a", "b"), recordKey(DefaultTenant, "user1", "a#b"))
+}
+
+func Test_Complete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error completing idempotency key \"key\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := IdempotencyStorage{
+				Client: dynamoRestaurantStorerStub{writeError: tc.stubError},
+				Table:  "IdempotencyTable-Test",
+				TTL:    time.Hour,
+			}
+			err := is.Complete(DefaultTenant, "user1", "key", 201, []byte(`{}`))
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_Release(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error releasing idempotency key \"key\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			is := IdempotencyStorage{
+				Client: dynamoRestaurantStorerStub{writeError: tc.stubError},
+				Table:  "IdempotencyTable-Test",
+			}
+			err := is.Release(DefaultTenant, "user1", "key")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+// idempotencyStub fails the conditional PutItem when a record exists, and
+// returns that record from GetItem.
+type idempotencyStub struct {
+	dynamoRestaurantStorerStub
+	record *IdempotencyRecord
+	// purged is the number of gets that find no record, as it was purged
+	// after the put failed. Another request reserves the key again before
+	// each put, except after the last purge, so that put succeeds.
+	purged int
+	free   bool
+}
+
+func (s *idempotencyStub) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if s.record != nil && !s.free {
+		return nil, stubErr(conditionalCheckFailed)
+	}
+	return s.dynamoRestaurantStorerStub.PutItem(ctx, input, optFns...)
+}
+
+func (s *idempotencyStub) GetItem(_ context.Context, _ *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if s.purged > 0 {
+		s.purged--
+		s.free = s.purged == 0
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	av, err := attributevalue.MarshalMap(s.record)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: av}, nil
+}
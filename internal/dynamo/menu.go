@@ -0,0 +1,150 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+)
+
+// The menu is stored in the Menu attribute of the restaurant item, so it is
+// deleted, restored and purged together with the restaurant.
+const menuAttribute = "Menu"
+
+// menuItem is the projection of a restaurant item read for its menu.
+type menuItem struct {
+	RestaurantId string
+	Menu         *model.Menu
+	DeletedAt    int64 `dynamodbav:",omitempty"`
+}
+
+// GetMenu returns the menu of the restaurant of the tenant.
+func (rs RestaurantStorage) GetMenu(tenant, restaurantId string) (model.Menu, error) {
+	log.Printf("RestaurantStorage.GetMenu tenant: %s  restaurantId: %s\n", tenant, restaurantId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	proj := expression.NamesList(expression.Name(key), expression.Name(menuAttribute), expression.Name("DeletedAt"))
+	expr, err := expression.NewBuilder().WithProjection(proj).Build()
+	if err != nil {
+		return model.Menu{}, err
+	}
+
+	input := dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			key: &types.AttributeValueMemberS{Value: restaurantId},
+		},
+		TableName:                aws.String(rs.Table),
+		ProjectionExpression:     expr.Projection(),
+		ExpressionAttributeNames: expr.Names(),
+	}
+
+	data, err := rs.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return model.Menu{}, fmt.Errorf("error getting menu of restaurant %q in dynamo: %w", restaurantId, err)
+	}
+	if data.Item == nil {
+		return model.Menu{}, ErrNotFound
+	}
+
+	item := menuItem{}
+	if err = attributevalue.UnmarshalMap(data.Item, &item); err != nil {
+		return model.Menu{}, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	if item.DeletedAt != 0 {
+		return model.Menu{}, ErrNotFound
+	}
+	if item.Menu == nil {
+		return model.Menu{}, ErrMenuNotFound
+	}
+
+	return *item.Menu, nil
+}
+
+// SaveMenu creates or replaces the menu of the restaurant. ErrForbidden is
+// returned when the actor cannot modify the restaurant.
+func (rs RestaurantStorage) SaveMenu(tenant, restaurantId string, menu model.Menu, actor Actor) error {
+	log.Printf("RestaurantStorage.SaveMenu tenant: %s  restaurantId: %s\n", tenant, restaurantId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	av, err := attributevalue.Marshal(menu)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	update := expression.Set(expression.Name(menuAttribute), expression.Value(av))
+	expr, err := expression.NewBuilder().WithCondition(modifiableCondition(actor)).WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			key: &types.AttributeValueMemberS{Value: restaurantId},
+		},
+		TableName:                 aws.String(rs.Table),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	}
+
+	_, err = rs.Client.UpdateItem(context.Background(), &input)
+	if err != nil {
+		if !conditionFailed(err) {
+			return fmt.Errorf("error saving menu of restaurant %q in dynamo: %w", restaurantId, err)
+		}
+		if err = rs.notModifiable(restaurantId, actor); err != nil {
+			return err
+		}
+		// The restaurant was restored after the write failed
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteMenu removes the menu of the restaurant. ErrForbidden is returned
+// when the actor cannot modify the restaurant.
+func (rs RestaurantStorage) DeleteMenu(tenant, restaurantId string, actor Actor) error {
+	log.Printf("RestaurantStorage.DeleteMenu tenant: %s  restaurantId: %s\n", tenant, restaurantId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	cond := modifiableCondition(actor).And(expression.AttributeExists(expression.Name(menuAttribute)))
+	update := expression.Remove(expression.Name(menuAttribute))
+	expr, err := expression.NewBuilder().WithCondition(cond).WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			key: &types.AttributeValueMemberS{Value: restaurantId},
+		},
+		TableName:                aws.String(rs.Table),
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+		UpdateExpression:         expr.Update(),
+	}
+
+	_, err = rs.Client.UpdateItem(context.Background(), &input)
+	if err != nil {
+		if !conditionFailed(err) {
+			return fmt.Errorf("error deleting menu of restaurant %q in dynamo: %w", restaurantId, err)
+		}
+
+		if err = rs.notModifiable(restaurantId, actor); err != nil {
+			return err
+		}
+		return ErrMenuNotFound
+	}
+
+	return nil
+}
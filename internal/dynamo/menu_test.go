@@ -0,0 +1,246 @@
+package dynamo
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testMenu = model.Menu{
+	Sections: []model.MenuSection{
+		{
+			Name: "Starters",
+			Items: []model.MenuItem{
+				{Name: "Soup", Price: model.Price{Amount: "6.50", Currency: "USD"}},
+			},
+		},
+	},
+}
+
+func Test_GetMenu(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		restId    string
+		menu      *model.Menu
+		deleted   bool
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:   "happy path",
+			restId: "restId",
+			menu:   &testMenu,
+		},
+		{
+			name:   "no menu",
+			restId: "restId",
+			errMsg: "menu not found",
+		},
+		{
+			name:   "restaurant does not exist",
+			errMsg: "restaurant not found",
+		},
+		{
+			name:    "restaurant deleted",
+			restId:  "restId",
+			menu:    &testMenu,
+			deleted: true,
+			errMsg:  "restaurant not found",
+		},
+		{
+			name:      "error",
+			restId:    "restId",
+			stubError: "an error occurred",
+			errMsg:    "error getting menu of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := RestaurantStorage{
+				Client: menuStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: tc.restId, deleted: tc.deleted, error: tc.stubError},
+					menu:                       tc.menu,
+				},
+				Table: "RestaurantsTable-Test",
+			}
+			menu, err := rs.GetMenu(DefaultTenant, "restId")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, *tc.menu, menu)
+			}
+		})
+	}
+}
+
+func Test_SaveMenu(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		exists    bool
+		actor     Actor
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:  "happy path",
+			actor: testOwner,
+		},
+		{
+			name:      "restaurant does not exist",
+			actor:     testOwner,
+			stubError: conditionalCheckFailed,
+			errMsg:    "restaurant not found",
+		},
+		{
+			name:      "not the owner",
+			exists:    true,
+			actor:     Actor{Id: "user2"},
+			stubError: conditionalCheckFailed,
+			errMsg:    "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:      "error",
+			actor:     testOwner,
+			stubError: "an error occurred",
+			errMsg:    "error saving menu of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := dynamoRestaurantStorerStub{writeError: tc.stubError}
+			if tc.exists {
+				stub.restaurantId = "restId"
+			}
+			rs := RestaurantStorage{
+				Client: stub,
+				Table:  "RestaurantsTable-Test",
+			}
+			err := rs.SaveMenu(DefaultTenant, "restId", testMenu, tc.actor)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_DeleteMenu(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		notExist  bool
+		deleted   bool
+		actor     Actor
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:  "admin",
+			actor: Actor{Id: "admin", Admin: true},
+		},
+		{
+			name:      "not the owner",
+			actor:     Actor{Id: "user2"},
+			stubError: conditionalCheckFailed,
+			errMsg:    "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:      "no menu",
+			stubError: conditionalCheckFailed,
+			errMsg:    "menu not found",
+		},
+		{
+			name:      "restaurant does not exist",
+			notExist:  true,
+			stubError: conditionalCheckFailed,
+			errMsg:    "restaurant not found",
+		},
+		{
+			name:      "restaurant deleted",
+			deleted:   true,
+			stubError: conditionalCheckFailed,
+			errMsg:    "restaurant not found",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error deleting menu of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := dynamoRestaurantStorerStub{deleted: tc.deleted, writeError: tc.stubError}
+			if !tc.notExist {
+				stub.restaurantId = "restId"
+			}
+			rs := RestaurantStorage{
+				Client: stub,
+				Table:  "RestaurantsTable-Test",
+			}
+			actor := tc.actor
+			if actor.Id == "" {
+				actor = testOwner
+			}
+			err := rs.DeleteMenu(DefaultTenant, "restId", actor)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+// menuStub returns the restaurant item with the menu from GetItem.
+type menuStub struct {
+	dynamoRestaurantStorerStub
+	menu *model.Menu
+}
+
+func (s menuStub) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	output, err := s.dynamoRestaurantStorerStub.GetItem(ctx, input, optFns...)
+	if err != nil || output.Item == nil || s.menu == nil {
+		return output, err
+	}
+
+	av, err := attributevalue.Marshal(s.menu)
+	if err != nil {
+		return nil, err
+	}
+	output.Item[menuAttribute] = av
+	return output, nil
+}
@@ -0,0 +1,172 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+)
+
+// The photos are stored in the Photos attribute of the restaurant item, in
+// their display order, so they are returned with the restaurant and
+// deleted, restored and purged together with it. Changing the photos does
+// not change the version of the restaurant and is not recorded in its
+// revisions.
+const photosAttribute = "Photos"
+
+// MaxPhotos is the maximum number of photos of a restaurant
+const MaxPhotos = 20
+
+// ListPhotos returns the photos of the restaurant of the tenant, in their
+// display order.
+func (rs RestaurantStorage) ListPhotos(tenant, restaurantId string) ([]model.Photo, error) {
+	log.Printf("RestaurantStorage.ListPhotos tenant: %s  restaurantId: %s\n", tenant, restaurantId)
+
+	item, err := rs.currentItem(tenantKey(tenant, restaurantId))
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || item.DeletedAt != 0 {
+		return nil, ErrNotFound
+	}
+	return item.Photos, nil
+}
+
+// AddPhoto adds the photo after the other photos of the restaurant.
+// ErrTooManyPhotos is returned when the restaurant has MaxPhotos photos.
+func (rs RestaurantStorage) AddPhoto(tenant, restaurantId string, photo model.Photo, actor Actor) error {
+	log.Printf("RestaurantStorage.AddPhoto tenant: %s  restaurantId: %s  photoId: %s\n", tenant, restaurantId, photo.Id)
+
+	_, err := rs.writePhotos(tenantKey(tenant, restaurantId), actor, func(photos []model.Photo) ([]model.Photo, error) {
+		if len(photos) >= MaxPhotos {
+			return nil, ErrTooManyPhotos
+		}
+		return append(photos, photo), nil
+	})
+	return err
+}
+
+// ReorderPhotos puts the photos of the restaurant in the order of the IDs
+// and returns them. ErrPhotoOrder is returned when the IDs are not the IDs
+// of every photo, each once.
+func (rs RestaurantStorage) ReorderPhotos(tenant, restaurantId string, photoIds []string, actor Actor) ([]model.Photo, error) {
+	log.Printf("RestaurantStorage.ReorderPhotos tenant: %s  restaurantId: %s  photoIds: %v\n", tenant, restaurantId, photoIds)
+
+	return rs.writePhotos(tenantKey(tenant, restaurantId), actor, func(photos []model.Photo) ([]model.Photo, error) {
+		if len(photoIds) != len(photos) {
+			return nil, ErrPhotoOrder
+		}
+		byId := make(map[string]model.Photo, len(photos))
+		for _, photo := range photos {
+			byId[photo.Id] = photo
+		}
+
+		ordered := make([]model.Photo, 0, len(photos))
+		for _, id := range photoIds {
+			photo, ok := byId[id]
+			if !ok {
+				return nil, ErrPhotoOrder
+			}
+			// An ID listed twice is not found the second time
+			delete(byId, id)
+			ordered = append(ordered, photo)
+		}
+		return ordered, nil
+	})
+}
+
+// DeletePhoto removes the photo from the restaurant and returns it, so its
+// blobs can be deleted. ErrPhotoNotFound is returned when the restaurant
+// has no such photo.
+func (rs RestaurantStorage) DeletePhoto(tenant, restaurantId, photoId string, actor Actor) (model.Photo, error) {
+	log.Printf("RestaurantStorage.DeletePhoto tenant: %s  restaurantId: %s  photoId: %s\n", tenant, restaurantId, photoId)
+
+	var deleted model.Photo
+	_, err := rs.writePhotos(tenantKey(tenant, restaurantId), actor, func(photos []model.Photo) ([]model.Photo, error) {
+		for i, photo := range photos {
+			if photo.Id == photoId {
+				deleted = photo
+				remaining := append([]model.Photo{}, photos[:i]...)
+				return append(remaining, photos[i+1:]...), nil
+			}
+		}
+		return nil, ErrPhotoNotFound
+	})
+	if err != nil {
+		return model.Photo{}, err
+	}
+	return deleted, nil
+}
+
+// writePhotos reads the photos of the restaurant item, whose key in its
+// tenant is restaurantId, and passes them to apply to build the new photos.
+// ErrNotFound is returned when the restaurant does not exist or is deleted,
+// and ErrForbidden when the actor cannot modify it. The new photos are only
+// written if the photos were not changed since they were read, otherwise
+// the change is attempted again, and ErrPhotosConflict is returned after
+// maxWriteAttempts. writePhotos returns the new photos.
+func (rs RestaurantStorage) writePhotos(restaurantId string, actor Actor, apply func(photos []model.Photo) ([]model.Photo, error)) ([]model.Photo, error) {
+	for attempt := 1; ; attempt++ {
+		item, err := rs.currentItem(restaurantId)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil || item.DeletedAt != 0 {
+			return nil, ErrNotFound
+		}
+		if !actor.CanModify(item.Restaurant) {
+			return nil, ErrForbidden
+		}
+
+		photos, err := apply(item.Photos)
+		if err != nil {
+			return nil, err
+		}
+
+		cond := existsCondition()
+		if len(item.Photos) == 0 {
+			cond = cond.And(expression.AttributeNotExists(expression.Name(photosAttribute)))
+		} else {
+			cond = cond.And(expression.Name(photosAttribute).Equal(expression.Value(item.Photos)))
+		}
+		var update expression.UpdateBuilder
+		if len(photos) == 0 {
+			update = expression.Remove(expression.Name(photosAttribute))
+		} else {
+			update = expression.Set(expression.Name(photosAttribute), expression.Value(photos))
+		}
+
+		expr, err := expression.NewBuilder().WithCondition(cond).WithUpdate(update).Build()
+		if err != nil {
+			return nil, err
+		}
+
+		input := dynamodb.UpdateItemInput{
+			Key: map[string]types.AttributeValue{
+				key: &types.AttributeValueMemberS{Value: restaurantId},
+			},
+			TableName:                 aws.String(rs.Table),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			UpdateExpression:          expr.Update(),
+		}
+
+		_, err = rs.Client.UpdateItem(context.Background(), &input)
+		if err == nil {
+			return photos, nil
+		}
+		if !conditionFailed(err) {
+			return nil, fmt.Errorf("error saving photos of restaurant %q in dynamo: %w", restaurantId, err)
+		}
+		if attempt == maxWriteAttempts {
+			return nil, ErrPhotosConflict
+		}
+
+		log.Printf("RestaurantStorage.writePhotos photos of restaurant %s modified concurrently, attempt %d\n", restaurantId, attempt)
+	}
+}
@@ -0,0 +1,377 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_ListPhotos(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		restId   string
+		photos   []model.Photo
+		deleted  bool
+		photoIds []string
+		errMsg   string
+	}{
+		{
+			name:     "happy path",
+			restId:   "restId",
+			photos:   testPhotos("p1", "p2"),
+			photoIds: []string{"p1", "p2"},
+		},
+		{
+			name:   "no photos",
+			restId: "restId",
+		},
+		{
+			name:   "restaurant does not exist",
+			errMsg: "restaurant not found",
+		},
+		{
+			name:    "restaurant deleted",
+			restId:  "restId",
+			deleted: true,
+			errMsg:  "restaurant not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := RestaurantStorage{
+				Client: photoStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: tc.restId, deleted: tc.deleted},
+					photos:                     tc.photos,
+				},
+				Table: "RestaurantsTable-Test",
+			}
+			photos, err := rs.ListPhotos(DefaultTenant, "restId")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.photoIds, ids(photos))
+		})
+	}
+}
+
+func Test_AddPhoto(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		restId    string
+		photos    []model.Photo
+		actor     Actor
+		stubError string
+		updates   int32
+		photoIds  []string
+		errMsg    string
+	}{
+		{
+			name:     "first photo",
+			restId:   "restId",
+			actor:    testOwner,
+			updates:  1,
+			photoIds: []string{"new"},
+		},
+		{
+			name:     "after the other photos",
+			restId:   "restId",
+			photos:   testPhotos("p1", "p2"),
+			actor:    testOwner,
+			updates:  1,
+			photoIds: []string{"p1", "p2", "new"},
+		},
+		{
+			name:    "too many photos",
+			restId:  "restId",
+			photos:  testPhotos(manyIds(MaxPhotos)...),
+			actor:   testOwner,
+			errMsg:  "restaurant has the maximum number of photos",
+			updates: 0,
+		},
+		{
+			name:   "restaurant does not exist",
+			actor:  testOwner,
+			errMsg: "restaurant not found",
+		},
+		{
+			name:   "not the owner",
+			restId: "restId",
+			actor:  Actor{Id: "user2"},
+			errMsg: "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:     "admin",
+			restId:   "restId",
+			actor:    Actor{Id: "user2", Admin: true},
+			updates:  1,
+			photoIds: []string{"new"},
+		},
+		{
+			name:      "modified concurrently",
+			restId:    "restId",
+			actor:     testOwner,
+			stubError: conditionalCheckFailed,
+			updates:   maxWriteAttempts,
+			errMsg:    "photos were modified concurrently",
+		},
+		{
+			name:      "error",
+			restId:    "restId",
+			actor:     testOwner,
+			stubError: "an error occurred",
+			updates:   1,
+			errMsg:    "error saving photos of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := photoStub{
+				dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: tc.restId, writeError: tc.stubError},
+				photos:                     tc.photos,
+				updates:                    new(int32),
+				written:                    new([]model.Photo),
+			}
+			rs := RestaurantStorage{Client: stub, Table: "RestaurantsTable-Test"}
+
+			err := rs.AddPhoto(DefaultTenant, "restId", testPhotos("new")[0], tc.actor)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.photoIds, ids(*stub.written))
+			}
+			assert.Equal(t, tc.updates, atomic.LoadInt32(stub.updates))
+		})
+	}
+}
+
+func Test_ReorderPhotos(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		photoIds []string
+		errMsg   string
+	}{
+		{
+			name:     "happy path",
+			photoIds: []string{"p3", "p1", "p2"},
+		},
+		{
+			name:     "missing photo",
+			photoIds: []string{"p3", "p1"},
+			errMsg:   "photo IDs do not match the photos of the restaurant",
+		},
+		{
+			name:     "unknown photo",
+			photoIds: []string{"p3", "p1", "p4"},
+			errMsg:   "photo IDs do not match the photos of the restaurant",
+		},
+		{
+			name:     "photo listed twice",
+			photoIds: []string{"p3", "p1", "p1"},
+			errMsg:   "photo IDs do not match the photos of the restaurant",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := photoStub{
+				dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: "restId"},
+				photos:                     testPhotos("p1", "p2", "p3"),
+				updates:                    new(int32),
+				written:                    new([]model.Photo),
+			}
+			rs := RestaurantStorage{Client: stub, Table: "RestaurantsTable-Test"}
+
+			photos, err := rs.ReorderPhotos(DefaultTenant, "restId", tc.photoIds, testOwner)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				assert.Equal(t, int32(0), atomic.LoadInt32(stub.updates))
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.photoIds, ids(photos))
+			assert.Equal(t, tc.photoIds, ids(*stub.written))
+		})
+	}
+}
+
+func Test_DeletePhoto(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		photos   []model.Photo
+		photoId  string
+		photoIds []string
+		errMsg   string
+	}{
+		{
+			name:     "happy path",
+			photos:   testPhotos("p1", "p2", "p3"),
+			photoId:  "p2",
+			photoIds: []string{"p1", "p3"},
+		},
+		{
+			name:    "last photo",
+			photos:  testPhotos("p1"),
+			photoId: "p1",
+		},
+		{
+			name:    "photo does not exist",
+			photos:  testPhotos("p1"),
+			photoId: "p2",
+			errMsg:  "photo not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := photoStub{
+				dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: "restId"},
+				photos:                     tc.photos,
+				updates:                    new(int32),
+				written:                    new([]model.Photo),
+			}
+			rs := RestaurantStorage{Client: stub, Table: "RestaurantsTable-Test"}
+
+			photo, err := rs.DeletePhoto(DefaultTenant, "restId", tc.photoId, testOwner)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.photoId, photo.Id)
+			assert.Equal(t, tc.photoIds, ids(*stub.written))
+		})
+	}
+}
+
+func Test_RestaurantPhotos(t *testing.T) {
+	t.Parallel()
+
+	// The photos of the item are returned with the restaurant
+	restId := "restId"
+	item := restaurantItem{Restaurant: model.Restaurant{Id: &restId}, Photos: testPhotos("p1")}
+	restaurant := item.restaurant()
+	if assert.NotNil(t, restaurant.Photos) {
+		assert.Equal(t, []string{"p1"}, ids(*restaurant.Photos))
+	}
+
+	item.Photos = nil
+	assert.Nil(t, item.restaurant().Photos)
+}
+
+// photoStub returns the restaurant with the photos, and records the photos
+// written by UpdateItem.
+type photoStub struct {
+	dynamoRestaurantStorerStub
+	photos  []model.Photo
+	updates *int32
+	written *[]model.Photo
+}
+
+func (s photoStub) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	output, err := s.dynamoRestaurantStorerStub.GetItem(ctx, input, optFns...)
+	if err != nil || output.Item == nil || len(s.photos) == 0 {
+		return output, err
+	}
+
+	av, err := attributevalue.Marshal(s.photos)
+	if err != nil {
+		return nil, err
+	}
+	output.Item[photosAttribute] = av
+	return output, nil
+}
+
+func (s photoStub) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	atomic.AddInt32(s.updates, 1)
+	if s.writeError != "" {
+		return nil, stubErr(s.writeError)
+	}
+
+	// The update either removes the photos or sets them to a single value
+	*s.written = nil
+	if update := strings.TrimSpace(aws.ToString(input.UpdateExpression)); strings.HasPrefix(update, "SET ") {
+		placeholder := update[strings.LastIndex(update, " ")+1:]
+		if err := attributevalue.Unmarshal(input.ExpressionAttributeValues[placeholder], s.written); err != nil {
+			return nil, err
+		}
+	}
+	return s.dynamoRestaurantStorerStub.UpdateItem(ctx, input, optFns...)
+}
+
+func testPhotos(photoIds ...string) []model.Photo {
+	photos := make([]model.Photo, 0, len(photoIds))
+	for _, id := range photoIds {
+		photos = append(photos, model.Photo{
+			Id:           id,
+			ContentType:  "image/jpeg",
+			Size:         1024,
+			Width:        640,
+			Height:       480,
+			Url:          "/photos/restId/" + id + ".jpg",
+			ThumbnailUrl: "/photos/restId/" + id + "-thumbnail.jpg",
+			CreatedAt:    time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC),
+		})
+	}
+	return photos
+}
+
+func manyIds(n int) []string {
+	photoIds := make([]string, n)
+	for i := range photoIds {
+		photoIds[i] = fmt.Sprintf("p%d", i)
+	}
+	return photoIds
+}
+
+func ids(photos []model.Photo) []string {
+	var photoIds []string
+	for _, photo := range photos {
+		photoIds = append(photoIds, photo.Id)
+	}
+	return photoIds
+}
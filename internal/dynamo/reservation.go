@@ -0,0 +1,557 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/booking"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+	"time"
+)
+
+// The reservations table has RestaurantId (the key of the restaurant in its
+// tenant) as partition key and ItemId as sort key. It holds two kinds of items:
+//   - RESERVATION#<reservationId>: the reservation
+//   - SLOT#<start>#<tableId>: a lock on a table in a slot, held by a
+//     reservation. The start is in UTC (RFC 3339), so the locks of a time
+//     range are a range of sort keys.
+//
+// Locks are created with a condition that they do not exist, in the same
+// transaction as the reservation, so a table can never be booked twice in
+// a slot. They expire through the TTL of the table after the slot.
+const (
+	itemKey           = "ItemId"
+	reservationPrefix = "RESERVATION#"
+	slotPrefix        = "SLOT#"
+	lockRetention     = 24 * time.Hour
+)
+
+// The reservation settings are stored in the ReservationSettings attribute
+// of the restaurant item, like the menu.
+const settingsAttribute = "ReservationSettings"
+
+type ReservationStorage struct {
+	Client           dynamoRestaurantStorer
+	Table            string
+	RestaurantsTable string
+}
+
+// Locks lists the sort keys of the locks held by the reservation, so they
+// can be released without recomputing them from settings that may have
+// changed since. TokenHash is the SHA-256 hash of the management token of
+// the reservation, which is only returned when it is booked; the
+// reservations booked before tokens were issued have none.
+type reservationItem struct {
+	RestaurantId string
+	ItemId       string
+	Reservation  model.Reservation
+	Locks        []string
+	TokenHash    string `dynamodbav:",omitempty"`
+	Version      int64
+}
+
+type lockItem struct {
+	RestaurantId  string
+	ItemId        string
+	Start         time.Time
+	TableId       string
+	ReservationId string
+	ExpiresAt     int64
+}
+
+// settingsItem is the projection of a restaurant item read for its
+// reservation settings.
+type settingsItem struct {
+	RestaurantId        string
+	ReservationSettings *model.ReservationSettings
+	DeletedAt           int64 `dynamodbav:",omitempty"`
+}
+
+func NewReservation(cfg aws.Config, table, restaurantsTable string) ReservationStorage {
+	return ReservationStorage{
+		Client:           dynamodb.NewFromConfig(cfg),
+		Table:            table,
+		RestaurantsTable: restaurantsTable,
+	}
+}
+
+// GetSettings returns the reservation settings of the restaurant.
+func (rs ReservationStorage) GetSettings(tenant, restaurantId string) (model.ReservationSettings, error) {
+	log.Printf("ReservationStorage.GetSettings tenant: %s  restaurantId: %s\n", tenant, restaurantId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	proj := expression.NamesList(expression.Name(key), expression.Name(settingsAttribute), expression.Name("DeletedAt"))
+	expr, err := expression.NewBuilder().WithProjection(proj).Build()
+	if err != nil {
+		return model.ReservationSettings{}, err
+	}
+
+	input := dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			key: &types.AttributeValueMemberS{Value: restaurantId},
+		},
+		TableName:                aws.String(rs.RestaurantsTable),
+		ProjectionExpression:     expr.Projection(),
+		ExpressionAttributeNames: expr.Names(),
+	}
+
+	data, err := rs.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return model.ReservationSettings{}, fmt.Errorf("error getting reservation settings of restaurant %q in dynamo: %w", restaurantId, err)
+	}
+	if data.Item == nil {
+		return model.ReservationSettings{}, ErrNotFound
+	}
+
+	item := settingsItem{}
+	if err = attributevalue.UnmarshalMap(data.Item, &item); err != nil {
+		return model.ReservationSettings{}, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	if item.DeletedAt != 0 {
+		return model.ReservationSettings{}, ErrNotFound
+	}
+	if item.ReservationSettings == nil {
+		return model.ReservationSettings{}, ErrSettingsNotFound
+	}
+
+	return *item.ReservationSettings, nil
+}
+
+// SaveSettings creates or replaces the reservation settings of the
+// restaurant. Existing reservations keep their tables. ErrForbidden is
+// returned when the actor cannot modify the restaurant.
+func (rs ReservationStorage) SaveSettings(tenant, restaurantId string, settings model.ReservationSettings, actor Actor) error {
+	log.Printf("ReservationStorage.SaveSettings tenant: %s  restaurantId: %s\n", tenant, restaurantId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	av, err := attributevalue.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	update := expression.Set(expression.Name(settingsAttribute), expression.Value(av))
+	expr, err := expression.NewBuilder().WithCondition(modifiableCondition(actor)).WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			key: &types.AttributeValueMemberS{Value: restaurantId},
+		},
+		TableName:                 aws.String(rs.RestaurantsTable),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	}
+
+	_, err = rs.Client.UpdateItem(context.Background(), &input)
+	if err != nil {
+		if !conditionFailed(err) {
+			return fmt.Errorf("error saving reservation settings of restaurant %q in dynamo: %w", restaurantId, err)
+		}
+		restaurants := RestaurantStorage{Client: rs.Client, Table: rs.RestaurantsTable}
+		if err = restaurants.notModifiable(restaurantId, actor); err != nil {
+			return err
+		}
+		// The restaurant was restored after the write failed
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Locks returns the locks of the restaurant on slots starting from from up
+// to and including to.
+func (rs ReservationStorage) Locks(tenant, restaurantId string, from, to time.Time) ([]booking.Lock, error) {
+	log.Printf("ReservationStorage.Locks tenant: %s  restaurantId: %s  from: %s  to: %s\n", tenant, restaurantId, from, to)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	// "$" sorts after the "#" that separates the start from the table ID,
+	// so the locks on the last slot are included
+	keyCond := expression.Key(key).Equal(expression.Value(restaurantId)).
+		And(expression.Key(itemKey).Between(
+			expression.Value(slotPrefix+slotTime(from)),
+			expression.Value(slotPrefix+slotTime(to)+"$"),
+		))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	input := dynamodb.QueryInput{
+		TableName:                 aws.String(rs.Table),
+		ConsistentRead:            aws.Bool(true),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	var locks []booking.Lock
+	paginator := dynamodb.NewQueryPaginator(rs.Client, &input)
+	for paginator.HasMorePages() {
+		data, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("error getting locks of restaurant %q in dynamo: %w", restaurantId, err)
+		}
+
+		var items []lockItem
+		if err = attributevalue.UnmarshalListOfMaps(data.Items, &items); err != nil {
+			return nil, fmt.Errorf("error unmarshalling value: %w", err)
+		}
+		for _, item := range items {
+			locks = append(locks, booking.Lock{Start: item.Start, TableId: item.TableId, ReservationId: item.ReservationId})
+		}
+	}
+
+	return locks, nil
+}
+
+// SaveReservation stores a new reservation, with the hash of its
+// management token, and locks its table in the slots. ErrSlotTaken is
+// returned when the table is already locked in one of them, and
+// ErrNotFound when the restaurant does not exist.
+func (rs ReservationStorage) SaveReservation(tenant, restaurantId string, reservation model.Reservation, tokenHash string, slots []time.Time) error {
+	log.Printf("ReservationStorage.SaveReservation tenant: %s  restaurantId: %s  reservationId: %s  tableId: %s\n", tenant, restaurantId, *reservation.Id, *reservation.TableId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	locks := lockKeys(*reservation.TableId, slots)
+	av, err := attributevalue.MarshalMap(reservationItem{
+		RestaurantId: restaurantId,
+		ItemId:       reservationPrefix + *reservation.Id,
+		Reservation:  reservation,
+		Locks:        locks,
+		TokenHash:    tokenHash,
+		Version:      1,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	putExpr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(itemKey))).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	checkExpr, err := expression.NewBuilder().WithCondition(existsCondition()).Build()
+	if err != nil {
+		return err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				Item:                     av,
+				TableName:                aws.String(rs.Table),
+				ConditionExpression:      putExpr.Condition(),
+				ExpressionAttributeNames: putExpr.Names(),
+			},
+		},
+		{
+			ConditionCheck: &types.ConditionCheck{
+				Key: map[string]types.AttributeValue{
+					key: &types.AttributeValueMemberS{Value: restaurantId},
+				},
+				TableName:                aws.String(rs.RestaurantsTable),
+				ConditionExpression:      checkExpr.Condition(),
+				ExpressionAttributeNames: checkExpr.Names(),
+			},
+		},
+	}
+	firstLock := len(items)
+
+	lockPuts, err := rs.lockPuts(restaurantId, *reservation.Id, *reservation.TableId, slots, putExpr)
+	if err != nil {
+		return err
+	}
+	items = append(items, lockPuts...)
+
+	_, err = rs.Client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		switch {
+		case transactionConditionFailed(err, 1):
+			return ErrNotFound
+		case anyConditionFailed(err, firstLock, len(items)):
+			return ErrSlotTaken
+		}
+		return fmt.Errorf("error saving reservation %q in dynamo: %w", *reservation.Id, err)
+	}
+
+	return nil
+}
+
+// GetReservation returns the reservation of the restaurant and the hash of
+// its management token, empty when it has none.
+func (rs ReservationStorage) GetReservation(tenant, restaurantId, reservationId string) (model.Reservation, string, error) {
+	log.Printf("ReservationStorage.GetReservation tenant: %s  restaurantId: %s  reservationId: %s\n", tenant, restaurantId, reservationId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	item, err := rs.reservationItem(restaurantId, reservationId)
+	if err != nil {
+		return model.Reservation{}, "", err
+	}
+	return item.Reservation, item.TokenHash, nil
+}
+
+// UpdateReservation replaces a booked reservation and moves its locks to
+// the table of the reservation in the slots. Locks it keeps are not
+// touched, so a reservation can be moved to overlapping slots.
+// ErrSlotTaken is returned when the table is locked by another reservation
+// in one of the slots.
+func (rs ReservationStorage) UpdateReservation(tenant, restaurantId string, reservation model.Reservation, slots []time.Time) error {
+	log.Printf("ReservationStorage.UpdateReservation tenant: %s  restaurantId: %s  reservationId: %s  tableId: %s\n", tenant, restaurantId, *reservation.Id, *reservation.TableId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	current, err := rs.reservationItem(restaurantId, *reservation.Id)
+	if err != nil {
+		return err
+	}
+	if current.Reservation.Status != nil && *current.Reservation.Status == model.Cancelled {
+		return ErrReservationCancelled
+	}
+
+	locks := lockKeys(*reservation.TableId, slots)
+	keep := make(map[string]bool, len(locks))
+	for _, l := range locks {
+		keep[l] = true
+	}
+
+	av, err := attributevalue.MarshalMap(reservation)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+	locksAv, err := attributevalue.Marshal(locks)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	update := expression.Set(expression.Name("Reservation"), expression.Value(&types.AttributeValueMemberM{Value: av})).
+		Set(expression.Name("Locks"), expression.Value(locksAv)).
+		Add(expression.Name("Version"), expression.Value(1))
+	items, err := rs.releaseTransaction(restaurantId, current, update, keep)
+	if err != nil {
+		return err
+	}
+	firstLock := len(items)
+
+	held := make(map[string]bool, len(current.Locks))
+	for _, l := range current.Locks {
+		held[l] = true
+	}
+	var added []time.Time
+	for i, l := range locks {
+		if !held[l] {
+			added = append(added, slots[i])
+		}
+	}
+
+	putExpr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(itemKey))).
+		Build()
+	if err != nil {
+		return err
+	}
+	lockPuts, err := rs.lockPuts(restaurantId, *reservation.Id, *reservation.TableId, added, putExpr)
+	if err != nil {
+		return err
+	}
+	items = append(items, lockPuts...)
+
+	_, err = rs.Client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		switch {
+		case anyConditionFailed(err, firstLock, len(items)):
+			return ErrSlotTaken
+		case anyConditionFailed(err, 0, firstLock):
+			return ErrReservationConflict
+		}
+		return fmt.Errorf("error updating reservation %q in dynamo: %w", *reservation.Id, err)
+	}
+
+	return nil
+}
+
+// CancelReservation marks the reservation as cancelled and releases its
+// locks.
+func (rs ReservationStorage) CancelReservation(tenant, restaurantId, reservationId string) error {
+	log.Printf("ReservationStorage.CancelReservation tenant: %s  restaurantId: %s  reservationId: %s\n", tenant, restaurantId, reservationId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	current, err := rs.reservationItem(restaurantId, reservationId)
+	if err != nil {
+		return err
+	}
+	if current.Reservation.Status != nil && *current.Reservation.Status == model.Cancelled {
+		return ErrReservationCancelled
+	}
+
+	update := expression.Set(expression.Name("Reservation.status"), expression.Value(model.Cancelled)).
+		Remove(expression.Name("Locks")).
+		Add(expression.Name("Version"), expression.Value(1))
+	items, err := rs.releaseTransaction(restaurantId, current, update, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = rs.Client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if anyConditionFailed(err, 0, len(items)) {
+			return ErrReservationConflict
+		}
+		return fmt.Errorf("error cancelling reservation %q in dynamo: %w", reservationId, err)
+	}
+
+	return nil
+}
+
+// reservationItem reads the reservation item consistently.
+func (rs ReservationStorage) reservationItem(restaurantId, reservationId string) (reservationItem, error) {
+	input := dynamodb.GetItemInput{
+		Key:            reservationKey(restaurantId, reservationPrefix+reservationId),
+		TableName:      aws.String(rs.Table),
+		ConsistentRead: aws.Bool(true),
+	}
+
+	data, err := rs.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return reservationItem{}, fmt.Errorf("error getting reservation %q in dynamo: %w", reservationId, err)
+	}
+	if data.Item == nil {
+		return reservationItem{}, ErrReservationNotFound
+	}
+
+	var item reservationItem
+	if err = attributevalue.UnmarshalMap(data.Item, &item); err != nil {
+		return reservationItem{}, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return item, nil
+}
+
+// releaseTransaction returns the items of a transaction that updates the
+// reservation item, provided it was not modified since it was read, and
+// deletes the locks it holds that are not kept.
+func (rs ReservationStorage) releaseTransaction(restaurantId string, current reservationItem, update expression.UpdateBuilder, keep map[string]bool) ([]types.TransactWriteItem, error) {
+	updateExpr, err := expression.NewBuilder().
+		WithCondition(expression.Name("Version").Equal(expression.Value(current.Version))).
+		WithUpdate(update).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				Key:                       reservationKey(restaurantId, current.ItemId),
+				TableName:                 aws.String(rs.Table),
+				ConditionExpression:       updateExpr.Condition(),
+				ExpressionAttributeNames:  updateExpr.Names(),
+				ExpressionAttributeValues: updateExpr.Values(),
+				UpdateExpression:          updateExpr.Update(),
+			},
+		},
+	}
+
+	reservationId := *current.Reservation.Id
+	deleteExpr, err := expression.NewBuilder().
+		WithCondition(expression.Name("ReservationId").Equal(expression.Value(reservationId))).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range current.Locks {
+		if keep[l] {
+			continue
+		}
+		items = append(items, types.TransactWriteItem{
+			Delete: &types.Delete{
+				Key:                       reservationKey(restaurantId, l),
+				TableName:                 aws.String(rs.Table),
+				ConditionExpression:       deleteExpr.Condition(),
+				ExpressionAttributeNames:  deleteExpr.Names(),
+				ExpressionAttributeValues: deleteExpr.Values(),
+			},
+		})
+	}
+
+	return items, nil
+}
+
+// lockPuts returns the transaction items that create the locks of the
+// reservation on the table in the slots.
+func (rs ReservationStorage) lockPuts(restaurantId, reservationId, tableId string, slots []time.Time, putExpr expression.Expression) ([]types.TransactWriteItem, error) {
+	items := make([]types.TransactWriteItem, 0, len(slots))
+	for _, start := range slots {
+		av, err := attributevalue.MarshalMap(lockItem{
+			RestaurantId:  restaurantId,
+			ItemId:        lockKey(tableId, start),
+			Start:         start.UTC(),
+			TableId:       tableId,
+			ReservationId: reservationId,
+			ExpiresAt:     start.Add(lockRetention).Unix(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling value: %w", err)
+		}
+
+		items = append(items, types.TransactWriteItem{
+			Put: &types.Put{
+				Item:                     av,
+				TableName:                aws.String(rs.Table),
+				ConditionExpression:      putExpr.Condition(),
+				ExpressionAttributeNames: putExpr.Names(),
+			},
+		})
+	}
+	return items, nil
+}
+
+func reservationKey(restaurantId, itemId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		key:     &types.AttributeValueMemberS{Value: restaurantId},
+		itemKey: &types.AttributeValueMemberS{Value: itemId},
+	}
+}
+
+func lockKeys(tableId string, slots []time.Time) []string {
+	keys := make([]string, 0, len(slots))
+	for _, start := range slots {
+		keys = append(keys, lockKey(tableId, start))
+	}
+	return keys
+}
+
+func lockKey(tableId string, start time.Time) string {
+	return slotPrefix + slotTime(start) + "#" + tableId
+}
+
+func slotTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// anyConditionFailed reports whether err is a canceled transaction in which
+// the condition expression of an item with index in [from, to) failed.
+func anyConditionFailed(err error, from, to int) bool {
+	for i := from; i < to; i++ {
+		if transactionConditionFailed(err, i) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,530 @@
+package dynamo
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/booking"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testSettings = model.ReservationSettings{
+	Tables: []model.Table{{Id: "t1", Capacity: 4}},
+}
+
+var testStart = time.Date(2023, time.June, 9, 19, 0, 0, 0, time.UTC)
+
+func Test_GetSettings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		restId    string
+		settings  *model.ReservationSettings
+		deleted   bool
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:     "happy path",
+			restId:   "restId",
+			settings: &testSettings,
+		},
+		{
+			name:   "no settings",
+			restId: "restId",
+			errMsg: "reservation settings not found",
+		},
+		{
+			name:   "restaurant does not exist",
+			errMsg: "restaurant not found",
+		},
+		{
+			name:     "restaurant deleted",
+			restId:   "restId",
+			settings: &testSettings,
+			deleted:  true,
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:      "error",
+			restId:    "restId",
+			stubError: "an error occurred",
+			errMsg:    "error getting reservation settings of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReservationStorage{
+				Client: reservationStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: tc.restId, deleted: tc.deleted, error: tc.stubError},
+					settings:                   tc.settings,
+				},
+				Table:            "ReservationsTable-Test",
+				RestaurantsTable: "RestaurantsTable-Test",
+			}
+			settings, err := rs.GetSettings(DefaultTenant, "restId")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, *tc.settings, settings)
+			}
+		})
+	}
+}
+
+func Test_SaveSettings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		exists    bool
+		actor     Actor
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:  "happy path",
+			actor: testOwner,
+		},
+		{
+			name:      "restaurant does not exist",
+			actor:     testOwner,
+			stubError: conditionalCheckFailed,
+			errMsg:    "restaurant not found",
+		},
+		{
+			name:      "not the owner",
+			exists:    true,
+			actor:     Actor{Id: "user2"},
+			stubError: conditionalCheckFailed,
+			errMsg:    "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:      "error",
+			actor:     testOwner,
+			stubError: "an error occurred",
+			errMsg:    "error saving reservation settings of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stub := dynamoRestaurantStorerStub{writeError: tc.stubError}
+			if tc.exists {
+				stub.restaurantId = "restId"
+			}
+			rs := ReservationStorage{
+				Client:           stub,
+				Table:            "ReservationsTable-Test",
+				RestaurantsTable: "RestaurantsTable-Test",
+			}
+			err := rs.SaveSettings(DefaultTenant, "restId", testSettings, tc.actor)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_Locks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		locks     []lockItem
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+			locks: []lockItem{
+				{RestaurantId: "restId", ItemId: lockKey("t1", testStart), Start: testStart, TableId: "t1", ReservationId: "r1"},
+			},
+		},
+		{
+			name: "no locks",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error getting locks of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReservationStorage{
+				Client: reservationStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError},
+					locks:                      tc.locks,
+				},
+				Table: "ReservationsTable-Test",
+			}
+			locks, err := rs.Locks(DefaultTenant, "restId", testStart, testStart.Add(time.Hour))
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				var expected []booking.Lock
+				for _, l := range tc.locks {
+					expected = append(expected, booking.Lock{Start: l.Start, TableId: l.TableId, ReservationId: l.ReservationId})
+				}
+				assert.Equal(t, expected, locks)
+			}
+		})
+	}
+}
+
+func Test_SaveReservation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		canceled  []string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:     "restaurant does not exist",
+			canceled: []string{"None", "ConditionalCheckFailed", "None", "None"},
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:     "slot taken",
+			canceled: []string{"None", "None", "None", "ConditionalCheckFailed"},
+			errMsg:   "table is already booked",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error saving reservation \"r1\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReservationStorage{
+				Client:           dynamoRestaurantStorerStub{writeError: tc.stubError, canceled: tc.canceled},
+				Table:            "ReservationsTable-Test",
+				RestaurantsTable: "RestaurantsTable-Test",
+			}
+			slots := []time.Time{testStart, testStart.Add(30 * time.Minute)}
+			err := rs.SaveReservation(DefaultTenant, "restId", testReservation(model.Booked), "hash", slots)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_GetReservation(t *testing.T) {
+	t.Parallel()
+
+	reservation := testReservation(model.Booked)
+
+	testCases := []struct {
+		name      string
+		item      *reservationItem
+		tokenHash string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:      "happy path",
+			item:      &reservationItem{RestaurantId: "restId", ItemId: "RESERVATION#r1", Reservation: reservation, TokenHash: "hash"},
+			tokenHash: "hash",
+		},
+		{
+			name: "booked before tokens",
+			item: &reservationItem{RestaurantId: "restId", ItemId: "RESERVATION#r1", Reservation: reservation},
+		},
+		{
+			name:   "reservation does not exist",
+			errMsg: "reservation not found",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error getting reservation \"r1\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReservationStorage{
+				Client: reservationStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError},
+					item:                       tc.item,
+				},
+				Table: "ReservationsTable-Test",
+			}
+			got, tokenHash, err := rs.GetReservation(DefaultTenant, "restId", "r1")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, reservation, got)
+				assert.Equal(t, tc.tokenHash, tokenHash)
+			}
+		})
+	}
+}
+
+func Test_UpdateReservation(t *testing.T) {
+	t.Parallel()
+
+	booked := &reservationItem{
+		RestaurantId: "restId",
+		ItemId:       "RESERVATION#r1",
+		Reservation:  testReservation(model.Booked),
+		Locks:        lockKeys("t1", []time.Time{testStart, testStart.Add(30 * time.Minute)}),
+		Version:      1,
+	}
+
+	testCases := []struct {
+		name      string
+		item      *reservationItem
+		canceled  []string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+			item: booked,
+		},
+		{
+			name:   "reservation does not exist",
+			errMsg: "reservation not found",
+		},
+		{
+			name:   "reservation cancelled",
+			item:   &reservationItem{RestaurantId: "restId", ItemId: "RESERVATION#r1", Reservation: testReservation(model.Cancelled)},
+			errMsg: "reservation is cancelled",
+		},
+		{
+			// Moved by 30 minutes: the first lock is released and one is added
+			name:     "slot taken",
+			item:     booked,
+			canceled: []string{"None", "None", "ConditionalCheckFailed"},
+			errMsg:   "table is already booked",
+		},
+		{
+			name:     "modified concurrently",
+			item:     booked,
+			canceled: []string{"ConditionalCheckFailed", "None", "None"},
+			errMsg:   "reservation was modified concurrently",
+		},
+		{
+			name:      "error",
+			item:      booked,
+			stubError: "an error occurred",
+			errMsg:    "error updating reservation \"r1\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReservationStorage{
+				Client: reservationStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{writeError: tc.stubError, canceled: tc.canceled},
+					item:                       tc.item,
+				},
+				Table: "ReservationsTable-Test",
+			}
+			slots := []time.Time{testStart.Add(30 * time.Minute), testStart.Add(time.Hour)}
+			err := rs.UpdateReservation(DefaultTenant, "restId", testReservation(model.Booked), slots)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_CancelReservation(t *testing.T) {
+	t.Parallel()
+
+	booked := &reservationItem{
+		RestaurantId: "restId",
+		ItemId:       "RESERVATION#r1",
+		Reservation:  testReservation(model.Booked),
+		Locks:        lockKeys("t1", []time.Time{testStart}),
+		Version:      1,
+	}
+
+	testCases := []struct {
+		name      string
+		item      *reservationItem
+		canceled  []string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+			item: booked,
+		},
+		{
+			name:   "reservation does not exist",
+			errMsg: "reservation not found",
+		},
+		{
+			name:   "reservation cancelled",
+			item:   &reservationItem{RestaurantId: "restId", ItemId: "RESERVATION#r1", Reservation: testReservation(model.Cancelled)},
+			errMsg: "reservation is cancelled",
+		},
+		{
+			name:     "modified concurrently",
+			item:     booked,
+			canceled: []string{"ConditionalCheckFailed", "None"},
+			errMsg:   "reservation was modified concurrently",
+		},
+		{
+			name:      "error",
+			item:      booked,
+			stubError: "an error occurred",
+			errMsg:    "error cancelling reservation \"r1\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReservationStorage{
+				Client: reservationStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{writeError: tc.stubError, canceled: tc.canceled},
+					item:                       tc.item,
+				},
+				Table: "ReservationsTable-Test",
+			}
+			err := rs.CancelReservation(DefaultTenant, "restId", "r1")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+// reservationStub returns the restaurant item with the settings from
+// GetItem on the restaurants table, the reservation item from GetItem on
+// the reservations table and the locks from Query.
+type reservationStub struct {
+	dynamoRestaurantStorerStub
+	settings *model.ReservationSettings
+	item     *reservationItem
+	locks    []lockItem
+}
+
+func (s reservationStub) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if aws.ToString(input.TableName) == "RestaurantsTable-Test" {
+		output, err := s.dynamoRestaurantStorerStub.GetItem(ctx, input, optFns...)
+		if err != nil || output.Item == nil || s.settings == nil {
+			return output, err
+		}
+
+		av, err := attributevalue.Marshal(s.settings)
+		if err != nil {
+			return nil, err
+		}
+		output.Item[settingsAttribute] = av
+		return output, nil
+	}
+
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	if s.item == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	av, err := attributevalue.MarshalMap(s.item)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: av}, nil
+}
+
+func (s reservationStub) Query(_ context.Context, _ *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+
+	output := &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{}}
+	for _, l := range s.locks {
+		av, err := attributevalue.MarshalMap(l)
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	return output, nil
+}
+
+func testReservation(status model.ReservationStatus) model.Reservation {
+	id, tableId := "r1", "t1"
+	return model.Reservation{
+		Id:        &id,
+		Name:      "Smith",
+		PartySize: 2,
+		Start:     testStart,
+		Status:    &status,
+		TableId:   &tableId,
+	}
+}
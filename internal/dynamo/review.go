@@ -0,0 +1,278 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+)
+
+const reviewKey = "ReviewId"
+
+// ReviewStorage stores the reviews in their own table, with RestaurantId
+// (the key of the restaurant in its tenant) as partition key and ReviewId as
+// sort key. The rating aggregates are atomic
+// counters on the restaurant item, written in the same transaction as the
+// review so they stay consistent under concurrent writes.
+type ReviewStorage struct {
+	Client           dynamoRestaurantStorer
+	Table            string
+	RestaurantsTable string
+}
+
+// The rating is duplicated outside the review for the condition that
+// protects the aggregates when the review is deleted.
+type reviewItem struct {
+	RestaurantId string
+	ReviewId     string
+	Review       model.Review
+	Rating       int
+}
+
+func NewReview(cfg aws.Config, table, restaurantsTable string) ReviewStorage {
+	return ReviewStorage{
+		Client:           dynamodb.NewFromConfig(cfg),
+		Table:            table,
+		RestaurantsTable: restaurantsTable,
+	}
+}
+
+// SaveReview stores a new review and adds its rating to the aggregates of
+// the restaurant. ErrNotFound is returned when the restaurant does not exist.
+func (rs ReviewStorage) SaveReview(tenant, restaurantId string, review model.Review) error {
+	log.Printf("ReviewStorage.SaveReview tenant: %s  restaurantId: %s  reviewId: %s\n", tenant, restaurantId, *review.Id)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	av, err := attributevalue.MarshalMap(reviewItem{
+		RestaurantId: restaurantId,
+		ReviewId:     *review.Id,
+		Review:       review,
+		Rating:       review.Rating,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	putExpr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(reviewKey))).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	update := expression.Add(
+		expression.Name("RatingCount"),
+		expression.Value(1),
+	).Add(
+		expression.Name("RatingSum"),
+		expression.Value(review.Rating),
+	)
+	updateExpr, err := expression.NewBuilder().WithCondition(existsCondition()).WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					Item:                      av,
+					TableName:                 aws.String(rs.Table),
+					ConditionExpression:       putExpr.Condition(),
+					ExpressionAttributeNames:  putExpr.Names(),
+					ExpressionAttributeValues: putExpr.Values(),
+				},
+			},
+			{
+				Update: &types.Update{
+					Key: map[string]types.AttributeValue{
+						key: &types.AttributeValueMemberS{Value: restaurantId},
+					},
+					TableName:                 aws.String(rs.RestaurantsTable),
+					ConditionExpression:       updateExpr.Condition(),
+					ExpressionAttributeNames:  updateExpr.Names(),
+					ExpressionAttributeValues: updateExpr.Values(),
+					UpdateExpression:          updateExpr.Update(),
+				},
+			},
+		},
+	}
+
+	_, err = rs.Client.TransactWriteItems(context.Background(), &input)
+	if err != nil {
+		if transactionConditionFailed(err, 1) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("error saving review %q in dynamo: %w", *review.Id, err)
+	}
+
+	return nil
+}
+
+// ListReviews returns up to limit reviews of the restaurant starting after
+// the position encoded in nextToken. The returned token is empty when there
+// are no more pages.
+func (rs ReviewStorage) ListReviews(tenant, restaurantId string, limit int32, nextToken string) ([]model.Review, string, error) {
+	log.Printf("ReviewStorage.ListReviews tenant: %s  restaurantId: %s  limit: %d  nextToken: %s\n", tenant, restaurantId, limit, nextToken)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	startKey, err := decodeToken(nextToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyCond := expression.Key(key).Equal(expression.Value(restaurantId))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, "", err
+	}
+
+	input := dynamodb.QueryInput{
+		TableName:                 aws.String(rs.Table),
+		Limit:                     aws.Int32(limit),
+		ExclusiveStartKey:         startKey,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	data, err := rs.Client.Query(context.Background(), &input)
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing reviews of restaurant %q in dynamo: %w", restaurantId, err)
+	}
+
+	var items []reviewItem
+	if err = attributevalue.UnmarshalListOfMaps(data.Items, &items); err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling value: %w", err)
+	}
+
+	reviews := make([]model.Review, 0, len(items))
+	for _, item := range items {
+		reviews = append(reviews, item.Review)
+	}
+
+	token, err := encodeToken(data.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encoding pagination token: %w", err)
+	}
+
+	return reviews, token, nil
+}
+
+// DeleteReview deletes the review and subtracts its rating from the
+// aggregates of the restaurant. ErrReviewNotFound is returned when the
+// review does not exist, and ErrForbidden when the actor cannot modify the
+// restaurant.
+func (rs ReviewStorage) DeleteReview(tenant, restaurantId, reviewId string, actor Actor) error {
+	log.Printf("ReviewStorage.DeleteReview tenant: %s  restaurantId: %s  reviewId: %s\n", tenant, restaurantId, reviewId)
+
+	restaurantId = tenantKey(tenant, restaurantId)
+
+	reviewKeys := map[string]types.AttributeValue{
+		key:       &types.AttributeValueMemberS{Value: restaurantId},
+		reviewKey: &types.AttributeValueMemberS{Value: reviewId},
+	}
+
+	getInput := dynamodb.GetItemInput{
+		Key:            reviewKeys,
+		TableName:      aws.String(rs.Table),
+		ConsistentRead: aws.Bool(true),
+	}
+
+	data, err := rs.Client.GetItem(context.Background(), &getInput)
+	if err != nil {
+		return fmt.Errorf("error getting review %q in dynamo: %w", reviewId, err)
+	}
+	if data.Item == nil {
+		return ErrReviewNotFound
+	}
+
+	var item reviewItem
+	if err = attributevalue.UnmarshalMap(data.Item, &item); err != nil {
+		return fmt.Errorf("error unmarshalling value: %w", err)
+	}
+
+	// The rating condition fails if the review was deleted in the meantime,
+	// so its rating is never subtracted twice
+	deleteExpr, err := expression.NewBuilder().
+		WithCondition(expression.Name("Rating").Equal(expression.Value(item.Rating))).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	update := expression.Add(
+		expression.Name("RatingCount"),
+		expression.Value(-1),
+	).Add(
+		expression.Name("RatingSum"),
+		expression.Value(-item.Rating),
+	)
+	// A deleted restaurant keeps its aggregates up to date until it is
+	// purged, so only the owner is checked
+	cond := expression.AttributeExists(expression.Name(key))
+	if !actor.Admin {
+		cond = cond.And(expression.Name(ownerAttribute).Equal(expression.Value(actor.Id)))
+	}
+	updateExpr, err := expression.NewBuilder().
+		WithCondition(cond).
+		WithUpdate(update).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					Key:                       reviewKeys,
+					TableName:                 aws.String(rs.Table),
+					ConditionExpression:       deleteExpr.Condition(),
+					ExpressionAttributeNames:  deleteExpr.Names(),
+					ExpressionAttributeValues: deleteExpr.Values(),
+				},
+			},
+			{
+				Update: &types.Update{
+					Key: map[string]types.AttributeValue{
+						key: &types.AttributeValueMemberS{Value: restaurantId},
+					},
+					TableName:                 aws.String(rs.RestaurantsTable),
+					ConditionExpression:       updateExpr.Condition(),
+					ExpressionAttributeNames:  updateExpr.Names(),
+					ExpressionAttributeValues: updateExpr.Values(),
+					UpdateExpression:          updateExpr.Update(),
+				},
+			},
+		},
+	}
+
+	_, err = rs.Client.TransactWriteItems(context.Background(), &input)
+	if err != nil {
+		switch {
+		case transactionConditionFailed(err, 0):
+			return ErrReviewNotFound
+		case transactionConditionFailed(err, 1):
+			restaurants := RestaurantStorage{Client: rs.Client, Table: rs.RestaurantsTable}
+			item, err := restaurants.itemState(restaurantId)
+			if err != nil {
+				return err
+			}
+			if item != nil && !actor.CanModify(item.Restaurant) {
+				return ErrForbidden
+			}
+			return ErrNotFound
+		}
+		return fmt.Errorf("error deleting review %q in dynamo: %w", reviewId, err)
+	}
+
+	return nil
+}
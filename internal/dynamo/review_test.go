@@ -0,0 +1,283 @@
+package dynamo
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_SaveReview(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		canceled  []string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:     "restaurant does not exist",
+			canceled: []string{"None", "ConditionalCheckFailed"},
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error saving review \"reviewId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReviewStorage{
+				Client:           dynamoRestaurantStorerStub{writeError: tc.stubError, canceled: tc.canceled},
+				Table:            "ReviewsTable-Test",
+				RestaurantsTable: "RestaurantsTable-Test",
+			}
+			reviewId := "reviewId"
+			err := rs.SaveReview(DefaultTenant, "restId", model.Review{Id: &reviewId, Rating: 4})
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_ListReviews(t *testing.T) {
+	t.Parallel()
+
+	reviewIds := []string{"review1", "review2", "review3"}
+
+	testCases := []struct {
+		name      string
+		nextToken string
+		reviews   []string
+		token     bool
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:    "first page",
+			reviews: []string{"review1", "review2"},
+			token:   true,
+		},
+		{
+			name:      "last page",
+			nextToken: "eyJSZXN0YXVyYW50SWQiOnsiUyI6InJlc3RJZCJ9LCJSZXZpZXdJZCI6eyJTIjoicmV2aWV3MiJ9fQ",
+			reviews:   []string{"review3"},
+		},
+		{
+			name:      "invalid token",
+			nextToken: "not a token",
+			errMsg:    "invalid pagination token",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error listing reviews of restaurant \"restId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rs := ReviewStorage{
+				Client: reviewStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError},
+					reviewIds:                  reviewIds,
+				},
+				Table: "ReviewsTable-Test",
+			}
+			reviews, token, err := rs.ListReviews(DefaultTenant, "restId", 2, tc.nextToken)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				var ids []string
+				for _, review := range reviews {
+					ids = append(ids, *review.Id)
+				}
+				assert.Equal(t, tc.reviews, ids)
+				assert.Equal(t, tc.token, token != "")
+			}
+		})
+	}
+}
+
+func Test_DeleteReview(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		notExist  bool
+		actor     Actor
+		canceled  []string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:     "review does not exist",
+			notExist: true,
+			errMsg:   "review not found",
+		},
+		{
+			name:     "review deleted concurrently",
+			canceled: []string{"ConditionalCheckFailed", "None"},
+			errMsg:   "review not found",
+		},
+		{
+			name:     "restaurant does not exist",
+			canceled: []string{"None", "ConditionalCheckFailed"},
+			errMsg:   "restaurant not found",
+		},
+		{
+			name:     "not the owner",
+			actor:    Actor{Id: "user2"},
+			canceled: []string{"None", "ConditionalCheckFailed"},
+			errMsg:   "only the owner of the restaurant or an admin can modify it",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error deleting review \"reviewId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			// The stub returns an item from GetItem when the ID is set
+			stub := dynamoRestaurantStorerStub{writeError: tc.stubError, canceled: tc.canceled}
+			if !tc.notExist {
+				stub.restaurantId = "restId"
+			}
+			rs := ReviewStorage{
+				Client:           stub,
+				Table:            "ReviewsTable-Test",
+				RestaurantsTable: "RestaurantsTable-Test",
+			}
+			actor := tc.actor
+			if actor.Id == "" {
+				actor = testOwner
+			}
+			err := rs.DeleteReview(DefaultTenant, "restId", "reviewId", actor)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_RatingAggregates(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		item    restaurantItem
+		count   *int
+		average *float64
+	}{
+		{
+			name: "no reviews",
+		},
+		{
+			name:    "reviews",
+			item:    restaurantItem{RatingCount: 3, RatingSum: 13},
+			count:   intPtr(3),
+			average: floatPtr(4.33),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			restaurant := tc.item.restaurant()
+
+			assert.Equal(t, tc.count, restaurant.RatingCount)
+			assert.Equal(t, tc.average, restaurant.RatingAverage)
+		})
+	}
+}
+
+// reviewStub returns one page of reviews per Query, starting after the
+// review in the ExclusiveStartKey.
+type reviewStub struct {
+	dynamoRestaurantStorerStub
+	reviewIds []string
+}
+
+func (s reviewStub) Query(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+
+	ids := s.reviewIds
+	if input.ExclusiveStartKey != nil {
+		var startKey map[string]string
+		_ = attributevalue.UnmarshalMap(input.ExclusiveStartKey, &startKey)
+		for i, id := range ids {
+			if id == startKey[reviewKey] {
+				ids = ids[i+1:]
+				break
+			}
+		}
+	}
+
+	output := &dynamodb.QueryOutput{}
+	for i, id := range ids {
+		if int32(i) == *input.Limit {
+			output.LastEvaluatedKey = map[string]types.AttributeValue{
+				key:       &types.AttributeValueMemberS{Value: "restId"},
+				reviewKey: &types.AttributeValueMemberS{Value: ids[i-1]},
+			}
+			break
+		}
+		id := id
+		av, err := attributevalue.MarshalMap(reviewItem{RestaurantId: "restId", ReviewId: id, Review: model.Review{Id: &id, Rating: 5}, Rating: 5})
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	return output, nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,434 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+	"sort"
+)
+
+// The tags table has TagId, the key of the tag in its tenant, as partition
+// key. It holds the vocabulary of tags the restaurants of each tenant can be
+// tagged with.
+const tagKey = "TagId"
+
+type TagStorage struct {
+	Client dynamoRestaurantStorer
+	Table  string
+	// MembersTable holds the restaurants having each tag
+	MembersTable string
+}
+
+// Tenant is empty for the tags of the default tenant. Members is the
+// number of restaurants having the tag, counted in the same transaction as
+// the restaurant (see countWrites), so a tag is only deleted when it has
+// none and a restaurant is only tagged with a tag that exists.
+type tagItem struct {
+	TagId   string
+	Tenant  string `dynamodbav:",omitempty"`
+	Tag     model.Tag
+	Members int64 `dynamodbav:",omitempty"`
+}
+
+// The tag members table has TagId, the key of the tag in its tenant, as
+// partition key and RestaurantId as sort key. It has an item for every
+// restaurant having the tag, written in the same transaction as the
+// restaurant, with every tag of the restaurant for the facet counts. The
+// deleted restaurants have no members.
+type memberItem struct {
+	TagId        string
+	RestaurantId string
+	Tags         []string
+}
+
+func NewTag(cfg aws.Config, table, membersTable string) TagStorage {
+	return TagStorage{
+		Client:       dynamodb.NewFromConfig(cfg),
+		Table:        table,
+		MembersTable: membersTable,
+	}
+}
+
+// ListTags returns the tags of the tenant, sorted by category and ID.
+func (ts TagStorage) ListTags(tenant string) ([]model.Tag, error) {
+	items, err := ts.tagItems(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]model.Tag, 0, len(items))
+	for _, item := range items {
+		tags = append(tags, item.Tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Category != tags[j].Category {
+			return tags[i].Category < tags[j].Category
+		}
+		return tags[i].Id < tags[j].Id
+	})
+	return tags, nil
+}
+
+// Facets returns the number of restaurants of the tenant having each tag,
+// read from the counts of the vocabulary. The tags without restaurants are
+// left out.
+func (ts TagStorage) Facets(tenant string) (map[string]int, error) {
+	items, err := ts.tagItems(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	facets := map[string]int{}
+	for _, item := range items {
+		if item.Members > 0 {
+			facets[item.Tag.Id] = int(item.Members)
+		}
+	}
+	return facets, nil
+}
+
+// tagItems returns the tag items of the tenant. The vocabulary is small, so
+// every page of the table is read.
+func (ts TagStorage) tagItems(tenant string) ([]tagItem, error) {
+	expr, err := expression.NewBuilder().WithFilter(tenantCondition(tenant)).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []tagItem
+	var startKey map[string]types.AttributeValue
+	for {
+		input := dynamodb.ScanInput{
+			TableName:                 aws.String(ts.Table),
+			ExclusiveStartKey:         startKey,
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}
+
+		data, err := ts.Client.Scan(context.Background(), &input)
+		if err != nil {
+			return nil, fmt.Errorf("error listing tags in dynamo: %w", err)
+		}
+
+		var items []tagItem
+		if err = attributevalue.UnmarshalListOfMaps(data.Items, &items); err != nil {
+			return nil, fmt.Errorf("error unmarshalling value: %w", err)
+		}
+		tags = append(tags, items...)
+
+		if len(data.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = data.LastEvaluatedKey
+	}
+	return tags, nil
+}
+
+// SaveTag adds the tag to the vocabulary of the tenant, or replaces the tag
+// with the same ID. The count of its restaurants is kept.
+func (ts TagStorage) SaveTag(tenant string, tag model.Tag) error {
+	log.Printf("TagStorage.SaveTag tenant: %s  tagId: %s\n", tenant, tag.Id)
+
+	update := expression.Set(expression.Name("Tag"), expression.Value(tag))
+	if tenant != DefaultTenant {
+		update = update.Set(expression.Name("Tenant"), expression.Value(tenant))
+	}
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			tagKey: &types.AttributeValueMemberS{Value: tenantKey(tenant, tag.Id)},
+		},
+		TableName:                 aws.String(ts.Table),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	_, err = ts.Client.UpdateItem(context.Background(), &input)
+	if err != nil {
+		return fmt.Errorf("error saving tag %q in dynamo: %w", tag.Id, err)
+	}
+	return nil
+}
+
+// DeleteTag removes the tag from the vocabulary of the tenant.
+// ErrTagNotFound is returned when the tag does not exist, and ErrTagInUse
+// when restaurants have it. The delete is conditional on the count of the
+// restaurants of the tag, so a restaurant tagged concurrently either fails
+// or keeps the tag.
+func (ts TagStorage) DeleteTag(tenant, tagId string) error {
+	log.Printf("TagStorage.DeleteTag tenant: %s  tagId: %s\n", tenant, tagId)
+
+	unused := expression.AttributeNotExists(expression.Name("Members")).
+		Or(expression.Name("Members").Equal(expression.Value(0)))
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeExists(expression.Name(tagKey)).And(tenantCondition(tenant), unused)).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	tagKeys := map[string]types.AttributeValue{
+		tagKey: &types.AttributeValueMemberS{Value: tenantKey(tenant, tagId)},
+	}
+	input := dynamodb.DeleteItemInput{
+		Key:                       tagKeys,
+		TableName:                 aws.String(ts.Table),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	_, err = ts.Client.DeleteItem(context.Background(), &input)
+	if err == nil {
+		return nil
+	}
+	if !conditionFailed(err) {
+		return fmt.Errorf("error deleting tag %q in dynamo: %w", tagId, err)
+	}
+
+	// The tag is either missing or in use
+	get := dynamodb.GetItemInput{
+		Key:            tagKeys,
+		TableName:      aws.String(ts.Table),
+		ConsistentRead: aws.Bool(true),
+	}
+	data, err := ts.Client.GetItem(context.Background(), &get)
+	if err != nil {
+		return fmt.Errorf("error getting tag %q in dynamo: %w", tagId, err)
+	}
+	if data.Item == nil {
+		return ErrTagNotFound
+	}
+	return ErrTagInUse
+}
+
+// Tagged returns the IDs of the restaurants of the tenant having every tag,
+// sorted, and the number of them having each tag. The members of the first
+// tag are queried and filtered on the other tags. Without tags, there are
+// no restaurants; the counts of every restaurant are returned by Facets.
+func (ts TagStorage) Tagged(tenant string, tags []string) ([]string, map[string]int, error) {
+	log.Printf("TagStorage.Tagged tenant: %s  tags: %v\n", tenant, tags)
+
+	var members []memberItem
+	if len(tags) > 0 {
+		var err error
+		if members, err = ts.members(tenant, tags[0], tags[1:]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	restaurantIds := []string{}
+	facets := map[string]int{}
+	seen := map[string]bool{}
+	for _, member := range members {
+		if seen[member.RestaurantId] {
+			continue
+		}
+		seen[member.RestaurantId] = true
+		restaurantIds = append(restaurantIds, member.RestaurantId)
+		for _, tag := range member.Tags {
+			facets[tag]++
+		}
+	}
+	sort.Strings(restaurantIds)
+	return restaurantIds, facets, nil
+}
+
+// members returns the members of the tag of the tenant having the other
+// tags too.
+func (ts TagStorage) members(tenant, tagId string, others []string) ([]memberItem, error) {
+	builder := expression.NewBuilder().WithKeyCondition(expression.Key(tagKey).Equal(expression.Value(tenantKey(tenant, tagId))))
+	if len(others) > 0 {
+		filter := expression.Contains(expression.Name("Tags"), others[0])
+		for _, tag := range others[1:] {
+			filter = filter.And(expression.Contains(expression.Name("Tags"), tag))
+		}
+		builder = builder.WithFilter(filter)
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	input := dynamodb.QueryInput{
+		TableName:                 aws.String(ts.MembersTable),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	var members []memberItem
+	paginator := dynamodb.NewQueryPaginator(ts.Client, &input)
+	for paginator.HasMorePages() {
+		data, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("error getting restaurants of tag %q in dynamo: %w", tagId, err)
+		}
+
+		var items []memberItem
+		if err = attributevalue.UnmarshalListOfMaps(data.Items, &items); err != nil {
+			return nil, fmt.Errorf("error unmarshalling value: %w", err)
+		}
+		members = append(members, items...)
+	}
+	return members, nil
+}
+
+// tagWrites returns the transaction items that update the members and the
+// counts of the tags of the restaurant (see memberWrites and countWrites).
+func (rs RestaurantStorage) tagWrites(tenant, restaurantId string, previous, current *model.Restaurant) ([]types.TransactWriteItem, error) {
+	members, err := memberWrites(rs.TagMembersTable, tenant, restaurantId, previous, current)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := countWrites(rs.TagsTable, tenant, previous, current)
+	if err != nil {
+		return nil, err
+	}
+	return append(members, counts...), nil
+}
+
+// memberWrites returns the transaction items that update the members of the
+// tags of the restaurant of the tenant, whose ID is restaurantId, for a
+// change from previous to current, nil when it does not exist. When the
+// tags change, every member is written again with the current tags.
+func memberWrites(table, tenant, restaurantId string, previous, current *model.Restaurant) ([]types.TransactWriteItem, error) {
+	before, after := restaurantTags(previous), restaurantTags(current)
+	if sameTags(before, after) {
+		return nil, nil
+	}
+
+	var items []types.TransactWriteItem
+	kept := make(map[string]bool, len(after))
+	for _, tag := range after {
+		kept[tag] = true
+		av, err := attributevalue.MarshalMap(memberItem{TagId: tenantKey(tenant, tag), RestaurantId: restaurantId, Tags: after})
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling value: %w", err)
+		}
+		items = append(items, types.TransactWriteItem{
+			Put: &types.Put{
+				Item:      av,
+				TableName: aws.String(table),
+			},
+		})
+	}
+	for _, tag := range before {
+		if kept[tag] {
+			continue
+		}
+		items = append(items, types.TransactWriteItem{
+			Delete: &types.Delete{
+				Key: map[string]types.AttributeValue{
+					tagKey: &types.AttributeValueMemberS{Value: tenantKey(tenant, tag)},
+					key:    &types.AttributeValueMemberS{Value: restaurantId},
+				},
+				TableName: aws.String(table),
+			},
+		})
+	}
+	return items, nil
+}
+
+// countWrites returns the transaction items that count the restaurant in
+// the tags of the tenant, in the tags table, for a change from previous to
+// current, nil when it does not exist. The count of an added tag is
+// incremented and the count of a removed tag decremented, on the condition
+// that the tag exists, so the transaction fails when a tag was deleted
+// from the vocabulary.
+func countWrites(table, tenant string, previous, current *model.Restaurant) ([]types.TransactWriteItem, error) {
+	before, after := restaurantTags(previous), restaurantTags(current)
+
+	var items []types.TransactWriteItem
+	for _, delta := range []struct {
+		tags  []string
+		value int
+	}{{missing(after, before), 1}, {missing(before, after), -1}} {
+		for _, tag := range delta.tags {
+			update, err := countUpdate(table, tenant, tag, delta.value)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, types.TransactWriteItem{Update: &update})
+		}
+	}
+	return items, nil
+}
+
+// countUpdate returns the update that adds delta to the count of the
+// restaurants of the tag of the tenant, on the condition that the tag
+// exists.
+func countUpdate(table, tenant, tagId string, delta int) (types.Update, error) {
+	expr, err := expression.NewBuilder().
+		WithUpdate(expression.Add(expression.Name("Members"), expression.Value(delta))).
+		WithCondition(expression.AttributeExists(expression.Name(tagKey))).
+		Build()
+	if err != nil {
+		return types.Update{}, err
+	}
+
+	return types.Update{
+		Key: map[string]types.AttributeValue{
+			tagKey: &types.AttributeValueMemberS{Value: tenantKey(tenant, tagId)},
+		},
+		TableName:                 aws.String(table),
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, nil
+}
+
+// restaurantTags returns the tags of the restaurant, nil when it does not
+// exist.
+func restaurantTags(restaurant *model.Restaurant) []string {
+	if restaurant == nil || restaurant.Tags == nil {
+		return nil
+	}
+	return *restaurant.Tags
+}
+
+// missing returns the tags that are not in others.
+func missing(tags, others []string) []string {
+	other := make(map[string]bool, len(others))
+	for _, tag := range others {
+		other[tag] = true
+	}
+	var missing []string
+	for _, tag := range tags {
+		if !other[tag] {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// sameTags reports whether both lists have the same tags, in any order.
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	tags := make(map[string]bool, len(a))
+	for _, tag := range a {
+		tags[tag] = true
+	}
+	for _, tag := range b {
+		if !tags[tag] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,642 @@
+package dynamo
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func Test_ListTags(t *testing.T) {
+	t.Parallel()
+
+	thai := model.Tag{Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"}
+	japanese := model.Tag{Id: "japanese", Category: model.TagCategoryCuisine, Label: "Japanese"}
+	terrace := model.Tag{Id: "outdoor-seating", Category: model.TagCategoryAmenity, Label: "Outdoor seating"}
+
+	testCases := []struct {
+		name      string
+		pages     [][]model.Tag
+		stubError string
+		expected  []model.Tag
+		errMsg    string
+	}{
+		{
+			name:     "sorted by category and ID across pages",
+			pages:    [][]model.Tag{{thai, terrace}, {japanese}},
+			expected: []model.Tag{terrace, japanese, thai},
+		},
+		{
+			name:     "no tags",
+			expected: []model.Tag{},
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error listing tags in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ts := TagStorage{
+				Client: tagStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, pages: tc.pages},
+				Table:  "TagsTable-Test",
+			}
+			tags, err := ts.ListTags("acme")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.expected, tags)
+			}
+		})
+	}
+}
+
+func Test_Tagged(t *testing.T) {
+	t.Parallel()
+
+	members := []memberItem{
+		{TagId: "acme#japanese", RestaurantId: "r3", Tags: []string{"japanese", "price-3"}},
+		{TagId: "acme#japanese", RestaurantId: "r1", Tags: []string{"japanese", "outdoor-seating", "price-2"}},
+		{TagId: "acme#outdoor-seating", RestaurantId: "r1", Tags: []string{"japanese", "outdoor-seating", "price-2"}},
+		{TagId: "acme#outdoor-seating", RestaurantId: "r2", Tags: []string{"cafe", "outdoor-seating"}},
+		{TagId: "japanese", RestaurantId: "r9", Tags: []string{"japanese"}},
+	}
+	vocabulary := []model.Tag{
+		{Id: "japanese", Category: model.TagCategoryCuisine, Label: "Japanese"},
+		{Id: "outdoor-seating", Category: model.TagCategoryAmenity, Label: "Outdoor seating"},
+		{Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"},
+	}
+
+	testCases := []struct {
+		name          string
+		tags          []string
+		queryError    string
+		restaurantIds []string
+		facets        map[string]int
+		errMsg        string
+	}{
+		{
+			name:          "tag",
+			tags:          []string{"japanese"},
+			restaurantIds: []string{"r1", "r3"},
+			facets:        map[string]int{"japanese": 2, "outdoor-seating": 1, "price-2": 1, "price-3": 1},
+		},
+		{
+			name:          "every tag must match",
+			tags:          []string{"outdoor-seating", "japanese"},
+			restaurantIds: []string{"r1"},
+			facets:        map[string]int{"japanese": 1, "outdoor-seating": 1, "price-2": 1},
+		},
+		{
+			name:          "no restaurants",
+			tags:          []string{"thai"},
+			restaurantIds: []string{},
+			facets:        map[string]int{},
+		},
+		{
+			name:          "no tags",
+			restaurantIds: []string{},
+			facets:        map[string]int{},
+		},
+		{
+			name:       "error",
+			tags:       []string{"japanese"},
+			queryError: "an error occurred",
+			errMsg:     "error getting restaurants of tag \"japanese\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ts := TagStorage{
+				Client:       tagStub{pages: [][]model.Tag{vocabulary}, members: members, queryError: tc.queryError},
+				Table:        "TagsTable-Test",
+				MembersTable: "TagMembersTable-Test",
+			}
+			restaurantIds, facets, err := ts.Tagged("acme", tc.tags)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.restaurantIds, restaurantIds)
+			assert.Equal(t, tc.facets, facets)
+		})
+	}
+}
+
+func Test_Facets(t *testing.T) {
+	t.Parallel()
+
+	vocabulary := []model.Tag{
+		{Id: "japanese", Category: model.TagCategoryCuisine, Label: "Japanese"},
+		{Id: "outdoor-seating", Category: model.TagCategoryAmenity, Label: "Outdoor seating"},
+		{Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"},
+	}
+
+	testCases := []struct {
+		name      string
+		counts    map[string]int64
+		stubError string
+		expected  map[string]int
+		errMsg    string
+	}{
+		{
+			name:     "counts of the tags",
+			counts:   map[string]int64{"japanese": 2, "outdoor-seating": 1},
+			expected: map[string]int{"japanese": 2, "outdoor-seating": 1},
+		},
+		{
+			name:     "no restaurants",
+			counts:   map[string]int64{"thai": 0},
+			expected: map[string]int{},
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error listing tags in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ts := TagStorage{
+				Client: tagStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, pages: [][]model.Tag{vocabulary}, counts: tc.counts},
+				Table:  "TagsTable-Test",
+			}
+			facets, err := ts.Facets("acme")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, facets)
+		})
+	}
+}
+
+func Test_memberWrites(t *testing.T) {
+	t.Parallel()
+	restId := "restId"
+	tagged := func(tags ...string) *model.Restaurant {
+		return &model.Restaurant{Id: &restId, Tags: &tags}
+	}
+
+	testCases := []struct {
+		name     string
+		previous *model.Restaurant
+		current  *model.Restaurant
+		puts     []memberItem
+		deletes  []string
+	}{
+		{
+			name:    "created",
+			current: tagged("thai", "price-2"),
+			puts: []memberItem{
+				{TagId: "acme#thai", RestaurantId: restId, Tags: []string{"thai", "price-2"}},
+				{TagId: "acme#price-2", RestaurantId: restId, Tags: []string{"thai", "price-2"}},
+			},
+		},
+		{
+			name:     "tags changed",
+			previous: tagged("thai", "price-2"),
+			current:  tagged("thai", "price-1"),
+			puts: []memberItem{
+				{TagId: "acme#thai", RestaurantId: restId, Tags: []string{"thai", "price-1"}},
+				{TagId: "acme#price-1", RestaurantId: restId, Tags: []string{"thai", "price-1"}},
+			},
+			deletes: []string{"acme#price-2"},
+		},
+		{
+			name:     "same tags in another order",
+			previous: tagged("thai", "price-2"),
+			current:  tagged("price-2", "thai"),
+		},
+		{
+			name:     "no tags",
+			previous: &model.Restaurant{Id: &restId},
+			current:  &model.Restaurant{Id: &restId, Name: "Thai Garden"},
+		},
+		{
+			name:     "deleted",
+			previous: tagged("thai"),
+			deletes:  []string{"acme#thai"},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			items, err := memberWrites("TagMembersTable-Test", "acme", restId, tc.previous, tc.current)
+			assert.Nil(t, err)
+
+			var puts []memberItem
+			var deletes []string
+			for _, item := range items {
+				if item.Put != nil {
+					assert.Equal(t, "TagMembersTable-Test", *item.Put.TableName)
+					var member memberItem
+					assert.Nil(t, attributevalue.UnmarshalMap(item.Put.Item, &member))
+					puts = append(puts, member)
+				}
+				if item.Delete != nil {
+					var tagId, restaurantId string
+					assert.Nil(t, attributevalue.Unmarshal(item.Delete.Key[tagKey], &tagId))
+					assert.Nil(t, attributevalue.Unmarshal(item.Delete.Key[key], &restaurantId))
+					assert.Equal(t, restId, restaurantId)
+					deletes = append(deletes, tagId)
+				}
+			}
+			assert.Equal(t, tc.puts, puts)
+			assert.Equal(t, tc.deletes, deletes)
+		})
+	}
+}
+
+func Test_countWrites(t *testing.T) {
+	t.Parallel()
+	restId := "restId"
+	tagged := func(tags ...string) *model.Restaurant {
+		return &model.Restaurant{Id: &restId, Tags: &tags}
+	}
+
+	testCases := []struct {
+		name     string
+		previous *model.Restaurant
+		current  *model.Restaurant
+		counts   map[string]int
+	}{
+		{
+			name:    "created",
+			current: tagged("thai", "price-2"),
+			counts:  map[string]int{"acme#thai": 1, "acme#price-2": 1},
+		},
+		{
+			name:     "tags changed",
+			previous: tagged("thai", "price-2"),
+			current:  tagged("thai", "price-1"),
+			counts:   map[string]int{"acme#price-1": 1, "acme#price-2": -1},
+		},
+		{
+			name:     "same tags in another order",
+			previous: tagged("thai", "price-2"),
+			current:  tagged("price-2", "thai"),
+			counts:   map[string]int{},
+		},
+		{
+			name:     "deleted",
+			previous: tagged("thai"),
+			counts:   map[string]int{"acme#thai": -1},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			items, err := countWrites("TagsTable-Test", "acme", tc.previous, tc.current)
+			assert.Nil(t, err)
+
+			// Every count is conditional on the tag existing
+			counts := map[string]int{}
+			for _, item := range items {
+				if assert.NotNil(t, item.Update) {
+					assert.Equal(t, "TagsTable-Test", *item.Update.TableName)
+					assert.NotNil(t, item.Update.ConditionExpression)
+					var tagId string
+					var delta int
+					assert.Nil(t, attributevalue.Unmarshal(item.Update.Key[tagKey], &tagId))
+					for _, v := range item.Update.ExpressionAttributeValues {
+						assert.Nil(t, attributevalue.Unmarshal(v, &delta))
+					}
+					counts[tagId] = delta
+				}
+			}
+			assert.Equal(t, tc.counts, counts)
+		})
+	}
+}
+
+func Test_UpdateTagMembers(t *testing.T) {
+	t.Parallel()
+	restId := "restId"
+
+	var transaction dynamodb.TransactWriteItemsInput
+	rs := RestaurantStorage{
+		Client: historyStub{
+			dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{restaurantId: restId, tenant: "acme"},
+			transaction:                &transaction,
+		},
+		Table:           "RestaurantsTable-Test",
+		HistoryTable:    "HistoryTable-Test",
+		TagsTable:       "TagsTable-Test",
+		TagMembersTable: "TagMembersTable-Test",
+	}
+	tags := []string{"thai"}
+	_, _, err := rs.Update("acme", model.Restaurant{Id: &restId, Tags: &tags}, nil, testOwner)
+
+	// The member and the count of the new tag are written with the
+	// restaurant
+	assert.Nil(t, err)
+	if assert.Len(t, transaction.TransactItems, 4) {
+		var member memberItem
+		assert.Nil(t, attributevalue.UnmarshalMap(transaction.TransactItems[2].Put.Item, &member))
+		assert.Equal(t, memberItem{TagId: "acme#thai", RestaurantId: restId, Tags: tags}, member)
+		var tagId string
+		assert.Nil(t, attributevalue.Unmarshal(transaction.TransactItems[3].Update.Key[tagKey], &tagId))
+		assert.Equal(t, "acme#thai", tagId)
+		assert.Equal(t, "TagsTable-Test", *transaction.TransactItems[3].Update.TableName)
+	}
+}
+
+func Test_SaveTag(t *testing.T) {
+	t.Parallel()
+
+	tag := model.Tag{Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"}
+
+	testCases := []struct {
+		name      string
+		tenant    string
+		tagId     string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name:  "happy path",
+			tagId: "thai",
+		},
+		{
+			name:   "tag of a tenant",
+			tenant: "acme",
+			tagId:  "acme#thai",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error saving tag \"thai\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var update dynamodb.UpdateItemInput
+			ts := TagStorage{
+				Client: tagStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{writeError: tc.stubError}, update: &update},
+				Table:  "TagsTable-Test",
+			}
+			err := ts.SaveTag(tc.tenant, tag)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+
+			// The tag is keyed by its key in the tenant, and only the tag and
+			// its tenant are set so the count is kept
+			assert.Nil(t, err)
+			var tagId, tenant string
+			var saved model.Tag
+			assert.Nil(t, attributevalue.Unmarshal(update.Key[tagKey], &tagId))
+			for _, v := range update.ExpressionAttributeValues {
+				if _, ok := v.(*types.AttributeValueMemberM); ok {
+					assert.Nil(t, attributevalue.Unmarshal(v, &saved))
+				} else {
+					assert.Nil(t, attributevalue.Unmarshal(v, &tenant))
+				}
+			}
+			assert.Equal(t, tc.tagId, tagId)
+			assert.Equal(t, tc.tenant, tenant)
+			assert.Equal(t, tag, saved)
+		})
+	}
+}
+
+func Test_DeleteTag(t *testing.T) {
+	t.Parallel()
+
+	thai := model.Tag{Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"}
+
+	testCases := []struct {
+		name      string
+		stored    []model.Tag
+		stubError string
+		readError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:      "tag in use",
+			stored:    []model.Tag{thai},
+			stubError: conditionalCheckFailed,
+			errMsg:    "tag is assigned to restaurants",
+		},
+		{
+			name:      "tag does not exist",
+			stubError: conditionalCheckFailed,
+			errMsg:    "tag not found",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error deleting tag \"thai\" in dynamo: an error occurred",
+		},
+		{
+			name:      "read error",
+			stubError: conditionalCheckFailed,
+			readError: "an error occurred",
+			errMsg:    "error getting tag \"thai\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var input dynamodb.DeleteItemInput
+			ts := TagStorage{
+				Client: tagStub{
+					dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.readError, writeError: tc.stubError},
+					pages:                      [][]model.Tag{tc.stored},
+					delete:                     &input,
+				},
+				Table:        "TagsTable-Test",
+				MembersTable: "TagMembersTable-Test",
+			}
+			err := ts.DeleteTag("acme", "thai")
+
+			// The delete is conditional on the count of the restaurants of
+			// the tag
+			names := []string{}
+			for _, name := range input.ExpressionAttributeNames {
+				names = append(names, name)
+			}
+			assert.Contains(t, names, "Members")
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+// tagStub serves the tags one page at a time, using the index of the next
+// page as key, with the count of their restaurants, and captures the
+// UpdateItem and DeleteItem inputs. GetItem returns the tag of the pages
+// whose key in the acme tenant is the key. Query returns the members whose
+// key is one of the values of the expression and whose tags are all the
+// other values.
+type tagStub struct {
+	dynamoRestaurantStorerStub
+	pages      [][]model.Tag
+	counts     map[string]int64
+	update     *dynamodb.UpdateItemInput
+	delete     *dynamodb.DeleteItemInput
+	members    []memberItem
+	queryError string
+}
+
+func (s tagStub) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if s.update != nil {
+		*s.update = *input
+	}
+	return s.dynamoRestaurantStorerStub.UpdateItem(ctx, input, optFns...)
+}
+
+func (s tagStub) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if s.delete != nil {
+		*s.delete = *input
+	}
+	return s.dynamoRestaurantStorerStub.DeleteItem(ctx, input, optFns...)
+}
+
+func (s tagStub) GetItem(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+
+	var tagId string
+	_ = attributevalue.Unmarshal(input.Key[tagKey], &tagId)
+	for _, page := range s.pages {
+		for _, tag := range page {
+			if tenantKey("acme", tag.Id) != tagId {
+				continue
+			}
+			av, err := attributevalue.MarshalMap(tagItem{TagId: tagId, Tenant: "acme", Tag: tag, Members: s.counts[tag.Id]})
+			if err != nil {
+				return nil, err
+			}
+			return &dynamodb.GetItemOutput{Item: av}, nil
+		}
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (s tagStub) Scan(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+
+	page := 0
+	if input.ExclusiveStartKey != nil {
+		_ = attributevalue.Unmarshal(input.ExclusiveStartKey[tagKey], &page)
+	}
+
+	output := &dynamodb.ScanOutput{}
+	if page >= len(s.pages) {
+		return output, nil
+	}
+	for _, tag := range s.pages[page] {
+		av, err := attributevalue.MarshalMap(tagItem{TagId: tag.Id, Tenant: "acme", Tag: tag, Members: s.counts[tag.Id]})
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	if page+1 < len(s.pages) {
+		output.LastEvaluatedKey = map[string]types.AttributeValue{tagKey: &types.AttributeValueMemberN{Value: strconv.Itoa(page + 1)}}
+	}
+	return output, nil
+}
+
+func (s tagStub) Query(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if s.queryError != "" {
+		return nil, stubErr(s.queryError)
+	}
+
+	values := map[string]bool{}
+	for _, v := range input.ExpressionAttributeValues {
+		if sv, ok := v.(*types.AttributeValueMemberS); ok {
+			values[sv.Value] = true
+		}
+	}
+
+	output := &dynamodb.QueryOutput{}
+	for _, member := range s.members {
+		if !values[member.TagId] {
+			continue
+		}
+		matches := true
+		for value := range values {
+			if value != member.TagId && !containsTag(member.Tags, value) {
+				matches = false
+			}
+		}
+		if !matches {
+			continue
+		}
+		av, err := attributevalue.MarshalMap(member)
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	return output, nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
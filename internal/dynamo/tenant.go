@@ -0,0 +1,49 @@
+package dynamo
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"strings"
+)
+
+// The restaurants of the tenants are isolated by their keys: every item of a
+// restaurant, in every table, is keyed by the tenant, tenantSeparator and the
+// restaurant ID, so the restaurant cannot be read or written with the ID
+// alone. The default tenant has no prefix, so the restaurants stored before
+// tenants were introduced belong to it.
+const (
+	DefaultTenant   = ""
+	tenantSeparator = "#"
+)
+
+// tenantKey returns the value of the key of an item of the tenant. An ID of
+// the default tenant with the separator is prefixed with the separator, so
+// it cannot be the key of an item of another tenant.
+func tenantKey(tenant, id string) string {
+	if tenant == DefaultTenant && !strings.Contains(id, tenantSeparator) {
+		return id
+	}
+	return tenant + tenantSeparator + id
+}
+
+// tenantCondition matches the items of the tenant, restaurants or API keys.
+func tenantCondition(tenant string) expression.ConditionBuilder {
+	if tenant == DefaultTenant {
+		return expression.AttributeNotExists(expression.Name("Tenant"))
+	}
+	return expression.Name("Tenant").Equal(expression.Value(tenant))
+}
+
+// tenantStartKey reports whether the start key of a pagination token is
+// the key of a restaurant of the tenant, so a token cannot resume the scan
+// of another tenant.
+func tenantStartKey(tenant string, startKey map[string]types.AttributeValue) bool {
+	v, ok := startKey[key].(*types.AttributeValueMemberS)
+	if !ok || len(startKey) != 1 {
+		return false
+	}
+	if tenant == DefaultTenant {
+		return !strings.Contains(v.Value, tenantSeparator) || strings.HasPrefix(v.Value, tenantSeparator)
+	}
+	return strings.HasPrefix(v.Value, tenant+tenantSeparator)
+}
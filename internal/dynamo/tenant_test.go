@@ -0,0 +1,95 @@
+package dynamo
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_TenantKey(t *testing.T) {
+	t.Parallel()
+
+	// The restaurants stored before tenants were introduced keep their key
+	assert.Equal(t, "restId", tenantKey(DefaultTenant, "restId"))
+	assert.Equal(t, "acme#restId", tenantKey("acme", "restId"))
+
+	// An ID with the separator cannot reach the items of another tenant
+	assert.Equal(t, "#acme#restId", tenantKey(DefaultTenant, "acme#restId"))
+	assert.Equal(t, "other#acme#restId", tenantKey("other", "acme#restId"))
+}
+
+func Test_NewRestaurantItem_Tenant(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		tenant        string
+		restaurantId  string
+		geohashPrefix string
+	}{
+		{
+			name:          "default tenant",
+			tenant:        DefaultTenant,
+			restaurantId:  "restId",
+			geohashPrefix: "c23n",
+		},
+		{
+			name:          "tenant",
+			tenant:        "acme",
+			restaurantId:  "acme#restId",
+			geohashPrefix: "acme#c23n",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			item := newRestaurantItem(tc.tenant, restaurantAt("restId", "47.606209,-122.332071"))
+
+			assert.Equal(t, tc.restaurantId, item.RestaurantId)
+			assert.Equal(t, tc.tenant, item.Tenant)
+			assert.Equal(t, tc.geohashPrefix, item.GeohashPrefix)
+			assert.Equal(t, "c23nb62w2", item.Geohash)
+			assert.Equal(t, "restId", *item.Restaurant.Id)
+		})
+	}
+}
+
+func Test_TenantCondition(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		tenant    string
+		condition string
+		values    int
+	}{
+		{
+			name:      "default tenant",
+			tenant:    DefaultTenant,
+			condition: "attribute_not_exists (#0)",
+		},
+		{
+			name:      "tenant",
+			tenant:    "acme",
+			condition: "#0 = :0",
+			values:    1,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			expr, err := expression.NewBuilder().WithCondition(tenantCondition(tc.tenant)).Build()
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.condition, *expr.Condition())
+			assert.Equal(t, map[string]string{"#0": "Tenant"}, expr.Names())
+			assert.Len(t, expr.Values(), tc.values)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package dynamo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"strconv"
+)
+
+// ErrInvalidToken is returned when a pagination token cannot be decoded.
+var ErrInvalidToken = errors.New("invalid pagination token")
+
+// tokenValue is an attribute of a key in a token. The type of the attribute
+// is kept, as the sort keys can be numbers (such as the revisions of the
+// history table), and an ExclusiveStartKey of another type is rejected.
+type tokenValue struct {
+	S *string `json:",omitempty"`
+	N *string `json:",omitempty"`
+	B []byte  `json:",omitempty"`
+}
+
+// encodeToken converts the LastEvaluatedKey of a Scan or Query into an
+// opaque token that can be handed to clients. An empty key (last page)
+// results in an empty token.
+func encodeToken(lastKey map[string]types.AttributeValue) (string, error) {
+	if len(lastKey) == 0 {
+		return "", nil
+	}
+
+	k := make(map[string]tokenValue, len(lastKey))
+	for name, av := range lastKey {
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			k[name] = tokenValue{S: &v.Value}
+		case *types.AttributeValueMemberN:
+			k[name] = tokenValue{N: &v.Value}
+		case *types.AttributeValueMemberB:
+			k[name] = tokenValue{B: v.Value}
+		default:
+			return "", fmt.Errorf("unsupported key attribute %q of type %T", name, av)
+		}
+	}
+
+	b, err := json.Marshal(k)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeToken converts a token created by encodeToken back into an
+// ExclusiveStartKey. An empty token results in a nil key (first page).
+func decodeToken(token string) (map[string]types.AttributeValue, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var k map[string]tokenValue
+	if err = json.Unmarshal(b, &k); err != nil || len(k) == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	startKey := make(map[string]types.AttributeValue, len(k))
+	for name, v := range k {
+		switch {
+		case v.S != nil && v.N == nil && v.B == nil:
+			startKey[name] = &types.AttributeValueMemberS{Value: *v.S}
+		case v.N != nil && v.S == nil && v.B == nil:
+			if _, err = strconv.ParseFloat(*v.N, 64); err != nil {
+				return nil, ErrInvalidToken
+			}
+			startKey[name] = &types.AttributeValueMemberN{Value: *v.N}
+		case v.B != nil && v.S == nil && v.N == nil:
+			startKey[name] = &types.AttributeValueMemberB{Value: v.B}
+		default:
+			return nil, ErrInvalidToken
+		}
+	}
+
+	return startKey, nil
+}
@@ -0,0 +1,282 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"log"
+	"sort"
+	"time"
+)
+
+// The webhooks table has WebhookId as partition key. It holds the webhook
+// subscriptions, keyed by their key in the tenant, and the deliveries that
+// failed after every retry (the dead letters), keyed by the key in the
+// tenant of deliveryPrefix and the delivery ID.
+const (
+	webhookKey     = "WebhookId"
+	deliveryPrefix = "delivery#"
+)
+
+type WebhookStorage struct {
+	Client dynamoRestaurantStorer
+	Table  string
+}
+
+// Tenant is empty for the items of the default tenant. An item is either a
+// subscription or a dead letter.
+type webhookItem struct {
+	WebhookId string
+	Tenant    string          `dynamodbav:",omitempty"`
+	Webhook   *model.Webhook  `dynamodbav:",omitempty"`
+	Delivery  *model.Delivery `dynamodbav:",omitempty"`
+}
+
+func NewWebhook(cfg aws.Config, table string) WebhookStorage {
+	return WebhookStorage{
+		Client: dynamodb.NewFromConfig(cfg),
+		Table:  table,
+	}
+}
+
+// SaveWebhook stores a new webhook subscription of the tenant, with its
+// secret.
+func (ws WebhookStorage) SaveWebhook(tenant string, webhook model.Webhook) error {
+	log.Printf("WebhookStorage.SaveWebhook tenant: %s  webhookId: %s\n", tenant, webhook.Id)
+
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(webhookKey))).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	err = ws.put(webhookItem{WebhookId: tenantKey(tenant, webhook.Id), Tenant: tenant, Webhook: &webhook}, &expr)
+	if err != nil {
+		return fmt.Errorf("error saving webhook %q in dynamo: %w", webhook.Id, err)
+	}
+	return nil
+}
+
+// GetWebhook returns the webhook subscription of the tenant, with its
+// secret. ErrWebhookNotFound is returned when it does not exist.
+func (ws WebhookStorage) GetWebhook(tenant, webhookId string) (model.Webhook, error) {
+	item, err := ws.get(tenantKey(tenant, webhookId))
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("error getting webhook %q in dynamo: %w", webhookId, err)
+	}
+	if item == nil || item.Webhook == nil || item.Tenant != tenant {
+		return model.Webhook{}, ErrWebhookNotFound
+	}
+	return *item.Webhook, nil
+}
+
+// ListWebhooks returns the webhook subscriptions of the tenant, with their
+// secret, the oldest first. There are few subscriptions, so every page of
+// the table is read.
+func (ws WebhookStorage) ListWebhooks(tenant string) ([]model.Webhook, error) {
+	items, err := ws.scan(tenant, "Webhook")
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhooks in dynamo: %w", err)
+	}
+
+	webhooks := make([]model.Webhook, 0, len(items))
+	for _, item := range items {
+		webhooks = append(webhooks, *item.Webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes the webhook subscription of the tenant. Its dead
+// letters are kept, but cannot be redelivered. ErrWebhookNotFound is
+// returned when it does not exist.
+func (ws WebhookStorage) DeleteWebhook(tenant, webhookId string) error {
+	log.Printf("WebhookStorage.DeleteWebhook tenant: %s  webhookId: %s\n", tenant, webhookId)
+
+	err := ws.delete(tenantKey(tenant, webhookId), tenant, "Webhook")
+	if err != nil {
+		if conditionFailed(err) {
+			return ErrWebhookNotFound
+		}
+		return fmt.Errorf("error deleting webhook %q in dynamo: %w", webhookId, err)
+	}
+	return nil
+}
+
+// SaveDeadLetter stores the delivery of the tenant that failed after every
+// retry, or replaces it after a failed redelivery.
+func (ws WebhookStorage) SaveDeadLetter(tenant string, delivery model.Delivery) error {
+	log.Printf("WebhookStorage.SaveDeadLetter tenant: %s  deliveryId: %s\n", tenant, delivery.Id)
+
+	err := ws.put(webhookItem{WebhookId: tenantKey(tenant, deliveryPrefix+delivery.Id), Tenant: tenant, Delivery: &delivery}, nil)
+	if err != nil {
+		return fmt.Errorf("error saving delivery %q in dynamo: %w", delivery.Id, err)
+	}
+	return nil
+}
+
+// GetDeadLetter returns the failed delivery of the tenant.
+// ErrDeliveryNotFound is returned when it does not exist.
+func (ws WebhookStorage) GetDeadLetter(tenant, deliveryId string) (model.Delivery, error) {
+	item, err := ws.get(tenantKey(tenant, deliveryPrefix+deliveryId))
+	if err != nil {
+		return model.Delivery{}, fmt.Errorf("error getting delivery %q in dynamo: %w", deliveryId, err)
+	}
+	if item == nil || item.Delivery == nil || item.Tenant != tenant {
+		return model.Delivery{}, ErrDeliveryNotFound
+	}
+	return *item.Delivery, nil
+}
+
+// ListDeadLetters returns the failed deliveries of the tenant, the most
+// recent attempt first.
+func (ws WebhookStorage) ListDeadLetters(tenant string) ([]model.Delivery, error) {
+	items, err := ws.scan(tenant, "Delivery")
+	if err != nil {
+		return nil, fmt.Errorf("error listing deliveries in dynamo: %w", err)
+	}
+
+	deliveries := make([]model.Delivery, 0, len(items))
+	for _, item := range items {
+		deliveries = append(deliveries, *item.Delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return lastAttempt(deliveries[i]).After(lastAttempt(deliveries[j]))
+	})
+	return deliveries, nil
+}
+
+// DeleteDeadLetter removes the failed delivery of the tenant, once it is
+// redelivered. ErrDeliveryNotFound is returned when it does not exist.
+func (ws WebhookStorage) DeleteDeadLetter(tenant, deliveryId string) error {
+	log.Printf("WebhookStorage.DeleteDeadLetter tenant: %s  deliveryId: %s\n", tenant, deliveryId)
+
+	err := ws.delete(tenantKey(tenant, deliveryPrefix+deliveryId), tenant, "Delivery")
+	if err != nil {
+		if conditionFailed(err) {
+			return ErrDeliveryNotFound
+		}
+		return fmt.Errorf("error deleting delivery %q in dynamo: %w", deliveryId, err)
+	}
+	return nil
+}
+
+func (ws WebhookStorage) put(item webhookItem, expr *expression.Expression) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+
+	input := dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(ws.Table),
+	}
+	if expr != nil {
+		input.ConditionExpression = expr.Condition()
+		input.ExpressionAttributeNames = expr.Names()
+	}
+
+	_, err = ws.Client.PutItem(context.Background(), &input)
+	return err
+}
+
+func (ws WebhookStorage) get(key string) (*webhookItem, error) {
+	input := dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			webhookKey: &types.AttributeValueMemberS{Value: key},
+		},
+		TableName: aws.String(ws.Table),
+	}
+
+	data, err := ws.Client.GetItem(context.Background(), &input)
+	if err != nil {
+		return nil, err
+	}
+	if data.Item == nil {
+		return nil, nil
+	}
+
+	var item webhookItem
+	if err = attributevalue.UnmarshalMap(data.Item, &item); err != nil {
+		return nil, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return &item, nil
+}
+
+// scan returns the items of the tenant with the attribute, subscriptions or
+// dead letters.
+func (ws WebhookStorage) scan(tenant, attribute string) ([]webhookItem, error) {
+	filter := expression.AttributeExists(expression.Name(attribute)).And(tenantCondition(tenant))
+	expr, err := expression.NewBuilder().WithFilter(filter).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []webhookItem
+	var startKey map[string]types.AttributeValue
+	for {
+		input := dynamodb.ScanInput{
+			TableName:                 aws.String(ws.Table),
+			ExclusiveStartKey:         startKey,
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}
+
+		data, err := ws.Client.Scan(context.Background(), &input)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []webhookItem
+		if err = attributevalue.UnmarshalListOfMaps(data.Items, &page); err != nil {
+			return nil, fmt.Errorf("error unmarshalling value: %w", err)
+		}
+		items = append(items, page...)
+
+		if len(data.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		startKey = data.LastEvaluatedKey
+	}
+}
+
+// delete deletes the item of the tenant with the attribute, subscription or
+// dead letter, on the condition that it exists.
+func (ws WebhookStorage) delete(key, tenant, attribute string) error {
+	cond := expression.AttributeExists(expression.Name(attribute)).And(tenantCondition(tenant))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.DeleteItemInput{
+		Key: map[string]types.AttributeValue{
+			webhookKey: &types.AttributeValueMemberS{Value: key},
+		},
+		TableName:                 aws.String(ws.Table),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	_, err = ws.Client.DeleteItem(context.Background(), &input)
+	return err
+}
+
+// lastAttempt returns the time of the last attempt of the delivery, zero
+// when it was never attempted.
+func lastAttempt(delivery model.Delivery) time.Time {
+	if delivery.LastAttemptAt == nil {
+		return time.Time{}
+	}
+	return *delivery.LastAttemptAt
+}
@@ -0,0 +1,321 @@
+package dynamo
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testWebhook = model.Webhook{
+	Id:        "webhookId",
+	Url:       "https://partner.example.com/hooks",
+	Secret:    aws.String("0123456789abcdef"),
+	Events:    []model.WebhookEventType{model.RestaurantCreated},
+	CreatedAt: time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC),
+}
+
+func Test_SaveWebhook(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		tenant    string
+		key       string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+			key:  "webhookId",
+		},
+		{
+			name:   "webhook of a tenant",
+			tenant: "acme",
+			key:    "acme#webhookId",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error saving webhook \"webhookId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var put dynamodb.PutItemInput
+			ws := WebhookStorage{
+				Client: webhookStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, put: &put},
+				Table:  "WebhooksTable-Test",
+			}
+			err := ws.SaveWebhook(tc.tenant, testWebhook)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+
+			// The secret is stored to sign the events
+			assert.Nil(t, err)
+			var item webhookItem
+			assert.Nil(t, attributevalue.UnmarshalMap(put.Item, &item))
+			webhook := testWebhook
+			assert.Equal(t, webhookItem{WebhookId: tc.key, Tenant: tc.tenant, Webhook: &webhook}, item)
+			assert.NotNil(t, put.ConditionExpression)
+		})
+	}
+}
+
+func Test_GetWebhook(t *testing.T) {
+	t.Parallel()
+
+	webhook := testWebhook
+
+	testCases := []struct {
+		name      string
+		item      *webhookItem
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+			item: &webhookItem{WebhookId: "acme#webhookId", Tenant: "acme", Webhook: &webhook},
+		},
+		{
+			name:   "webhook does not exist",
+			errMsg: "webhook not found",
+		},
+		{
+			name:   "webhook of another tenant",
+			item:   &webhookItem{WebhookId: "acme#webhookId", Tenant: "globex", Webhook: &webhook},
+			errMsg: "webhook not found",
+		},
+		{
+			name:   "dead letter",
+			item:   &webhookItem{WebhookId: "acme#webhookId", Tenant: "acme", Delivery: &model.Delivery{Id: "webhookId"}},
+			errMsg: "webhook not found",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error getting webhook \"webhookId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ws := WebhookStorage{
+				Client: webhookStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, item: tc.item},
+				Table:  "WebhooksTable-Test",
+			}
+			got, err := ws.GetWebhook("acme", "webhookId")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, testWebhook, got)
+			}
+		})
+	}
+}
+
+func Test_ListWebhooks(t *testing.T) {
+	t.Parallel()
+
+	older, newer := testWebhook, testWebhook
+	older.Id = "older"
+	older.CreatedAt = newer.CreatedAt.Add(-time.Hour)
+
+	testCases := []struct {
+		name      string
+		items     []webhookItem
+		stubError string
+		expected  []model.Webhook
+		errMsg    string
+	}{
+		{
+			name:     "oldest first",
+			items:    []webhookItem{{WebhookId: "webhookId", Webhook: &newer}, {WebhookId: "older", Webhook: &older}},
+			expected: []model.Webhook{older, newer},
+		},
+		{
+			name:     "no webhooks",
+			expected: []model.Webhook{},
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error listing webhooks in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ws := WebhookStorage{
+				Client: webhookStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: tc.stubError}, items: tc.items},
+				Table:  "WebhooksTable-Test",
+			}
+			webhooks, err := ws.ListWebhooks(DefaultTenant)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.expected, webhooks)
+			}
+		})
+	}
+}
+
+func Test_DeleteWebhook(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		stubError string
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:      "webhook does not exist",
+			stubError: conditionalCheckFailed,
+			errMsg:    "webhook not found",
+		},
+		{
+			name:      "error",
+			stubError: "an error occurred",
+			errMsg:    "error deleting webhook \"webhookId\" in dynamo: an error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ws := WebhookStorage{
+				Client: webhookStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{writeError: tc.stubError}},
+				Table:  "WebhooksTable-Test",
+			}
+			err := ws.DeleteWebhook("acme", "webhookId")
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_DeadLetters(t *testing.T) {
+	t.Parallel()
+
+	first := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	last := first.Add(time.Hour)
+	older := model.Delivery{Id: "older", WebhookId: "webhookId", Attempts: 5, LastAttemptAt: &first}
+	newer := model.Delivery{Id: "newer", WebhookId: "webhookId", Attempts: 5, LastAttemptAt: &last}
+
+	// The dead letters are keyed apart from the webhooks
+	var put dynamodb.PutItemInput
+	ws := WebhookStorage{
+		Client: webhookStub{put: &put},
+		Table:  "WebhooksTable-Test",
+	}
+	assert.Nil(t, ws.SaveDeadLetter("acme", newer))
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "acme#delivery#newer"}, put.Item[webhookKey])
+	assert.Nil(t, ws.SaveDeadLetter(DefaultTenant, newer))
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "#delivery#newer"}, put.Item[webhookKey])
+
+	// The most recent attempt first
+	ws.Client = webhookStub{items: []webhookItem{{WebhookId: "#delivery#older", Delivery: &older}, {WebhookId: "#delivery#newer", Delivery: &newer}}}
+	deliveries, err := ws.ListDeadLetters(DefaultTenant)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Delivery{newer, older}, deliveries)
+
+	ws.Client = webhookStub{item: &webhookItem{WebhookId: "acme#delivery#newer", Tenant: "acme", Delivery: &newer}}
+	delivery, err := ws.GetDeadLetter("acme", "newer")
+	assert.Nil(t, err)
+	assert.Equal(t, newer, delivery)
+	_, err = ws.GetDeadLetter(DefaultTenant, "newer")
+	assert.Equal(t, ErrDeliveryNotFound, err)
+
+	ws.Client = webhookStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{writeError: conditionalCheckFailed}}
+	assert.Equal(t, ErrDeliveryNotFound, ws.DeleteDeadLetter("acme", "newer"))
+
+	ws.Client = webhookStub{dynamoRestaurantStorerStub: dynamoRestaurantStorerStub{error: "an error occurred"}}
+	_, err = ws.ListDeadLetters("acme")
+	if assert.Error(t, err) {
+		assert.Equal(t, "error listing deliveries in dynamo: an error occurred", err.Error())
+	}
+}
+
+// webhookStub serves the webhook item for every key and the items in one
+// page, and captures the PutItem input.
+type webhookStub struct {
+	dynamoRestaurantStorerStub
+	item  *webhookItem
+	items []webhookItem
+	put   *dynamodb.PutItemInput
+}
+
+func (s webhookStub) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if s.put != nil {
+		*s.put = *input
+	}
+	return s.dynamoRestaurantStorerStub.PutItem(ctx, input, optFns...)
+}
+
+func (s webhookStub) GetItem(_ context.Context, _ *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	if s.item == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	av, err := attributevalue.MarshalMap(s.item)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: av}, nil
+}
+
+func (s webhookStub) Scan(_ context.Context, _ *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if s.error != "" {
+		return nil, stubErr(s.error)
+	}
+	output := &dynamodb.ScanOutput{}
+	for _, item := range s.items {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	return output, nil
+}
@@ -0,0 +1,167 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/lfroomin/restaurant-container/internal/geo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"io"
+)
+
+// Export formats
+const (
+	NDJSON  = "ndjson"
+	CSV     = "csv"
+	GeoJSON = "geojson"
+)
+
+// ErrUnknownFormat is returned for a format that is not supported.
+var ErrUnknownFormat = errors.New("format must be ndjson, csv or geojson")
+
+// Encoder writes restaurants one at a time, so an export never holds more
+// than one restaurant in memory. Close must be called after the last
+// restaurant to complete the output.
+type Encoder interface {
+	Encode(restaurant model.Restaurant) error
+	Close() error
+}
+
+// NewEncoder returns an encoder for the format and the content type of its output.
+func NewEncoder(format string, w io.Writer) (Encoder, string, error) {
+	switch format {
+	case NDJSON:
+		return ndjsonEncoder{enc: json.NewEncoder(w)}, "application/x-ndjson", nil
+	case CSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, "text/csv", nil
+	case GeoJSON:
+		return &geojsonEncoder{w: w}, "application/geo+json", nil
+	default:
+		return nil, "", ErrUnknownFormat
+	}
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e ndjsonEncoder) Encode(restaurant model.Restaurant) error {
+	return e.enc.Encode(restaurant)
+}
+
+func (e ndjsonEncoder) Close() error {
+	return nil
+}
+
+// csvHeader is the header of the CSV export. The address and location are
+// flattened into columns prefixed with their path.
+var csvHeader = []string{
+	"id", "name", "description", "phoneNumber",
+	"address.line1", "address.line2", "address.city", "address.state", "address.zipCode", "address.country", "address.timezoneName",
+	"address.location.geocode", "address.location.addressNumber", "address.location.street", "address.location.municipality",
+	"address.location.postalCode", "address.location.region", "address.location.subRegion", "address.location.country",
+}
+
+type csvEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvEncoder) Encode(restaurant model.Restaurant) error {
+	if !e.headerWritten {
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	address := restaurant.Address
+	if address == nil {
+		address = &model.Address{}
+	}
+	location := address.Location
+	if location == nil {
+		location = &model.Location{}
+	}
+
+	return e.w.Write([]string{
+		str(restaurant.Id), restaurant.Name, str(restaurant.Description), str(restaurant.PhoneNumber),
+		str(address.Line1), str(address.Line2), str(address.City), str(address.State), str(address.ZipCode), str(address.Country), str(address.TimezoneName),
+		str(location.Geocode), str(location.AddressNumber), str(location.Street), str(location.Municipality),
+		str(location.PostalCode), str(location.Region), str(location.SubRegion), str(location.Country),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.headerWritten {
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type geojsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+type feature struct {
+	Type       string           `json:"type"`
+	Id         *string          `json:"id,omitempty"`
+	Geometry   *point           `json:"geometry"`
+	Properties model.Restaurant `json:"properties"`
+}
+
+type point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// Encode writes the restaurant as a feature of the collection, with the
+// geocode as point geometry. A restaurant without a geocode has no geometry.
+func (e *geojsonEncoder) Encode(restaurant model.Restaurant) error {
+	prefix := `,`
+	if e.count == 0 {
+		prefix = `{"type":"FeatureCollection","features":[`
+	}
+
+	f := feature{Type: "Feature", Id: restaurant.Id, Properties: restaurant}
+	if restaurant.Address != nil && restaurant.Address.Location != nil && restaurant.Address.Location.Geocode != nil {
+		if p, err := geo.ParseGeocode(*restaurant.Address.Location.Geocode); err == nil {
+			// GeoJSON positions are longitude first
+			f.Geometry = &point{Type: "Point", Coordinates: [2]float64{p.Lon, p.Lat}}
+		}
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	if _, err = e.w.Write(b); err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+func (e *geojsonEncoder) Close() error {
+	suffix := `]}`
+	if e.count == 0 {
+		suffix = `{"type":"FeatureCollection","features":[]}`
+	}
+	_, err := io.WriteString(e.w, suffix)
+	return err
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
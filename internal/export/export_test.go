@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Encoder(t *testing.T) {
+	t.Parallel()
+
+	id1, id2, city, geocode, phone := "rest1", "rest2", "Seattle", "47.606209,-122.332071", "555-1234"
+	restaurants := []model.Restaurant{
+		{
+			Id:   &id1,
+			Name: "Rest, 1",
+			Address: &model.Address{
+				City:     &city,
+				Location: &model.Location{Geocode: &geocode},
+			},
+		},
+		{
+			Id:          &id2,
+			Name:        "Rest 2",
+			PhoneNumber: &phone,
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		format      string
+		restaurants []model.Restaurant
+		contentType string
+		output      string
+		errMsg      string
+	}{
+		{
+			name:        "ndjson",
+			format:      NDJSON,
+			restaurants: restaurants,
+			contentType: "application/x-ndjson",
+			output: `{"address":{"city":"Seattle","location":{"geocode":"47.606209,-122.332071"}},"id":"rest1","name":"Rest, 1"}
+{"id":"rest2","name":"Rest 2","phoneNumber":"555-1234"}
+`,
+		},
+		{
+			name:        "csv",
+			format:      CSV,
+			restaurants: restaurants,
+			contentType: "text/csv",
+			output: `id,name,description,phoneNumber,address.line1,address.line2,address.city,address.state,address.zipCode,address.country,address.timezoneName,address.location.geocode,address.location.addressNumber,address.location.street,address.location.municipality,address.location.postalCode,address.location.region,address.location.subRegion,address.location.country
+rest1,"Rest, 1",,,,,Seattle,,,,,"47.606209,-122.332071",,,,,,,
+rest2,Rest 2,,555-1234,,,,,,,,,,,,,,,
+`,
+		},
+		{
+			name:        "csv empty",
+			format:      CSV,
+			contentType: "text/csv",
+			output: `id,name,description,phoneNumber,address.line1,address.line2,address.city,address.state,address.zipCode,address.country,address.timezoneName,address.location.geocode,address.location.addressNumber,address.location.street,address.location.municipality,address.location.postalCode,address.location.region,address.location.subRegion,address.location.country
+`,
+		},
+		{
+			name:        "geojson",
+			format:      GeoJSON,
+			restaurants: restaurants,
+			contentType: "application/geo+json",
+			output:      `{"type":"FeatureCollection","features":[{"type":"Feature","id":"rest1","geometry":{"type":"Point","coordinates":[-122.332071,47.606209]},"properties":{"address":{"city":"Seattle","location":{"geocode":"47.606209,-122.332071"}},"id":"rest1","name":"Rest, 1"}},{"type":"Feature","id":"rest2","geometry":null,"properties":{"id":"rest2","name":"Rest 2","phoneNumber":"555-1234"}}]}`,
+		},
+		{
+			name:        "geojson empty",
+			format:      GeoJSON,
+			contentType: "application/geo+json",
+			output:      `{"type":"FeatureCollection","features":[]}`,
+		},
+		{
+			name:   "unknown format",
+			format: "xml",
+			errMsg: "format must be ndjson, csv or geojson",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			enc, contentType, err := NewEncoder(tc.format, &buf)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.contentType, contentType)
+			for _, restaurant := range tc.restaurants {
+				assert.Nil(t, enc.Encode(restaurant))
+			}
+			assert.Nil(t, enc.Close())
+			assert.Equal(t, tc.output, buf.String())
+
+			if tc.format == GeoJSON {
+				assert.True(t, json.Valid(buf.Bytes()))
+			}
+		})
+	}
+}
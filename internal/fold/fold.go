@@ -0,0 +1,30 @@
+// Package fold folds text for comparisons that ignore case and diacritics,
+// so the search and the duplicate detection match names the same way.
+package fold
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// String lower cases the text and removes diacritics, so "Café" becomes
+// "cafe".
+func String(text string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, text)
+	if err != nil {
+		folded = text
+	}
+	return strings.ToLower(folded)
+}
+
+// Words splits the folded text into words of letters and numbers, the other
+// characters separating them.
+func Words(text string) []string {
+	return strings.FieldsFunc(String(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,21 @@
+package fold
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "cafe creme", String("Café Crème"))
+	assert.Equal(t, "pho 24", String("PHỞ 24"))
+}
+
+func Test_Words(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"the", "cafe", "bar", "2"}, Words("The Café & Bar #2"))
+	assert.Equal(t, []string{"joe", "s"}, Words("Joe's"))
+	assert.Empty(t, Words(" - "))
+}
@@ -0,0 +1,180 @@
+package geo
+
+import (
+	"errors"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const earthRadius = 6371008.8 // mean radius in meters
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// ErrInvalidGeocode is returned when a geocode is not in the format "lat,lon".
+var ErrInvalidGeocode = errors.New("invalid geocode")
+
+// Point is a position in decimal degrees.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// ParseGeocode parses a geocode in the format "lat,lon" as stored in
+// model.Location.Geocode.
+func ParseGeocode(geocode string) (Point, error) {
+	latStr, lonStr, ok := strings.Cut(geocode, ",")
+	if !ok {
+		return Point{}, ErrInvalidGeocode
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil {
+		return Point{}, ErrInvalidGeocode
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err != nil {
+		return Point{}, ErrInvalidGeocode
+	}
+
+	p := Point{Lat: lat, Lon: lon}
+	if !p.Valid() {
+		return Point{}, ErrInvalidGeocode
+	}
+	return p, nil
+}
+
+// Location returns the parsed geocode of the restaurant, ok is false when
+// it has no valid one.
+func Location(restaurant model.Restaurant) (Point, bool) {
+	if restaurant.Address == nil || restaurant.Address.Location == nil || restaurant.Address.Location.Geocode == nil {
+		return Point{}, false
+	}
+	point, err := ParseGeocode(*restaurant.Address.Location.Geocode)
+	return point, err == nil
+}
+
+// Valid reports whether the point is within the valid latitude and longitude ranges.
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// Distance returns the great-circle distance in meters between two points
+// using the haversine formula.
+func Distance(p1, p2 Point) float64 {
+	lat1, lat2 := radians(p1.Lat), radians(p2.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(p2.Lon - p1.Lon)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Geohash encodes the point as a geohash with the given number of characters.
+func Geohash(p Point, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	var sb strings.Builder
+	even := true
+	bit, ch := 0, 0
+	for sb.Len() < precision {
+		if even {
+			ch = ch<<1 | bisect(&lonRange, p.Lon)
+		} else {
+			ch = ch<<1 | bisect(&latRange, p.Lat)
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			sb.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// CoveringCells returns the geohash cells that together cover the circle
+// with the given center and radius (in meters). The precision of the cells
+// is the highest precision, between minPrecision and maxPrecision, for which
+// at most maxCells cells are needed.
+func CoveringCells(center Point, radius float64, minPrecision, maxPrecision, maxCells int) []string {
+	dLat := degrees(radius / earthRadius)
+	minLat, maxLat := math.Max(center.Lat-dLat, -90), math.Min(center.Lat+dLat, 90)
+
+	// The longitude span of the circle widens towards the poles
+	maxAbsLat := math.Max(math.Abs(minLat), math.Abs(maxLat))
+	dLon := 180.0
+	if cos := math.Cos(radians(maxAbsLat)); cos > 0 {
+		dLon = math.Min(degrees(radius/(earthRadius*cos)), 180)
+	}
+	minLon, maxLon := center.Lon-dLon, center.Lon+dLon
+
+	precision := minPrecision
+	for p := maxPrecision; p > minPrecision; p-- {
+		latStep, lonStep := cellSize(p)
+		nLat := math.Floor((maxLat-minLat)/latStep) + 2
+		nLon := math.Floor((maxLon-minLon)/lonStep) + 2
+		if nLat*nLon <= float64(maxCells) {
+			precision = p
+			break
+		}
+	}
+
+	latStep, lonStep := cellSize(precision)
+	seen := map[string]bool{}
+	var cells []string
+	for lat := minLat; ; lat = math.Min(lat+latStep, maxLat) {
+		for lon := minLon; ; lon = math.Min(lon+lonStep, maxLon) {
+			cell := Geohash(Point{Lat: lat, Lon: normalizeLon(lon)}, precision)
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+			if lon == maxLon {
+				break
+			}
+		}
+		if lat == maxLat {
+			break
+		}
+	}
+	return cells
+}
+
+// cellSize returns the height and width in degrees of a geohash cell of the given precision.
+func cellSize(precision int) (float64, float64) {
+	bits := precision * 5
+	latBits := bits / 2
+	lonBits := bits - latBits
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+func bisect(r *[2]float64, v float64) int {
+	mid := (r[0] + r[1]) / 2
+	if v >= mid {
+		r[0] = mid
+		return 1
+	}
+	r[1] = mid
+	return 0
+}
+
+func normalizeLon(lon float64) float64 {
+	for lon < -180 {
+		lon += 360
+	}
+	for lon >= 180 {
+		lon -= 360
+	}
+	return lon
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,165 @@
+package geo
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_ParseGeocode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		geocode string
+		point   Point
+		errMsg  string
+	}{
+		{
+			name:    "happy path",
+			geocode: "47.606209,-122.332071",
+			point:   Point{Lat: 47.606209, Lon: -122.332071},
+		},
+		{
+			name:    "spaces",
+			geocode: " 47.5, -122.5 ",
+			point:   Point{Lat: 47.5, Lon: -122.5},
+		},
+		{
+			name:    "missing separator",
+			geocode: "47.606209",
+			errMsg:  "invalid geocode",
+		},
+		{
+			name:    "not a number",
+			geocode: "abc,-122.332071",
+			errMsg:  "invalid geocode",
+		},
+		{
+			name:    "out of range",
+			geocode: "91,0",
+			errMsg:  "invalid geocode",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			point, err := ParseGeocode(tc.geocode)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.point, point)
+			}
+		})
+	}
+}
+
+func Test_Location(t *testing.T) {
+	t.Parallel()
+
+	geocode, invalid := "47.6,-122.3", "47.6"
+	point, ok := Location(model.Restaurant{Address: &model.Address{Location: &model.Location{Geocode: &geocode}}})
+	assert.True(t, ok)
+	assert.Equal(t, Point{Lat: 47.6, Lon: -122.3}, point)
+
+	_, ok = Location(model.Restaurant{Address: &model.Address{Location: &model.Location{Geocode: &invalid}}})
+	assert.False(t, ok)
+
+	_, ok = Location(model.Restaurant{Address: &model.Address{}})
+	assert.False(t, ok)
+
+	_, ok = Location(model.Restaurant{})
+	assert.False(t, ok)
+}
+
+func Test_Distance(t *testing.T) {
+	t.Parallel()
+
+	seattle := Point{Lat: 47.606209, Lon: -122.332071}
+	portland := Point{Lat: 45.515232, Lon: -122.678385}
+
+	assert.Zero(t, Distance(seattle, seattle))
+	assert.InDelta(t, 233800, Distance(seattle, portland), 500)
+	assert.Equal(t, Distance(seattle, portland), Distance(portland, seattle))
+}
+
+func Test_Geohash(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "ezs42", Geohash(Point{Lat: 42.6, Lon: -5.6}, 5))
+	assert.Equal(t, "c23nb62w2", Geohash(Point{Lat: 47.606209, Lon: -122.332071}, 9))
+}
+
+func Test_CoveringCells(t *testing.T) {
+	t.Parallel()
+
+	seattle := Point{Lat: 47.606209, Lon: -122.332071}
+
+	testCases := []struct {
+		name      string
+		center    Point
+		radius    float64
+		precision int
+	}{
+		{
+			name:      "small radius",
+			center:    seattle,
+			radius:    100,
+			precision: 7,
+		},
+		{
+			name:      "large radius",
+			center:    seattle,
+			radius:    20000,
+			precision: 4,
+		},
+		{
+			name:      "antimeridian",
+			center:    Point{Lat: 0, Lon: 179.999},
+			radius:    1000,
+			precision: 5,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cells := CoveringCells(tc.center, tc.radius, 4, 9, 9)
+
+			assert.NotEmpty(t, cells)
+			assert.LessOrEqual(t, len(cells), 9)
+			for _, cell := range cells {
+				assert.Len(t, cell, tc.precision)
+			}
+
+			// Points on the circle must be in one of the cells
+			dLat := degrees(tc.radius/earthRadius) * 0.99
+			for _, p := range []Point{
+				tc.center,
+				{Lat: tc.center.Lat + dLat, Lon: tc.center.Lon},
+				{Lat: tc.center.Lat - dLat, Lon: tc.center.Lon},
+			} {
+				assert.True(t, covered(cells, Geohash(p, 9)), "point %v not covered", p)
+			}
+		})
+	}
+}
+
+func covered(cells []string, hash string) bool {
+	for _, cell := range cells {
+		if strings.HasPrefix(hash, cell) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package geocode
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/location"
@@ -11,6 +12,10 @@ import (
 	"strings"
 )
 
+// ErrGeocode is returned when the Location Service cannot be queried. It
+// wraps the error of the service, which is only meant for the logs.
+var ErrGeocode = errors.New("the address could not be geocoded")
+
 type placeSearcher interface {
 	SearchPlaceIndexForText(ctx context.Context, input *location.SearchPlaceIndexForTextInput, optFns ...func(*location.Options)) (*location.SearchPlaceIndexForTextOutput, error)
 }
@@ -41,7 +46,7 @@ func (ls LocationService) Geocode(address model.Address) (model.Location, string
 
 	data, err := ls.Client.SearchPlaceIndexForText(context.Background(), input)
 	if err != nil {
-		return model.Location{}, "", err
+		return model.Location{}, "", fmt.Errorf("%w: %v", ErrGeocode, err)
 	}
 
 	d, _ := json.Marshal(data)
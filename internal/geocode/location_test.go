@@ -58,7 +58,7 @@ func Test_Geocode(t *testing.T) {
 		{
 			name:      "error",
 			stubError: "an error occurred",
-			errMsg:    "an error occurred",
+			errMsg:    "the address could not be geocoded: an error occurred",
 		},
 	}
 
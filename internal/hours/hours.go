@@ -0,0 +1,148 @@
+package hours
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"sort"
+	"time"
+
+	// The container image has no timezone database
+	_ "time/tzdata"
+)
+
+var weekdays = map[model.OpeningIntervalDay]time.Weekday{
+	model.Sunday:    time.Sunday,
+	model.Monday:    time.Monday,
+	model.Tuesday:   time.Tuesday,
+	model.Wednesday: time.Wednesday,
+	model.Thursday:  time.Thursday,
+	model.Friday:    time.Friday,
+	model.Saturday:  time.Saturday,
+}
+
+// Validate checks that every interval has a day of the week and open and
+// close times in HH:MM format. 24:00 is only allowed as a close time. The
+// errors are prefixed with the name of the field holding the intervals.
+func Validate(field string, intervals []model.OpeningInterval) error {
+	for i, interval := range intervals {
+		if _, ok := weekdays[interval.Day]; !ok {
+			return fmt.Errorf("%s[%d]: day must be a day of the week", field, i)
+		}
+		opens, ok := parseClock(interval.Open)
+		if !ok || opens == 24*60 {
+			return fmt.Errorf("%s[%d]: open must be a time in HH:MM format", field, i)
+		}
+		closes, ok := parseClock(interval.Close)
+		if !ok {
+			return fmt.Errorf("%s[%d]: close must be a time in HH:MM format", field, i)
+		}
+		if opens == closes {
+			return fmt.Errorf("%s[%d]: open and close must be different", field, i)
+		}
+	}
+	return nil
+}
+
+// Status reports whether a restaurant with the (valid) opening hours is open
+// at the time, and when it next opens or closes. The hours are evaluated in
+// the timezone of the restaurant, so daylight saving time changes are taken
+// into account. next is nil when the restaurant has no opening hours or is
+// always open.
+func Status(intervals []model.OpeningInterval, loc *time.Location, at time.Time) (open bool, next *time.Time) {
+	for _, s := range spans(intervals, loc, at) {
+		if at.Before(s.start) {
+			return false, &s.start
+		}
+		if at.Before(s.end) {
+			// The spans cover more than a week, a span as long is always open
+			if s.end.Sub(s.start) > 7*24*time.Hour {
+				return true, nil
+			}
+			return true, &s.end
+		}
+	}
+	return false, nil
+}
+
+// OpenBetween reports whether a restaurant with the (valid) opening hours is
+// open without interruption from start to end.
+func OpenBetween(intervals []model.OpeningInterval, loc *time.Location, start, end time.Time) bool {
+	for _, s := range spans(intervals, loc, start) {
+		if !start.Before(s.start) && !end.After(s.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// span is a period the restaurant is open without interruption.
+type span struct {
+	start, end time.Time
+}
+
+// spans returns the merged open periods from the day before the time,
+// which can span midnight into the day of the time, to a week after it.
+func spans(intervals []model.OpeningInterval, loc *time.Location, at time.Time) []span {
+	local := at.In(loc)
+	y, m, d := local.Date()
+
+	var all []span
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(y, m, d+offset, 0, 0, 0, 0, loc)
+		for _, interval := range intervals {
+			if weekdays[interval.Day] != day.Weekday() {
+				continue
+			}
+			opens, _ := parseClock(interval.Open)
+			closes, _ := parseClock(interval.Close)
+			closeDay := d + offset
+			if closes <= opens {
+				closeDay++
+			}
+			all = append(all, span{
+				start: time.Date(y, m, d+offset, 0, opens, 0, 0, loc),
+				end:   time.Date(y, m, closeDay, 0, closes, 0, 0, loc),
+			})
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].start.Before(all[j].start)
+	})
+
+	var merged []span
+	for _, s := range all {
+		if n := len(merged); n > 0 && !s.start.After(merged[n-1].end) {
+			if s.end.After(merged[n-1].end) {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// parseClock returns the minutes since midnight of a time in HH:MM format.
+func parseClock(clock string) (int, bool) {
+	if len(clock) != 5 || clock[2] != ':' {
+		return 0, false
+	}
+	h, ok1 := digits(clock[0:2])
+	m, ok2 := digits(clock[3:5])
+	if !ok1 || !ok2 || h > 24 || m > 59 || (h == 24 && m != 0) {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+func digits(s string) (int, bool) {
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
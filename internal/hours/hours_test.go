@@ -0,0 +1,261 @@
+package hours
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		intervals []model.OpeningInterval
+		errMsg    string
+	}{
+		{
+			name: "happy path",
+			intervals: []model.OpeningInterval{
+				{Day: model.Monday, Open: "11:30", Close: "14:00"},
+				{Day: model.Monday, Open: "17:00", Close: "24:00"},
+				{Day: model.Friday, Open: "18:00", Close: "02:00"},
+			},
+		},
+		{
+			name: "no intervals",
+		},
+		{
+			name:      "invalid day",
+			intervals: []model.OpeningInterval{{Day: "someday", Open: "11:30", Close: "14:00"}},
+			errMsg:    "openingHours[0]: day must be a day of the week",
+		},
+		{
+			name:      "invalid open",
+			intervals: []model.OpeningInterval{{Day: model.Monday, Open: "11", Close: "14:00"}},
+			errMsg:    "openingHours[0]: open must be a time in HH:MM format",
+		},
+		{
+			name:      "open at end of day",
+			intervals: []model.OpeningInterval{{Day: model.Monday, Open: "24:00", Close: "02:00"}},
+			errMsg:    "openingHours[0]: open must be a time in HH:MM format",
+		},
+		{
+			name: "invalid close",
+			intervals: []model.OpeningInterval{
+				{Day: model.Monday, Open: "11:30", Close: "14:00"},
+				{Day: model.Tuesday, Open: "11:30", Close: "14:60"},
+			},
+			errMsg: "openingHours[1]: close must be a time in HH:MM format",
+		},
+		{
+			name:      "empty interval",
+			intervals: []model.OpeningInterval{{Day: model.Monday, Open: "11:30", Close: "11:30"}},
+			errMsg:    "openingHours[0]: open and close must be different",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := Validate("openingHours", tc.intervals)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func Test_Status(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, min int) time.Time {
+		// June 2023, the 5th is a Monday
+		return time.Date(2023, time.June, day, hour, min, 0, 0, loc)
+	}
+
+	lunchAndDinner := []model.OpeningInterval{
+		{Day: model.Monday, Open: "11:30", Close: "14:00"},
+		{Day: model.Monday, Open: "17:00", Close: "22:00"},
+	}
+	overnight := []model.OpeningInterval{
+		{Day: model.Friday, Open: "18:00", Close: "02:00"},
+		{Day: model.Saturday, Open: "18:00", Close: "02:00"},
+	}
+	alwaysOpen := []model.OpeningInterval{
+		{Day: model.Monday, Open: "00:00", Close: "24:00"},
+		{Day: model.Tuesday, Open: "00:00", Close: "24:00"},
+		{Day: model.Wednesday, Open: "00:00", Close: "24:00"},
+		{Day: model.Thursday, Open: "00:00", Close: "24:00"},
+		{Day: model.Friday, Open: "00:00", Close: "24:00"},
+		{Day: model.Saturday, Open: "00:00", Close: "24:00"},
+		{Day: model.Sunday, Open: "00:00", Close: "24:00"},
+	}
+
+	testCases := []struct {
+		name      string
+		intervals []model.OpeningInterval
+		at        time.Time
+		open      bool
+		next      *time.Time
+	}{
+		{
+			name:      "before opening",
+			intervals: lunchAndDinner,
+			at:        at(5, 9, 0),
+			next:      timePtr(at(5, 11, 30)),
+		},
+		{
+			name:      "open",
+			intervals: lunchAndDinner,
+			at:        at(5, 12, 0),
+			open:      true,
+			next:      timePtr(at(5, 14, 0)),
+		},
+		{
+			name:      "between intervals",
+			intervals: lunchAndDinner,
+			at:        at(5, 14, 0),
+			next:      timePtr(at(5, 17, 0)),
+		},
+		{
+			name:      "opens next week",
+			intervals: lunchAndDinner,
+			at:        at(5, 22, 0),
+			next:      timePtr(at(12, 11, 30)),
+		},
+		{
+			name:      "open past midnight",
+			intervals: overnight,
+			at:        at(10, 1, 0),
+			open:      true,
+			next:      timePtr(at(10, 2, 0)),
+		},
+		{
+			name:      "overnight spans merged",
+			intervals: append(overnight, model.OpeningInterval{Day: model.Saturday, Open: "00:00", Close: "12:00"}),
+			at:        at(10, 1, 0),
+			open:      true,
+			next:      timePtr(at(10, 12, 0)),
+		},
+		{
+			name:      "always open",
+			intervals: alwaysOpen,
+			at:        at(7, 3, 0),
+			open:      true,
+		},
+		{
+			name: "no intervals",
+			at:   at(5, 12, 0),
+		},
+		{
+			name:      "time in another timezone",
+			intervals: lunchAndDinner,
+			at:        time.Date(2023, time.June, 5, 19, 0, 0, 0, time.UTC),
+			open:      true,
+			next:      timePtr(at(5, 14, 0)),
+		},
+		{
+			name:      "daylight saving time",
+			intervals: []model.OpeningInterval{{Day: model.Sunday, Open: "01:00", Close: "05:00"}},
+			// Clocks are set back from 2:00 to 1:00 on November 5th
+			at:   time.Date(2023, time.November, 5, 9, 30, 0, 0, time.UTC),
+			open: true,
+			next: timePtr(time.Date(2023, time.November, 5, 5, 0, 0, 0, loc)),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			open, next := Status(tc.intervals, loc, tc.at)
+
+			assert.Equal(t, tc.open, open)
+			if tc.next == nil {
+				assert.Nil(t, next)
+			} else if assert.NotNil(t, next) {
+				assert.True(t, tc.next.Equal(*next), "expected %s, got %s", tc.next, next)
+			}
+		})
+	}
+}
+
+func Test_OpenBetween(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, min int) time.Time {
+		// June 2023, the 9th is a Friday
+		return time.Date(2023, time.June, day, hour, min, 0, 0, loc)
+	}
+
+	intervals := []model.OpeningInterval{
+		{Day: model.Friday, Open: "11:30", Close: "14:00"},
+		{Day: model.Friday, Open: "18:00", Close: "02:00"},
+	}
+
+	testCases := []struct {
+		name       string
+		start, end time.Time
+		open       bool
+	}{
+		{
+			name:  "within an interval",
+			start: at(9, 12, 0),
+			end:   at(9, 13, 30),
+			open:  true,
+		},
+		{
+			name:  "up to closing",
+			start: at(9, 12, 30),
+			end:   at(9, 14, 0),
+			open:  true,
+		},
+		{
+			name:  "past closing",
+			start: at(9, 13, 0),
+			end:   at(9, 14, 30),
+		},
+		{
+			name:  "across midnight",
+			start: at(9, 23, 30),
+			end:   at(10, 1, 0),
+			open:  true,
+		},
+		{
+			name:  "before opening",
+			start: at(9, 17, 30),
+			end:   at(9, 19, 0),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.open, OpenBetween(intervals, loc, tc.start, tc.end))
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,52 @@
+package mergepatch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Apply(t *testing.T) {
+	t.Parallel()
+
+	// Test cases from RFC 7396 Appendix A
+	testCases := []struct {
+		name   string
+		target string
+		patch  string
+		result string
+		errMsg string
+	}{
+		{name: "replace value", target: `{"a":"b"}`, patch: `{"a":"c"}`, result: `{"a":"c"}`},
+		{name: "add value", target: `{"a":"b"}`, patch: `{"b":"c"}`, result: `{"a":"b","b":"c"}`},
+		{name: "remove value", target: `{"a":"b"}`, patch: `{"a":null}`, result: `{}`},
+		{name: "remove one of many", target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, result: `{"b":"c"}`},
+		{name: "replace array", target: `{"a":["b"]}`, patch: `{"a":"c"}`, result: `{"a":"c"}`},
+		{name: "replace with array", target: `{"a":"c"}`, patch: `{"a":["b"]}`, result: `{"a":["b"]}`},
+		{name: "nested", target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, result: `{"a":{"b":"d"}}`},
+		{name: "arrays are not merged", target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, result: `{"a":[1]}`},
+		{name: "non-object patch", target: `["a","b"]`, patch: `["c","d"]`, result: `["c","d"]`},
+		{name: "object patch on array", target: `["a"]`, patch: `{"a":"b"}`, result: `{"a":"b"}`},
+		{name: "null in nested new object", target: `{"e":null}`, patch: `{"a":1}`, result: `{"a":1,"e":null}`},
+		{name: "remove nested", target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, result: `{"a":{"bb":{}}}`},
+		{name: "empty target", patch: `{"a":"b"}`, result: `{"a":"b"}`},
+		{name: "invalid patch", target: `{}`, patch: `{`, errMsg: "invalid merge patch"},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := Apply([]byte(tc.target), []byte(tc.patch))
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.JSONEq(t, tc.result, string(result))
+			}
+		})
+	}
+}
@@ -3,9 +3,85 @@
 // Code generated by github.com/deepmap/oapi-codegen version v1.12.4 DO NOT EDIT.
 package model
 
+import (
+	"time"
+)
+
+// Defines values for GetExportParamsFormat.
+const (
+	Csv     GetExportParamsFormat = "csv"
+	Geojson GetExportParamsFormat = "geojson"
+	Ndjson  GetExportParamsFormat = "ndjson"
+)
+
+// Defines values for MenuItemAllergens.
+const (
+	Eggs      MenuItemAllergens = "eggs"
+	Fish      MenuItemAllergens = "fish"
+	Milk      MenuItemAllergens = "milk"
+	Peanuts   MenuItemAllergens = "peanuts"
+	Sesame    MenuItemAllergens = "sesame"
+	Shellfish MenuItemAllergens = "shellfish"
+	Soy       MenuItemAllergens = "soy"
+	TreeNuts  MenuItemAllergens = "tree-nuts"
+	Wheat     MenuItemAllergens = "wheat"
+)
+
+// Defines values for MenuItemDietaryTags.
+const (
+	DairyFree  MenuItemDietaryTags = "dairy-free"
+	GlutenFree MenuItemDietaryTags = "gluten-free"
+	Halal      MenuItemDietaryTags = "halal"
+	Kosher     MenuItemDietaryTags = "kosher"
+	Vegan      MenuItemDietaryTags = "vegan"
+	Vegetarian MenuItemDietaryTags = "vegetarian"
+)
+
+// Defines values for OpeningIntervalDay.
+const (
+	Friday    OpeningIntervalDay = "friday"
+	Monday    OpeningIntervalDay = "monday"
+	Saturday  OpeningIntervalDay = "saturday"
+	Sunday    OpeningIntervalDay = "sunday"
+	Thursday  OpeningIntervalDay = "thursday"
+	Tuesday   OpeningIntervalDay = "tuesday"
+	Wednesday OpeningIntervalDay = "wednesday"
+)
+
+// Defines values for ReservationStatus.
+const (
+	Booked    ReservationStatus = "booked"
+	Cancelled ReservationStatus = "cancelled"
+)
+
+// Defines values for RevisionAction.
+const (
+	Create  RevisionAction = "create"
+	Delete  RevisionAction = "delete"
+	Restore RevisionAction = "restore"
+	Revert  RevisionAction = "revert"
+	Update  RevisionAction = "update"
+)
+
+// Defines values for TagCategory.
+const (
+	TagCategoryAmenity TagCategory = "amenity"
+	TagCategoryCuisine TagCategory = "cuisine"
+	TagCategoryPrice   TagCategory = "price"
+)
+
+// Defines values for WebhookEventType.
+const (
+	RestaurantCreated WebhookEventType = "restaurant.created"
+	RestaurantDeleted WebhookEventType = "restaurant.deleted"
+	RestaurantUpdated WebhookEventType = "restaurant.updated"
+)
+
 // Address defines model for Address.
 type Address struct {
-	City    *string `json:"city,omitempty"`
+	City *string `json:"city,omitempty"`
+
+	// Country ISO 3166-1 alpha-2 country code
 	Country *string `json:"country,omitempty"`
 	Line1   *string `json:"line1,omitempty"`
 	Line2   *string `json:"line2,omitempty"`
@@ -16,7 +92,147 @@ type Address struct {
 
 	// TimezoneName Name of the timezone following the IANA standard (https://www.iana.org/time-zones)
 	TimezoneName *string `json:"timezoneName,omitempty"`
-	ZipCode      *string `json:"zipCode,omitempty"`
+
+	// ZipCode Postal code, in the format of the country when it is known
+	ZipCode *string `json:"zipCode,omitempty"`
+}
+
+// ApiKey defines model for ApiKey.
+type ApiKey struct {
+	// Burst Number of requests the key can make at once
+	Burst int `json:"burst"`
+
+	// CreatedAt Time the key was issued
+	CreatedAt time.Time `json:"createdAt"`
+
+	// DailyQuota Number of requests the key can make per day (UTC)
+	DailyQuota int `json:"dailyQuota"`
+
+	// Id ID of the API key, used to revoke it
+	Id string `json:"id"`
+
+	// Key The API key to send in the X-API-Key header, only returned when it is issued
+	Key *string `json:"key,omitempty"`
+
+	// Name Name of the partner the key is issued to
+	Name string `json:"name"`
+
+	// RateLimit Number of requests per second the key can make, on average
+	RateLimit float64 `json:"rateLimit"`
+
+	// RevokedAt Time the key was revoked
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+
+	// Tenant Tenant of the restaurants the key can access, the tenant of the request that issued it, absent for the default tenant
+	Tenant *string `json:"tenant,omitempty"`
+}
+
+// ApiKeyRequest defines model for ApiKeyRequest.
+type ApiKeyRequest struct {
+	// Burst Number of requests the key can make at once, the configured default when absent
+	Burst *int `json:"burst,omitempty"`
+
+	// DailyQuota Number of requests the key can make per day (UTC), the configured default when absent
+	DailyQuota *int `json:"dailyQuota,omitempty"`
+
+	// Name Name of the partner the key is issued to
+	Name string `json:"name"`
+
+	// RateLimit Number of requests per second the key can make, on average, the configured default when absent
+	RateLimit *float64 `json:"rateLimit,omitempty"`
+}
+
+// Availability defines model for Availability.
+type Availability struct {
+	// Date Date in the timezone of the restaurant (YYYY-MM-DD)
+	Date      string `json:"date"`
+	PartySize int    `json:"partySize"`
+
+	// Slots Start times of the slots in which a table is available for the party
+	Slots []time.Time `json:"slots"`
+}
+
+// Delivery defines model for Delivery.
+type Delivery struct {
+	// Attempts Number of times the event was POSTed
+	Attempts int `json:"attempts"`
+
+	// DeliveredAt Time the event was delivered, absent while it is not
+	DeliveredAt *time.Time   `json:"deliveredAt,omitempty"`
+	Event       WebhookEvent `json:"event"`
+
+	// Id ID of the delivery
+	Id            string     `json:"id"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty"`
+
+	// LastError Reason the last attempt failed
+	LastError *string `json:"lastError,omitempty"`
+
+	// LastStatus HTTP status of the last response, absent when there was none
+	LastStatus *int   `json:"lastStatus,omitempty"`
+	WebhookId  string `json:"webhookId"`
+}
+
+// DeliveryList defines model for DeliveryList.
+type DeliveryList struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// DuplicateCandidate An existing restaurant that is probably the same as the restaurant created
+type DuplicateCandidate struct {
+	// Distance Distance in meters from the restaurant created
+	Distance   float64    `json:"distance"`
+	Restaurant Restaurant `json:"restaurant"`
+
+	// Similarity Similarity of the normalized names, from 0 to 1
+	Similarity float64 `json:"similarity"`
+}
+
+// DuplicateCluster Restaurants that are probably the same
+type DuplicateCluster struct {
+	Restaurants []Restaurant `json:"restaurants"`
+}
+
+// DuplicateReport defines model for DuplicateReport.
+type DuplicateReport struct {
+	Clusters []DuplicateCluster `json:"clusters"`
+
+	// Distance Maximum distance in meters between duplicates
+	Distance float64 `json:"distance"`
+}
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	// Code Machine-readable reason, such as required, too_long or invalid_country
+	Code string `json:"code"`
+
+	// Field Dotted path of the invalid field, such as address.zipCode
+	Field string `json:"field"`
+
+	// Message Description of the error for display
+	Message string `json:"message"`
+}
+
+// Facets Number of restaurants matching the request with each tag, for every page of the results
+type Facets map[string]int
+
+// ImportReport defines model for ImportReport.
+type ImportReport struct {
+	Created int            `json:"created"`
+	Failed  int            `json:"failed"`
+	Results []ImportResult `json:"results"`
+}
+
+// ImportResult defines model for ImportResult.
+type ImportResult struct {
+	// Error Reason the restaurant was not created
+	Error *string `json:"error,omitempty"`
+
+	// Id ID of the created restaurant
+	Id *string `json:"id,omitempty"`
+
+	// Index Position of the restaurant in the request body
+	Index int `json:"index"`
 }
 
 // Location Data returned from the Location service
@@ -33,6 +249,176 @@ type Location struct {
 	SubRegion    *string `json:"subRegion,omitempty"`
 }
 
+// Menu defines model for Menu.
+type Menu struct {
+	Sections []MenuSection `json:"sections"`
+}
+
+// MenuItem defines model for MenuItem.
+type MenuItem struct {
+	Allergens *[]MenuItemAllergens `json:"allergens,omitempty"`
+
+	// Availability Times the item is served, always when absent
+	Availability *[]OpeningInterval `json:"availability,omitempty"`
+
+	// Available Whether the item can be ordered, false when it is sold out
+	Available   *bool                  `json:"available,omitempty"`
+	Description *string                `json:"description,omitempty"`
+	DietaryTags *[]MenuItemDietaryTags `json:"dietaryTags,omitempty"`
+	Name        string                 `json:"name"`
+	Price       Price                  `json:"price"`
+}
+
+// MenuItemAllergens defines model for MenuItem.Allergens.
+type MenuItemAllergens string
+
+// MenuItemDietaryTags defines model for MenuItem.DietaryTags.
+type MenuItemDietaryTags string
+
+// MenuSection defines model for MenuSection.
+type MenuSection struct {
+	// Availability Times the section is served, always when absent
+	Availability *[]OpeningInterval `json:"availability,omitempty"`
+	Description  *string            `json:"description,omitempty"`
+	Items        []MenuItem         `json:"items"`
+	Name         string             `json:"name"`
+}
+
+// NearbyRestaurant defines model for NearbyRestaurant.
+type NearbyRestaurant struct {
+	// Distance Distance in meters from the center of the search
+	Distance   float64    `json:"distance"`
+	Restaurant Restaurant `json:"restaurant"`
+}
+
+// NearbyRestaurantList defines model for NearbyRestaurantList.
+type NearbyRestaurantList struct {
+	Restaurants []NearbyRestaurant `json:"restaurants"`
+}
+
+// OpeningInterval A period of a day the restaurant is open. A close time that is not after the open time spans midnight into the next day.
+type OpeningInterval struct {
+	// Close Closing time (HH:MM, 24:00 for midnight)
+	Close string             `json:"close"`
+	Day   OpeningIntervalDay `json:"day"`
+
+	// Open Opening time (HH:MM)
+	Open string `json:"open"`
+}
+
+// OpeningIntervalDay defines model for OpeningInterval.Day.
+type OpeningIntervalDay string
+
+// Photo defines model for Photo.
+type Photo struct {
+	// ContentType Media type of the photo, image/jpeg or image/png
+	ContentType string `json:"contentType"`
+
+	// CreatedAt Time the photo was uploaded
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Height Height of the photo in pixels
+	Height int `json:"height"`
+
+	// Id ID of the photo
+	Id string `json:"id"`
+
+	// Size Size of the photo in bytes
+	Size int64 `json:"size"`
+
+	// ThumbnailUrl URL of the thumbnail, a JPEG image that fits in 320 by 320 pixels
+	ThumbnailUrl string `json:"thumbnailUrl"`
+
+	// Url URL of the photo
+	Url string `json:"url"`
+
+	// Width Width of the photo in pixels
+	Width int `json:"width"`
+}
+
+// PhotoList defines model for PhotoList.
+type PhotoList struct {
+	Photos []Photo `json:"photos"`
+}
+
+// PhotoOrder defines model for PhotoOrder.
+type PhotoOrder struct {
+	// PhotoIds IDs of every photo of the restaurant, in their new order
+	PhotoIds []string `json:"photoIds"`
+}
+
+// Price defines model for Price.
+type Price struct {
+	// Amount Decimal amount
+	Amount string `json:"amount"`
+
+	// Currency ISO 4217 currency code
+	Currency string `json:"currency"`
+}
+
+// Problem Error response (RFC 7807), with the application/problem+json media type. Every error of the API is a problem; unexpected errors have a generic detail and are logged with the request ID.
+type Problem struct {
+	// Detail Explanation of this occurrence of the problem
+	Detail *string `json:"detail,omitempty"`
+
+	// Duplicates Restaurants that are probably the same as the one created, for 409 Conflict
+	Duplicates *[]DuplicateCandidate `json:"duplicates,omitempty"`
+
+	// Errors Errors of the invalid fields, for 422 Unprocessable Entity
+	Errors *[]FieldError `json:"errors,omitempty"`
+
+	// Instance Path of the request
+	Instance *string `json:"instance,omitempty"`
+
+	// RequestId ID of the request, also returned in the X-Request-Id header
+	RequestId string `json:"requestId"`
+
+	// Status HTTP status code
+	Status int `json:"status"`
+
+	// Title Summary of the problem type, the reason phrase of the status
+	Title string `json:"title"`
+
+	// Type URI of the problem type, about:blank when the status is enough
+	Type string `json:"type"`
+}
+
+// Reservation defines model for Reservation.
+type Reservation struct {
+	// Id ID of the reservation
+	Id *string `json:"id,omitempty"`
+
+	// ManagementToken Token to read, modify and cancel the reservation in the X-Reservation-Token header, only returned when it is booked
+	ManagementToken *string `json:"managementToken,omitempty"`
+
+	// Name Name the reservation is made under
+	Name string `json:"name"`
+
+	// PartySize Number of guests
+	PartySize   int     `json:"partySize"`
+	PhoneNumber *string `json:"phoneNumber,omitempty"`
+
+	// Start Start of the slot that is booked
+	Start  time.Time          `json:"start"`
+	Status *ReservationStatus `json:"status,omitempty"`
+
+	// TableId ID of the booked table
+	TableId *string `json:"tableId,omitempty"`
+}
+
+// ReservationStatus defines model for Reservation.Status.
+type ReservationStatus string
+
+// ReservationSettings defines model for ReservationSettings.
+type ReservationSettings struct {
+	// DurationMinutes Duration a table is booked for, a multiple of the slot interval
+	DurationMinutes *int `json:"durationMinutes,omitempty"`
+
+	// SlotMinutes Interval between the start times of the slots, must divide a day
+	SlotMinutes *int    `json:"slotMinutes,omitempty"`
+	Tables      []Table `json:"tables"`
+}
+
 // Restaurant defines model for Restaurant.
 type Restaurant struct {
 	Address *Address `json:"address,omitempty"`
@@ -44,20 +430,397 @@ type Restaurant struct {
 	Id *string `json:"id,omitempty"`
 
 	// Name Name of the restaurant
-	Name        string  `json:"name"`
+	Name string `json:"name"`
+
+	// NextChange Time the restaurant next opens or closes, computed from the opening hours
+	NextChange *time.Time `json:"nextChange,omitempty"`
+
+	// OpenNow Whether the restaurant is open now, computed from the opening hours
+	OpenNow *bool `json:"openNow,omitempty"`
+
+	// OpeningHours Weekly opening hours, evaluated in the timezone of the address
+	OpeningHours *[]OpeningInterval `json:"openingHours,omitempty"`
+
+	// Owner Subject of the user who created the restaurant, who can modify it along with the admins
+	Owner *string `json:"owner,omitempty"`
+
+	// PhoneNumber Phone number in E.164 format, spaces, dashes, dots and parentheses are removed
 	PhoneNumber *string `json:"phoneNumber,omitempty"`
+
+	// Photos Photos of the restaurant, in their display order
+	Photos *[]Photo `json:"photos,omitempty"`
+
+	// RatingAverage Average rating of the reviews, computed from the reviews
+	RatingAverage *float64 `json:"ratingAverage,omitempty"`
+
+	// RatingCount Number of reviews, computed from the reviews
+	RatingCount *int `json:"ratingCount,omitempty"`
+
+	// Tags IDs of the tags of the restaurant, from the vocabulary of tags, with at most one price level
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// RestaurantList defines model for RestaurantList.
+type RestaurantList struct {
+	// Facets Number of restaurants matching the request with each tag, for every page of the results
+	Facets *Facets `json:"facets,omitempty"`
+
+	// NextToken Opaque token used to retrieve the next page, absent on the last page
+	NextToken   *string      `json:"nextToken,omitempty"`
+	Restaurants []Restaurant `json:"restaurants"`
+}
+
+// Review defines model for Review.
+type Review struct {
+	// CreatedAt Time the review was created
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+
+	// Id ID of the review
+	Id *string `json:"id,omitempty"`
+
+	// Rating Rating from 1 to 5
+	Rating int `json:"rating"`
+
+	// Text Text of the review
+	Text *string `json:"text,omitempty"`
+}
+
+// ReviewList defines model for ReviewList.
+type ReviewList struct {
+	// NextToken Opaque token used to retrieve the next page, absent on the last page
+	NextToken *string  `json:"nextToken,omitempty"`
+	Reviews   []Review `json:"reviews"`
+}
+
+// Revision defines model for Revision.
+type Revision struct {
+	Action RevisionAction `json:"action"`
+
+	// Actor Who made the change
+	Actor        string           `json:"actor"`
+	Changes      []RevisionChange `json:"changes"`
+	Current      *Restaurant      `json:"current,omitempty"`
+	Previous     *Restaurant      `json:"previous,omitempty"`
+	RestaurantId string           `json:"restaurantId"`
+
+	// RevertedFrom Revision the restaurant was reverted to, for revert revisions
+	RevertedFrom *int64 `json:"revertedFrom,omitempty"`
+
+	// Revision Number of the revision, the version (ETag) of the restaurant it resulted in
+	Revision  int64     `json:"revision"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
+// RevisionAction defines model for Revision.Action.
+type RevisionAction string
+
+// RevisionChange defines model for RevisionChange.
+type RevisionChange struct {
+	// Field Dotted path of the field, such as address.city
+	Field string `json:"field"`
+
+	// From Previous value of the field, null when it was not set
+	From interface{} `json:"from"`
+
+	// To New value of the field, null when it is not set
+	To interface{} `json:"to"`
+}
+
+// RevisionList defines model for RevisionList.
+type RevisionList struct {
+	// NextToken Opaque token used to retrieve the next page, absent on the last page
+	NextToken *string    `json:"nextToken,omitempty"`
+	Revisions []Revision `json:"revisions"`
+}
+
+// SearchResult defines model for SearchResult.
+type SearchResult struct {
+	Restaurant Restaurant `json:"restaurant"`
+
+	// Score Relevance of the restaurant to the query
+	Score float64 `json:"score"`
+}
+
+// SearchResults defines model for SearchResults.
+type SearchResults struct {
+	// Facets Number of restaurants matching the request with each tag, for every page of the results
+	Facets  *Facets        `json:"facets,omitempty"`
+	Results []SearchResult `json:"results"`
+}
+
+// Table defines model for Table.
+type Table struct {
+	// Capacity Maximum number of guests at the table
+	Capacity int `json:"capacity"`
+
+	// Id ID of the table, unique in the restaurant
+	Id string `json:"id"`
+}
+
+// Tag defines model for Tag.
+type Tag struct {
+	// Category Category of the tag. A restaurant has at most one tag of the price category.
+	Category TagCategory `json:"category"`
+
+	// Id ID of the tag, assigned to the restaurants
+	Id string `json:"id"`
+
+	// Label Label of the tag, shown to the users
+	Label string `json:"label"`
+}
+
+// TagCategory Category of the tag. A restaurant has at most one tag of the price category.
+type TagCategory string
+
+// TagList defines model for TagList.
+type TagList struct {
+	Tags []Tag `json:"tags"`
+}
+
+// Webhook defines model for Webhook.
+type Webhook struct {
+	// CreatedAt Time the subscription was created
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Events Types of the events delivered to the URL
+	Events []WebhookEventType `json:"events"`
+
+	// Id ID of the webhook subscription
+	Id string `json:"id"`
+
+	// Secret Secret the signatures of the events are keyed with, never returned
+	Secret *string `json:"secret,omitempty"`
+
+	// Url HTTP or HTTPS URL the events are POSTed to
+	Url string `json:"url"`
+}
+
+// WebhookEvent defines model for WebhookEvent.
+type WebhookEvent struct {
+	// CreatedAt Time of the change
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Id ID of the event, the same in every delivery and redelivery of the event
+	Id           string           `json:"id"`
+	Restaurant   *Restaurant      `json:"restaurant,omitempty"`
+	RestaurantId string           `json:"restaurantId"`
+	Type         WebhookEventType `json:"type"`
+}
+
+// WebhookEventType defines model for WebhookEventType.
+type WebhookEventType string
+
+// WebhookList defines model for WebhookList.
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// ApiKeyId defines model for ApiKeyId.
+type ApiKeyId = string
+
+// DeliveryId defines model for DeliveryId.
+type DeliveryId = string
+
+// IdempotencyKey defines model for IdempotencyKey.
+type IdempotencyKey = string
+
+// IfMatch defines model for IfMatch.
+type IfMatch = string
+
+// Limit defines model for Limit.
+type Limit = int32
+
+// NextToken defines model for NextToken.
+type NextToken = string
+
+// OpenAt defines model for OpenAt.
+type OpenAt = time.Time
+
+// PhotoId defines model for PhotoId.
+type PhotoId = string
+
+// ReservationId defines model for ReservationId.
+type ReservationId = string
+
 // RestaurantId defines model for RestaurantId.
 type RestaurantId = string
 
-// N404Error defines model for 404Error.
-type N404Error struct {
-	Message *string `json:"message,omitempty"`
+// ReviewId defines model for ReviewId.
+type ReviewId = string
+
+// RevisionNumber defines model for RevisionNumber.
+type RevisionNumber = int64
+
+// TagId defines model for TagId.
+type TagId = string
+
+// Tags defines model for Tags.
+type Tags = []string
+
+// WebhookId defines model for WebhookId.
+type WebhookId = string
+
+// GetParams defines parameters for Get.
+type GetParams struct {
+	// Limit The maximum number of items to return
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// NextToken The token returned by the previous page
+	NextToken *NextToken `form:"nextToken,omitempty" json:"nextToken,omitempty"`
+
+	// OpenAt Only return the restaurants open at this time (RFC 3339)
+	OpenAt *OpenAt `form:"openAt,omitempty" json:"openAt,omitempty"`
+
+	// Tags Only return the restaurants with every tag, with the facet counts of the matching restaurants. The list is then ordered by ID.
+	Tags *Tags `form:"tags,omitempty" json:"tags,omitempty"`
+
+	// Facets Return the facet counts of every restaurant, without filtering by tags
+	Facets *bool `form:"facets,omitempty" json:"facets,omitempty"`
+}
+
+// PostParams defines parameters for Post.
+type PostParams struct {
+	// Force Create the restaurant even when it is probably a duplicate of an existing one
+	Force *bool `form:"force,omitempty" json:"force,omitempty"`
+
+	// IdempotencyKey Unique key of the request, a retry with the same key and body returns the original response
+	IdempotencyKey *IdempotencyKey `json:"Idempotency-Key,omitempty"`
 }
 
+// GetDuplicatesParams defines parameters for GetDuplicates.
+type GetDuplicatesParams struct {
+	// Distance Maximum distance in meters between duplicates, the configured duplicate distance by default
+	Distance *float64 `form:"distance,omitempty" json:"distance,omitempty"`
+}
+
+// GetExportParams defines parameters for GetExport.
+type GetExportParams struct {
+	// Format Format of the export
+	Format *GetExportParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+}
+
+// GetExportParamsFormat defines parameters for GetExport.
+type GetExportParamsFormat string
+
+// GetNearbyParams defines parameters for GetNearby.
+type GetNearbyParams struct {
+	// Lat Latitude of the center of the search
+	Lat float64 `form:"lat" json:"lat"`
+
+	// Lon Longitude of the center of the search
+	Lon float64 `form:"lon" json:"lon"`
+
+	// Radius Radius of the search in meters
+	Radius *float64 `form:"radius,omitempty" json:"radius,omitempty"`
+
+	// OpenAt Only return the restaurants open at this time (RFC 3339)
+	OpenAt *OpenAt `form:"openAt,omitempty" json:"openAt,omitempty"`
+}
+
+// GetSearchParams defines parameters for GetSearch.
+type GetSearchParams struct {
+	// Q Words to search for. Every word must match, the last one also as a prefix
+	Q string `form:"q" json:"q"`
+
+	// Limit The maximum number of items to return
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Tags Only return the restaurants with every tag, with the facet counts of the matching restaurants. The list is then ordered by ID.
+	Tags *Tags `form:"tags,omitempty" json:"tags,omitempty"`
+}
+
+// DeleteRestaurantIdParams defines parameters for DeleteRestaurantId.
+type DeleteRestaurantIdParams struct {
+	// IfMatch Only modify the restaurant if its current ETag is one of the listed ETags
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// PatchRestaurantIdApplicationMergePatchPlusJSONBody defines parameters for PatchRestaurantId.
+type PatchRestaurantIdApplicationMergePatchPlusJSONBody = map[string]interface{}
+
+// PatchRestaurantIdParams defines parameters for PatchRestaurantId.
+type PatchRestaurantIdParams struct {
+	// IfMatch Only modify the restaurant if its current ETag is one of the listed ETags
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// GetRestaurantIdHistoryParams defines parameters for GetRestaurantIdHistory.
+type GetRestaurantIdHistoryParams struct {
+	// Limit The maximum number of items to return
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// NextToken The token returned by the previous page
+	NextToken *NextToken `form:"nextToken,omitempty" json:"nextToken,omitempty"`
+}
+
+// PostRestaurantIdRevertRevisionParams defines parameters for PostRestaurantIdRevertRevision.
+type PostRestaurantIdRevertRevisionParams struct {
+	// IfMatch Only modify the restaurant if its current ETag is one of the listed ETags
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// GetRestaurantIdReservationsAvailabilityParams defines parameters for GetRestaurantIdReservationsAvailability.
+type GetRestaurantIdReservationsAvailabilityParams struct {
+	// Date Date in the timezone of the restaurant (YYYY-MM-DD)
+	Date string `form:"date" json:"date"`
+
+	// PartySize Number of guests
+	PartySize int `form:"partySize" json:"partySize"`
+}
+
+// GetRestaurantIdReviewsParams defines parameters for GetRestaurantIdReviews.
+type GetRestaurantIdReviewsParams struct {
+	// Limit The maximum number of items to return
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// NextToken The token returned by the previous page
+	NextToken *NextToken `form:"nextToken,omitempty" json:"nextToken,omitempty"`
+}
+
+// PostRestaurantIdParams defines parameters for PostRestaurantId.
+type PostRestaurantIdParams struct {
+	// IfMatch Only modify the restaurant if its current ETag is one of the listed ETags
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// PatchRestaurantIdApplicationMergePatchPlusJSONRequestBody defines body for PatchRestaurantId for application/merge-patch+json ContentType.
+type PatchRestaurantIdApplicationMergePatchPlusJSONRequestBody = PatchRestaurantIdApplicationMergePatchPlusJSONBody
+
+// PostImportJSONBody defines parameters for PostImport.
+type PostImportJSONBody = []Restaurant
+
 // PostJSONRequestBody defines body for Post for application/json ContentType.
 type PostJSONRequestBody = Restaurant
 
+// PostApikeysJSONRequestBody defines body for PostApikeys for application/json ContentType.
+type PostApikeysJSONRequestBody = ApiKeyRequest
+
+// PostImportJSONRequestBody defines body for PostImport for application/json ContentType.
+type PostImportJSONRequestBody = PostImportJSONBody
+
+// PostTagsJSONRequestBody defines body for PostTags for application/json ContentType.
+type PostTagsJSONRequestBody = Tag
+
+// PostWebhooksJSONRequestBody defines body for PostWebhooks for application/json ContentType.
+type PostWebhooksJSONRequestBody = Webhook
+
 // PostRestaurantIdJSONRequestBody defines body for PostRestaurantId for application/json ContentType.
 type PostRestaurantIdJSONRequestBody = Restaurant
+
+// PostRestaurantIdMenuJSONRequestBody defines body for PostRestaurantIdMenu for application/json ContentType.
+type PostRestaurantIdMenuJSONRequestBody = Menu
+
+// PostRestaurantIdPhotosOrderJSONRequestBody defines body for PostRestaurantIdPhotosOrder for application/json ContentType.
+type PostRestaurantIdPhotosOrderJSONRequestBody = PhotoOrder
+
+// PostRestaurantIdReservationsJSONRequestBody defines body for PostRestaurantIdReservations for application/json ContentType.
+type PostRestaurantIdReservationsJSONRequestBody = Reservation
+
+// PostRestaurantIdReservationsSettingsJSONRequestBody defines body for PostRestaurantIdReservationsSettings for application/json ContentType.
+type PostRestaurantIdReservationsSettingsJSONRequestBody = ReservationSettings
+
+// PostRestaurantIdReservationsReservationIdJSONRequestBody defines body for PostRestaurantIdReservationsReservationId for application/json ContentType.
+type PostRestaurantIdReservationsReservationIdJSONRequestBody = Reservation
+
+// PostRestaurantIdReviewsJSONRequestBody defines body for PostRestaurantIdReviews for application/json ContentType.
+type PostRestaurantIdReviewsJSONRequestBody = Review
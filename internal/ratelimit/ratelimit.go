@@ -0,0 +1,107 @@
+// Package ratelimit limits the rate of requests of a client with a token
+// bucket: the bucket holds up to burst tokens, refilled at rate tokens per
+// second, and each request takes a token. A client can make burst requests
+// at once, then rate requests per second.
+//
+// The buckets are in memory, so each instance of the service limits the
+// requests it receives.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the full buckets are removed, as a full bucket
+// is the same as no bucket
+const sweepInterval = time.Minute
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, when the request
+	// is not allowed
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	rate    float64
+	burst   float64
+	updated time.Time
+}
+
+// refill adds the tokens accumulated since the last update.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.updated = now
+	}
+}
+
+// Limiter is the set of token buckets of the clients. It is safe for
+// concurrent use.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{buckets: map[string]*bucket{}}
+}
+
+// Take takes a token from the bucket of the key, created full. The rate and
+// burst of the bucket are updated when they change, keeping its tokens.
+func (l *Limiter) Take(key string, rate float64, burst int, now time.Time) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.rate, b.burst = rate, float64(burst)
+	b.refill(now)
+
+	result := Result{Limit: burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((b.burst - b.tokens) / rate)
+	return result
+}
+
+// sweep removes the buckets that are full again, at most once per sweep
+// interval.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepInterval {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= b.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Take(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		takes   []time.Duration
+		results []Result
+	}{
+		{
+			name:  "burst",
+			takes: []time.Duration{0, 0, 0},
+			results: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: time.Second, RetryAfter: 500 * time.Millisecond},
+			},
+		},
+		{
+			name:  "refilled",
+			takes: []time.Duration{0, 0, 250 * time.Millisecond, 500 * time.Millisecond},
+			results: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: 750 * time.Millisecond, RetryAfter: 250 * time.Millisecond},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second},
+			},
+		},
+		{
+			name:  "full bucket is not overfilled",
+			takes: []time.Duration{0, time.Hour},
+			results: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond},
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			l := New()
+			for i, take := range tc.takes {
+				assert.Equal(t, tc.results[i], l.Take("key", 2, 2, start.Add(take)), "take %d", i)
+			}
+		})
+	}
+}
+
+func Test_TakeKeys(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+
+	l := New()
+	assert.True(t, l.Take("key1", 1, 1, now).Allowed)
+	assert.False(t, l.Take("key1", 1, 1, now).Allowed)
+
+	// Each key has its own bucket
+	assert.True(t, l.Take("key2", 1, 1, now).Allowed)
+}
+
+func Test_Sweep(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+
+	l := New()
+	l.Take("idle", 1, 10, now)
+	l.Take("busy", 0.001, 10, now)
+
+	// The bucket of idle is full again after 1s, the other one is not
+	l.Take("other", 1, 1, now.Add(sweepInterval))
+	assert.NotContains(t, l.buckets, "idle")
+	assert.Contains(t, l.buckets, "busy")
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/fold"
+	"strings"
+)
+
+// stopWords are not indexed, they occur in too many restaurants to rank them
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "at": true, "by": true, "for": true,
+	"in": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"with": true,
+}
+
+// tokens splits the text into folded words, leaving out the stop words.
+func tokens(text string) []string {
+	words := fold.Words(text)
+
+	result := words[:0]
+	for _, w := range words {
+		if !stopWords[w] {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// stem reduces an English word to its stem with step 1 of the Porter
+// stemming algorithm, which removes plurals and -ed and -ing endings:
+// "noodles" becomes "noodle" and "grilled" becomes "grill".
+// Words that are not ASCII letters are returned as they are.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	// Step 1a
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"):
+	case strings.HasSuffix(word, "s"):
+		word = word[:len(word)-1]
+	}
+
+	// Step 1b
+	removed := false
+	switch {
+	case strings.HasSuffix(word, "eed"):
+		if measure(word[:len(word)-3]) > 0 {
+			word = word[:len(word)-1]
+		}
+	case strings.HasSuffix(word, "ed") && hasVowel(word[:len(word)-2]):
+		word, removed = word[:len(word)-2], true
+	case strings.HasSuffix(word, "ing") && hasVowel(word[:len(word)-3]):
+		word, removed = word[:len(word)-3], true
+	}
+	if removed {
+		switch {
+		case strings.HasSuffix(word, "at"), strings.HasSuffix(word, "bl"), strings.HasSuffix(word, "iz"):
+			word += "e"
+		case doubleConsonant(word) && !strings.HasSuffix(word, "l") && !strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "z"):
+			word = word[:len(word)-1]
+		case measure(word) == 1 && cvc(word):
+			word += "e"
+		}
+	}
+
+	// Step 1c
+	if strings.HasSuffix(word, "y") && hasVowel(word[:len(word)-1]) {
+		word = word[:len(word)-1] + "i"
+	}
+
+	return word
+}
+
+// consonant reports whether the letter at i is a consonant. Y is a
+// consonant when it follows a vowel or starts the word.
+func consonant(word string, i int) bool {
+	switch word[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !consonant(word, i-1)
+	}
+	return true
+}
+
+// measure returns the number of vowel-consonant sequences in the word.
+func measure(word string) int {
+	m := 0
+	vowel := false
+	for i := range word {
+		if consonant(word, i) {
+			if vowel {
+				m++
+			}
+			vowel = false
+		} else {
+			vowel = true
+		}
+	}
+	return m
+}
+
+func hasVowel(word string) bool {
+	for i := range word {
+		if !consonant(word, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func doubleConsonant(word string) bool {
+	n := len(word)
+	return n >= 2 && word[n-1] == word[n-2] && consonant(word, n-1)
+}
+
+// cvc reports whether the word ends with consonant-vowel-consonant, where
+// the last consonant is not w, x or y.
+func cvc(word string) bool {
+	n := len(word)
+	if n < 3 || !consonant(word, n-1) || consonant(word, n-2) || !consonant(word, n-3) {
+		return false
+	}
+	last := word[n-1]
+	return last != 'w' && last != 'x' && last != 'y'
+}
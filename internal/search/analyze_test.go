@@ -0,0 +1,82 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Tokens(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		text   string
+		tokens []string
+	}{
+		{
+			name:   "words",
+			text:   "Ramen Bar",
+			tokens: []string{"ramen", "bar"},
+		},
+		{
+			name:   "punctuation and stop words",
+			text:   "Fish & chips, on the Rooftop!",
+			tokens: []string{"fish", "chips", "rooftop"},
+		},
+		{
+			name:   "diacritics",
+			text:   "Café Crème Brûlée",
+			tokens: []string{"cafe", "creme", "brulee"},
+		},
+		{
+			name:   "numbers",
+			text:   "Pier 39",
+			tokens: []string{"pier", "39"},
+		},
+		{
+			name:   "empty",
+			tokens: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.tokens, tokens(tc.text))
+		})
+	}
+}
+
+func Test_Stem(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		word string
+		stem string
+	}{
+		{word: "restaurants", stem: "restaurant"},
+		{word: "glasses", stem: "glass"},
+		{word: "pastries", stem: "pastri"},
+		{word: "pastry", stem: "pastri"},
+		{word: "grilled", stem: "grill"},
+		{word: "grilling", stem: "grill"},
+		{word: "agreed", stem: "agree"},
+		{word: "hopping", stem: "hop"},
+		{word: "hoping", stem: "hope"},
+		{word: "troubled", stem: "trouble"},
+		{word: "bbq", stem: "bbq"},
+		{word: "sushi", stem: "sushi"},
+		{word: "ñoquis", stem: "ñoquis"},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.word, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.stem, stem(tc.word))
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Package search is an in-memory full-text index of the restaurants.
+//
+// The name, description and address of a restaurant are split into words,
+// which are folded (lower case, no diacritics) and stemmed. Each term of a
+// restaurant is weighted by the field it occurs in, and results are ranked
+// with BM25. Every word of a query must match; the last one also matches
+// as a prefix, so results can be shown while the user types.
+//
+// The tags of the restaurants are indexed too, so the restaurants with some
+// tags are found without reading every restaurant, and the results come with
+// the number of matching restaurants with each tag (facets).
+//
+// Each tenant has its own index, loaded on the first search of the tenant.
+package search
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field weights: a match in the name counts more than one in the address,
+// which counts more than one in the description
+const (
+	nameWeight        = 3.0
+	addressWeight     = 1.5
+	descriptionWeight = 1.0
+
+	// prefixWeight discounts terms that only match the last word of a
+	// query as a prefix
+	prefixWeight = 0.5
+
+	// BM25 parameters
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Hit is a restaurant matching a query.
+type Hit struct {
+	RestaurantId string
+	Score        float64
+}
+
+// Results are the restaurants matching a search. Facets counts
+// the restaurants with each tag among all the matching restaurants, not only
+// the returned hits.
+type Results struct {
+	Hits   []Hit
+	Facets map[string]int
+}
+
+type document struct {
+	// terms maps the terms of the restaurant to their weighted frequency
+	terms  map[string]float64
+	length float64
+	tags   []string
+}
+
+// Index is safe for concurrent use.
+type Index struct {
+	mu        sync.RWMutex
+	documents map[string]document
+	// postings maps a term to the restaurants it occurs in and its weighted
+	// frequency in them
+	postings map[string]map[string]float64
+	// terms is the sorted list of the terms, for prefix matching
+	terms       []string
+	totalLength float64
+	// tags maps a tag to the restaurants that have it
+	tags map[string]map[string]bool
+}
+
+func New() *Index {
+	return &Index{
+		documents: make(map[string]document),
+		postings:  make(map[string]map[string]float64),
+		tags:      make(map[string]map[string]bool),
+	}
+}
+
+// Add indexes the restaurant, replacing the previous version of it.
+// Restaurants without an ID are ignored.
+func (ix *Index) Add(restaurant model.Restaurant) {
+	if restaurant.Id == nil {
+		return
+	}
+	doc := analyze(restaurant)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(*restaurant.Id)
+	for term, tf := range doc.terms {
+		postings, ok := ix.postings[term]
+		if !ok {
+			postings = make(map[string]float64)
+			ix.postings[term] = postings
+			ix.insertTerm(term)
+		}
+		postings[*restaurant.Id] = tf
+	}
+	for _, tag := range doc.tags {
+		tagged, ok := ix.tags[tag]
+		if !ok {
+			tagged = make(map[string]bool)
+			ix.tags[tag] = tagged
+		}
+		tagged[*restaurant.Id] = true
+	}
+	ix.documents[*restaurant.Id] = doc
+	ix.totalLength += doc.length
+}
+
+// Remove removes the restaurant from the index.
+func (ix *Index) Remove(restaurantId string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(restaurantId)
+}
+
+// Len returns the number of restaurants in the index.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.documents)
+}
+
+// Search returns up to limit restaurants matching every word of the query
+// and having every tag, the best match first. Restaurants with the same
+// score are ordered by ID.
+func (ix *Index) Search(query string, tags []string, limit int) Results {
+	words := tokens(query)
+	if len(words) == 0 || limit < 1 {
+		return Results{}
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var scores map[string]float64
+	for i, word := range words {
+		wordScores := ix.score(word, i == len(words)-1)
+
+		// Keep the restaurants that match every word so far
+		if scores == nil {
+			scores = wordScores
+		} else {
+			for id := range scores {
+				if s, ok := wordScores[id]; ok {
+					scores[id] += s
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+		if len(scores) == 0 {
+			return Results{}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, s := range scores {
+		if ix.tagged(id, tags) {
+			hits = append(hits, Hit{RestaurantId: id, Score: math.Round(s*1000) / 1000})
+		}
+	}
+	facets := ix.facets(hits)
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].RestaurantId < hits[j].RestaurantId
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return Results{Hits: hits, Facets: facets}
+}
+
+// tagged reports whether the restaurant has every tag.
+func (ix *Index) tagged(restaurantId string, tags []string) bool {
+	for _, tag := range tags {
+		if !ix.tags[tag][restaurantId] {
+			return false
+		}
+	}
+	return true
+}
+
+// facets counts the restaurants of the hits with each tag.
+func (ix *Index) facets(hits []Hit) map[string]int {
+	facets := make(map[string]int)
+	for _, hit := range hits {
+		for _, tag := range ix.documents[hit.RestaurantId].tags {
+			facets[tag]++
+		}
+	}
+	return facets
+}
+
+// score returns the score of the word for the restaurants it matches. A
+// restaurant matching the word through several terms gets the best score.
+func (ix *Index) score(word string, prefix bool) map[string]float64 {
+	scores := make(map[string]float64)
+	match := func(term string, weight float64) {
+		for id, s := range ix.bm25(term) {
+			if s*weight > scores[id] {
+				scores[id] = s * weight
+			}
+		}
+	}
+
+	stemmed := stem(word)
+	match(stemmed, 1)
+	if prefix {
+		for i := sort.SearchStrings(ix.terms, word); i < len(ix.terms) && strings.HasPrefix(ix.terms[i], word); i++ {
+			if ix.terms[i] != stemmed {
+				match(ix.terms[i], prefixWeight)
+			}
+		}
+	}
+	return scores
+}
+
+// bm25 returns the BM25 score of the term for the restaurants it occurs in.
+func (ix *Index) bm25(term string) map[string]float64 {
+	postings := ix.postings[term]
+	if len(postings) == 0 {
+		return nil
+	}
+
+	n := float64(len(ix.documents))
+	df := float64(len(postings))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+	avgLength := ix.totalLength / n
+
+	scores := make(map[string]float64, len(postings))
+	for id, tf := range postings {
+		norm := 1 - b + b*ix.documents[id].length/avgLength
+		scores[id] = idf * tf * (k1 + 1) / (tf + k1*norm)
+	}
+	return scores
+}
+
+func (ix *Index) remove(restaurantId string) {
+	doc, ok := ix.documents[restaurantId]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		postings := ix.postings[term]
+		delete(postings, restaurantId)
+		if len(postings) == 0 {
+			delete(ix.postings, term)
+			ix.deleteTerm(term)
+		}
+	}
+	for _, tag := range doc.tags {
+		tagged := ix.tags[tag]
+		delete(tagged, restaurantId)
+		if len(tagged) == 0 {
+			delete(ix.tags, tag)
+		}
+	}
+	delete(ix.documents, restaurantId)
+	ix.totalLength -= doc.length
+}
+
+func (ix *Index) insertTerm(term string) {
+	i := sort.SearchStrings(ix.terms, term)
+	ix.terms = append(ix.terms, "")
+	copy(ix.terms[i+1:], ix.terms[i:])
+	ix.terms[i] = term
+}
+
+func (ix *Index) deleteTerm(term string) {
+	i := sort.SearchStrings(ix.terms, term)
+	if i < len(ix.terms) && ix.terms[i] == term {
+		ix.terms = append(ix.terms[:i], ix.terms[i+1:]...)
+	}
+}
+
+// analyze returns the weighted terms of the indexed fields of the
+// restaurant, and its tags.
+func analyze(restaurant model.Restaurant) document {
+	doc := document{terms: make(map[string]float64)}
+	add := func(text *string, weight float64) {
+		if text == nil {
+			return
+		}
+		for _, word := range tokens(*text) {
+			doc.terms[stem(word)] += weight
+			doc.length += weight
+		}
+	}
+
+	add(&restaurant.Name, nameWeight)
+	add(restaurant.Description, descriptionWeight)
+	if a := restaurant.Address; a != nil {
+		for _, field := range []*string{a.Line1, a.Line2, a.City, a.State, a.ZipCode, a.Country} {
+			add(field, addressWeight)
+		}
+	}
+
+	if restaurant.Tags != nil {
+		seen := make(map[string]bool)
+		for _, tag := range *restaurant.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				doc.tags = append(doc.tags, tag)
+			}
+		}
+	}
+	return doc
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Search(t *testing.T) {
+	t.Parallel()
+
+	ix := New()
+	for _, r := range testRestaurants() {
+		ix.Add(r)
+	}
+
+	testCases := []struct {
+		name   string
+		query  string
+		tags   []string
+		limit  int
+		ids    []string
+		facets map[string]int
+	}{
+		{
+			name:   "name ranks before description",
+			query:  "ramen",
+			limit:  10,
+			ids:    []string{"r1", "r3"},
+			facets: map[string]int{"japanese": 2, "outdoor-seating": 1, "price-2": 1, "price-3": 1},
+		},
+		{
+			name:   "tags",
+			query:  "ramen",
+			tags:   []string{"outdoor-seating"},
+			limit:  10,
+			ids:    []string{"r3"},
+			facets: map[string]int{"japanese": 1, "outdoor-seating": 1, "price-3": 1},
+		},
+		{
+			name:   "every tag must match",
+			query:  "ramen",
+			tags:   []string{"outdoor-seating", "price-2"},
+			limit:  10,
+			facets: map[string]int{},
+		},
+		{
+			name:  "stemmed",
+			query: "Noodle",
+			limit: 10,
+			ids:   []string{"r1"},
+		},
+		{
+			name:  "prefix of last word",
+			query: "pastr",
+			limit: 10,
+			ids:   []string{"r2"},
+		},
+		{
+			name:  "every word must match",
+			query: "ramen rooftop",
+			limit: 10,
+			ids:   []string{"r3"},
+		},
+		{
+			name:  "prefix of other words",
+			query: "pastr view",
+			limit: 10,
+		},
+		{
+			name:  "address",
+			query: "oakland",
+			limit: 10,
+			ids:   []string{"r2"},
+		},
+		{
+			name:  "diacritics",
+			query: "cafe",
+			limit: 10,
+			ids:   []string{"r2"},
+		},
+		{
+			name:   "facets of every match",
+			query:  "ramen",
+			limit:  1,
+			ids:    []string{"r1"},
+			facets: map[string]int{"japanese": 2, "outdoor-seating": 1, "price-2": 1, "price-3": 1},
+		},
+		{
+			name:  "stop words only",
+			query: "the and",
+			limit: 10,
+		},
+		{
+			name:  "no match",
+			query: "pizza",
+			limit: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			results := ix.Search(tc.query, tc.tags, tc.limit)
+			var ids []string
+			for _, hit := range results.Hits {
+				ids = append(ids, hit.RestaurantId)
+			}
+			assert.Equal(t, tc.ids, ids)
+			if tc.facets != nil {
+				assert.Equal(t, tc.facets, results.Facets)
+			}
+		})
+	}
+}
+
+func Test_AddRemove(t *testing.T) {
+	t.Parallel()
+
+	ix := New()
+	for _, r := range testRestaurants() {
+		ix.Add(r)
+	}
+	assert.Equal(t, 3, ix.Len())
+
+	// Replacing a restaurant removes its old terms
+	id, name := "r1", "Taqueria"
+	ix.Add(model.Restaurant{Id: &id, Name: name})
+	assert.Equal(t, 3, ix.Len())
+	assert.Empty(t, ix.Search("noodle", nil, 10).Hits)
+	if hits := ix.Search("taq", nil, 10).Hits; assert.Len(t, hits, 1) {
+		assert.Equal(t, "r1", hits[0].RestaurantId)
+	}
+	// and its old tags
+	assert.Empty(t, ix.Search("taq", []string{"price-2"}, 10).Hits)
+
+	ix.Remove("r1")
+	ix.Remove("unknown")
+	assert.Equal(t, 2, ix.Len())
+	assert.Empty(t, ix.Search("taqueria", nil, 10).Hits)
+	assert.NotContains(t, ix.terms, "taqueria")
+	ix.Remove("r3")
+	assert.NotContains(t, ix.tags, "price-3")
+
+	// Restaurants without an ID are not indexed
+	ix.Add(model.Restaurant{Name: name})
+	assert.Equal(t, 1, ix.Len())
+}
+
+func testRestaurants() []model.Restaurant {
+	ids := []string{"r1", "r2", "r3"}
+	desc1 := "Hand-pulled noodles in a rich broth"
+	desc2 := "Coffee and pastries with a view"
+	desc3 := "Late night ramen and cocktails"
+	city := "Oakland"
+	tags := [][]string{
+		{"japanese", "price-2"},
+		{"cafe", "outdoor-seating", "price-1", "cafe"},
+		{"japanese", "outdoor-seating", "price-3"},
+	}
+	return []model.Restaurant{
+		{Id: &ids[0], Name: "Ramen Ichiban", Description: &desc1, Tags: &tags[0]},
+		{Id: &ids[1], Name: "Rooftop Café", Description: &desc2, Address: &model.Address{City: &city}, Tags: &tags[1]},
+		{Id: &ids[2], Name: "Rooftop Bar", Description: &desc3, Tags: &tags[2]},
+	}
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"sync"
+)
+
+// Loader returns every restaurant of the tenant in storage.
+type Loader func(tenant string) ([]model.Restaurant, error)
+
+// Tenants holds an index per tenant, so the restaurants of a tenant are only
+// found, and ranked, by the searches of the tenant. The index of a tenant is
+// loaded from storage on its first search. Tenants is safe for concurrent
+// use.
+type Tenants struct {
+	load Loader
+
+	mu      sync.Mutex
+	indexes map[string]*Index
+	loads   map[string]*loading
+}
+
+// loading is a load of the index of a tenant in progress. The restaurants
+// added and removed while the tenant is read from storage are kept, and
+// applied to the index once it is read, as they may be missing from, or
+// older in, what was read.
+type loading struct {
+	done    chan struct{}
+	changes []change
+
+	count int
+	err   error
+}
+
+// change is a restaurant added, or the ID of a restaurant removed.
+type change struct {
+	restaurant   model.Restaurant
+	restaurantId string
+}
+
+func NewTenants(load Loader) *Tenants {
+	return &Tenants{
+		load:    load,
+		indexes: make(map[string]*Index),
+		loads:   make(map[string]*loading),
+	}
+}
+
+// Load loads the index of the tenant from storage, replacing the loaded
+// one, and returns the number of restaurants indexed. A Load of the tenant
+// in progress is waited for instead of loading the tenant again.
+func (t *Tenants) Load(tenant string) (int, error) {
+	t.mu.Lock()
+	if l, ok := t.loads[tenant]; ok {
+		t.mu.Unlock()
+		<-l.done
+		return l.count, l.err
+	}
+	l := &loading{done: make(chan struct{})}
+	t.loads[tenant] = l
+	t.mu.Unlock()
+
+	restaurants, err := t.load(tenant)
+
+	ix := New()
+	for _, restaurant := range restaurants {
+		ix.Add(restaurant)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range l.changes {
+		if c.restaurant.Id != nil {
+			ix.Add(c.restaurant)
+		} else {
+			ix.Remove(c.restaurantId)
+		}
+	}
+	if err == nil {
+		t.indexes[tenant] = ix
+		l.count = ix.Len()
+	}
+	l.err = err
+	delete(t.loads, tenant)
+	close(l.done)
+	return l.count, l.err
+}
+
+// Add indexes the restaurant of the tenant. The restaurants of a tenant
+// whose index is not loaded are indexed when it is loaded.
+func (t *Tenants) Add(tenant string, restaurant model.Restaurant) {
+	if restaurant.Id == nil {
+		return
+	}
+	if ix, ok := t.change(tenant, change{restaurant: restaurant}); ok {
+		ix.Add(restaurant)
+	}
+}
+
+// Remove removes the restaurant of the tenant from the index.
+func (t *Tenants) Remove(tenant, restaurantId string) {
+	if ix, ok := t.change(tenant, change{restaurantId: restaurantId}); ok {
+		ix.Remove(restaurantId)
+	}
+}
+
+// Search returns up to limit restaurants of the tenant matching every word
+// of the query and having every tag, the best match first. The index of the
+// tenant is loaded first when it is not loaded yet.
+func (t *Tenants) Search(tenant, query string, tags []string, limit int) (Results, error) {
+	ix, err := t.loaded(tenant)
+	if err != nil {
+		return Results{}, err
+	}
+	return ix.Search(query, tags, limit), nil
+}
+
+// loaded returns the index of the tenant, loading it when it is not loaded.
+func (t *Tenants) loaded(tenant string) (*Index, error) {
+	if ix, ok := t.index(tenant); ok {
+		return ix, nil
+	}
+	if _, err := t.Load(tenant); err != nil {
+		return nil, err
+	}
+	ix, _ := t.index(tenant)
+	return ix, nil
+}
+
+// change keeps the change for the load of the tenant in progress, if any,
+// and returns the loaded index of the tenant. The loaded index is changed
+// too, so the searches during a reload find the change.
+func (t *Tenants) change(tenant string, c change) (*Index, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.loads[tenant]; ok {
+		l.changes = append(l.changes, c)
+	}
+	ix, ok := t.indexes[tenant]
+	return ix, ok
+}
+
+func (t *Tenants) index(tenant string) (*Index, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ix, ok := t.indexes[tenant]
+	return ix, ok
+}
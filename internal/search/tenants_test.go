@@ -0,0 +1,157 @@
+package search
+
+import (
+	"errors"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_Tenants(t *testing.T) {
+	t.Parallel()
+
+	var loads int32
+	tenants := NewTenants(func(tenant string) ([]model.Restaurant, error) {
+		atomic.AddInt32(&loads, 1)
+		if tenant == "acme" {
+			return testRestaurants(), nil
+		}
+		return nil, nil
+	})
+
+	// The index of a tenant is loaded on its first search
+	results, err := tenants.Search("acme", "ramen", nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"r1", "r3"}, hitIds(results.Hits))
+	_, err = tenants.Search("acme", "ramen", nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// The restaurants of a tenant are not found by the other tenants
+	results, err = tenants.Search("globex", "ramen", nil, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, results.Hits)
+
+	id := "r9"
+	tenants.Add("globex", model.Restaurant{Id: &id, Name: "Ramen Stand"})
+	tenants.Remove("acme", "r1")
+	results, _ = tenants.Search("globex", "ramen", nil, 10)
+	assert.Equal(t, []string{"r9"}, hitIds(results.Hits))
+	results, _ = tenants.Search("acme", "ramen", nil, 10)
+	assert.Equal(t, []string{"r3"}, hitIds(results.Hits))
+}
+
+func Test_TenantsNotLoaded(t *testing.T) {
+	t.Parallel()
+
+	id := "r1"
+	stored := []model.Restaurant{{Id: &id, Name: "Ramen Bar"}}
+	tenants := NewTenants(func(_ string) ([]model.Restaurant, error) {
+		return stored, nil
+	})
+
+	// The restaurants written before the index is loaded are read from
+	// storage when it is
+	tenants.Add("acme", stored[0])
+	tenants.Remove("acme", "r1")
+
+	results, err := tenants.Search("acme", "ramen", nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"r1"}, hitIds(results.Hits))
+}
+
+func Test_TenantsLoad(t *testing.T) {
+	t.Parallel()
+
+	tenants := NewTenants(func(tenant string) ([]model.Restaurant, error) {
+		if tenant == "acme" {
+			return nil, errors.New("an error occurred")
+		}
+		return testRestaurants(), nil
+	})
+
+	count, err := tenants.Load("")
+	assert.Nil(t, err)
+	assert.Equal(t, len(testRestaurants()), count)
+
+	_, err = tenants.Load("acme")
+	if assert.Error(t, err) {
+		assert.Equal(t, "an error occurred", err.Error())
+	}
+	_, err = tenants.Search("acme", "ramen", nil, 10)
+	if assert.Error(t, err) {
+		assert.Equal(t, "an error occurred", err.Error())
+	}
+}
+
+func Test_TenantsLoadInterleaved(t *testing.T) {
+	t.Parallel()
+
+	r1, r2 := "r1", "r2"
+	reading, read := make(chan struct{}), make(chan struct{})
+	tenants := NewTenants(func(_ string) ([]model.Restaurant, error) {
+		close(reading)
+		<-read
+		return []model.Restaurant{{Id: &r1, Name: "Ramen Bar"}}, nil
+	})
+
+	loaded := make(chan int)
+	go func() {
+		count, _ := tenants.Load("acme")
+		loaded <- count
+	}()
+
+	// The restaurants added and removed while the tenant is read from
+	// storage are applied to the index once it is read
+	<-reading
+	tenants.Add("acme", model.Restaurant{Id: &r2, Name: "Ramen Stand"})
+	tenants.Remove("acme", "r1")
+	close(read)
+
+	assert.Equal(t, 1, <-loaded)
+	results, err := tenants.Search("acme", "ramen", nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"r2"}, hitIds(results.Hits))
+}
+
+func Test_TenantsLoadOnce(t *testing.T) {
+	t.Parallel()
+
+	var loads int32
+	read := make(chan struct{})
+	tenants := NewTenants(func(_ string) ([]model.Restaurant, error) {
+		atomic.AddInt32(&loads, 1)
+		<-read
+		return testRestaurants(), nil
+	})
+
+	// The first searches of a tenant share a single load of the tenant
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := tenants.Search("acme", "ramen", nil, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"r1", "r3"}, hitIds(results.Hits))
+		}()
+	}
+	for atomic.LoadInt32(&loads) == 0 {
+		runtime.Gosched()
+	}
+	close(read)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
+
+func hitIds(hits []Hit) []string {
+	ids := []string{}
+	for _, hit := range hits {
+		ids = append(ids, hit.RestaurantId)
+	}
+	return ids
+}
@@ -0,0 +1,142 @@
+// Package thumbnail decodes the photos of the restaurants and resizes them
+// into thumbnails, with the image packages of the standard library.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// The media types of the photos that can be decoded
+const (
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+)
+
+// maxPixels is the maximum number of pixels of a photo, so a small file
+// cannot decode into a huge image
+const maxPixels = 40_000_000
+
+// jpegQuality is the quality of the thumbnails
+const jpegQuality = 85
+
+var (
+	// ErrUnsupported is returned when a photo is not a JPEG or PNG image.
+	ErrUnsupported = errors.New("the photo is not a JPEG or PNG image")
+	// ErrTooLarge is returned when a photo has too many pixels.
+	ErrTooLarge = errors.New("the photo has too many pixels")
+)
+
+// Decode returns the image of the photo and its media type. The type is
+// detected from the content, whatever the client declared.
+func Decode(data []byte) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	if contentType != JPEG && contentType != PNG {
+		return nil, "", ErrUnsupported
+	}
+
+	config, err := decodeConfig(contentType, data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	var img image.Image
+	if contentType == JPEG {
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	} else {
+		img, err = png.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return img, contentType, nil
+}
+
+func decodeConfig(contentType string, data []byte) (image.Config, error) {
+	if contentType == JPEG {
+		return jpeg.DecodeConfig(bytes.NewReader(data))
+	}
+	return png.DecodeConfig(bytes.NewReader(data))
+}
+
+// Generate returns the thumbnail of the image as a JPEG, resized to fit in
+// size by size pixels with its aspect ratio. A smaller image is not
+// enlarged. Transparent pixels are drawn on white.
+func Generate(img image.Image, size int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := fit(bounds.Dx(), bounds.Dy(), size)
+
+	// The image is converted once, so the pixels are averaged from a slice
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(src, width, height), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fit returns the dimensions of a width by height image scaled down to fit
+// in size by size, at least 1 by 1.
+func fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, maxInt(1, height*size/width)
+	}
+	return maxInt(1, width*size/height), size
+}
+
+// resize scales the image down to width by height pixels, each the average
+// of the pixels of the image it covers (a box filter).
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, maxInt((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, maxInt((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
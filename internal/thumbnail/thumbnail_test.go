@@ -0,0 +1,184 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func Test_Decode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		data        []byte
+		contentType string
+		errMsg      string
+	}{
+		{
+			name:        "jpeg",
+			data:        encodeJPEG(t, 64, 48),
+			contentType: JPEG,
+		},
+		{
+			name:        "png",
+			data:        encodePNG(t, 64, 48),
+			contentType: PNG,
+		},
+		{
+			name:   "gif",
+			data:   encodeGIF(t, 64, 48),
+			errMsg: "the photo is not a JPEG or PNG image",
+		},
+		{
+			name:   "text",
+			data:   []byte("not a photo"),
+			errMsg: "the photo is not a JPEG or PNG image",
+		},
+		{
+			name:   "truncated png",
+			data:   encodePNG(t, 64, 48)[:60],
+			errMsg: "the photo is not a JPEG or PNG image: png: invalid format: not enough pixel data",
+		},
+		{
+			name:   "too many pixels",
+			data:   resizedPNG(t, 10000, 10000),
+			errMsg: "the photo has too many pixels",
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			img, contentType, err := Decode(tc.data)
+
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tc.errMsg, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.contentType, contentType)
+			assert.Equal(t, image.Rect(0, 0, 64, 48), img.Bounds())
+		})
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		width, height int
+		size          int
+		thumbnail     image.Rectangle
+	}{
+		{
+			name:      "landscape",
+			width:     640,
+			height:    480,
+			size:      320,
+			thumbnail: image.Rect(0, 0, 320, 240),
+		},
+		{
+			name:      "portrait",
+			width:     480,
+			height:    640,
+			size:      320,
+			thumbnail: image.Rect(0, 0, 240, 320),
+		},
+		{
+			name:      "small image is not enlarged",
+			width:     100,
+			height:    50,
+			size:      320,
+			thumbnail: image.Rect(0, 0, 100, 50),
+		},
+		{
+			name:      "thin image",
+			width:     2000,
+			height:    2,
+			size:      320,
+			thumbnail: image.Rect(0, 0, 320, 1),
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			data, err := Generate(image.NewRGBA(image.Rect(0, 0, tc.width, tc.height)), tc.size)
+			assert.Nil(t, err)
+
+			thumbnail, err := jpeg.Decode(bytes.NewReader(data))
+			if assert.Nil(t, err) {
+				assert.Equal(t, tc.thumbnail, thumbnail.Bounds())
+			}
+		})
+	}
+}
+
+func Test_GenerateAverage(t *testing.T) {
+	t.Parallel()
+
+	// Half black and half transparent, which is drawn on white, averages to
+	// grey
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 2; x++ {
+			img.Set(x, y, color.Black)
+		}
+	}
+
+	data, err := Generate(img, 1)
+	assert.Nil(t, err)
+
+	thumbnail, err := jpeg.Decode(bytes.NewReader(data))
+	if assert.Nil(t, err) {
+		r, g, b, _ := thumbnail.At(0, 0).RGBA()
+		assert.InDelta(t, 127, r>>8, 3)
+		assert.InDelta(t, 127, g>>8, 3)
+		assert.InDelta(t, 127, b>>8, 3)
+	}
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	assert.Nil(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	assert.Nil(t, gif.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	return buf.Bytes()
+}
+
+// resizedPNG returns a small PNG whose header claims the given dimensions,
+// as a decompression bomb would.
+func resizedPNG(t *testing.T, width, height int) []byte {
+	data := encodePNG(t, 1, 1)
+	// The IHDR chunk follows the 8 byte signature: length, type, data, CRC
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	return data
+}
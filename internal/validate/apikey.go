@@ -0,0 +1,44 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the API keys
+const (
+	MaxRateLimit = 1000
+	MinRateLimit = 0.01
+	MaxBurst     = 10000
+)
+
+// ApiKeyRequest trims the name of the API key request and returns the
+// errors of the invalid fields, nil when the request is valid. The limits
+// that are not set are valid, the defaults are used.
+func ApiKeyRequest(request *model.ApiKeyRequest) []model.FieldError {
+	var errs []model.FieldError
+	add := func(field, code, message string) {
+		errs = append(errs, model.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		add("name", Required, "name is required")
+	} else if utf8.RuneCountInString(request.Name) > maxName {
+		add("name", TooLong, fmt.Sprintf("name must be at most %d characters", maxName))
+	}
+
+	if request.RateLimit != nil && (*request.RateLimit < MinRateLimit || *request.RateLimit > MaxRateLimit) {
+		add("rateLimit", OutOfRange, fmt.Sprintf("rateLimit must be between %g and %d", MinRateLimit, MaxRateLimit))
+	}
+	if request.Burst != nil && (*request.Burst < 1 || *request.Burst > MaxBurst) {
+		add("burst", OutOfRange, fmt.Sprintf("burst must be between 1 and %d", MaxBurst))
+	}
+	if request.DailyQuota != nil && *request.DailyQuota < 1 {
+		add("dailyQuota", OutOfRange, "dailyQuota must be at least 1")
+	}
+
+	return errs
+}
@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ApiKeyRequest(t *testing.T) {
+	t.Parallel()
+
+	rate, burst, quota := 10.0, 20, 1000
+	zeroRate, bigBurst, zeroQuota := 0.0, 10001, 0
+
+	testCases := []struct {
+		name    string
+		request model.ApiKeyRequest
+		errs    []model.FieldError
+	}{
+		{
+			name:    "valid",
+			request: model.ApiKeyRequest{Name: "Partner", RateLimit: &rate, Burst: &burst, DailyQuota: &quota},
+		},
+		{
+			name:    "default limits",
+			request: model.ApiKeyRequest{Name: "Partner"},
+		},
+		{
+			name:    "blank name",
+			request: model.ApiKeyRequest{Name: " "},
+			errs:    []model.FieldError{{Field: "name", Code: Required, Message: "name is required"}},
+		},
+		{
+			name:    "limits out of range",
+			request: model.ApiKeyRequest{Name: "Partner", RateLimit: &zeroRate, Burst: &bigBurst, DailyQuota: &zeroQuota},
+			errs: []model.FieldError{
+				{Field: "rateLimit", Code: OutOfRange, Message: "rateLimit must be between 0.01 and 1000"},
+				{Field: "burst", Code: OutOfRange, Message: "burst must be between 1 and 10000"},
+				{Field: "dailyQuota", Code: OutOfRange, Message: "dailyQuota must be at least 1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.errs, ApiKeyRequest(&tc.request))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package validate
+
+import (
+	"regexp"
+)
+
+// postalCodes are the formats of the postal codes of the countries whose
+// format is known. The codes are upper cased before they are matched.
+var postalCodes = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^[0-9]{4}$`),
+	"AU": regexp.MustCompile(`^[0-9]{4}$`),
+	"BE": regexp.MustCompile(`^[0-9]{4}$`),
+	"BR": regexp.MustCompile(`^[0-9]{5}-?[0-9]{3}$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY][0-9][ABCEGHJ-NPRSTV-Z] ?[0-9][ABCEGHJ-NPRSTV-Z][0-9]$`),
+	"CH": regexp.MustCompile(`^[0-9]{4}$`),
+	"DE": regexp.MustCompile(`^[0-9]{5}$`),
+	"DK": regexp.MustCompile(`^[0-9]{4}$`),
+	"ES": regexp.MustCompile(`^[0-9]{5}$`),
+	"FR": regexp.MustCompile(`^[0-9]{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z][0-9][0-9W] ?[0-9AC-FHKNPRTV-Y]{4}$`),
+	"IN": regexp.MustCompile(`^[1-9][0-9]{5}$`),
+	"IT": regexp.MustCompile(`^[0-9]{5}$`),
+	"JP": regexp.MustCompile(`^[0-9]{3}-?[0-9]{4}$`),
+	"MX": regexp.MustCompile(`^[0-9]{5}$`),
+	"NL": regexp.MustCompile(`^[1-9][0-9]{3} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^[0-9]{4}$`),
+	"NZ": regexp.MustCompile(`^[0-9]{4}$`),
+	"PL": regexp.MustCompile(`^[0-9]{2}-[0-9]{3}$`),
+	"PT": regexp.MustCompile(`^[0-9]{4}-[0-9]{3}$`),
+	"SE": regexp.MustCompile(`^[0-9]{3} ?[0-9]{2}$`),
+	"US": regexp.MustCompile(`^[0-9]{5}(-[0-9]{4})?$`),
+}
+
+// genericPostalCode is the format of the postal codes of the other
+// countries, and when the country is unknown: letters, digits, spaces and
+// dashes.
+var genericPostalCode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+
+// postalCodeExamples are shown in the error messages.
+var postalCodeExamples = map[string]string{
+	"AT": "1010", "AU": "2000", "BE": "1000", "BR": "01310-100", "CA": "K1A 0B1",
+	"CH": "8001", "DE": "10115", "DK": "1050", "ES": "28001", "FR": "75001",
+	"GB": "SW1A 1AA", "IE": "D02 X285", "IN": "110001", "IT": "00118", "JP": "100-0001",
+	"MX": "06000", "NL": "1012 AB", "NO": "0150", "NZ": "6011", "PL": "00-950",
+	"PT": "1000-001", "SE": "111 22", "US": "94105 or 94105-1234",
+}
+
+// validPostalCode reports whether the code is a postal code of the country,
+// which is empty when it is unknown.
+func validPostalCode(country, code string) bool {
+	if format, ok := postalCodes[country]; ok {
+		return format.MatchString(code)
+	}
+	return genericPostalCode.MatchString(code)
+}
+
+func postalCodeMessage(country string) string {
+	if example, ok := postalCodeExamples[country]; ok {
+		return "address.zipCode is not a valid postal code of " + country + ", such as " + example
+	}
+	return "address.zipCode must be 2 to 10 letters, digits, spaces or dashes"
+}
@@ -0,0 +1,31 @@
+package validate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"strings"
+)
+
+// Reservation trims the name of the reservation and returns the errors of
+// the invalid fields, nil when the reservation is valid. Whether the start
+// is a free slot of the restaurant is checked when the table is booked.
+func Reservation(reservation *model.Reservation) []model.FieldError {
+	var errs []model.FieldError
+	add := func(field, code, message string) {
+		errs = append(errs, model.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	reservation.Name = strings.TrimSpace(reservation.Name)
+	if reservation.Name == "" {
+		add("name", Required, "name is required")
+	} else if err := text(&reservation.Name, "name", maxName); err != nil {
+		errs = append(errs, *err)
+	}
+	if reservation.PartySize < 1 {
+		add("partySize", OutOfRange, "partySize must be at least 1")
+	}
+	if reservation.Start.IsZero() {
+		add("start", Required, "start is required")
+	}
+
+	return errs
+}
@@ -0,0 +1,53 @@
+package validate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Reservation(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2023, 6, 9, 19, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		reservation model.Reservation
+		reserveName string
+		errs        []model.FieldError
+	}{
+		{
+			name:        "valid",
+			reservation: model.Reservation{Name: " Jane ", PartySize: 2, Start: start},
+			reserveName: "Jane",
+		},
+		{
+			name:        "missing fields",
+			reservation: model.Reservation{Name: " "},
+			errs: []model.FieldError{
+				{Field: "name", Code: Required, Message: "name is required"},
+				{Field: "partySize", Code: OutOfRange, Message: "partySize must be at least 1"},
+				{Field: "start", Code: Required, Message: "start is required"},
+			},
+		},
+		{
+			name:        "long name",
+			reservation: model.Reservation{Name: strings.Repeat("a", 201), PartySize: 4, Start: start},
+			reserveName: strings.Repeat("a", 201),
+			errs:        []model.FieldError{{Field: "name", Code: TooLong, Message: "name must be at most 200 characters"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.errs, Reservation(&tc.reservation))
+			assert.Equal(t, tc.reserveName, tc.reservation.Name)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+)
+
+// Limits of the reviews
+const (
+	MinRating = 1
+	MaxRating = 5
+	maxReview = 4000
+)
+
+// Review trims the text of the review and returns the errors of the
+// invalid fields, nil when the review is valid.
+func Review(review *model.Review) []model.FieldError {
+	var errs []model.FieldError
+
+	if review.Rating < MinRating || review.Rating > MaxRating {
+		errs = append(errs, model.FieldError{
+			Field:   "rating",
+			Code:    OutOfRange,
+			Message: fmt.Sprintf("rating must be between %d and %d", MinRating, MaxRating),
+		})
+	}
+	if err := text(review.Text, "text", maxReview); err != nil {
+		errs = append(errs, *err)
+	}
+
+	return errs
+}
@@ -0,0 +1,53 @@
+package validate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Review(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		review model.Review
+		text   *string
+		errs   []model.FieldError
+	}{
+		{
+			name:   "valid",
+			review: model.Review{Rating: 5, Text: str(" Great pasta ")},
+			text:   str("Great pasta"),
+		},
+		{
+			name:   "no text",
+			review: model.Review{Rating: 1},
+		},
+		{
+			name:   "rating too low",
+			review: model.Review{Rating: 0},
+			errs:   []model.FieldError{{Field: "rating", Code: OutOfRange, Message: "rating must be between 1 and 5"}},
+		},
+		{
+			name:   "rating too high and long text",
+			review: model.Review{Rating: 6, Text: str(strings.Repeat("a", 4001))},
+			text:   str(strings.Repeat("a", 4001)),
+			errs: []model.FieldError{
+				{Field: "rating", Code: OutOfRange, Message: "rating must be between 1 and 5"},
+				{Field: "text", Code: TooLong, Message: "text must be at most 4000 characters"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.errs, Review(&tc.review))
+			assert.Equal(t, tc.text, tc.review.Text)
+		})
+	}
+}
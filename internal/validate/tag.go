@@ -0,0 +1,93 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTags is the maximum number of tags of a restaurant.
+const MaxTags = 30
+
+const maxLabel = 100
+
+// tagId is the ID of a tag: lower case letters, digits and dashes, such as
+// outdoor-seating.
+var tagId = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// Tag normalizes the fields of the tag of the vocabulary and returns the
+// errors of the invalid ones, nil when the tag is valid.
+func Tag(tag *model.Tag) []model.FieldError {
+	var errs []model.FieldError
+	add := func(field, code, message string) {
+		errs = append(errs, model.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	tag.Id = strings.ToLower(strings.TrimSpace(tag.Id))
+	if tag.Id == "" {
+		add("id", Required, "id is required")
+	} else if !tagId.MatchString(tag.Id) {
+		add("id", InvalidTag, "id must be at most 64 lower case letters, digits or dashes, such as outdoor-seating")
+	}
+
+	switch tag.Category {
+	case model.TagCategoryAmenity, model.TagCategoryCuisine, model.TagCategoryPrice:
+	default:
+		add("category", InvalidCategory, "category must be one of amenity, cuisine or price")
+	}
+
+	tag.Label = strings.TrimSpace(tag.Label)
+	if tag.Label == "" {
+		add("label", Required, "label is required")
+	} else if utf8.RuneCountInString(tag.Label) > maxLabel {
+		add("label", TooLong, fmt.Sprintf("label must be at most %d characters", maxLabel))
+	}
+
+	return errs
+}
+
+// Tags returns the errors of the tags of a restaurant that are not in the
+// vocabulary, keyed by ID, nil when every tag is. A restaurant has at most
+// one price level.
+func Tags(tags []string, vocabulary map[string]model.Tag) []model.FieldError {
+	var errs []model.FieldError
+	price := ""
+	for i, id := range tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		tag, ok := vocabulary[id]
+		if !ok {
+			errs = append(errs, model.FieldError{Field: field, Code: UnknownTag, Message: fmt.Sprintf("%s is not a known tag", id)})
+			continue
+		}
+		if tag.Category != model.TagCategoryPrice {
+			continue
+		}
+		if price != "" {
+			errs = append(errs, model.FieldError{
+				Field:   field,
+				Code:    ConflictingTags,
+				Message: fmt.Sprintf("%s conflicts with %s, a restaurant has one price level", id, price),
+			})
+			continue
+		}
+		price = id
+	}
+	return errs
+}
+
+// normalizeTags trims and lower cases the tags and removes the duplicates,
+// keeping the order of the first occurrences.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
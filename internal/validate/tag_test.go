@@ -0,0 +1,96 @@
+package validate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Tag(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		tag  model.Tag
+		errs []model.FieldError
+	}{
+		{
+			name: "valid",
+			tag:  model.Tag{Id: "outdoor-seating", Category: model.TagCategoryAmenity, Label: "Outdoor seating"},
+		},
+		{
+			name: "ID lower cased",
+			tag:  model.Tag{Id: " Thai ", Category: model.TagCategoryCuisine, Label: "Thai"},
+		},
+		{
+			name: "blank fields",
+			tag:  model.Tag{Id: " ", Label: " "},
+			errs: []model.FieldError{
+				{Field: "id", Code: Required, Message: "id is required"},
+				{Field: "category", Code: InvalidCategory, Message: "category must be one of amenity, cuisine or price"},
+				{Field: "label", Code: Required, Message: "label is required"},
+			},
+		},
+		{
+			name: "invalid fields",
+			tag:  model.Tag{Id: "outdoor seating", Category: "diet", Label: strings.Repeat("a", 101)},
+			errs: []model.FieldError{
+				{Field: "id", Code: InvalidTag, Message: "id must be at most 64 lower case letters, digits or dashes, such as outdoor-seating"},
+				{Field: "category", Code: InvalidCategory, Message: "category must be one of amenity, cuisine or price"},
+				{Field: "label", Code: TooLong, Message: "label must be at most 100 characters"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.errs, Tag(&tc.tag))
+		})
+	}
+}
+
+func Test_Tags(t *testing.T) {
+	t.Parallel()
+
+	vocabulary := map[string]model.Tag{
+		"thai":            {Id: "thai", Category: model.TagCategoryCuisine, Label: "Thai"},
+		"outdoor-seating": {Id: "outdoor-seating", Category: model.TagCategoryAmenity, Label: "Outdoor seating"},
+		"price-1":         {Id: "price-1", Category: model.TagCategoryPrice, Label: "$"},
+		"price-2":         {Id: "price-2", Category: model.TagCategoryPrice, Label: "$$"},
+	}
+
+	testCases := []struct {
+		name string
+		tags []string
+		errs []model.FieldError
+	}{
+		{
+			name: "valid",
+			tags: []string{"thai", "outdoor-seating", "price-1"},
+		},
+		{
+			name: "unknown tag",
+			tags: []string{"thai", "vegan"},
+			errs: []model.FieldError{{Field: "tags[1]", Code: UnknownTag, Message: "vegan is not a known tag"}},
+		},
+		{
+			name: "two price levels",
+			tags: []string{"price-1", "thai", "price-2"},
+			errs: []model.FieldError{{Field: "tags[2]", Code: ConflictingTags,
+				Message: "price-2 conflicts with price-1, a restaurant has one price level"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.errs, Tags(tc.tags, vocabulary))
+		})
+	}
+}
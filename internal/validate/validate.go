@@ -0,0 +1,180 @@
+// Package validate checks the fields of a restaurant, and of the other
+// resources sent by the clients, before they are stored.
+//
+// Every invalid field is reported, so a client can show all the errors of
+// a form at once. The text fields are normalized while they are checked:
+// they are trimmed, the country and postal code are upper cased, the
+// formatting characters are removed from the phone number and the tags are
+// lower cased and deduplicated.
+package validate
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/hours"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"golang.org/x/text/language"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error codes of the field errors
+const (
+	Required            = "required"
+	TooLong             = "too_long"
+	InvalidCountry      = "invalid_country"
+	InvalidPhoneNumber  = "invalid_phone_number"
+	InvalidPostalCode   = "invalid_postal_code"
+	InvalidOpeningHours = "invalid_opening_hours"
+	OutOfRange          = "out_of_range"
+	InvalidTag          = "invalid_tag"
+	InvalidCategory     = "invalid_category"
+	TooManyTags         = "too_many_tags"
+	UnknownTag          = "unknown_tag"
+	ConflictingTags     = "conflicting_tags"
+	TooShort            = "too_short"
+	InvalidUrl          = "invalid_url"
+	InvalidEvent        = "invalid_event"
+	// InvalidType is the code of a value of the wrong JSON type, which is
+	// detected when the request body is bound
+	InvalidType = "invalid_type"
+)
+
+// Maximum lengths of the text fields, in characters
+const (
+	maxName        = 200
+	maxDescription = 2000
+	maxLine        = 200
+	maxCity        = 100
+	maxState       = 100
+)
+
+// e164 is a phone number in E.164 format: a plus sign and at most 15
+// digits, the first of which is not zero.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// phoneFormatting are the characters used to format phone numbers, which
+// are removed before the number is checked.
+var phoneFormatting = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// Restaurant normalizes the fields of the restaurant and returns the errors
+// of the invalid ones, nil when the restaurant is valid.
+func Restaurant(restaurant *model.Restaurant) []model.FieldError {
+	var errs []model.FieldError
+	add := func(field, code, message string) {
+		errs = append(errs, model.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	restaurant.Name = strings.TrimSpace(restaurant.Name)
+	if restaurant.Name == "" {
+		add("name", Required, "name is required")
+	} else if utf8.RuneCountInString(restaurant.Name) > maxName {
+		add("name", TooLong, fmt.Sprintf("name must be at most %d characters", maxName))
+	}
+
+	if err := text(restaurant.Description, "description", maxDescription); err != nil {
+		errs = append(errs, *err)
+	}
+
+	if restaurant.PhoneNumber != nil {
+		phone := phoneFormatting.Replace(strings.TrimSpace(*restaurant.PhoneNumber))
+		restaurant.PhoneNumber = &phone
+		if !e164.MatchString(phone) {
+			add("phoneNumber", InvalidPhoneNumber, "phoneNumber must be in E.164 format, such as +14155550123")
+		}
+	}
+
+	if restaurant.Address != nil {
+		errs = append(errs, address(restaurant.Address)...)
+	}
+
+	if restaurant.OpeningHours != nil {
+		if err := hours.Validate("openingHours", *restaurant.OpeningHours); err != nil {
+			add("openingHours", InvalidOpeningHours, err.Error())
+		}
+	}
+
+	if restaurant.Tags != nil {
+		*restaurant.Tags = normalizeTags(*restaurant.Tags)
+		if len(*restaurant.Tags) > MaxTags {
+			add("tags", TooManyTags, fmt.Sprintf("tags must have at most %d tags", MaxTags))
+		}
+	}
+
+	return errs
+}
+
+// address normalizes the fields of the address and returns the errors of
+// the invalid ones. The postal code is only checked against the format of
+// the country when the country is valid.
+func address(a *model.Address) []model.FieldError {
+	var errs []model.FieldError
+	for _, f := range []struct {
+		value *string
+		field string
+		max   int
+	}{
+		{a.Line1, "address.line1", maxLine},
+		{a.Line2, "address.line2", maxLine},
+		{a.City, "address.city", maxCity},
+		{a.State, "address.state", maxState},
+	} {
+		if err := text(f.value, f.field, f.max); err != nil {
+			errs = append(errs, *err)
+		}
+	}
+
+	country := ""
+	if a.Country != nil {
+		*a.Country = strings.ToUpper(strings.TrimSpace(*a.Country))
+		if isCountry(*a.Country) {
+			country = *a.Country
+		} else {
+			errs = append(errs, model.FieldError{
+				Field:   "address.country",
+				Code:    InvalidCountry,
+				Message: "address.country must be an ISO 3166-1 alpha-2 country code, such as US",
+			})
+		}
+	}
+
+	if a.ZipCode != nil {
+		*a.ZipCode = strings.ToUpper(strings.TrimSpace(*a.ZipCode))
+		if !validPostalCode(country, *a.ZipCode) {
+			errs = append(errs, model.FieldError{
+				Field:   "address.zipCode",
+				Code:    InvalidPostalCode,
+				Message: postalCodeMessage(country),
+			})
+		}
+	}
+
+	return errs
+}
+
+// text trims the optional text field and checks its length.
+func text(value *string, field string, max int) *model.FieldError {
+	if value == nil {
+		return nil
+	}
+	*value = strings.TrimSpace(*value)
+	if utf8.RuneCountInString(*value) > max {
+		return &model.FieldError{
+			Field:   field,
+			Code:    TooLong,
+			Message: fmt.Sprintf("%s must be at most %d characters", field, max),
+		}
+	}
+	return nil
+}
+
+// isCountry reports whether the code is an assigned ISO 3166-1 alpha-2
+// country code. Aliases, such as UK for GB, and regions that are not
+// countries, such as EU, are not.
+func isCountry(code string) bool {
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return false
+	}
+	region, err := language.ParseRegion(code)
+	return err == nil && region.IsCountry() && region.String() == code
+}
@@ -0,0 +1,188 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Restaurant(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		restaurant model.Restaurant
+		errs       []model.FieldError
+	}{
+		{
+			name:       "valid",
+			restaurant: model.Restaurant{Name: "Ramen Bar", PhoneNumber: str("+14155550123"), Address: usAddress("94105")},
+		},
+		{
+			name:       "blank name",
+			restaurant: model.Restaurant{Name: "  "},
+			errs:       []model.FieldError{{Field: "name", Code: Required, Message: "name is required"}},
+		},
+		{
+			name:       "name too long",
+			restaurant: model.Restaurant{Name: strings.Repeat("é", 201)},
+			errs:       []model.FieldError{{Field: "name", Code: TooLong, Message: "name must be at most 200 characters"}},
+		},
+		{
+			name:       "description too long",
+			restaurant: model.Restaurant{Name: "Ramen Bar", Description: str(strings.Repeat("a", 2001))},
+			errs:       []model.FieldError{{Field: "description", Code: TooLong, Message: "description must be at most 2000 characters"}},
+		},
+		{
+			name:       "phone number without country code",
+			restaurant: model.Restaurant{Name: "Ramen Bar", PhoneNumber: str("415-555-0123")},
+			errs: []model.FieldError{{Field: "phoneNumber", Code: InvalidPhoneNumber,
+				Message: "phoneNumber must be in E.164 format, such as +14155550123"}},
+		},
+		{
+			name:       "phone number too long",
+			restaurant: model.Restaurant{Name: "Ramen Bar", PhoneNumber: str("+1234567890123456")},
+			errs: []model.FieldError{{Field: "phoneNumber", Code: InvalidPhoneNumber,
+				Message: "phoneNumber must be in E.164 format, such as +14155550123"}},
+		},
+		{
+			name:       "unknown country",
+			restaurant: model.Restaurant{Name: "Ramen Bar", Address: &model.Address{Country: str("XX")}},
+			errs: []model.FieldError{{Field: "address.country", Code: InvalidCountry,
+				Message: "address.country must be an ISO 3166-1 alpha-2 country code, such as US"}},
+		},
+		{
+			name:       "alpha-3 country",
+			restaurant: model.Restaurant{Name: "Ramen Bar", Address: &model.Address{Country: str("USA")}},
+			errs: []model.FieldError{{Field: "address.country", Code: InvalidCountry,
+				Message: "address.country must be an ISO 3166-1 alpha-2 country code, such as US"}},
+		},
+		{
+			name:       "region that is not a country",
+			restaurant: model.Restaurant{Name: "Ramen Bar", Address: &model.Address{Country: str("EU")}},
+			errs: []model.FieldError{{Field: "address.country", Code: InvalidCountry,
+				Message: "address.country must be an ISO 3166-1 alpha-2 country code, such as US"}},
+		},
+		{
+			name:       "postal code of the country",
+			restaurant: model.Restaurant{Name: "Ramen Bar", Address: usAddress("9410")},
+			errs: []model.FieldError{{Field: "address.zipCode", Code: InvalidPostalCode,
+				Message: "address.zipCode is not a valid postal code of US, such as 94105 or 94105-1234"}},
+		},
+		{
+			name:       "postal code without country",
+			restaurant: model.Restaurant{Name: "Ramen Bar", Address: &model.Address{ZipCode: str("#1")}},
+			errs: []model.FieldError{{Field: "address.zipCode", Code: InvalidPostalCode,
+				Message: "address.zipCode must be 2 to 10 letters, digits, spaces or dashes"}},
+		},
+		{
+			name:       "opening hours",
+			restaurant: model.Restaurant{Name: "Ramen Bar", OpeningHours: &[]model.OpeningInterval{{Day: "monday", Open: "9", Close: "17:00"}}},
+			errs: []model.FieldError{{Field: "openingHours", Code: InvalidOpeningHours,
+				Message: "openingHours[0]: open must be a time in HH:MM format"}},
+		},
+		{
+			name:       "too many tags",
+			restaurant: model.Restaurant{Name: "Ramen Bar", Tags: tags(31)},
+			errs:       []model.FieldError{{Field: "tags", Code: TooManyTags, Message: "tags must have at most 30 tags"}},
+		},
+		{
+			name: "every invalid field",
+			restaurant: model.Restaurant{
+				PhoneNumber: str("12"),
+				Address:     &model.Address{City: str(strings.Repeat("a", 101)), Country: str("ZZ"), ZipCode: str("")},
+			},
+			errs: []model.FieldError{
+				{Field: "name", Code: Required, Message: "name is required"},
+				{Field: "phoneNumber", Code: InvalidPhoneNumber, Message: "phoneNumber must be in E.164 format, such as +14155550123"},
+				{Field: "address.city", Code: TooLong, Message: "address.city must be at most 100 characters"},
+				{Field: "address.country", Code: InvalidCountry, Message: "address.country must be an ISO 3166-1 alpha-2 country code, such as US"},
+				{Field: "address.zipCode", Code: InvalidPostalCode, Message: "address.zipCode must be 2 to 10 letters, digits, spaces or dashes"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.errs, Restaurant(&tc.restaurant))
+		})
+	}
+}
+
+func Test_RestaurantNormalized(t *testing.T) {
+	t.Parallel()
+
+	restaurant := model.Restaurant{
+		Name:        "  Ramen Bar ",
+		Description: str(" Noodles\n"),
+		PhoneNumber: str(" +1 (415) 555-0123"),
+		Address: &model.Address{
+			City:    str(" Toronto "),
+			Country: str("ca "),
+			ZipCode: str("m5v 2t6"),
+		},
+		Tags: &[]string{" Thai", "thai", "Price-2 "},
+	}
+
+	assert.Nil(t, Restaurant(&restaurant))
+	assert.Equal(t, "Ramen Bar", restaurant.Name)
+	assert.Equal(t, "Noodles", *restaurant.Description)
+	assert.Equal(t, "+14155550123", *restaurant.PhoneNumber)
+	assert.Equal(t, "Toronto", *restaurant.Address.City)
+	assert.Equal(t, "CA", *restaurant.Address.Country)
+	assert.Equal(t, "M5V 2T6", *restaurant.Address.ZipCode)
+	assert.Equal(t, []string{"thai", "price-2"}, *restaurant.Tags)
+}
+
+func Test_PostalCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		country string
+		code    string
+		valid   bool
+	}{
+		{country: "US", code: "94105-1234", valid: true},
+		{country: "US", code: "94105-12", valid: false},
+		{country: "CA", code: "K1A0B1", valid: true},
+		{country: "CA", code: "D1A 0B1", valid: false},
+		{country: "GB", code: "SW1A 1AA", valid: true},
+		{country: "GB", code: "EC1 1BB", valid: true},
+		{country: "GB", code: "1AA SW1", valid: false},
+		{country: "NL", code: "1012 AB", valid: true},
+		{country: "JP", code: "1000001", valid: true},
+		{country: "DE", code: "1011", valid: false},
+		{country: "KE", code: "00100", valid: true},
+		{code: "SW1A 1AA", valid: true},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.country+" "+tc.code, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.valid, validPostalCode(tc.country, tc.code))
+		})
+	}
+}
+
+func usAddress(zipCode string) *model.Address {
+	return &model.Address{City: str("San Francisco"), State: str("CA"), ZipCode: &zipCode, Country: str("US")}
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func tags(n int) *[]string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag-%d", i)
+	}
+	return &tags
+}
@@ -0,0 +1,64 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the webhook subscriptions
+const (
+	maxUrl    = 2000
+	MinSecret = 16
+	MaxSecret = 256
+)
+
+// Webhook trims the URL of the webhook subscription, removes the duplicate
+// event types and returns the errors of the invalid fields, nil when the
+// subscription is valid.
+func Webhook(webhook *model.Webhook) []model.FieldError {
+	var errs []model.FieldError
+	add := func(field, code, message string) {
+		errs = append(errs, model.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	webhook.Url = strings.TrimSpace(webhook.Url)
+	if webhook.Url == "" {
+		add("url", Required, "url is required")
+	} else if utf8.RuneCountInString(webhook.Url) > maxUrl {
+		add("url", TooLong, fmt.Sprintf("url must be at most %d characters", maxUrl))
+	} else if u, err := url.Parse(webhook.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("url", InvalidUrl, "url must be an absolute HTTP or HTTPS URL")
+	}
+
+	if webhook.Secret == nil || *webhook.Secret == "" {
+		add("secret", Required, "secret is required")
+	} else if n := utf8.RuneCountInString(*webhook.Secret); n < MinSecret {
+		add("secret", TooShort, fmt.Sprintf("secret must be at least %d characters", MinSecret))
+	} else if n > MaxSecret {
+		add("secret", TooLong, fmt.Sprintf("secret must be at most %d characters", MaxSecret))
+	}
+
+	if len(webhook.Events) == 0 {
+		add("events", Required, "events is required")
+	}
+	seen := make(map[model.WebhookEventType]bool, len(webhook.Events))
+	events := make([]model.WebhookEventType, 0, len(webhook.Events))
+	for i, event := range webhook.Events {
+		switch event {
+		case model.RestaurantCreated, model.RestaurantUpdated, model.RestaurantDeleted:
+		default:
+			add(fmt.Sprintf("events[%d]", i), InvalidEvent,
+				fmt.Sprintf("events[%d] must be one of restaurant.created, restaurant.updated or restaurant.deleted", i))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	webhook.Events = events
+
+	return errs
+}
@@ -0,0 +1,66 @@
+package validate
+
+import (
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Webhook(t *testing.T) {
+	t.Parallel()
+
+	created, deleted := model.RestaurantCreated, model.RestaurantDeleted
+
+	testCases := []struct {
+		name    string
+		webhook model.Webhook
+		events  []model.WebhookEventType
+		errs    []model.FieldError
+	}{
+		{
+			name:    "valid",
+			webhook: model.Webhook{Url: " https://partner.example.com/hooks ", Secret: str("0123456789abcdef"), Events: []model.WebhookEventType{created, deleted, created}},
+			events:  []model.WebhookEventType{created, deleted},
+		},
+		{
+			name:    "missing fields",
+			webhook: model.Webhook{Url: " "},
+			events:  []model.WebhookEventType{},
+			errs: []model.FieldError{
+				{Field: "url", Code: Required, Message: "url is required"},
+				{Field: "secret", Code: Required, Message: "secret is required"},
+				{Field: "events", Code: Required, Message: "events is required"},
+			},
+		},
+		{
+			name:    "invalid fields",
+			webhook: model.Webhook{Url: "ftp://partner.example.com", Secret: str("secret"), Events: []model.WebhookEventType{created, "restaurant.viewed"}},
+			events:  []model.WebhookEventType{created, "restaurant.viewed"},
+			errs: []model.FieldError{
+				{Field: "url", Code: InvalidUrl, Message: "url must be an absolute HTTP or HTTPS URL"},
+				{Field: "secret", Code: TooShort, Message: "secret must be at least 16 characters"},
+				{Field: "events[1]", Code: InvalidEvent, Message: "events[1] must be one of restaurant.created, restaurant.updated or restaurant.deleted"},
+			},
+		},
+		{
+			name:    "relative URL and long secret",
+			webhook: model.Webhook{Url: "/hooks", Secret: str(strings.Repeat("s", 257)), Events: []model.WebhookEventType{deleted}},
+			events:  []model.WebhookEventType{deleted},
+			errs: []model.FieldError{
+				{Field: "url", Code: InvalidUrl, Message: "url must be an absolute HTTP or HTTPS URL"},
+				{Field: "secret", Code: TooLong, Message: "secret must be at most 256 characters"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.errs, Webhook(&tc.webhook))
+			assert.Equal(t, tc.events, tc.webhook.Events)
+		})
+	}
+}
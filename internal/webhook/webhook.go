@@ -0,0 +1,302 @@
+// Package webhook delivers the events of the restaurants to the URLs
+// subscribed to them.
+//
+// An event is POSTed as JSON to every webhook subscribed to its type, with
+// the signature of the body in the X-Webhook-Signature header: the hex
+// HMAC-SHA256, keyed with the secret of the webhook, of the
+// X-Webhook-Timestamp header (Unix seconds), a dot and the body. The
+// receivers check the timestamp too, so a captured request cannot be
+// replayed later. A delivery that fails, with an error or a status other
+// than 2xx, is retried with exponential backoff; after the last attempt it
+// is stored as a dead letter, to be redelivered once the receiver is fixed.
+// The subscription is read again before each retry, so the retries stop
+// when it is deleted.
+//
+// The subscriptions of a tenant are cached, so an event does not read
+// every subscription. The retries are in memory, so the deliveries that
+// are being retried when the service stops are lost.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of the deliveries
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Defaults of the retries, used when none are configured
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = time.Minute
+	defaultCacheTTL    = time.Minute
+)
+
+// maxResponseBody is the maximum size of the response of a receiver that
+// is read, so the connection can be reused
+const maxResponseBody = 4096
+
+// ErrDelivery is returned when the receiver cannot be reached or does not
+// respond with a 2xx status. It wraps the reason, which is recorded in the
+// delivery.
+var ErrDelivery = errors.New("the webhook could not be delivered")
+
+// Store is the storage of the subscriptions and of the dead letters.
+// GetWebhook returns dynamo.ErrWebhookNotFound when the subscription was
+// deleted.
+type Store interface {
+	ListWebhooks(tenant string) ([]model.Webhook, error)
+	GetWebhook(tenant, webhookId string) (model.Webhook, error)
+	SaveDeadLetter(tenant string, delivery model.Delivery) error
+}
+
+// Dispatcher delivers the events to the webhooks. It is safe for
+// concurrent use.
+type Dispatcher struct {
+	Store  Store
+	Client *http.Client
+	// MaxAttempts is the number of attempts of a delivery before it is a
+	// dead letter. The delay before a retry is Backoff, doubled after each
+	// attempt up to MaxBackoff.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// CacheTTL is how long the subscriptions of a tenant are cached. The
+	// subscriptions created or deleted through another instance of the
+	// service are seen by the new events after at most CacheTTL.
+	CacheTTL time.Duration
+
+	wg sync.WaitGroup
+
+	mu    sync.Mutex
+	cache map[string]subscriptions
+}
+
+// subscriptions are the cached subscriptions of a tenant. generation
+// counts the times they were forgotten, so a list read before they changed
+// is not cached.
+type subscriptions struct {
+	webhooks   []model.Webhook
+	expiresAt  time.Time
+	generation int
+}
+
+func New(store Store) *Dispatcher {
+	return &Dispatcher{
+		Store:       store,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: defaultMaxAttempts,
+		Backoff:     defaultBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		CacheTTL:    defaultCacheTTL,
+	}
+}
+
+// Publish delivers the event of the tenant to the webhooks of the tenant
+// subscribed to its type, in the background, so the request that changed
+// the restaurant does not wait for the receivers.
+func (d *Dispatcher) Publish(tenant string, event model.WebhookEvent) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		webhooks, err := d.webhooks(tenant)
+		if err != nil {
+			log.Printf("error listing the webhooks of event %s: %s\n", event.Id, err)
+			return
+		}
+		for _, webhook := range webhooks {
+			if !subscribed(webhook, event.Type) {
+				continue
+			}
+			delivery := model.Delivery{Id: uuid.NewString(), WebhookId: webhook.Id, Event: event}
+			d.wg.Add(1)
+			go func(webhook model.Webhook) {
+				defer d.wg.Done()
+				d.retry(tenant, webhook, delivery)
+			}(webhook)
+		}
+	}()
+}
+
+// Forget drops the cached subscriptions of the tenant, after one of them
+// was created or deleted.
+func (d *Dispatcher) Forget(tenant string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache == nil {
+		d.cache = make(map[string]subscriptions)
+	}
+	d.cache[tenant] = subscriptions{generation: d.cache[tenant].generation + 1}
+}
+
+// webhooks returns the subscriptions of the tenant, from the cache while
+// they have not expired.
+func (d *Dispatcher) webhooks(tenant string) ([]model.Webhook, error) {
+	d.mu.Lock()
+	cached := d.cache[tenant]
+	d.mu.Unlock()
+	if cached.webhooks != nil && time.Now().Before(cached.expiresAt) {
+		return cached.webhooks, nil
+	}
+
+	webhooks, err := d.Store.ListWebhooks(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []model.Webhook{}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache == nil {
+		d.cache = make(map[string]subscriptions)
+	}
+	if d.cache[tenant].generation == cached.generation {
+		d.cache[tenant] = subscriptions{webhooks: webhooks, expiresAt: time.Now().Add(d.CacheTTL), generation: cached.generation}
+	}
+	return webhooks, nil
+}
+
+// Wait waits for the deliveries in progress, including their retries.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// retry attempts the delivery until it succeeds or MaxAttempts attempts
+// failed, then stores it as a dead letter. The subscription is read again
+// before each retry: the delivery is dropped when it was deleted, and the
+// subscription read before is used when it cannot be read.
+func (d *Dispatcher) retry(tenant string, webhook model.Webhook, delivery model.Delivery) {
+	delay := d.Backoff
+	for {
+		var err error
+		delivery, err = d.Deliver(webhook, delivery)
+		if err == nil {
+			return
+		}
+		log.Printf("delivery %s of event %s to webhook %s failed (attempt %d): %s\n", delivery.Id, delivery.Event.Id, webhook.Id, delivery.Attempts, err)
+
+		if delivery.Attempts >= d.MaxAttempts {
+			break
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > d.MaxBackoff {
+			delay = d.MaxBackoff
+		}
+
+		current, err := d.Store.GetWebhook(tenant, webhook.Id)
+		switch {
+		case errors.Is(err, dynamo.ErrWebhookNotFound):
+			log.Printf("delivery %s of event %s dropped: webhook %s was deleted\n", delivery.Id, delivery.Event.Id, webhook.Id)
+			return
+		case err != nil:
+			log.Printf("error getting webhook %s of delivery %s: %s\n", webhook.Id, delivery.Id, err)
+		default:
+			webhook = current
+		}
+	}
+
+	if err := d.Store.SaveDeadLetter(tenant, delivery); err != nil {
+		log.Printf("error saving the dead letter of delivery %s: %s\n", delivery.Id, err)
+	}
+}
+
+// Deliver makes one attempt of the delivery to the webhook, and returns the
+// delivery with the outcome of the attempt. ErrDelivery is returned when
+// the attempt failed.
+func (d *Dispatcher) Deliver(webhook model.Webhook, delivery model.Delivery) (model.Delivery, error) {
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.LastStatus, delivery.LastError = nil, nil
+
+	status, err := d.post(webhook, delivery, now)
+	if status != 0 {
+		delivery.LastStatus = &status
+	}
+	if err != nil {
+		msg := err.Error()
+		delivery.LastError = &msg
+		return delivery, fmt.Errorf("%w: %s", ErrDelivery, msg)
+	}
+
+	delivery.DeliveredAt = &now
+	return delivery, nil
+}
+
+// post POSTs the signed event to the URL of the webhook and returns the
+// status of the response, zero when there was none.
+func (d *Dispatcher) post(webhook model.Webhook, delivery model.Delivery, now time.Time) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+	req.Header.Set(DeliveryHeader, delivery.Id)
+	if webhook.Secret != nil {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(*webhook.Secret, timestamp, body))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("the receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256, keyed with the secret, of the timestamp,
+// a dot and the body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribed reports whether the webhook is subscribed to the type of
+// event.
+func subscribed(webhook model.Webhook, eventType model.WebhookEventType) bool {
+	for _, t := range webhook.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
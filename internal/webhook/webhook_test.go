@@ -0,0 +1,260 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/lfroomin/restaurant-container/internal/dynamo"
+	"github.com/lfroomin/restaurant-container/internal/model"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+func Test_Publish(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		// failures is the number of requests the receiver fails before it
+		// succeeds
+		failures  int32
+		eventType model.WebhookEventType
+		// deleteAfter is the number of reads of the subscription before
+		// it is deleted
+		deleteAfter int32
+		requests    int32
+		dead        int
+	}{
+		{
+			name:      "delivered",
+			eventType: model.RestaurantCreated,
+			requests:  1,
+		},
+		{
+			name:      "retried",
+			failures:  2,
+			eventType: model.RestaurantCreated,
+			requests:  3,
+		},
+		{
+			name:      "dead letter",
+			failures:  10,
+			eventType: model.RestaurantCreated,
+			requests:  3,
+			dead:      1,
+		},
+		{
+			name:        "deleted while retried",
+			failures:    10,
+			eventType:   model.RestaurantCreated,
+			deleteAfter: 1,
+			requests:    2,
+		},
+		{
+			name:      "not subscribed",
+			eventType: model.RestaurantDeleted,
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var requests int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				// The signature is checked as a receiver would
+				signature := "sha256=" + Sign(testSecret, r.Header.Get(TimestampHeader), body)
+				assert.Equal(t, signature, r.Header.Get(SignatureHeader))
+				assert.Equal(t, string(tc.eventType), r.Header.Get(EventHeader))
+				var event model.WebhookEvent
+				assert.Nil(t, json.Unmarshal(body, &event))
+				assert.Equal(t, "eventId", event.Id)
+
+				if atomic.AddInt32(&requests, 1) <= tc.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer receiver.Close()
+
+			store := &storeStub{webhooks: []model.Webhook{testWebhook(receiver.URL)}, deleteAfter: tc.deleteAfter}
+			d := testDispatcher(store)
+
+			d.Publish("acme", model.WebhookEvent{Id: "eventId", Type: tc.eventType, RestaurantId: "restId"})
+			d.Wait()
+
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+			if assert.Len(t, store.dead, tc.dead) && tc.dead > 0 {
+				delivery := store.dead[0]
+				assert.Equal(t, 3, delivery.Attempts)
+				assert.Equal(t, "webhookId", delivery.WebhookId)
+				assert.Equal(t, http.StatusInternalServerError, *delivery.LastStatus)
+				assert.Equal(t, "the receiver responded with status 500", *delivery.LastError)
+				assert.Nil(t, delivery.DeliveredAt)
+			}
+		})
+	}
+}
+
+func Test_PublishListError(t *testing.T) {
+	t.Parallel()
+
+	store := &storeStub{error: "an error occurred"}
+	d := testDispatcher(store)
+
+	// The event is not delivered, and not a dead letter
+	d.Publish("acme", model.WebhookEvent{Id: "eventId", Type: model.RestaurantCreated})
+	d.Wait()
+	assert.Empty(t, store.dead)
+}
+
+func Test_PublishCache(t *testing.T) {
+	t.Parallel()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	store := &storeStub{webhooks: []model.Webhook{testWebhook(receiver.URL)}}
+	d := testDispatcher(store)
+	event := model.WebhookEvent{Id: "eventId", Type: model.RestaurantCreated}
+
+	// The subscriptions are listed once for the events of a tenant
+	d.Publish("acme", event)
+	d.Wait()
+	d.Publish("acme", event)
+	d.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&store.lists))
+
+	// Each tenant has its subscriptions
+	d.Publish("other", event)
+	d.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&store.lists))
+
+	// They are listed again after they changed
+	d.Forget("acme")
+	d.Publish("acme", event)
+	d.Wait()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&store.lists))
+
+	// and after they expired
+	d.CacheTTL = 0
+	d.Forget("acme")
+	d.Publish("acme", event)
+	d.Wait()
+	d.Publish("acme", event)
+	d.Wait()
+	assert.Equal(t, int32(5), atomic.LoadInt32(&store.lists))
+}
+
+func Test_Deliver(t *testing.T) {
+	t.Parallel()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer receiver.Close()
+
+	d := testDispatcher(&storeStub{})
+	failed := "the receiver responded with status 500"
+	dead := model.Delivery{Id: "deliveryId", WebhookId: "webhookId", Attempts: 3, LastError: &failed}
+
+	// A redelivery counts as an attempt and clears the previous failure
+	delivery, err := d.Deliver(testWebhook(receiver.URL), dead)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, *delivery.LastStatus)
+	assert.Nil(t, delivery.LastError)
+	assert.NotNil(t, delivery.DeliveredAt)
+
+	delivery, err = d.Deliver(testWebhook(receiver.URL+"/gone"), dead)
+	if assert.Error(t, err) {
+		assert.True(t, errors.Is(err, ErrDelivery))
+		assert.Equal(t, "the webhook could not be delivered: the receiver responded with status 410", err.Error())
+	}
+	assert.Equal(t, http.StatusGone, *delivery.LastStatus)
+	assert.Nil(t, delivery.DeliveredAt)
+
+	// A receiver that cannot be reached has no status
+	receiver.Close()
+	delivery, err = d.Deliver(testWebhook(receiver.URL), dead)
+	assert.True(t, errors.Is(err, ErrDelivery))
+	assert.Nil(t, delivery.LastStatus)
+	assert.NotNil(t, delivery.LastError)
+}
+
+func Test_Sign(t *testing.T) {
+	t.Parallel()
+
+	// Computed with: printf '1760702400.{}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	assert.Equal(t, "c0b3cbfd6921b861da7fd4a23a08b2d239b18f3294052da48fc0afc4c918a874", Sign(testSecret, "1760702400", []byte("{}")))
+}
+
+func testDispatcher(store Store) *Dispatcher {
+	d := New(store)
+	d.MaxAttempts = 3
+	d.Backoff = time.Millisecond
+	d.MaxBackoff = 2 * time.Millisecond
+	return d
+}
+
+func testWebhook(url string) model.Webhook {
+	secret := testSecret
+	return model.Webhook{
+		Id:     "webhookId",
+		Url:    url,
+		Secret: &secret,
+		Events: []model.WebhookEventType{model.RestaurantCreated, model.RestaurantUpdated},
+	}
+}
+
+// storeStub counts the lists of the subscriptions. deleteAfter, when set,
+// is the number of times a subscription is read before it is deleted.
+type storeStub struct {
+	webhooks    []model.Webhook
+	deleteAfter int32
+	error       string
+
+	lists int32
+	gets  int32
+	mu    sync.Mutex
+	dead  []model.Delivery
+}
+
+func (s *storeStub) ListWebhooks(_ string) ([]model.Webhook, error) {
+	atomic.AddInt32(&s.lists, 1)
+	if s.error != "" {
+		return nil, errors.New(s.error)
+	}
+	return s.webhooks, nil
+}
+
+func (s *storeStub) GetWebhook(_, webhookId string) (model.Webhook, error) {
+	gets := atomic.AddInt32(&s.gets, 1)
+	if s.deleteAfter > 0 && gets > s.deleteAfter {
+		return model.Webhook{}, dynamo.ErrWebhookNotFound
+	}
+	for _, webhook := range s.webhooks {
+		if webhook.Id == webhookId {
+			return webhook, nil
+		}
+	}
+	return model.Webhook{}, dynamo.ErrWebhookNotFound
+}
+
+func (s *storeStub) SaveDeadLetter(_ string, delivery model.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dead = append(s.dead, delivery)
+	return nil
+}
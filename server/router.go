@@ -6,9 +6,14 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/lfroomin/restaurant-container/controllers"
+	"github.com/lfroomin/restaurant-container/internal/ratelimit"
 	"io"
 	"log"
+	"net/http"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func NewRouter(env Env) *gin.Engine {
@@ -18,34 +23,202 @@ func NewRouter(env Env) *gin.Engine {
 	config.AllowCredentials = true
 	router.Use(cors.New(config))
 
+	router.Use(controllers.RequestId)
 	router.Use(logRequest)
+	router.Use(controllers.Tenant)
 
+	// The partners authenticate with an API key on every route, and the
+	// limits of a key are shared by the versions of the API
+	apiKey := controllers.ApiKey{
+		ApiKey:  env.ApiKey,
+		Limiter: ratelimit.New(),
+	}
+	router.Use(apiKey.Authenticate)
+
+	// The X-Tenant-Id header is only accepted from the admins of no tenant
+	authn := controllers.Auth{Verifier: env.Verifier, AdminRole: env.AdminRole}
+	router.Use(authn.AuthorizeTenant)
+
+	// The photos of the local store are served by the server, outside of
+	// the versions of the API
+	if env.PhotoDir != "" {
+		router.Static(env.PhotoPath, env.PhotoDir)
+	}
+
+	v1Routes(router.Group("/v1"), env)
+
+	// The routes at the root are the v1 routes from before the API was
+	// versioned, kept until their sunset
+	v1Routes(router.Group("/", deprecated(legacyDeprecation, legacySunset, "/v1")), env)
+
+	return router
+}
+
+// Dates of the deprecation and sunset (removal) of the routes at the root
+var (
+	legacyDeprecation = time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	legacySunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// v1Routes registers the routes of version 1 of the API in the group. A
+// version with different models gets its own function and group, so the
+// versions can be served side by side.
+func v1Routes(rg *gin.RouterGroup, env Env) {
 	restaurant := controllers.Restaurant{
-		Restaurant: env.Restaurant,
-		Location:   env.Location,
+		Restaurant:        env.Restaurant,
+		Location:          env.Location,
+		Index:             env.Index,
+		Tags:              env.Tags,
+		Events:            env.Events,
+		DuplicateDistance: env.DuplicateDistance,
+	}
+
+	idempotency := controllers.Idempotency{
+		Idempotency: env.Idempotency,
+	}
+
+	menu := controllers.Menu{
+		Menu: env.Menu,
 	}
 
-	router.POST("/", restaurant.Create)
+	review := controllers.Review{
+		Review: env.Review,
+	}
 
-	idGrp := router.Group("/:restaurantId")
+	reservation := controllers.Reservation{
+		Restaurant:  env.Restaurant,
+		Reservation: env.Reservation,
+	}
+
+	photo := controllers.Photo{
+		Photo: env.Photo,
+		Blob:  env.Blob,
+	}
+
+	tag := controllers.Tag{
+		Tag: env.Tags,
+	}
+
+	history := controllers.History{
+		History: env.History,
+		Index:   env.Index,
+		Events:  env.Events,
+		Tags:    env.Tags,
+	}
+
+	webhooks := controllers.Webhook{
+		Webhook:       env.Webhook,
+		Deliverer:     env.Deliverer,
+		Subscriptions: env.Subscriptions,
+	}
+
+	// The restaurants can only be changed by authenticated users. The
+	// duplicates, the export and the revision history show the owners and
+	// read every restaurant or revision, so they need authentication too
+	authn := controllers.Auth{
+		Verifier:  env.Verifier,
+		AdminRole: env.AdminRole,
+	}
+
+	apiKeys := controllers.ApiKey{
+		ApiKey:     env.ApiKey,
+		RateLimit:  env.ApiKeyRateLimit,
+		Burst:      env.ApiKeyBurst,
+		DailyQuota: env.ApiKeyDailyQuota,
+	}
+
+	rg.GET("", restaurant.List)
+	rg.POST("", authn.Authenticate, idempotency.Handle, restaurant.Create)
+	rg.GET("/nearby", restaurant.Nearby)
+	rg.GET("/duplicates", authn.Authenticate, restaurant.Duplicates)
+	rg.GET("/search", restaurant.Search)
+	rg.POST("/import", authn.Authenticate, restaurant.Import)
+	rg.GET("/export", authn.Authenticate, authn.RequireAdmin, restaurant.Export)
+	rg.POST("/apikeys", authn.Authenticate, authn.RequireAdmin, apiKeys.Issue)
+	rg.DELETE("/apikeys/:keyId", authn.Authenticate, authn.RequireAdmin, apiKeys.Revoke)
+	rg.GET("/tags", tag.List)
+	rg.POST("/tags", authn.Authenticate, authn.RequireAdmin, tag.Save)
+	rg.DELETE("/tags/:tagId", authn.Authenticate, authn.RequireAdmin, tag.Delete)
+	rg.GET("/webhooks", authn.Authenticate, authn.RequireAdmin, webhooks.List)
+	rg.POST("/webhooks", authn.Authenticate, authn.RequireAdmin, webhooks.Create)
+	rg.DELETE("/webhooks/:webhookId", authn.Authenticate, authn.RequireAdmin, webhooks.Delete)
+	rg.GET("/webhooks/deliveries", authn.Authenticate, authn.RequireAdmin, webhooks.ListDeliveries)
+	rg.POST("/webhooks/deliveries/:deliveryId/redeliver", authn.Authenticate, authn.RequireAdmin, webhooks.Redeliver)
+
+	idGrp := rg.Group("/:restaurantId")
 	idGrp.GET("", restaurant.Read)
-	idGrp.POST("", restaurant.Update)
-	idGrp.DELETE("", restaurant.Delete)
+	idGrp.POST("", authn.Authenticate, restaurant.Update)
+	idGrp.PATCH("", authn.Authenticate, restaurant.Patch)
+	idGrp.DELETE("", authn.Authenticate, restaurant.Delete)
+	idGrp.POST("/restore", authn.Authenticate, restaurant.Restore)
+	idGrp.GET("/history", authn.Authenticate, history.List)
+	idGrp.POST("/revert/:revision", authn.Authenticate, history.Revert)
+	idGrp.GET("/menu", menu.Read)
+	idGrp.POST("/menu", authn.Authenticate, menu.Save)
+	idGrp.DELETE("/menu", authn.Authenticate, menu.Delete)
+	idGrp.GET("/photos", photo.List)
+	idGrp.POST("/photos", authn.Authenticate, photo.Upload)
+	idGrp.POST("/photos/order", authn.Authenticate, photo.Reorder)
+	idGrp.DELETE("/photos/:photoId", authn.Authenticate, photo.Delete)
+	idGrp.GET("/reviews", review.List)
+	idGrp.POST("/reviews", review.Create)
+	idGrp.DELETE("/reviews/:reviewId", authn.Authenticate, review.Delete)
+	idGrp.GET("/reservations/settings", reservation.ReadSettings)
+	idGrp.POST("/reservations/settings", authn.Authenticate, reservation.SaveSettings)
+	idGrp.GET("/reservations/availability", reservation.Availability)
+	idGrp.POST("/reservations", reservation.Create)
+	idGrp.GET("/reservations/:reservationId", authn.Identify, reservation.Read)
+	idGrp.POST("/reservations/:reservationId", authn.Identify, reservation.Update)
+	idGrp.DELETE("/reservations/:reservationId", authn.Identify, reservation.Cancel)
+}
 
-	return router
+// deprecated is a middleware that marks the responses of deprecated routes
+// with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and links
+// to the same route under successor, the prefix of the version replacing
+// them.
+func deprecated(deprecation, sunset time.Time, successor string) gin.HandlerFunc {
+	deprecationHeader := "@" + strconv.FormatInt(deprecation.Unix(), 10)
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecationHeader)
+		c.Header("Sunset", sunsetHeader)
+		c.Header("Link", "<"+path.Join(successor, c.Request.URL.Path)+`>; rel="successor-version"`)
+		c.Next()
+	}
 }
 
+// logRequest logs the request and its body. The multipart bodies of the
+// photo uploads are only logged by their size.
 func logRequest(c *gin.Context) {
-	byteBody, _ := io.ReadAll(c.Request.Body)
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(byteBody))
+	var body string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		body = fmt.Sprintf("multipart, %d bytes", c.Request.ContentLength)
+	} else {
+		byteBody, _ := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(byteBody))
+		body = string(byteBody)
+	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("\n  Request: [%s] %s%s (%s)\n", c.Request.Method, c.Request.Host, c.Request.URL, c.Request.Proto))
-	sb.WriteString(fmt.Sprintf("  Header: %+v\n", c.Request.Header))
-	if len(byteBody) > 0 {
-		sb.WriteString(fmt.Sprintf("  Body: %s\n", string(byteBody)))
+	sb.WriteString(fmt.Sprintf("  RequestId: %s\n", c.Writer.Header().Get(controllers.RequestIdHeader)))
+	sb.WriteString(fmt.Sprintf("  Header: %+v\n", redactHeader(c.Request.Header)))
+	if len(body) > 0 {
+		sb.WriteString(fmt.Sprintf("  Body: %s\n", body))
 	}
 	log.Print(sb.String())
 
 	c.Next()
 }
+
+// redactHeader returns a copy of the header without the credentials, so
+// they are not written to the logs.
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range []string{"Authorization", controllers.ApiKeyHeader, controllers.ReservationTokenHeader} {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
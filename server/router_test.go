@@ -0,0 +1,387 @@
+package server
+
+import (
+	"github.com/lfroomin/restaurant-container/controllers"
+	"github.com/lfroomin/restaurant-container/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_NewRouter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		path        string
+		deprecation string
+		sunset      string
+		link        string
+	}{
+		{
+			name: "v1",
+			path: "/v1/search",
+		},
+		{
+			name:        "legacy",
+			path:        "/search",
+			deprecation: "@1792195200",
+			sunset:      "Fri, 30 Apr 2027 00:00:00 GMT",
+			link:        `</v1/search>; rel="successor-version"`,
+		},
+	}
+
+	router := NewRouter(Env{})
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+
+			router.ServeHTTP(w, r)
+
+			// The query is missing, so the request does not reach storage
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, tc.deprecation, w.Header().Get("Deprecation"))
+			assert.Equal(t, tc.sunset, w.Header().Get("Sunset"))
+			assert.Equal(t, tc.link, w.Header().Get("Link"))
+		})
+	}
+}
+
+func Test_NewRouter_Authentication(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/v1",
+		},
+		{
+			name:   "import",
+			method: http.MethodPost,
+			path:   "/v1/import",
+		},
+		{
+			name:   "update",
+			method: http.MethodPost,
+			path:   "/v1/restId",
+		},
+		{
+			name:   "patch",
+			method: http.MethodPatch,
+			path:   "/v1/restId",
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			path:   "/v1/restId",
+		},
+		{
+			name:   "restore",
+			method: http.MethodPost,
+			path:   "/v1/restId/restore",
+		},
+		{
+			name:   "revert",
+			method: http.MethodPost,
+			path:   "/v1/restId/revert/1",
+		},
+		{
+			name:   "upload photo",
+			method: http.MethodPost,
+			path:   "/v1/restId/photos",
+		},
+		{
+			name:   "reorder photos",
+			method: http.MethodPost,
+			path:   "/v1/restId/photos/order",
+		},
+		{
+			name:   "delete photo",
+			method: http.MethodDelete,
+			path:   "/v1/restId/photos/photoId",
+		},
+		{
+			name:   "save menu",
+			method: http.MethodPost,
+			path:   "/v1/restId/menu",
+		},
+		{
+			name:   "delete menu",
+			method: http.MethodDelete,
+			path:   "/v1/restId/menu",
+		},
+		{
+			name:   "delete review",
+			method: http.MethodDelete,
+			path:   "/v1/restId/reviews/reviewId",
+		},
+		{
+			name:   "save reservation settings",
+			method: http.MethodPost,
+			path:   "/v1/restId/reservations/settings",
+		},
+		{
+			name:   "issue API key",
+			method: http.MethodPost,
+			path:   "/v1/apikeys",
+		},
+		{
+			name:   "duplicates",
+			method: http.MethodGet,
+			path:   "/v1/duplicates",
+		},
+		{
+			name:   "export",
+			method: http.MethodGet,
+			path:   "/v1/export",
+		},
+		{
+			name:   "history",
+			method: http.MethodGet,
+			path:   "/v1/restId/history",
+		},
+		{
+			name:   "revoke API key",
+			method: http.MethodDelete,
+			path:   "/v1/apikeys/keyId",
+		},
+		{
+			name:   "save tag",
+			method: http.MethodPost,
+			path:   "/v1/tags",
+		},
+		{
+			name:   "delete tag",
+			method: http.MethodDelete,
+			path:   "/v1/tags/thai",
+		},
+		{
+			name:   "list webhooks",
+			method: http.MethodGet,
+			path:   "/v1/webhooks",
+		},
+		{
+			name:   "create webhook",
+			method: http.MethodPost,
+			path:   "/v1/webhooks",
+		},
+		{
+			name:   "redeliver",
+			method: http.MethodPost,
+			path:   "/v1/webhooks/deliveries/deliveryId/redeliver",
+		},
+		{
+			name:   "legacy delete",
+			method: http.MethodDelete,
+			path:   "/restId",
+		},
+	}
+
+	router := NewRouter(Env{})
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+
+			router.ServeHTTP(w, r)
+
+			// No verifier is configured, so the request does not reach storage
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func Test_NewRouter_ReservationToken(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		method string
+	}{
+		{
+			name:   "read reservation",
+			method: http.MethodGet,
+		},
+		{
+			name:   "update reservation",
+			method: http.MethodPost,
+		},
+		{
+			name:   "cancel reservation",
+			method: http.MethodDelete,
+		},
+	}
+
+	router := NewRouter(Env{})
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "/v1/restId/reservations/reservationId", nil)
+			r.Header.Set("Authorization", "Bearer user-token")
+
+			router.ServeHTTP(w, r)
+
+			// A guest needs no bearer token, but a token that is sent must
+			// be valid, and no verifier is configured
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+}
+
+func Test_NewRouter_Photos(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "_"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "_", "p1.jpg"), []byte("photo"), 0o644))
+
+	// The photos of the local store are served beside the restaurants
+	router := NewRouter(Env{PhotoDir: dir, PhotoPath: "/photos"})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/photos/_/p1.jpg", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "photo", w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/photos/_/p2.jpg", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_NewRouter_ApiKey(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(Env{})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/search?q=pizza", nil)
+	r.Header.Set(controllers.ApiKeyHeader, "not a key")
+
+	router.ServeHTTP(w, r)
+
+	// A request with an API key that is not valid is rejected on every route
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func Test_NewRouter_Tenant(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		claims *auth.Claims
+	}{
+		{
+			name:   "anonymous read",
+			method: http.MethodGet,
+			path:   "/v1/restId",
+		},
+		{
+			name:   "anonymous list",
+			method: http.MethodGet,
+			path:   "/v1",
+		},
+		{
+			name:   "anonymous export",
+			method: http.MethodGet,
+			path:   "/v1/export",
+		},
+		{
+			name:   "anonymous search",
+			method: http.MethodGet,
+			path:   "/v1/search?q=pizza",
+		},
+		{
+			name:   "read by a principal of no tenant",
+			method: http.MethodGet,
+			path:   "/v1/restId",
+			claims: &auth.Claims{Subject: "user1"},
+		},
+		{
+			name:   "create by a principal of no tenant",
+			method: http.MethodPost,
+			path:   "/v1",
+			claims: &auth.Claims{Subject: "user1"},
+		},
+		{
+			name:   "update by a principal of another tenant",
+			method: http.MethodPost,
+			path:   "/v1/restId",
+			claims: &auth.Claims{Subject: "user1", Tenant: "brand-a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		// scoped variable
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			env := Env{AdminRole: "admin"}
+			if tc.claims != nil {
+				env.Verifier = verifierStub{claims: *tc.claims}
+			}
+			router := NewRouter(env)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			r.Header.Set(controllers.TenantHeader, "brand-b")
+			if tc.claims != nil {
+				r.Header.Set("Authorization", "Bearer user-token")
+			}
+
+			router.ServeHTTP(w, r)
+
+			// No storage is configured, so the request does not reach storage
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+func Test_RedactHeader(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer token")
+	header.Set(controllers.ApiKeyHeader, "rk_keyId_secret")
+	header.Set(controllers.ReservationTokenHeader, "guest-token")
+	header.Set("Accept", "application/json")
+
+	redacted := redactHeader(header)
+
+	assert.Equal(t, "[REDACTED]", redacted.Get("Authorization"))
+	assert.Equal(t, "[REDACTED]", redacted.Get(controllers.ApiKeyHeader))
+	assert.Equal(t, "[REDACTED]", redacted.Get(controllers.ReservationTokenHeader))
+	assert.Equal(t, "application/json", redacted.Get("Accept"))
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+}
+
+type verifierStub struct {
+	claims auth.Claims
+}
+
+func (s verifierStub) Verify(_ string) (auth.Claims, error) {
+	return s.claims, nil
+}
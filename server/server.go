@@ -3,21 +3,65 @@ package server
 import (
 	cfg "github.com/lfroomin/restaurant-container/config"
 	"github.com/lfroomin/restaurant-container/controllers"
+	"github.com/lfroomin/restaurant-container/internal/auth"
 	"github.com/lfroomin/restaurant-container/internal/awsConfig"
+	"github.com/lfroomin/restaurant-container/internal/blob"
 	"github.com/lfroomin/restaurant-container/internal/dynamo"
 	"github.com/lfroomin/restaurant-container/internal/geocode"
+	"github.com/lfroomin/restaurant-container/internal/search"
+	"github.com/lfroomin/restaurant-container/internal/webhook"
 	"log"
 )
 
 func Init(appCfg cfg.Config) {
 	env := newEnv(appCfg)
+
 	r := NewRouter(env)
 	r.Run(appCfg.ServerAddress)
 }
 
 type Env struct {
-	Restaurant controllers.RestaurantStorer
-	Location   controllers.Geocoder
+	Restaurant  controllers.RestaurantStorer
+	Location    controllers.Geocoder
+	Idempotency controllers.IdempotencyStorer
+	Menu        controllers.MenuStorer
+	Review      controllers.ReviewStorer
+	Reservation controllers.ReservationStorer
+	History     controllers.HistoryStorer
+	Index       controllers.SearchIndex
+	Photo       controllers.PhotoStorer
+	Tags        controllers.TagStorer
+
+	// Webhook stores the subscriptions and their failed deliveries. Events
+	// delivers the changes of the restaurants to them, and Deliverer
+	// redelivers the failed deliveries.
+	Webhook   controllers.WebhookStorer
+	Events    controllers.EventPublisher
+	Deliverer controllers.Deliverer
+	// Subscriptions caches the subscriptions for the events, and is told
+	// when they change
+	Subscriptions controllers.SubscriptionCache
+
+	// Blob stores the photos. PhotoDir is the directory of the local store
+	// served under PhotoPath, both empty when the photos are stored in S3.
+	Blob      controllers.BlobStore
+	PhotoDir  string
+	PhotoPath string
+
+	// DuplicateDistance is the distance in meters within which restaurants
+	// with similar names are duplicates
+	DuplicateDistance float64
+
+	// Verifier verifies the bearer tokens, nil when no JWKS is configured
+	Verifier  controllers.TokenVerifier
+	AdminRole string
+
+	// ApiKey stores the API keys of the partners, and their limits when
+	// they are issued without limits
+	ApiKey           controllers.ApiKeyStorer
+	ApiKeyRateLimit  float64
+	ApiKeyBurst      int
+	ApiKeyDailyQuota int
 }
 
 func newEnv(appCfg cfg.Config) Env {
@@ -26,10 +70,76 @@ func newEnv(appCfg cfg.Config) Env {
 		log.Fatal(err)
 	}
 
-	log.Printf("Config: RestaurantsTable: %s  LocationPlaceIndex: %s\n", appCfg.RestaurantsTable, appCfg.PlaceIndex)
+	log.Printf("Config: RestaurantsTable: %s  LocationPlaceIndex: %s  DeletedRetention: %s\n", appCfg.RestaurantsTable, appCfg.PlaceIndex, appCfg.DeletedRetention)
+	log.Printf("Config: IdempotencyTable: %s  IdempotencyTTL: %s  ReviewsTable: %s  ReservationsTable: %s  HistoryTable: %s\n", appCfg.IdempotencyTable, appCfg.IdempotencyTTL, appCfg.ReviewsTable, appCfg.ReservationsTable, appCfg.HistoryTable)
+	log.Printf("Config: DuplicateDistance: %f\n", appCfg.DuplicateDistance)
+	log.Printf("Config: JWKSSource: %s  JWTIssuer: %s  JWTAudience: %s  AdminRole: %s\n", appCfg.JWKSSource, appCfg.JWTIssuer, appCfg.JWTAudience, appCfg.AdminRole)
+	log.Printf("Config: ApiKeysTable: %s  ApiKeyRateLimit: %f  ApiKeyBurst: %d  ApiKeyDailyQuota: %d\n", appCfg.ApiKeysTable, appCfg.ApiKeyRateLimit, appCfg.ApiKeyBurst, appCfg.ApiKeyDailyQuota)
+	log.Printf("Config: PhotoStore: %s  PhotoDir: %s  PhotoBucket: %s  PhotoBaseURL: %s\n", appCfg.PhotoStore, appCfg.PhotoDir, appCfg.PhotoBucket, appCfg.PhotoBaseURL)
+	log.Printf("Config: TagsTable: %s  TagMembersTable: %s  WebhooksTable: %s\n", appCfg.TagsTable, appCfg.TagMembersTable, appCfg.WebhooksTable)
+
+	restaurantStorage := dynamo.New(awsCfg, appCfg.RestaurantsTable, appCfg.HistoryTable, appCfg.TagsTable, appCfg.TagMembersTable, appCfg.DeletedRetention)
 
-	return Env{
-		Restaurant: dynamo.New(awsCfg, appCfg.RestaurantsTable),
-		Location:   geocode.New(awsCfg, appCfg.PlaceIndex),
+	// The search index is in memory, so it is rebuilt from storage on
+	// startup for the default tenant, and on their first search for the
+	// other tenants
+	index := search.NewTenants(controllers.LoadRestaurants(restaurantStorage))
+	count, err := index.Load(dynamo.DefaultTenant)
+	if err != nil {
+		log.Printf("error building the search index: %s\n", err)
 	}
+	log.Printf("Search index: %d restaurants\n", count)
+
+	webhookStorage := dynamo.NewWebhook(awsCfg, appCfg.WebhooksTable)
+	dispatcher := webhook.New(webhookStorage)
+
+	env := Env{
+		Restaurant:  restaurantStorage,
+		Location:    geocode.New(awsCfg, appCfg.PlaceIndex),
+		Idempotency: dynamo.NewIdempotency(awsCfg, appCfg.IdempotencyTable, appCfg.IdempotencyTTL),
+		Review:      dynamo.NewReview(awsCfg, appCfg.ReviewsTable, appCfg.RestaurantsTable),
+		Reservation: dynamo.NewReservation(awsCfg, appCfg.ReservationsTable, appCfg.RestaurantsTable),
+		Index:       index,
+		Tags:        dynamo.NewTag(awsCfg, appCfg.TagsTable, appCfg.TagMembersTable),
+		Webhook:     webhookStorage,
+		Events:      dispatcher,
+		Deliverer:   dispatcher,
+		// The dispatcher caches the subscriptions of the events
+		Subscriptions: dispatcher,
+		// The menu is stored in the restaurant item
+		Menu: restaurantStorage,
+		// The revision log is written along with the restaurant
+		History: restaurantStorage,
+		// The photos are listed in the restaurant item
+		Photo:             restaurantStorage,
+		DuplicateDistance: appCfg.DuplicateDistance,
+		AdminRole:         appCfg.AdminRole,
+		ApiKey:            dynamo.NewApiKey(awsCfg, appCfg.ApiKeysTable),
+		ApiKeyRateLimit:   appCfg.ApiKeyRateLimit,
+		ApiKeyBurst:       appCfg.ApiKeyBurst,
+		ApiKeyDailyQuota:  appCfg.ApiKeyDailyQuota,
+	}
+
+	switch appCfg.PhotoStore {
+	case "s3":
+		env.Blob = blob.NewS3(awsCfg, appCfg.PhotoBucket, appCfg.PhotoBaseURL)
+	case "local":
+		env.Blob = blob.NewLocal(appCfg.PhotoDir, appCfg.PhotoBaseURL)
+		env.PhotoDir, env.PhotoPath = appCfg.PhotoDir, appCfg.PhotoBaseURL
+	default:
+		log.Fatalf("PHOTO_STORE must be local or s3, not %q", appCfg.PhotoStore)
+	}
+
+	// Without a key set no token can be verified, so every write is rejected
+	if appCfg.JWKSSource == "" {
+		log.Println("JWKS_SOURCE is not set, the restaurants cannot be modified")
+		return env
+	}
+	jwks, err := auth.LoadJWKS(appCfg.JWKSSource)
+	if err != nil {
+		log.Fatal(err)
+	}
+	env.Verifier = auth.NewVerifier(jwks, appCfg.JWTIssuer, appCfg.JWTAudience)
+
+	return env
 }